@@ -0,0 +1,92 @@
+package backup
+
+import (
+	"net"
+	"sync"
+	"time"
+
+	"github.com/ztp-server/backend/models"
+)
+
+// DefaultWorkers is the number of backups run at once when the settings don't say
+const DefaultWorkers = 4
+
+// limitRetry is how long a backup held back by a vendor or subnet cap waits before
+// trying again
+const limitRetry = 2 * time.Second
+
+// limiter enforces the per-vendor and per-subnet concurrency caps and the global start
+// rate. The worker count itself is enforced by the job queue.
+type limiter struct {
+	mu      sync.Mutex
+	vendors map[string]int
+	subnets map[string]int
+	starts  []time.Time // Backups started within the last minute
+}
+
+func newLimiter() *limiter {
+	return &limiter{
+		vendors: make(map[string]int),
+		subnets: make(map[string]int),
+	}
+}
+
+// acquire takes a slot for the device's vendor and subnet. If a cap or the rate limit
+// is reached it returns how long to wait instead; otherwise the returned func releases
+// the slot.
+func (l *limiter) acquire(device *models.Device, settings *models.Settings) (func(), time.Duration) {
+	vendor := device.Vendor
+	subnet := subnetKey(device.IP)
+	now := time.Now()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if settings.BackupVendorLimit > 0 && vendor != "" && l.vendors[vendor] >= settings.BackupVendorLimit {
+		return nil, limitRetry
+	}
+	if settings.BackupSubnetLimit > 0 && subnet != "" && l.subnets[subnet] >= settings.BackupSubnetLimit {
+		return nil, limitRetry
+	}
+
+	if settings.BackupRateLimit > 0 {
+		cutoff := now.Add(-time.Minute)
+		for len(l.starts) > 0 && !l.starts[0].After(cutoff) {
+			l.starts = l.starts[1:]
+		}
+		if len(l.starts) >= settings.BackupRateLimit {
+			return nil, l.starts[0].Add(time.Minute).Sub(now)
+		}
+		l.starts = append(l.starts, now)
+	}
+
+	l.vendors[vendor]++
+	l.subnets[subnet]++
+	return func() {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		if l.vendors[vendor]--; l.vendors[vendor] <= 0 {
+			delete(l.vendors, vendor)
+		}
+		if l.subnets[subnet]--; l.subnets[subnet] <= 0 {
+			delete(l.subnets, subnet)
+		}
+	}, 0
+}
+
+// subnetKey returns the /24 a device address belongs to
+func subnetKey(ip string) string {
+	addr := net.ParseIP(ip).To4()
+	if addr == nil {
+		return ""
+	}
+	return addr.Mask(net.CIDRMask(24, 32)).String()
+}
+
+// workers returns the configured worker pool size
+func workers(settings *models.Settings) int {
+	if settings.BackupWorkers > 0 {
+		return settings.BackupWorkers
+	}
+	return DefaultWorkers
+}
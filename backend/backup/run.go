@@ -0,0 +1,253 @@
+package backup
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"time"
+
+	"github.com/ztp-server/backend/models"
+	"github.com/ztp-server/backend/ws"
+)
+
+// maxRuns is how many bulk runs are remembered for progress queries
+const maxRuns = 20
+
+// Device states within a bulk run
+const (
+	RunDevicePending   = "pending"
+	RunDeviceRunning   = "running"
+	RunDeviceCompleted = "completed"
+	RunDeviceFailed    = "failed"
+)
+
+// ErrNoDevices is returned when a bulk run's filter matches no devices
+var ErrNoDevices = errors.New("no devices match the filter")
+
+// Filter selects the devices of a bulk backup run. Empty fields match every device.
+type Filter struct {
+	MACs   []string `json:"macs"`
+	Vendor string   `json:"vendor"`
+	Site   string   `json:"site"`
+	Role   string   `json:"role"`
+	Status string   `json:"status"`
+	Subnet string   `json:"subnet"` // CIDR, e.g. 10.0.0.0/24
+}
+
+// Run tracks the progress of a bulk backup
+type Run struct {
+	ID         string            `json:"id"`
+	Filter     Filter            `json:"filter"`
+	Total      int               `json:"total"`
+	Pending    int               `json:"pending"`
+	Running    int               `json:"running"`
+	Completed  int               `json:"completed"`
+	Failed     int               `json:"failed"`
+	Done       bool              `json:"done"`
+	StartedAt  time.Time         `json:"started_at"`
+	FinishedAt *time.Time        `json:"finished_at,omitempty"`
+	Devices    map[string]string `json:"devices"`          // MAC -> state
+	Errors     map[string]string `json:"errors,omitempty"` // MAC -> last error
+}
+
+// StartRun queues a backup for every device matching the filter. Devices that already
+// have a backup queued share that job.
+func (s *Service) StartRun(filter Filter) (*Run, error) {
+	var subnet *net.IPNet
+	if filter.Subnet != "" {
+		_, parsed, err := net.ParseCIDR(filter.Subnet)
+		if err != nil {
+			return nil, fmt.Errorf("invalid subnet: %s", filter.Subnet)
+		}
+		subnet = parsed
+	}
+
+	devices, err := s.store.ListDevices()
+	if err != nil {
+		return nil, err
+	}
+
+	run := &Run{
+		ID:        newRunID(),
+		Filter:    filter,
+		StartedAt: time.Now(),
+		Devices:   make(map[string]string),
+		Errors:    make(map[string]string),
+	}
+	for i := range devices {
+		if matches(&filter, subnet, &devices[i]) {
+			run.Devices[devices[i].MAC] = RunDevicePending
+		}
+	}
+	if len(run.Devices) == 0 {
+		return nil, ErrNoDevices
+	}
+	run.count()
+
+	s.runsMu.Lock()
+	s.runs[run.ID] = run
+	s.runOrder = append(s.runOrder, run.ID)
+	if len(s.runOrder) > maxRuns {
+		delete(s.runs, s.runOrder[0])
+		s.runOrder = s.runOrder[1:]
+	}
+	snapshot := run.snapshot()
+	s.runsMu.Unlock()
+
+	macs := make([]string, 0, len(run.Devices))
+	for mac := range run.Devices {
+		macs = append(macs, mac)
+	}
+	sort.Strings(macs)
+	for _, mac := range macs {
+		if _, err := s.QueueBackup(mac, 0); err != nil {
+			s.updateRuns(mac, RunDeviceFailed, err.Error())
+		}
+	}
+
+	s.broadcastProgress(snapshot)
+	return snapshot, nil
+}
+
+// GetRun returns a bulk run's progress, or nil if it is unknown
+func (s *Service) GetRun(id string) *Run {
+	s.runsMu.Lock()
+	defer s.runsMu.Unlock()
+	run, ok := s.runs[id]
+	if !ok {
+		return nil
+	}
+	return run.snapshot()
+}
+
+// ListRuns returns recent bulk runs, newest first
+func (s *Service) ListRuns() []Run {
+	s.runsMu.Lock()
+	defer s.runsMu.Unlock()
+	runs := make([]Run, 0, len(s.runOrder))
+	for i := len(s.runOrder) - 1; i >= 0; i-- {
+		runs = append(runs, *s.runs[s.runOrder[i]].snapshot())
+	}
+	return runs
+}
+
+// updateRuns records a device's backup state in every unfinished run that includes it
+func (s *Service) updateRuns(mac, state, errMsg string) {
+	var changed []*Run
+	s.runsMu.Lock()
+	for _, id := range s.runOrder {
+		run := s.runs[id]
+		current, ok := run.Devices[mac]
+		if !ok || run.Done || current == RunDeviceCompleted || current == RunDeviceFailed {
+			continue
+		}
+		run.Devices[mac] = state
+		if errMsg != "" {
+			run.Errors[mac] = errMsg
+		}
+		run.count()
+		changed = append(changed, run.snapshot())
+	}
+	s.runsMu.Unlock()
+
+	for _, run := range changed {
+		s.broadcastProgress(run)
+	}
+}
+
+func (s *Service) broadcastProgress(run *Run) {
+	if s.hub == nil {
+		return
+	}
+	s.hub.BroadcastBackupProgress(ws.BackupProgressPayload{
+		RunID:     run.ID,
+		Total:     run.Total,
+		Pending:   run.Pending,
+		Running:   run.Running,
+		Completed: run.Completed,
+		Failed:    run.Failed,
+		Done:      run.Done,
+	})
+}
+
+// count recomputes the run's totals from its device states
+func (r *Run) count() {
+	r.Total, r.Pending, r.Running, r.Completed, r.Failed = len(r.Devices), 0, 0, 0, 0
+	for _, state := range r.Devices {
+		switch state {
+		case RunDevicePending:
+			r.Pending++
+		case RunDeviceRunning:
+			r.Running++
+		case RunDeviceCompleted:
+			r.Completed++
+		case RunDeviceFailed:
+			r.Failed++
+		}
+	}
+	if !r.Done && r.Completed+r.Failed == r.Total {
+		now := time.Now()
+		r.Done = true
+		r.FinishedAt = &now
+	}
+}
+
+// snapshot returns a copy that is safe to use without the runs lock
+func (r *Run) snapshot() *Run {
+	c := *r
+	c.Devices = make(map[string]string, len(r.Devices))
+	for mac, state := range r.Devices {
+		c.Devices[mac] = state
+	}
+	c.Errors = make(map[string]string, len(r.Errors))
+	for mac, msg := range r.Errors {
+		c.Errors[mac] = msg
+	}
+	return &c
+}
+
+// matches reports whether a device is selected by a filter
+func matches(f *Filter, subnet *net.IPNet, d *models.Device) bool {
+	if len(f.MACs) > 0 {
+		found := false
+		for _, mac := range f.MACs {
+			if mac == d.MAC {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if f.Vendor != "" && f.Vendor != d.Vendor {
+		return false
+	}
+	if f.Site != "" && f.Site != d.Site {
+		return false
+	}
+	if f.Role != "" && f.Role != d.Role {
+		return false
+	}
+	if f.Status != "" && f.Status != d.Status {
+		return false
+	}
+	if subnet != nil {
+		ip := net.ParseIP(d.IP)
+		if ip == nil || !subnet.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+func newRunID() string {
+	b := make([]byte, 6)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("%x", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b)
+}
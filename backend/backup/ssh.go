@@ -1,59 +1,96 @@
 package backup
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 
-	"golang.org/x/crypto/ssh"
-
 	"github.com/ztp-server/backend/db"
+	"github.com/ztp-server/backend/drivers"
+	"github.com/ztp-server/backend/jobs"
 	"github.com/ztp-server/backend/models"
+	"github.com/ztp-server/backend/ws"
 )
 
-// Service handles automated config backups via SSH
+// backupAttempts is how many times a queued backup is tried before it fails
+const backupAttempts = 3
+
+// dialTimeout bounds connecting to a device, and each NETCONF exchange
+const dialTimeout = 30 * time.Second
+
+// sessionTimeout bounds a whole backup session, from connecting to the last output
+const sessionTimeout = 5 * time.Minute
+
+// Service handles automated config backups via SSH. Backups run as jobs in the
+// persistent queue, so delayed and pending backups survive restarts. Up to the
+// configured number of workers back up devices in parallel, subject to the per-vendor
+// and per-subnet caps and the global rate limit.
 type Service struct {
-	store       *db.Store
-	backupDir   string
-	pendingMACs chan string
-	stopCh      chan struct{}
+	store     *db.Store
+	backupDir string
+	queue     *jobs.Queue
+	hub       *ws.Hub
+	limits    *limiter
+
+	// onProvisioned is called after the first successful backup of a device that
+	// came up through ZTP
+	onProvisioned func(device *models.Device)
+
+	runsMu   sync.Mutex
+	runs     map[string]*Run
+	runOrder []string // Run IDs, oldest first
 }
 
-// NewService creates a new backup service
-func NewService(store *db.Store, backupDir string) *Service {
-	return &Service{
-		store:       store,
-		backupDir:   backupDir,
-		pendingMACs: make(chan string, 100),
-		stopCh:      make(chan struct{}),
-	}
+// backupPayload is the payload of a backup job
+type backupPayload struct {
+	MAC string `json:"mac"`
 }
 
-// Start begins the backup worker
-func (s *Service) Start() {
-	go s.worker()
+// NewService creates a new backup service and registers its job handler. Progress is
+// broadcast to WebSocket clients through hub.
+func NewService(store *db.Store, backupDir string, queue *jobs.Queue, hub *ws.Hub) *Service {
+	s := &Service{
+		store:     store,
+		backupDir: backupDir,
+		queue:     queue,
+		hub:       hub,
+		limits:    newLimiter(),
+		runs:      make(map[string]*Run),
+	}
+	concurrency := DefaultWorkers
+	if settings, err := store.GetSettings(); err == nil {
+		concurrency = workers(settings)
+	}
+	queue.Register(jobs.TypeBackup, concurrency, s.runJob)
+	return s
 }
 
-// Stop stops the backup service
-func (s *Service) Stop() {
-	close(s.stopCh)
+// SetProvisionedHook sets a function to call once a device that was provisioning has
+// been backed up, i.e. it is reachable over SSH with its new config
+func (s *Service) SetProvisionedHook(fn func(device *models.Device)) {
+	s.onProvisioned = fn
 }
 
-// QueueBackup adds a device to the backup queue
-func (s *Service) QueueBackup(mac string) {
-	select {
-	case s.pendingMACs <- mac:
-	default:
-		log.Printf("Backup queue full, dropping MAC: %s", mac)
-	}
+// QueueBackup adds a device to the backup queue, to run after the given delay. A backup
+// already queued for the device is reused.
+func (s *Service) QueueBackup(mac string, delay time.Duration) (*models.Job, error) {
+	return s.queue.Enqueue(jobs.TypeBackup, backupPayload{MAC: mac}, jobs.Options{
+		Delay:       delay,
+		MaxAttempts: backupAttempts,
+		Key:         mac,
+	})
 }
 
-// TriggerBackup immediately triggers a backup for a device
+// TriggerBackup queues an immediate backup for a device
 func (s *Service) TriggerBackup(mac string) error {
-	return s.performBackup(mac)
+	_, err := s.QueueBackup(mac, 0)
+	return err
 }
 
 // OnNewLease handles a new DHCP lease event
@@ -74,88 +111,152 @@ func (s *Service) OnNewLease(lease *models.Lease) {
 	}
 
 	// Schedule backup after delay
-	go func() {
-		log.Printf("Scheduling backup for %s (%s) in %d seconds", device.Hostname, lease.IP, settings.BackupDelay)
-		time.Sleep(time.Duration(settings.BackupDelay) * time.Second)
-		s.QueueBackup(lease.MAC)
-	}()
+	log.Printf("Scheduling backup for %s (%s) in %d seconds", device.Hostname, lease.IP, settings.BackupDelay)
+	if _, err := s.QueueBackup(lease.MAC, time.Duration(settings.BackupDelay)*time.Second); err != nil {
+		log.Printf("Failed to queue backup for %s: %v", lease.MAC, err)
+	}
 }
 
-func (s *Service) worker() {
-	for {
-		select {
-		case <-s.stopCh:
-			return
-		case mac := <-s.pendingMACs:
-			if err := s.performBackup(mac); err != nil {
-				log.Printf("Backup failed for %s: %v", mac, err)
-			}
-		}
+// runJob performs one backup attempt. The device is marked offline once the last
+// attempt has failed.
+func (s *Service) runJob(ctx context.Context, job *models.Job) (any, error) {
+	var payload backupPayload
+	if err := jobs.Decode(job, &payload); err != nil {
+		return nil, err
 	}
-}
 
-func (s *Service) performBackup(mac string) error {
-	device, err := s.store.GetDevice(mac)
+	device, err := s.store.GetDevice(payload.MAC)
 	if err != nil {
-		return fmt.Errorf("failed to get device: %w", err)
+		return nil, err
 	}
 	if device == nil {
-		return fmt.Errorf("device not found: %s", mac)
+		s.updateRuns(payload.MAC, RunDeviceFailed, errNoDevice.Error())
+		return nil, jobs.Permanent(fmt.Errorf("%w: %s", errNoDevice, payload.MAC))
 	}
-
 	settings, err := s.store.GetSettings()
 	if err != nil {
-		return fmt.Errorf("failed to get settings: %w", err)
+		return nil, fmt.Errorf("failed to get settings: %w", err)
 	}
 
-	// Determine credentials
-	user := device.SSHUser
-	pass := device.SSHPass
-	if user == "" {
-		user = settings.DefaultSSHUser
+	// Pick up worker pool changes; they apply from the next dispatch
+	s.queue.SetConcurrency(jobs.TypeBackup, workers(settings))
+
+	release, wait := s.limits.acquire(device, settings)
+	if release == nil {
+		return nil, jobs.Defer(wait)
 	}
-	if pass == "" {
-		pass = settings.DefaultSSHPass
+	defer release()
+
+	s.updateRuns(device.MAC, RunDeviceRunning, "")
+	s.broadcast(ws.EventBackupStarted, device, job.Attempts, nil)
+
+	_, err = s.performBackup(ctx, device.MAC)
+	switch {
+	case err == nil:
+		s.updateRuns(device.MAC, RunDeviceCompleted, "")
+		s.broadcast(ws.EventBackupCompleted, device, job.Attempts, nil)
+		if device.Status == "provisioning" {
+			if err := s.store.SetDeviceProvisioned(device.MAC); err != nil {
+				log.Printf("Failed to mark %s provisioned: %v", device.MAC, err)
+			}
+			if s.onProvisioned != nil {
+				s.onProvisioned(device)
+			}
+		}
+	case ctx.Err() != nil:
+		// Cancelled or interrupted by shutdown; the queue records which
+		s.updateRuns(device.MAC, RunDeviceFailed, err.Error())
+		s.broadcast(ws.EventBackupFailed, device, job.Attempts, err)
+	case errors.Is(err, errNoDevice):
+		s.updateRuns(device.MAC, RunDeviceFailed, err.Error())
+		s.broadcast(ws.EventBackupFailed, device, job.Attempts, err)
+		return nil, jobs.Permanent(err)
+	case job.Attempts >= job.MaxAttempts:
+		s.store.UpdateDeviceStatus(device.MAC, "offline")
+		s.updateRuns(device.MAC, RunDeviceFailed, err.Error())
+		s.broadcast(ws.EventBackupFailed, device, job.Attempts, err)
+	default:
+		// Retried by the queue
+		s.updateRuns(device.MAC, RunDevicePending, err.Error())
+		s.broadcast(ws.EventBackupFailed, device, job.Attempts, err)
 	}
+	return nil, err
+}
 
-	// Determine command - use vendor-specific command if device has a vendor
-	command := settings.BackupCommand
-	if device.Vendor != "" {
-		if vendor, err := s.store.GetVendor(device.Vendor); err == nil && vendor != nil && vendor.BackupCommand != "" {
-			command = vendor.BackupCommand
-		}
+func (s *Service) broadcast(eventType ws.EventType, device *models.Device, attempt int, err error) {
+	if s.hub == nil {
+		return
 	}
-	if command == "" {
-		command = "show running-config"
+	payload := ws.BackupPayload{MAC: device.MAC, Hostname: device.Hostname, Attempt: attempt}
+	if err != nil {
+		payload.Error = err.Error()
 	}
+	s.hub.BroadcastBackup(eventType, payload)
+}
 
-	log.Printf("Starting backup for %s (%s) as %s", device.Hostname, device.IP, user)
+// errNoDevice is returned when backing up a device that has been deleted
+var errNoDevice = errors.New("device not found")
 
-	// Connect via SSH with retries
-	var config string
-	var lastErr error
+// BackupNow backs up a device immediately, bypassing the queue and its limits, and
+// returns the recorded backup. It is used around config changes.
+func (s *Service) BackupNow(ctx context.Context, mac string) (*models.Backup, error) {
+	return s.performBackup(ctx, mac)
+}
 
-	for attempt := 1; attempt <= 3; attempt++ {
-		config, lastErr = s.sshCommand(device.IP, user, pass, command)
-		if lastErr == nil {
-			break
-		}
-		log.Printf("SSH attempt %d failed for %s: %v", attempt, device.IP, lastErr)
-		time.Sleep(time.Duration(attempt*5) * time.Second)
+// performBackup backs up a device. The session is cut off once ctx is done or after
+// sessionTimeout, so a cancelled backup or a hung device doesn't hold a worker.
+func (s *Service) performBackup(ctx context.Context, mac string) (*models.Backup, error) {
+	ctx, cancel := context.WithTimeout(ctx, sessionTimeout)
+	defer cancel()
+
+	device, err := s.store.GetDevice(mac)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get device: %w", err)
+	}
+	if device == nil {
+		return nil, fmt.Errorf("%w: %s", errNoDevice, mac)
 	}
 
-	if lastErr != nil {
-		errMsg := fmt.Sprintf("SSH failed: %v", lastErr)
-		s.store.UpdateDeviceStatus(mac, "offline")
-		s.store.UpdateDeviceError(mac, errMsg)
-		return fmt.Errorf("all SSH attempts failed: %w", lastErr)
+	settings, err := s.store.GetSettings()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get settings: %w", err)
+	}
+
+	var vendor *models.Vendor
+	if device.Vendor != "" {
+		vendor, _ = s.store.GetVendor(device.Vendor)
+	}
+	target := drivers.NewTarget(device, vendor, settings)
+	driver := drivers.For(target.VendorID())
+
+	// Connect via SSH or NETCONF; failed attempts are retried by the job queue
+	var config string
+	format := models.BackupFormatText
+	if target.Netconf() {
+		log.Printf("Starting NETCONF backup for %s (%s:%d) as %s", device.Hostname, device.IP, target.NetconfPort, target.User)
+		format = models.BackupFormatXML
+		config, err = drivers.NetconfBackup(ctx, target, dialTimeout)
+		if err != nil {
+			errMsg := fmt.Sprintf("NETCONF failed: %v", err)
+			s.store.UpdateDeviceError(mac, errMsg)
+			return nil, fmt.Errorf("NETCONF failed: %w", err)
+		}
+	} else {
+		log.Printf("Starting backup for %s (%s) as %s using the %s driver", device.Hostname, device.IP, target.User, driver.Name())
+		config, err = s.sshBackup(ctx, target, driver)
+		if err != nil {
+			errMsg := fmt.Sprintf("SSH failed: %v", err)
+			s.store.UpdateDeviceError(mac, errMsg)
+			return nil, fmt.Errorf("SSH failed: %w", err)
+		}
 	}
 
 	// Save backup
-	if err := s.saveBackup(device, config); err != nil {
+	backup, err := s.saveBackup(device, config, format)
+	if err != nil {
 		errMsg := fmt.Sprintf("Failed to save backup: %v", err)
 		s.store.UpdateDeviceError(mac, errMsg)
-		return fmt.Errorf("failed to save backup: %w", err)
+		return nil, fmt.Errorf("failed to save backup: %w", err)
 	}
 
 	// Update device status and clear any previous error
@@ -164,58 +265,54 @@ func (s *Service) performBackup(mac string) error {
 	s.store.ClearDeviceError(mac)
 
 	log.Printf("Backup completed for %s", device.Hostname)
-	return nil
+	return backup, nil
 }
 
-func (s *Service) sshCommand(host, user, pass, command string) (string, error) {
-	config := &ssh.ClientConfig{
-		User: user,
-		Auth: []ssh.AuthMethod{
-			ssh.Password(pass),
-		},
-		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
-		Timeout:         30 * time.Second,
-	}
-
-	// Connect
-	addr := fmt.Sprintf("%s:22", host)
-	client, err := ssh.Dial("tcp", addr, config)
-	if err != nil {
-		return "", fmt.Errorf("failed to dial: %w", err)
-	}
-	defer client.Close()
-
-	// Create session
-	session, err := client.NewSession()
+// sshBackup connects to the device and runs its driver's backup
+func (s *Service) sshBackup(ctx context.Context, target drivers.Target, driver drivers.Driver) (string, error) {
+	conn, err := drivers.DialContext(ctx, target, dialTimeout)
 	if err != nil {
-		return "", fmt.Errorf("failed to create session: %w", err)
+		return "", err
 	}
-	defer session.Close()
+	defer conn.Close()
 
-	// Run command
-	output, err := session.CombinedOutput(command)
-	if err != nil {
-		return "", fmt.Errorf("command failed: %w", err)
+	config, err := driver.Backup(conn)
+	if err != nil && ctx.Err() != nil {
+		// The connection was closed under the driver; report why
+		return "", fmt.Errorf("session cut off: %w", ctx.Err())
 	}
-
-	return string(output), nil
+	return config, err
 }
 
-func (s *Service) saveBackup(device *models.Device, config string) error {
+// saveBackup writes a backup to the backup directory, as .xml for NETCONF backups and
+// .cfg otherwise, and records it
+func (s *Service) saveBackup(device *models.Device, config, format string) (*models.Backup, error) {
 	// Ensure backup directory exists
 	if err := os.MkdirAll(s.backupDir, 0755); err != nil {
-		return err
+		return nil, err
 	}
 
 	// Generate filename
 	timestamp := time.Now().Format("20060102_150405")
 	safeName := strings.ReplaceAll(device.Hostname, "/", "_")
-	filename := fmt.Sprintf("%s_%s.cfg", safeName, timestamp)
+	ext := "cfg"
+	if format == models.BackupFormatXML {
+		ext = "xml"
+	}
+	filename := fmt.Sprintf("%s_%s.%s", safeName, timestamp, ext)
 	filePath := filepath.Join(s.backupDir, filename)
+	// Backups taken within the same second, e.g. around a config change, get a suffix
+	for n := 2; ; n++ {
+		if _, err := os.Stat(filePath); os.IsNotExist(err) {
+			break
+		}
+		filename = fmt.Sprintf("%s_%s_%d.%s", safeName, timestamp, n, ext)
+		filePath = filepath.Join(s.backupDir, filename)
+	}
 
 	// Write file
 	if err := os.WriteFile(filePath, []byte(config), 0644); err != nil {
-		return err
+		return nil, err
 	}
 
 	// Record in database
@@ -223,9 +320,13 @@ func (s *Service) saveBackup(device *models.Device, config string) error {
 	backup := &models.Backup{
 		DeviceMAC: device.MAC,
 		Filename:  filename,
+		Format:    format,
 		Size:      info.Size(),
 		CreatedAt: time.Now(),
 	}
 
-	return s.store.CreateBackup(backup)
+	if err := s.store.CreateBackup(backup); err != nil {
+		return nil, err
+	}
+	return backup, nil
 }
@@ -0,0 +1,86 @@
+// Package bootstrap builds the URLs of vendor-native ZTP bootstrap scripts. Vendors with
+// a bootstrap template get DHCP option 67 pointing at a stable per-vendor script URL.
+// The script is rendered for the device fetching it, downloads its config and target
+// image, and posts its progress back to the device's signed callback URL.
+package bootstrap
+
+import (
+	"net/url"
+
+	"github.com/ztp-server/backend/models"
+	"github.com/ztp-server/backend/utils"
+)
+
+// Path is the URL path scripts are served under
+const Path = "/bootstrap"
+
+// CAPath is the URL path the server's own CA certificate is served at
+const CAPath = "/ca.crt"
+
+// Context is the bootstrap URLs of a device as seen by templates
+type Context struct {
+	ScriptURL string // The vendor's bootstrap script
+	StatusURL string // Where the script posts its progress, the device's callback URL
+	CAURL     string // The CA certificate HTTPS URLs are signed with, if the server issues its own
+	configURL func() (string, error)
+}
+
+// NewContext returns the bootstrap URLs of a device. host is the address devices reach
+// the server at, listenAddr the HTTP server's listen address, statusURL the device's
+// signed callback URL and configURL returns the URL of its config.
+func NewContext(device *models.Device, host, listenAddr, statusURL string, configURL func() (string, error)) *Context {
+	return &Context{
+		ScriptURL: ScriptURL(device.Vendor, host, listenAddr),
+		StatusURL: statusURL,
+		configURL: configURL,
+	}
+}
+
+// ConfigURL returns the URL of the device's generated config. It is a method so that
+// a download token is only issued when a template asks for the URL.
+func (c *Context) ConfigURL() (string, error) {
+	return c.configURL()
+}
+
+// ScriptURL returns the URL a vendor's bootstrap script is served at
+func ScriptURL(vendorID, host, listenAddr string) string {
+	return httpURL(host, listenAddr, Path+"/"+vendorID)
+}
+
+// ConfigURL returns the URL a device's generated config is served at: over HTTPS if
+// tlsAddr, the HTTPS listen address, is set, and with its download token if it has one
+func ConfigURL(device *models.Device, host, listenAddr, tlsAddr, token string) string {
+	u := url.URL{Scheme: "http", Host: utils.HTTPHost(host, listenAddr), Path: "/configs/" + utils.MacToFilename(device.MAC) + ".cfg"}
+	if tlsAddr != "" {
+		u.Scheme, u.Host = "https", utils.HTTPSHost(host, tlsAddr)
+	}
+	if token != "" {
+		u.RawQuery = url.Values{"token": {token}}.Encode()
+	}
+	return u.String()
+}
+
+// CheckConfigURL applies the config download protections that tie a config to its
+// device before a config URL, which carries a download token if configs need one, is
+// handed to the client at remoteIP. Provisioned devices are refused under the lockout,
+// and the client must be the device's reserved address if source addresses are checked
+// or a token would be issued. It returns why the client is refused, or "" if it isn't.
+func CheckConfigURL(device *models.Device, settings *models.Settings, remoteIP string) string {
+	if settings.ConfigLockout && device.ProvisionedAt != nil {
+		return "Device already provisioned"
+	}
+	if (settings.ConfigCheckSourceIP || settings.ConfigTokenRequired) && remoteIP != device.IP {
+		return "Source address does not match the device's reservation"
+	}
+	return ""
+}
+
+// CAURL returns the URL the server's CA certificate is served at. It is plain HTTP, as
+// devices fetch it before they can verify the server.
+func CAURL(host, listenAddr string) string {
+	return httpURL(host, listenAddr, CAPath)
+}
+
+func httpURL(host, listenAddr, path string) string {
+	return (&url.URL{Scheme: "http", Host: utils.HTTPHost(host, listenAddr), Path: path}).String()
+}
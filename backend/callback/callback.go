@@ -0,0 +1,65 @@
+// Package callback signs the per-device callback URLs devices report the result of
+// provisioning to. Templates embed the URL in rendered configs and bootstrap scripts;
+// its token is an HMAC of the device's MAC address, so a device can only report for
+// itself and no per-device state has to be stored.
+package callback
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/url"
+	"strings"
+
+	"github.com/ztp-server/backend/models"
+	"github.com/ztp-server/backend/utils"
+)
+
+// Path is the URL path callbacks are served under
+const Path = "/callback"
+
+// SecretName is the name of the server secret tokens are signed with
+const SecretName = "callback"
+
+// Context is the callback of a device as seen by templates
+type Context struct {
+	URL   string // Signed callback URL, token included
+	Token string // Token alone, for devices that send it as the X-ZTP-Token header
+}
+
+// NewContext returns the callback of a device. host is the address devices reach the
+// server at and listenAddr the HTTP server's listen address.
+func NewContext(device *models.Device, secret, host, listenAddr string) *Context {
+	token := Token(secret, device.MAC)
+	u := url.URL{
+		Scheme:   "http",
+		Host:     utils.HTTPHost(host, listenAddr),
+		Path:     Path + "/" + utils.MacToFilename(device.MAC),
+		RawQuery: url.Values{"token": {token}}.Encode(),
+	}
+	return &Context{URL: u.String(), Token: token}
+}
+
+// Token returns the callback token of a device: the hex HMAC-SHA256 of its MAC address
+// keyed with the callback secret
+func Token(secret, mac string) string {
+	return hex.EncodeToString(sign(secret, mac))
+}
+
+// Verify reports whether token is the callback token of a device
+func Verify(secret, mac, token string) bool {
+	if secret == "" || token == "" {
+		return false
+	}
+	got, err := hex.DecodeString(strings.TrimSpace(token))
+	if err != nil {
+		return false
+	}
+	return hmac.Equal(sign(secret, mac), got)
+}
+
+func sign(secret, mac string) []byte {
+	h := hmac.New(sha256.New, []byte(secret))
+	h.Write([]byte(mac))
+	return h.Sum(nil)
+}
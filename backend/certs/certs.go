@@ -0,0 +1,200 @@
+// Package certs provides the certificates of the HTTPS listener, either loaded from
+// files or issued by a self-signed CA the server generates on first start. Issued
+// certificates are made on demand for the name or address a client connects to, so
+// devices reaching the server at any of its addresses get a matching certificate.
+package certs
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const (
+	caCertFile = "ca.crt"
+	caKeyFile  = "ca.key"
+
+	caValidity   = 10 * 365 * 24 * time.Hour
+	leafValidity = 365 * 24 * time.Hour
+	// Leaf certificates are reissued once they get this close to expiring
+	leafRenewBefore = 24 * time.Hour
+)
+
+// Load returns a TLS config serving the certificate and key in the given PEM files
+func Load(certFile, keyFile string) (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load TLS certificate: %w", err)
+	}
+	return &tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12}, nil
+}
+
+// Authority is a self-signed CA that issues the HTTPS listener's certificates
+type Authority struct {
+	cert    *x509.Certificate
+	key     *ecdsa.PrivateKey
+	certPEM []byte
+
+	mu     sync.Mutex
+	leaves map[string]*tls.Certificate
+}
+
+// NewAuthority loads the CA kept in dir, generating it if there is none yet
+func NewAuthority(dir string) (*Authority, error) {
+	certPath, keyPath := filepath.Join(dir, caCertFile), filepath.Join(dir, caKeyFile)
+	certPEM, certErr := os.ReadFile(certPath)
+	keyPEM, keyErr := os.ReadFile(keyPath)
+	if os.IsNotExist(certErr) && os.IsNotExist(keyErr) {
+		var err error
+		if certPEM, keyPEM, err = generateCA(); err != nil {
+			return nil, fmt.Errorf("failed to generate CA: %w", err)
+		}
+		if err := os.MkdirAll(dir, 0700); err != nil {
+			return nil, err
+		}
+		if err := os.WriteFile(keyPath, keyPEM, 0600); err != nil {
+			return nil, err
+		}
+		if err := os.WriteFile(certPath, certPEM, 0644); err != nil {
+			return nil, err
+		}
+	} else if certErr != nil {
+		return nil, certErr
+	} else if keyErr != nil {
+		return nil, keyErr
+	}
+
+	pair, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return nil, fmt.Errorf("invalid CA in %s: %w", dir, err)
+	}
+	cert, err := x509.ParseCertificate(pair.Certificate[0])
+	if err != nil {
+		return nil, err
+	}
+	key, ok := pair.PrivateKey.(*ecdsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("CA key in %s is not an ECDSA key", dir)
+	}
+	return &Authority{cert: cert, key: key, certPEM: certPEM, leaves: make(map[string]*tls.Certificate)}, nil
+}
+
+// CertPEM returns the CA certificate, for devices to trust
+func (a *Authority) CertPEM() []byte {
+	return a.certPEM
+}
+
+// TLSConfig returns a TLS config serving certificates issued by the CA
+func (a *Authority) TLSConfig() *tls.Config {
+	return &tls.Config{GetCertificate: a.getCertificate, MinVersion: tls.VersionTLS12}
+}
+
+// getCertificate returns a certificate for the name the client asked for, or for the
+// address it connected to if it didn't send one
+func (a *Authority) getCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	name := hello.ServerName
+	if name == "" && hello.Conn != nil {
+		if host, _, err := net.SplitHostPort(hello.Conn.LocalAddr().String()); err == nil {
+			name = host
+		}
+	}
+	if name == "" {
+		name = "localhost"
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if leaf, ok := a.leaves[name]; ok && time.Until(leaf.Leaf.NotAfter) > leafRenewBefore {
+		return leaf, nil
+	}
+	leaf, err := a.issue(name)
+	if err != nil {
+		return nil, err
+	}
+	a.leaves[name] = leaf
+	return leaf, nil
+}
+
+// issue creates a certificate for a host name or IP address
+func (a *Authority) issue(name string) (*tls.Certificate, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	serial, err := serialNumber()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	tmpl := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    now.Add(-time.Hour),
+		NotAfter:     now.Add(leafValidity),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	if ip := net.ParseIP(name); ip != nil {
+		tmpl.IPAddresses = []net.IP{ip}
+	} else {
+		tmpl.DNSNames = []string{name}
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, a.cert, &key.PublicKey, a.key)
+	if err != nil {
+		return nil, err
+	}
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, err
+	}
+	return &tls.Certificate{Certificate: [][]byte{der, a.cert.Raw}, PrivateKey: key, Leaf: leaf}, nil
+}
+
+// generateCA creates a CA certificate and key, PEM encoded
+func generateCA() (certPEM, keyPEM []byte, err error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	serial, err := serialNumber()
+	if err != nil {
+		return nil, nil, err
+	}
+	now := time.Now()
+	tmpl := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: "ZTP Server CA"},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(caValidity),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		MaxPathLenZero:        true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		return nil, nil, err
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	certPEM = pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM = pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	return certPEM, keyPEM, nil
+}
+
+func serialNumber() (*big.Int, error) {
+	return rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+}
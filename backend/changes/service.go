@@ -0,0 +1,294 @@
+// Package changes pushes configuration changes to live devices through their vendor
+// drivers, with a backup before and after and, where the driver supports it, a
+// rollback timer that is only confirmed once the device is still reachable.
+package changes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/ztp-server/backend/backup"
+	"github.com/ztp-server/backend/db"
+	"github.com/ztp-server/backend/drivers"
+	"github.com/ztp-server/backend/jobs"
+	"github.com/ztp-server/backend/models"
+)
+
+// Rollback timer limits in minutes. IOS revert timers go up to 120.
+const (
+	DefaultConfirmMinutes = 5
+	MaxConfirmMinutes     = 120
+)
+
+// dialTimeout bounds each SSH connection made while applying a change
+const dialTimeout = 30 * time.Second
+
+// ErrNoDevice is returned when applying config to a device that doesn't exist
+var ErrNoDevice = errors.New("device not found")
+
+// RequestError is returned by Apply for a request that can't be applied as given
+type RequestError struct {
+	msg string
+}
+
+func (e *RequestError) Error() string { return e.msg }
+
+func requestError(format string, args ...any) error {
+	return &RequestError{msg: fmt.Sprintf(format, args...)}
+}
+
+// Renderer renders a template for a device
+type Renderer func(device *models.Device, templateID string) (string, error)
+
+// Request describes a change to apply. Exactly one of Config and TemplateID is set.
+type Request struct {
+	Config         string `json:"config"`          // Config snippet to apply
+	TemplateID     string `json:"template_id"`     // Template to render for the device and apply
+	ConfirmMinutes *int   `json:"confirm_minutes"` // Rollback timer, DefaultConfirmMinutes if unset; 0 disables commit-confirm
+}
+
+// Service applies config changes as jobs
+type Service struct {
+	store   *db.Store
+	backups *backup.Service
+	queue   *jobs.Queue
+	render  Renderer
+}
+
+// changePayload is the payload of a config.apply job
+type changePayload struct {
+	ChangeID int64 `json:"change_id"`
+}
+
+// NewService creates a change service and registers its job handler
+func NewService(store *db.Store, backups *backup.Service, queue *jobs.Queue, render Renderer) *Service {
+	s := &Service{store: store, backups: backups, queue: queue, render: render}
+	queue.Register(jobs.TypeConfigApply, 4, s.runJob)
+	// Pushing a change twice isn't safe, and how far an interrupted one got is unknown
+	queue.NotResumable(jobs.TypeConfigApply, s.interrupted)
+	return s
+}
+
+// Apply records a change for a device and queues it. Templates are rendered now, so
+// the change records exactly what was pushed.
+func (s *Service) Apply(device *models.Device, req Request) (*models.ConfigChange, error) {
+	if (req.Config == "") == (req.TemplateID == "") {
+		return nil, requestError("either config or template_id is required")
+	}
+	minutes := DefaultConfirmMinutes
+	if req.ConfirmMinutes != nil {
+		minutes = *req.ConfirmMinutes
+	}
+	if minutes < 0 || minutes > MaxConfirmMinutes {
+		return nil, requestError("confirm_minutes must be between 0 and %d", MaxConfirmMinutes)
+	}
+
+	config := req.Config
+	if req.TemplateID != "" {
+		tmpl, err := s.store.GetTemplate(req.TemplateID)
+		if err != nil {
+			return nil, err
+		}
+		if tmpl == nil {
+			return nil, requestError("template not found: %s", req.TemplateID)
+		}
+		if config, err = s.render(device, req.TemplateID); err != nil {
+			return nil, requestError("failed to render template: %v", err)
+		}
+	}
+	if strings.TrimSpace(config) == "" {
+		return nil, requestError("config is empty")
+	}
+
+	change := &models.ConfigChange{
+		DeviceMAC:      device.MAC,
+		TemplateID:     req.TemplateID,
+		Config:         config,
+		ConfirmMinutes: minutes,
+	}
+	if err := s.store.CreateConfigChange(change); err != nil {
+		return nil, err
+	}
+
+	job, err := s.queue.Enqueue(jobs.TypeConfigApply, changePayload{ChangeID: change.ID}, jobs.Options{})
+	if err != nil {
+		return nil, err
+	}
+	change.JobID = job.ID
+	if err := s.store.UpdateConfigChange(change); err != nil {
+		return nil, err
+	}
+	return change, nil
+}
+
+// runJob applies a change. Changes are never retried: a failed push may have been
+// partially applied, so it needs a person to look at it.
+func (s *Service) runJob(ctx context.Context, job *models.Job) (any, error) {
+	var payload changePayload
+	if err := jobs.Decode(job, &payload); err != nil {
+		return nil, err
+	}
+	change, err := s.store.GetConfigChange(payload.ChangeID)
+	if err != nil {
+		return nil, err
+	}
+	if change == nil {
+		return nil, jobs.Permanent(fmt.Errorf("config change not found: %d", payload.ChangeID))
+	}
+
+	change.Status = models.ChangeRunning
+	s.store.UpdateConfigChange(change)
+
+	err = s.apply(ctx, change)
+	now := time.Now()
+	change.FinishedAt = &now
+	if err != nil {
+		if change.Status == models.ChangeRunning {
+			change.Status = models.ChangeFailed
+		}
+		change.Error = err.Error()
+	}
+	if saveErr := s.store.UpdateConfigChange(change); saveErr != nil {
+		log.Printf("Failed to save config change %d: %v", change.ID, saveErr)
+	}
+	if err != nil {
+		return change, jobs.Permanent(err)
+	}
+	return change, nil
+}
+
+// interrupted marks the change of a job left running by the last process as failed
+func (s *Service) interrupted(job *models.Job) {
+	var payload changePayload
+	if err := jobs.Decode(job, &payload); err != nil {
+		return
+	}
+	change, err := s.store.GetConfigChange(payload.ChangeID)
+	if err != nil || change == nil {
+		return
+	}
+	if change.Status != models.ChangePending && change.Status != models.ChangeRunning {
+		return
+	}
+	now := time.Now()
+	change.Status = models.ChangeFailed
+	change.FinishedAt = &now
+	change.Error = "interrupted by a server restart; check the device before applying the change again"
+	if err := s.store.UpdateConfigChange(change); err != nil {
+		log.Printf("Failed to save config change %d: %v", change.ID, err)
+	}
+}
+
+// apply backs up the device, pushes the change and backs up again, updating the change
+// as it goes. A shutdown before the push stops it there.
+func (s *Service) apply(ctx context.Context, change *models.ConfigChange) error {
+	device, err := s.store.GetDevice(change.DeviceMAC)
+	if err != nil {
+		return fmt.Errorf("failed to get device: %w", err)
+	}
+	if device == nil {
+		return fmt.Errorf("%w: %s", ErrNoDevice, change.DeviceMAC)
+	}
+	settings, err := s.store.GetSettings()
+	if err != nil {
+		return fmt.Errorf("failed to get settings: %w", err)
+	}
+	var vendor *models.Vendor
+	if device.Vendor != "" {
+		vendor, _ = s.store.GetVendor(device.Vendor)
+	}
+	target := drivers.NewTarget(device, vendor, settings)
+	driver := drivers.For(target.VendorID())
+	change.Driver = driver.Name()
+
+	// Without a pre-change backup there is nothing to go back to, so don't push
+	pre, err := s.backups.BackupNow(ctx, device.MAC)
+	if err != nil {
+		return fmt.Errorf("pre-change backup failed: %w", err)
+	}
+	change.PreBackupID = pre.ID
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("interrupted before the change was pushed: %w", err)
+	}
+
+	log.Printf("Applying config change %d to %s using the %s driver", change.ID, device.Hostname, driver.Name())
+	applier, confirmable := driver.(drivers.ConfirmedApplier)
+	if confirmable && change.ConfirmMinutes > 0 {
+		err = s.applyConfirmed(change, target, applier)
+	} else {
+		err = s.applyDirect(change, target, driver)
+	}
+	if err != nil {
+		log.Printf("Config change %d to %s failed: %v", change.ID, device.Hostname, err)
+		return err
+	}
+
+	if post, err := s.backups.BackupNow(ctx, device.MAC); err != nil {
+		log.Printf("Post-change backup of %s failed: %v", device.Hostname, err)
+		change.Error = fmt.Sprintf("change applied, but the post-change backup failed: %v", err)
+	} else {
+		change.PostBackupID = post.ID
+	}
+	log.Printf("Config change %d to %s %s", change.ID, device.Hostname, change.Status)
+	return nil
+}
+
+// applyDirect applies the change with the driver's plain ApplyConfig
+func (s *Service) applyDirect(change *models.ConfigChange, target drivers.Target, driver drivers.Driver) error {
+	conn, err := drivers.Dial(target, dialTimeout)
+	if err != nil {
+		return fmt.Errorf("SSH failed: %w", err)
+	}
+	defer conn.Close()
+
+	output, err := driver.ApplyConfig(conn, change.Config)
+	change.Output = output
+	if err != nil {
+		return err
+	}
+	change.Status = models.ChangeApplied
+	return nil
+}
+
+// applyConfirmed applies the change under the device's rollback timer, then confirms
+// it over a fresh connection. If the device can't be reached again the change is left
+// to roll back.
+func (s *Service) applyConfirmed(change *models.ConfigChange, target drivers.Target, applier drivers.ConfirmedApplier) error {
+	name := fmt.Sprintf("ztp-change-%d", change.ID)
+
+	conn, err := drivers.Dial(target, dialTimeout)
+	if err != nil {
+		return fmt.Errorf("SSH failed: %w", err)
+	}
+	output, err := applier.ApplyConfirmed(conn, change.Config, name, change.ConfirmMinutes)
+	conn.Close()
+	change.Output = output
+	if errors.Is(err, drivers.ErrRollbackPending) {
+		change.Status = models.ChangeRolledBack
+		return fmt.Errorf("%w; the change will roll back within %d minutes", err, change.ConfirmMinutes)
+	}
+	if err != nil {
+		return err
+	}
+
+	// Reconnecting proves the change left the device manageable
+	conn, err = drivers.Dial(target, dialTimeout)
+	if err != nil {
+		change.Status = models.ChangeRolledBack
+		return fmt.Errorf("device unreachable after the change, it will roll back within %d minutes: %w", change.ConfirmMinutes, err)
+	}
+	defer conn.Close()
+
+	output, err = applier.Confirm(conn, name)
+	change.Output += output
+	if err != nil {
+		change.Status = models.ChangeRolledBack
+		return fmt.Errorf("confirm failed, the change will roll back within %d minutes: %w", change.ConfirmMinutes, err)
+	}
+	change.Status = models.ChangeConfirmed
+	return nil
+}
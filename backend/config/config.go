@@ -14,6 +14,12 @@ type Config struct {
 	ListenAddr    string
 	DHCPInterface string
 	FrontendDir   string
+	// Optional HTTPS listener, enabled by a certificate and key or a self-signed CA
+	TLSListenAddr string
+	TLSCertFile   string
+	TLSKeyFile    string
+	TLSSelfSigned bool   // Issue certificates from a CA generated in TLSDir
+	TLSDir        string // Where the self-signed CA is kept
 }
 
 // Load returns configuration from environment variables with defaults
@@ -29,9 +35,19 @@ func Load() *Config {
 		ListenAddr:    getEnv("LISTEN_ADDR", ":8080"),
 		DHCPInterface: getEnv("DHCP_INTERFACE", "eth0"),
 		FrontendDir:   getEnv("FRONTEND_DIR", "/app/frontend"),
+		TLSListenAddr: getEnv("TLS_LISTEN_ADDR", ":8443"),
+		TLSCertFile:   getEnv("TLS_CERT_FILE", ""),
+		TLSKeyFile:    getEnv("TLS_KEY_FILE", ""),
+		TLSSelfSigned: getEnv("TLS_SELF_SIGNED", "") == "true",
+		TLSDir:        getEnv("TLS_DIR", "/data/tls"),
 	}
 }
 
+// TLSEnabled reports whether the HTTPS listener should be started
+func (c *Config) TLSEnabled() bool {
+	return c.TLSSelfSigned || (c.TLSCertFile != "" && c.TLSKeyFile != "")
+}
+
 func getEnv(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
 		return value
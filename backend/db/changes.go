@@ -0,0 +1,87 @@
+package db
+
+import (
+	"database/sql"
+	"strconv"
+	"time"
+
+	"github.com/ztp-server/backend/models"
+)
+
+// Config change operations
+
+const changeColumns = `id, device_mac, template_id, config, confirm_minutes, status, driver, output, error,
+	pre_backup_id, post_backup_id, job_id, created_at, finished_at`
+
+// scanConfigChange scans a config_changes row into a model
+func scanConfigChange(scanner interface{ Scan(...any) error }) (*models.ConfigChange, error) {
+	var ch models.ConfigChange
+	var finishedAt sql.NullTime
+	if err := scanner.Scan(&ch.ID, &ch.DeviceMAC, &ch.TemplateID, &ch.Config, &ch.ConfirmMinutes, &ch.Status,
+		&ch.Driver, &ch.Output, &ch.Error, &ch.PreBackupID, &ch.PostBackupID, &ch.JobID, &ch.CreatedAt, &finishedAt); err != nil {
+		return nil, err
+	}
+	if finishedAt.Valid {
+		ch.FinishedAt = &finishedAt.Time
+	}
+	return &ch, nil
+}
+
+// CreateConfigChange records a pending config change
+func (s *Store) CreateConfigChange(ch *models.ConfigChange) error {
+	ch.CreatedAt = time.Now()
+	if ch.Status == "" {
+		ch.Status = models.ChangePending
+	}
+	result, err := s.db.Exec(`
+		INSERT INTO config_changes (device_mac, template_id, config, confirm_minutes, status, created_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`, ch.DeviceMAC, ch.TemplateID, ch.Config, ch.ConfirmMinutes, ch.Status, ch.CreatedAt)
+	if err != nil {
+		return err
+	}
+	ch.ID, _ = result.LastInsertId()
+	return nil
+}
+
+// UpdateConfigChange saves a change's progress and outcome
+func (s *Store) UpdateConfigChange(ch *models.ConfigChange) error {
+	return s.execWithRowCheck("config change", strconv.FormatInt(ch.ID, 10), `
+		UPDATE config_changes SET status = ?, driver = ?, output = ?, error = ?,
+		       pre_backup_id = ?, post_backup_id = ?, job_id = ?, finished_at = ?
+		WHERE id = ?
+	`, ch.Status, ch.Driver, ch.Output, ch.Error, ch.PreBackupID, ch.PostBackupID, ch.JobID, ch.FinishedAt, ch.ID)
+}
+
+// GetConfigChange returns a config change by ID, or nil if it doesn't exist
+func (s *Store) GetConfigChange(id int64) (*models.ConfigChange, error) {
+	ch, err := scanConfigChange(s.db.QueryRow(`SELECT `+changeColumns+` FROM config_changes WHERE id = ?`, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return ch, nil
+}
+
+// ListConfigChanges returns a device's config changes, newest first
+func (s *Store) ListConfigChanges(mac string) ([]models.ConfigChange, error) {
+	rows, err := s.db.Query(`SELECT `+changeColumns+` FROM config_changes
+		WHERE device_mac = ? ORDER BY id DESC`, mac)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	changes := []models.ConfigChange{}
+	for rows.Next() {
+		ch, err := scanConfigChange(rows)
+		if err != nil {
+			return nil, err
+		}
+		changes = append(changes, *ch)
+	}
+
+	return changes, rows.Err()
+}
@@ -0,0 +1,120 @@
+package db
+
+import (
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/ztp-server/backend/models"
+)
+
+// Device facts operations
+
+// MaxFactsHistory is how many facts snapshots are kept per device
+const MaxFactsHistory = 50
+
+const factsColumns = `id, device_mac, driver, hostname, model, serial, version, uptime,
+	interfaces, neighbors, serial_mismatch, collected_at`
+
+// scanDeviceFacts scans a device_facts row into a model
+func scanDeviceFacts(scanner interface{ Scan(...any) error }) (*models.DeviceFacts, error) {
+	var f models.DeviceFacts
+	var interfaces, neighbors string
+	var mismatch int
+	if err := scanner.Scan(&f.ID, &f.DeviceMAC, &f.Driver, &f.Hostname, &f.Model, &f.Serial, &f.Version, &f.Uptime,
+		&interfaces, &neighbors, &mismatch, &f.CollectedAt); err != nil {
+		return nil, err
+	}
+	f.SerialMismatch = mismatch == 1
+	f.Interfaces = []models.DeviceInterface{}
+	f.Neighbors = []models.LLDPNeighbor{}
+	if interfaces != "" {
+		json.Unmarshal([]byte(interfaces), &f.Interfaces)
+	}
+	if neighbors != "" {
+		json.Unmarshal([]byte(neighbors), &f.Neighbors)
+	}
+	return &f, nil
+}
+
+// CreateDeviceFacts records a facts snapshot and drops the device's oldest snapshots
+// beyond MaxFactsHistory
+func (s *Store) CreateDeviceFacts(f *models.DeviceFacts) error {
+	if f.CollectedAt.IsZero() {
+		f.CollectedAt = time.Now()
+	}
+	if f.Interfaces == nil {
+		f.Interfaces = []models.DeviceInterface{}
+	}
+	if f.Neighbors == nil {
+		f.Neighbors = []models.LLDPNeighbor{}
+	}
+	interfaces, _ := json.Marshal(f.Interfaces)
+	neighbors, _ := json.Marshal(f.Neighbors)
+
+	result, err := s.db.Exec(`
+		INSERT INTO device_facts (device_mac, driver, hostname, model, serial, version, uptime,
+		                          interfaces, neighbors, serial_mismatch, collected_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, f.DeviceMAC, f.Driver, f.Hostname, f.Model, f.Serial, f.Version, f.Uptime,
+		string(interfaces), string(neighbors), boolToInt(f.SerialMismatch), f.CollectedAt)
+	if err != nil {
+		return err
+	}
+	f.ID, _ = result.LastInsertId()
+
+	_, err = s.db.Exec(`
+		DELETE FROM device_facts WHERE device_mac = ? AND id NOT IN (
+			SELECT id FROM device_facts WHERE device_mac = ? ORDER BY id DESC LIMIT ?
+		)
+	`, f.DeviceMAC, f.DeviceMAC, MaxFactsHistory)
+	return err
+}
+
+// ListDeviceFacts returns a device's facts snapshots, newest first. A limit of 0
+// returns all of them.
+func (s *Store) ListDeviceFacts(mac string, limit int) ([]models.DeviceFacts, error) {
+	if limit <= 0 {
+		limit = -1
+	}
+	rows, err := s.db.Query(`SELECT `+factsColumns+` FROM device_facts
+		WHERE device_mac = ? ORDER BY id DESC LIMIT ?`, mac, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	facts := []models.DeviceFacts{}
+	for rows.Next() {
+		f, err := scanDeviceFacts(rows)
+		if err != nil {
+			return nil, err
+		}
+		facts = append(facts, *f)
+	}
+
+	return facts, rows.Err()
+}
+
+// GetLatestDeviceFacts returns a device's most recent facts, or nil if none have been
+// collected
+func (s *Store) GetLatestDeviceFacts(mac string) (*models.DeviceFacts, error) {
+	f, err := scanDeviceFacts(s.db.QueryRow(`SELECT `+factsColumns+` FROM device_facts
+		WHERE device_mac = ? ORDER BY id DESC LIMIT 1`, mac))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// UpdateDeviceInventory sets the model and serial number a device reported
+func (s *Store) UpdateDeviceInventory(mac, model, serial string) error {
+	_, err := s.db.Exec(`
+		UPDATE devices SET model = ?, serial_number = ?, updated_at = ?
+		WHERE mac = ?
+	`, model, serial, time.Now(), mac)
+	return err
+}
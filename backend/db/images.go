@@ -0,0 +1,145 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/ztp-server/backend/models"
+)
+
+// Image operations
+
+const imageColumns = `id, vendor, model, version, filename, size, sha256, md5, target, created_at`
+
+// scanImage scans an images row into a model
+func scanImage(scanner interface{ Scan(...any) error }) (*models.Image, error) {
+	var img models.Image
+	var target int
+	if err := scanner.Scan(&img.ID, &img.Vendor, &img.Model, &img.Version, &img.Filename, &img.Size,
+		&img.SHA256, &img.MD5, &target, &img.CreatedAt); err != nil {
+		return nil, err
+	}
+	img.Target = target == 1
+	return &img, nil
+}
+
+// ListImages returns the images in the repository, for one vendor if vendor is set
+func (s *Store) ListImages(vendor string) ([]models.Image, error) {
+	query := `SELECT ` + imageColumns + ` FROM images`
+	var args []any
+	if vendor != "" {
+		query += ` WHERE vendor = ?`
+		args = append(args, vendor)
+	}
+	rows, err := s.db.Query(query+` ORDER BY vendor, model, created_at DESC`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	images := []models.Image{}
+	for rows.Next() {
+		img, err := scanImage(rows)
+		if err != nil {
+			return nil, err
+		}
+		images = append(images, *img)
+	}
+
+	return images, rows.Err()
+}
+
+// GetImage returns an image by ID, or nil if it doesn't exist
+func (s *Store) GetImage(id int64) (*models.Image, error) {
+	return s.getImage(`WHERE id = ?`, id)
+}
+
+// GetImageByFilename returns the image stored under a filename, or nil if there is none
+func (s *Store) GetImageByFilename(filename string) (*models.Image, error) {
+	return s.getImage(`WHERE filename = ?`, filename)
+}
+
+// GetTargetImage returns the image devices of a vendor and model are upgraded to. An
+// image for the model wins over one for every model of the vendor. Nil if there is none.
+func (s *Store) GetTargetImage(vendor, model string) (*models.Image, error) {
+	return s.getImage(`WHERE vendor = ? AND target = 1 AND (model = ? OR model = '')
+		ORDER BY model = '' LIMIT 1`, vendor, model)
+}
+
+func (s *Store) getImage(where string, args ...any) (*models.Image, error) {
+	img, err := scanImage(s.db.QueryRow(`SELECT `+imageColumns+` FROM images `+where, args...))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return img, nil
+}
+
+// CreateImage records an image. A target image replaces the previous target for its
+// vendor and model.
+func (s *Store) CreateImage(img *models.Image) error {
+	img.CreatedAt = time.Now()
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if img.Target {
+		if err := clearTargetImage(tx, img); err != nil {
+			return err
+		}
+	}
+	result, err := tx.Exec(`
+		INSERT INTO images (vendor, model, version, filename, size, sha256, md5, target, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, img.Vendor, img.Model, img.Version, img.Filename, img.Size, img.SHA256, img.MD5, boolToInt(img.Target), img.CreatedAt)
+	if err != nil {
+		return err
+	}
+	img.ID, _ = result.LastInsertId()
+	return tx.Commit()
+}
+
+// UpdateImage updates an image's vendor, model, version and target flag. A target image
+// replaces the previous target for its vendor and model.
+func (s *Store) UpdateImage(img *models.Image) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if img.Target {
+		if err := clearTargetImage(tx, img); err != nil {
+			return err
+		}
+	}
+	result, err := tx.Exec(`
+		UPDATE images SET vendor = ?, model = ?, version = ?, target = ? WHERE id = ?
+	`, img.Vendor, img.Model, img.Version, boolToInt(img.Target), img.ID)
+	if err != nil {
+		return err
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return fmt.Errorf("image not found: %d", img.ID)
+	}
+	return tx.Commit()
+}
+
+// clearTargetImage unsets the target flag on the other images for img's vendor and model
+func clearTargetImage(tx *sql.Tx, img *models.Image) error {
+	_, err := tx.Exec(`UPDATE images SET target = 0 WHERE vendor = ? AND model = ? AND id != ?`,
+		img.Vendor, img.Model, img.ID)
+	return err
+}
+
+// DeleteImage removes an image record
+func (s *Store) DeleteImage(id int64) error {
+	return s.execWithRowCheck("image", strconv.FormatInt(id, 10), "DELETE FROM images WHERE id = ?", id)
+}
@@ -0,0 +1,99 @@
+package db
+
+import (
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/ztp-server/backend/models"
+)
+
+// Inventory source operations
+
+// scanInventorySource scans an inventory_sources row into a model
+func scanInventorySource(scanner interface{ Scan(...any) error }) (*models.InventorySource, error) {
+	var src models.InventorySource
+	var enabled int
+	var headers sql.NullString
+	if err := scanner.Scan(&src.ID, &src.Name, &src.Type, &enabled, &src.URL, &src.Token, &src.Format, &headers, &src.CreatedAt, &src.UpdatedAt); err != nil {
+		return nil, err
+	}
+	src.Enabled = enabled == 1
+	if headers.Valid && headers.String != "" {
+		json.Unmarshal([]byte(headers.String), &src.Headers)
+	}
+	return &src, nil
+}
+
+// ListInventorySources returns all configured inventory sources
+func (s *Store) ListInventorySources() ([]models.InventorySource, error) {
+	rows, err := s.db.Query(`
+		SELECT id, name, type, enabled, url, token, format, headers, created_at, updated_at
+		FROM inventory_sources ORDER BY name
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var sources []models.InventorySource
+	for rows.Next() {
+		src, err := scanInventorySource(rows)
+		if err != nil {
+			return nil, err
+		}
+		sources = append(sources, *src)
+	}
+
+	return sources, rows.Err()
+}
+
+// GetInventorySource returns an inventory source by ID
+func (s *Store) GetInventorySource(id string) (*models.InventorySource, error) {
+	src, err := scanInventorySource(s.db.QueryRow(`
+		SELECT id, name, type, enabled, url, token, format, headers, created_at, updated_at
+		FROM inventory_sources WHERE id = ?
+	`, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return src, nil
+}
+
+// CreateInventorySource creates a new inventory source
+func (s *Store) CreateInventorySource(src *models.InventorySource) error {
+	now := time.Now()
+	src.CreatedAt = now
+	src.UpdatedAt = now
+
+	_, err := s.db.Exec(`
+		INSERT INTO inventory_sources (id, name, type, enabled, url, token, format, headers, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, src.ID, src.Name, src.Type, boolToInt(src.Enabled), src.URL, src.Token, src.Format, marshalHeaders(src.Headers), src.CreatedAt, src.UpdatedAt)
+	return err
+}
+
+// UpdateInventorySource updates an existing inventory source
+func (s *Store) UpdateInventorySource(src *models.InventorySource) error {
+	src.UpdatedAt = time.Now()
+	return s.execWithRowCheck("inventory source", src.ID, `
+		UPDATE inventory_sources SET name = ?, type = ?, enabled = ?, url = ?, token = ?, format = ?, headers = ?, updated_at = ?
+		WHERE id = ?
+	`, src.Name, src.Type, boolToInt(src.Enabled), src.URL, src.Token, src.Format, marshalHeaders(src.Headers), src.UpdatedAt, src.ID)
+}
+
+// DeleteInventorySource removes an inventory source
+func (s *Store) DeleteInventorySource(id string) error {
+	return s.execWithRowCheck("inventory source", id, "DELETE FROM inventory_sources WHERE id = ?", id)
+}
+
+func marshalHeaders(headers map[string]string) string {
+	if headers == nil {
+		headers = map[string]string{}
+	}
+	data, _ := json.Marshal(headers)
+	return string(data)
+}
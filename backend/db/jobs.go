@@ -0,0 +1,294 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/ztp-server/backend/models"
+)
+
+// Job queue operations. Times are stored in UTC so run_at compares correctly as text.
+
+const jobColumns = `id, type, status, key, payload, result, error, attempts, max_attempts,
+	run_at, started_at, finished_at, created_at, updated_at`
+
+// JobFilter narrows ListJobs. Empty fields match everything.
+type JobFilter struct {
+	Type   string
+	Status string
+	Limit  int
+}
+
+// scanJob scans a jobs row into a model
+func scanJob(scanner interface{ Scan(...any) error }) (*models.Job, error) {
+	var job models.Job
+	var payload, result string
+	var startedAt, finishedAt sql.NullTime
+	if err := scanner.Scan(&job.ID, &job.Type, &job.Status, &job.Key, &payload, &result, &job.Error,
+		&job.Attempts, &job.MaxAttempts, &job.RunAt, &startedAt, &finishedAt, &job.CreatedAt, &job.UpdatedAt); err != nil {
+		return nil, err
+	}
+	if payload != "" {
+		job.Payload = []byte(payload)
+	}
+	if result != "" {
+		job.Result = []byte(result)
+	}
+	if startedAt.Valid {
+		job.StartedAt = &startedAt.Time
+	}
+	if finishedAt.Valid {
+		job.FinishedAt = &finishedAt.Time
+	}
+	return &job, nil
+}
+
+// CreateJob adds a job to the queue. If the job has a key and a pending job of the same
+// type and key exists, that job is returned instead and run no later than the new one.
+// Callers creating keyed jobs concurrently must serialize, as the jobs queue does.
+func (s *Store) CreateJob(job *models.Job) (*models.Job, error) {
+	now := time.Now().UTC()
+	if job.RunAt.IsZero() {
+		job.RunAt = now
+	}
+	job.RunAt = job.RunAt.UTC()
+	if job.MaxAttempts < 1 {
+		job.MaxAttempts = 1
+	}
+
+	if job.Key != "" {
+		existing, err := scanJob(s.db.QueryRow(`SELECT `+jobColumns+` FROM jobs
+			WHERE type = ? AND key = ? AND status = ? ORDER BY id LIMIT 1`, job.Type, job.Key, models.JobPending))
+		if err == nil {
+			if job.RunAt.Before(existing.RunAt) {
+				existing.RunAt = job.RunAt
+				if _, err := s.db.Exec("UPDATE jobs SET run_at = ?, updated_at = ? WHERE id = ?", existing.RunAt, now, existing.ID); err != nil {
+					return nil, err
+				}
+			}
+			return existing, nil
+		}
+		if err != sql.ErrNoRows {
+			return nil, err
+		}
+	}
+
+	res, err := s.db.Exec(`
+		INSERT INTO jobs (type, status, key, payload, max_attempts, run_at, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`, job.Type, models.JobPending, job.Key, string(job.Payload), job.MaxAttempts, job.RunAt, now, now)
+	if err != nil {
+		return nil, err
+	}
+
+	job.ID, _ = res.LastInsertId()
+	job.Status = models.JobPending
+	job.CreatedAt = now
+	job.UpdatedAt = now
+	return job, nil
+}
+
+// GetJob returns a job by ID
+func (s *Store) GetJob(id int64) (*models.Job, error) {
+	job, err := scanJob(s.db.QueryRow(`SELECT `+jobColumns+` FROM jobs WHERE id = ?`, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+// ListJobs returns jobs, newest first
+func (s *Store) ListJobs(filter JobFilter) ([]models.Job, error) {
+	query := `SELECT ` + jobColumns + ` FROM jobs WHERE 1 = 1`
+	var args []interface{}
+	if filter.Type != "" {
+		query += " AND type = ?"
+		args = append(args, filter.Type)
+	}
+	if filter.Status != "" {
+		query += " AND status = ?"
+		args = append(args, filter.Status)
+	}
+	query += " ORDER BY id DESC"
+	if filter.Limit > 0 {
+		query += fmt.Sprintf(" LIMIT %d", filter.Limit)
+	}
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var jobs []models.Job
+	for rows.Next() {
+		job, err := scanJob(rows)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, *job)
+	}
+
+	return jobs, rows.Err()
+}
+
+// ClaimJob marks the next due pending job of a type as running and returns it, or nil
+// if none is due
+func (s *Store) ClaimJob(jobType string) (*models.Job, error) {
+	now := time.Now().UTC()
+	job, err := scanJob(s.db.QueryRow(`SELECT `+jobColumns+` FROM jobs
+		WHERE type = ? AND status = ? AND run_at <= ? ORDER BY run_at, id LIMIT 1`, jobType, models.JobPending, now))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := s.db.Exec(`
+		UPDATE jobs SET status = ?, attempts = attempts + 1, started_at = ?, finished_at = NULL, updated_at = ?
+		WHERE id = ? AND status = ?
+	`, models.JobRunning, now, now, job.ID, models.JobPending)
+	if err != nil {
+		return nil, err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return nil, nil // Cancelled in the meantime
+	}
+
+	job.Status = models.JobRunning
+	job.Attempts++
+	job.StartedAt = &now
+	job.FinishedAt = nil
+	job.UpdatedAt = now
+	return job, nil
+}
+
+// FinishJob records the outcome of a running job
+func (s *Store) FinishJob(id int64, status string, result []byte, errMsg string) error {
+	now := time.Now().UTC()
+	return s.execWithRowCheck("job", fmt.Sprint(id), `
+		UPDATE jobs SET status = ?, result = ?, error = ?, finished_at = ?, updated_at = ?
+		WHERE id = ?
+	`, status, string(result), errMsg, now, now, id)
+}
+
+// RescheduleJob puts a job back in the queue to run at the given time. If refund is
+// set the interrupted attempt isn't counted.
+func (s *Store) RescheduleJob(id int64, runAt time.Time, errMsg string, refund bool) error {
+	refundAttempts := 0
+	if refund {
+		refundAttempts = 1
+	}
+	return s.execWithRowCheck("job", fmt.Sprint(id), `
+		UPDATE jobs SET status = ?, run_at = ?, error = ?, attempts = MAX(attempts - ?, 0), updated_at = ?
+		WHERE id = ?
+	`, models.JobPending, runAt.UTC(), errMsg, refundAttempts, time.Now().UTC(), id)
+}
+
+// CancelPendingJob cancels a job that hasn't started. It reports whether the job was
+// pending.
+func (s *Store) CancelPendingJob(id int64) (bool, error) {
+	now := time.Now().UTC()
+	res, err := s.db.Exec(`
+		UPDATE jobs SET status = ?, finished_at = ?, updated_at = ? WHERE id = ? AND status = ?
+	`, models.JobCancelled, now, now, id, models.JobPending)
+	if err != nil {
+		return false, err
+	}
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
+
+// RetryJob queues a failed or cancelled job again with a fresh set of attempts. It
+// reports whether the job was in a retryable state.
+func (s *Store) RetryJob(id int64) (bool, error) {
+	now := time.Now().UTC()
+	res, err := s.db.Exec(`
+		UPDATE jobs SET status = ?, attempts = 0, error = '', result = '', run_at = ?, started_at = NULL, finished_at = NULL, updated_at = ?
+		WHERE id = ? AND status IN (?, ?)
+	`, models.JobPending, now, now, id, models.JobFailed, models.JobCancelled)
+	if err != nil {
+		return false, err
+	}
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
+
+// RequeueRunningJobs returns jobs left running by a previous process to the queue,
+// without counting the interrupted attempt
+func (s *Store) RequeueRunningJobs() (int, error) {
+	now := time.Now().UTC()
+	res, err := s.db.Exec(`
+		UPDATE jobs SET status = ?, attempts = MAX(attempts - 1, 0), run_at = ?, updated_at = ? WHERE status = ?
+	`, models.JobPending, now, now, models.JobRunning)
+	if err != nil {
+		return 0, err
+	}
+	n, _ := res.RowsAffected()
+	return int(n), nil
+}
+
+// FailRunningJobs marks jobs of a type left running by a previous process as failed
+// and returns them, for job types that mustn't run twice
+func (s *Store) FailRunningJobs(jobType, errMsg string) ([]models.Job, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query(`SELECT `+jobColumns+` FROM jobs WHERE type = ? AND status = ?`, jobType, models.JobRunning)
+	if err != nil {
+		return nil, err
+	}
+	var jobs []models.Job
+	for rows.Next() {
+		job, err := scanJob(rows)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		jobs = append(jobs, *job)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	for i := range jobs {
+		if _, err := tx.Exec(`
+			UPDATE jobs SET status = ?, error = ?, finished_at = ?, updated_at = ? WHERE id = ?
+		`, models.JobFailed, errMsg, now, now, jobs[i].ID); err != nil {
+			return nil, err
+		}
+		jobs[i].Status = models.JobFailed
+		jobs[i].Error = errMsg
+		jobs[i].FinishedAt = &now
+	}
+	return jobs, tx.Commit()
+}
+
+// DeleteJob removes a finished job
+func (s *Store) DeleteJob(id int64) error {
+	return s.execWithRowCheck("job", fmt.Sprint(id), `
+		DELETE FROM jobs WHERE id = ? AND status IN (?, ?, ?)
+	`, id, models.JobSucceeded, models.JobFailed, models.JobCancelled)
+}
+
+// PruneJobs deletes finished jobs that finished before the cutoff
+func (s *Store) PruneJobs(before time.Time) (int, error) {
+	res, err := s.db.Exec(`
+		DELETE FROM jobs WHERE status IN (?, ?, ?) AND finished_at < ?
+	`, models.JobSucceeded, models.JobFailed, models.JobCancelled, before.UTC())
+	if err != nil {
+		return 0, err
+	}
+	n, _ := res.RowsAffected()
+	return int(n), nil
+}
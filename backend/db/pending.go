@@ -0,0 +1,124 @@
+package db
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/ztp-server/backend/models"
+)
+
+// Pending device operations
+
+// scanPendingDevice scans a pending_devices row into a model
+func scanPendingDevice(scanner interface{ Scan(...any) error }) (*models.PendingDevice, error) {
+	var p models.PendingDevice
+	var decidedAt sql.NullTime
+	if err := scanner.Scan(&p.MAC, &p.IP, &p.Hostname, &p.Vendor, &p.Status, &p.Note, &p.FirstSeen, &p.LastSeen, &decidedAt); err != nil {
+		return nil, err
+	}
+	if decidedAt.Valid {
+		p.DecidedAt = &decidedAt.Time
+	}
+	return &p, nil
+}
+
+// ListPendingDevices returns pending devices, optionally filtered by status
+func (s *Store) ListPendingDevices(status string) ([]models.PendingDevice, error) {
+	query := `
+		SELECT mac, ip, hostname, vendor, status, note, first_seen, last_seen, decided_at
+		FROM pending_devices`
+	var args []interface{}
+	if status != "" {
+		query += " WHERE status = ?"
+		args = append(args, status)
+	}
+	query += " ORDER BY last_seen DESC"
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var pending []models.PendingDevice
+	for rows.Next() {
+		p, err := scanPendingDevice(rows)
+		if err != nil {
+			return nil, err
+		}
+		pending = append(pending, *p)
+	}
+
+	return pending, rows.Err()
+}
+
+// GetPendingDevice returns a pending device by MAC address
+func (s *Store) GetPendingDevice(mac string) (*models.PendingDevice, error) {
+	p, err := scanPendingDevice(s.db.QueryRow(`
+		SELECT mac, ip, hostname, vendor, status, note, first_seen, last_seen, decided_at
+		FROM pending_devices WHERE mac = ?
+	`, mac))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// UpsertPendingDevice records a sighting of an unregistered device.
+// New MACs enter the queue as pending and rejected entries stay rejected. An approved entry
+// whose device has since been deleted goes back to pending.
+func (s *Store) UpsertPendingDevice(p *models.PendingDevice) error {
+	now := time.Now()
+	_, err := s.db.Exec(`
+		INSERT INTO pending_devices (mac, ip, hostname, vendor, status, first_seen, last_seen)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(mac) DO UPDATE SET
+			ip = excluded.ip,
+			hostname = CASE WHEN excluded.hostname != '' THEN excluded.hostname ELSE pending_devices.hostname END,
+			vendor = CASE WHEN excluded.vendor != '' THEN excluded.vendor ELSE pending_devices.vendor END,
+			status = CASE WHEN pending_devices.status = 'approved' THEN 'pending' ELSE pending_devices.status END,
+			decided_at = CASE WHEN pending_devices.status = 'approved' THEN NULL ELSE pending_devices.decided_at END,
+			last_seen = excluded.last_seen
+	`, p.MAC, p.IP, p.Hostname, p.Vendor, models.PendingStatusPending, now, now)
+	return err
+}
+
+// SetPendingDeviceStatus records an approval decision for a pending device
+func (s *Store) SetPendingDeviceStatus(mac, status, note string) error {
+	var decidedAt interface{}
+	if status != models.PendingStatusPending {
+		decidedAt = time.Now()
+	}
+	return s.execWithRowCheck("pending device", mac, `
+		UPDATE pending_devices SET status = ?, note = ?, decided_at = ?
+		WHERE mac = ?
+	`, status, note, decidedAt, mac)
+}
+
+// DeletePendingDevice removes a device from the approval queue
+func (s *Store) DeletePendingDevice(mac string) error {
+	return s.execWithRowCheck("pending device", mac, "DELETE FROM pending_devices WHERE mac = ?", mac)
+}
+
+// ListRejectedMACs returns the MACs that are blacklisted from receiving ZTP options
+func (s *Store) ListRejectedMACs() ([]string, error) {
+	rows, err := s.db.Query("SELECT mac FROM pending_devices WHERE status = ? ORDER BY mac", models.PendingStatusRejected)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var macs []string
+	for rows.Next() {
+		var mac string
+		if err := rows.Scan(&mac); err != nil {
+			return nil, err
+		}
+		macs = append(macs, mac)
+	}
+
+	return macs, rows.Err()
+}
@@ -0,0 +1,78 @@
+package db
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/ztp-server/backend/models"
+)
+
+// PnP session operations
+
+const pnpSessionColumns = `serial, udi, pid, ip, hostname, device_mac, state, step, correlator, last_error,
+	created_at, updated_at`
+
+// scanPnPSession scans a pnp_sessions row into a model
+func scanPnPSession(scanner interface{ Scan(...any) error }) (*models.PnPSession, error) {
+	var p models.PnPSession
+	if err := scanner.Scan(&p.Serial, &p.UDI, &p.PID, &p.IP, &p.Hostname, &p.DeviceMAC, &p.State, &p.Step,
+		&p.Correlator, &p.LastError, &p.CreatedAt, &p.UpdatedAt); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// ListPnPSessions returns all PnP sessions, most recently active first
+func (s *Store) ListPnPSessions() ([]models.PnPSession, error) {
+	rows, err := s.db.Query(`SELECT ` + pnpSessionColumns + ` FROM pnp_sessions ORDER BY updated_at DESC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sessions := []models.PnPSession{}
+	for rows.Next() {
+		p, err := scanPnPSession(rows)
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, *p)
+	}
+
+	return sessions, rows.Err()
+}
+
+// GetPnPSession returns the session of the agent with a serial number, or nil if there is none
+func (s *Store) GetPnPSession(serial string) (*models.PnPSession, error) {
+	p, err := scanPnPSession(s.db.QueryRow(`SELECT `+pnpSessionColumns+` FROM pnp_sessions WHERE serial = ?`, serial))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// SavePnPSession creates or replaces a PnP session, stamping its last contact time
+func (s *Store) SavePnPSession(p *models.PnPSession) error {
+	p.UpdatedAt = time.Now()
+	if p.CreatedAt.IsZero() {
+		p.CreatedAt = p.UpdatedAt
+	}
+	_, err := s.db.Exec(`
+		INSERT INTO pnp_sessions (`+pnpSessionColumns+`)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(serial) DO UPDATE SET
+			udi = excluded.udi, pid = excluded.pid, ip = excluded.ip, hostname = excluded.hostname,
+			device_mac = excluded.device_mac, state = excluded.state, step = excluded.step,
+			correlator = excluded.correlator, last_error = excluded.last_error, updated_at = excluded.updated_at
+	`, p.Serial, p.UDI, p.PID, p.IP, p.Hostname, p.DeviceMAC, p.State, p.Step, p.Correlator, p.LastError,
+		p.CreatedAt, p.UpdatedAt)
+	return err
+}
+
+// DeletePnPSession removes a PnP session, so the agent starts over on its next request
+func (s *Store) DeletePnPSession(serial string) error {
+	return s.execWithRowCheck("pnp session", serial, "DELETE FROM pnp_sessions WHERE serial = ?", serial)
+}
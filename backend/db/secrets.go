@@ -0,0 +1,24 @@
+package db
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+)
+
+// Server secret operations
+
+// Secret returns the server secret of the given name, generating a random one the
+// first time it is asked for. Secrets never leave the server; they sign tokens handed
+// out to devices.
+func (s *Store) Secret(name string) (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	if _, err := s.db.Exec("INSERT OR IGNORE INTO secrets (name, value) VALUES (?, ?)", name, hex.EncodeToString(b)); err != nil {
+		return "", err
+	}
+	var value string
+	err := s.db.QueryRow("SELECT value FROM secrets WHERE name = ?", name).Scan(&value)
+	return value, err
+}
@@ -4,9 +4,12 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"log"
+	"strings"
 	"time"
 
 	"github.com/ztp-server/backend/models"
+	"github.com/ztp-server/backend/utils"
 
 	_ "github.com/mattn/go-sqlite3"
 )
@@ -40,6 +43,7 @@ type defaultDhcpOption struct {
 	Name         string
 	Value        string
 	Type         string
+	SubOptions   []models.DhcpSubOption
 	VendorID     string
 	Description  string
 	Enabled      bool
@@ -54,6 +58,11 @@ type defaultVendor struct {
 	MacPrefixes     []string
 	VendorClass     string
 	DefaultTemplate string
+	BackupMode      string
+	PromptPattern   string
+	PagerPattern    string
+	PreCommands     []string
+	EnableCommand   string
 }
 
 // getDefaultVendors returns the default vendors with MAC prefixes
@@ -67,6 +76,8 @@ func getDefaultVendors() []defaultVendor {
 			MacPrefixes:     []string{"00:13:C6"},
 			VendorClass:     "OpenGear",
 			DefaultTemplate: "opengear-lighthouse",
+			BackupMode:      models.BackupModeInteractive,
+			PromptPattern:   `[\w.\-@:~/]+\s?[#$]\s*$`,
 		},
 		{
 			ID:              "cisco",
@@ -76,6 +87,10 @@ func getDefaultVendors() []defaultVendor {
 			MacPrefixes:     []string{"00:00:0C", "00:1A:2F", "00:1B:0D", "00:1C:0E", "00:1D:45", "00:22:55", "00:26:99", "2C:31:24", "64:F6:9D", "F8:C2:88"},
 			VendorClass:     "Cisco Systems, Inc.",
 			DefaultTemplate: "cisco-ios",
+			BackupMode:      models.BackupModeInteractive,
+			PromptPattern:   `[\w.\-()/:]+[>#]\s*$`,
+			PreCommands:     []string{"terminal length 0", "terminal width 0"},
+			EnableCommand:   "enable",
 		},
 		{
 			ID:              "arista",
@@ -85,6 +100,10 @@ func getDefaultVendors() []defaultVendor {
 			MacPrefixes:     []string{"00:1C:73", "28:99:3A", "44:4C:A8", "50:01:00", "74:83:C2"},
 			VendorClass:     "Arista Networks",
 			DefaultTemplate: "arista-eos",
+			BackupMode:      models.BackupModeInteractive,
+			PromptPattern:   `[\w.\-()/:]+[>#]\s*$`,
+			PreCommands:     []string{"terminal length 0", "terminal width 32767"},
+			EnableCommand:   "enable",
 		},
 		{
 			ID:              "juniper",
@@ -94,6 +113,7 @@ func getDefaultVendors() []defaultVendor {
 			MacPrefixes:     []string{"00:05:85", "00:10:DB", "00:12:1E", "00:14:F6", "00:17:CB", "00:19:E2", "00:21:59", "00:23:9C", "00:26:88", "2C:6B:F5", "3C:61:04", "50:C7:09", "78:FE:3D", "84:B5:9C", "AC:4B:C8", "F4:B5:2F", "F8:C0:01"},
 			VendorClass:     "Juniper Networks",
 			DefaultTemplate: "juniper-junos",
+			BackupMode:      models.BackupModeExec,
 		},
 		{
 			ID:              "raspberry-pi",
@@ -103,6 +123,7 @@ func getDefaultVendors() []defaultVendor {
 			MacPrefixes:     []string{"B8:27:EB", "DC:A6:32", "E4:5F:01", "D8:3A:DD", "28:CD:C1"},
 			VendorClass:     "Raspberry Pi",
 			DefaultTemplate: "raspberry-pi",
+			BackupMode:      models.BackupModeExec,
 		},
 	}
 }
@@ -120,6 +141,14 @@ func GetDefaultVendors() []models.Vendor {
 			MacPrefixes:     d.MacPrefixes,
 			VendorClass:     d.VendorClass,
 			DefaultTemplate: d.DefaultTemplate,
+			BackupMode:      d.BackupMode,
+			PromptPattern:   d.PromptPattern,
+			PagerPattern:    d.PagerPattern,
+			PreCommands:     d.PreCommands,
+			EnableCommand:   d.EnableCommand,
+		}
+		if vendors[i].PreCommands == nil {
+			vendors[i].PreCommands = []string{}
 		}
 	}
 	return vendors
@@ -137,6 +166,7 @@ func GetDefaultDhcpOptions() []models.DhcpOption {
 			Name:         d.Name,
 			Value:        d.Value,
 			Type:         d.Type,
+			SubOptions:   d.SubOptions,
 			VendorID:     d.VendorID,
 			Description:  d.Description,
 			Enabled:      d.Enabled,
@@ -155,7 +185,7 @@ func getDefaultDhcpOptions() []defaultDhcpOption {
 			OptionNumber: 66,
 			Name:         "TFTP Server",
 			Value:        "${tftp_server_ip}",
-			Type:         "ip",
+			Type:         "string",
 			VendorID:     "",
 			Description:  "TFTP server for config files",
 			Enabled:      true,
@@ -182,6 +212,16 @@ func getDefaultDhcpOptions() []defaultDhcpOption {
 			Description:  "Cisco IOS config filename",
 			Enabled:      true,
 		},
+		{
+			ID:           "pnp-cisco",
+			OptionNumber: 43,
+			Name:         "Cisco PnP Server",
+			Value:        "5A1N;B2;K4;I${tftp_server_ip};J8080",
+			Type:         "string",
+			VendorID:     "cisco",
+			Description:  "Cisco Plug and Play server discovery; J is the ZTP server's HTTP port",
+			Enabled:      false,
+		},
 
 		// Arista-specific options
 		{
@@ -214,9 +254,14 @@ func getDefaultDhcpOptions() []defaultDhcpOption {
 			Name:         "OpenGear ZTP",
 			Value:        "",
 			Type:         "hex",
-			VendorID:     "opengear",
-			Description:  "OpenGear vendor-specific enrollment options",
-			Enabled:      false,
+			SubOptions: []models.DhcpSubOption{
+				{Code: 1, Type: "string", Value: "${opengear_enroll_url}"},
+				{Code: 2, Type: "string", Value: "${opengear_enroll_bundle}"},
+				{Code: 3, Type: "string", Value: "${opengear_enroll_password}"},
+			},
+			VendorID:    "opengear",
+			Description: "OpenGear vendor-specific enrollment options",
+			Enabled:     false,
 		},
 	}
 }
@@ -363,6 +408,172 @@ config.services.ssh.enable=on
 
 # Web interface
 config.services.webui.enable=on`,
+		},
+		{
+			ID:          "arista-ztp",
+			Name:        "Arista EOS ZTP Script",
+			Description: "EOS ZTP bootstrap script: installs the target image, fetches the config and reports status",
+			VendorID:    "arista",
+			Content: `#!/usr/bin/env python3
+# ZTP bootstrap script for {{.Hostname}}
+# Generated by ZTP Server
+import hashlib
+import json
+import shutil
+import ssl
+import urllib.request
+
+STATUS_URL = "{{.Bootstrap.StatusURL}}"
+CONFIG_URL = "{{.Bootstrap.ConfigURL}}"
+CA_URL = "{{.Bootstrap.CAURL}}"
+
+
+def status(state, message=""):
+    try:
+        body = json.dumps({"status": state, "message": message}).encode()
+        req = urllib.request.Request(STATUS_URL, data=body, headers={"Content-Type": "application/json"})
+        urllib.request.urlopen(req, timeout=10)
+    except Exception:
+        pass
+
+
+def sha256(path):
+    digest = hashlib.sha256()
+    with open(path, "rb") as f:
+        for chunk in iter(lambda: f.read(1 << 20), b""):
+            digest.update(chunk)
+    return digest.hexdigest()
+
+
+def download(url, path):
+    # HTTPS URLs are signed by the server's own CA if it has one
+    context = None
+    if CA_URL and url.startswith("https:"):
+        context = ssl.create_default_context(cadata=urllib.request.urlopen(CA_URL, timeout=10).read().decode())
+    with urllib.request.urlopen(url, context=context) as resp, open(path, "wb") as f:
+        shutil.copyfileobj(resp, f)
+
+
+try:
+    status("started")
+{{- with .Image}}
+    status("downloading_image", "{{.Version}}")
+    urllib.request.urlretrieve("{{.URL}}", "/mnt/flash/{{.Filename}}")
+    if sha256("/mnt/flash/{{.Filename}}") != "{{.SHA256}}":
+        raise Exception("image checksum mismatch")
+    status("installing_image", "{{.Version}}")
+    with open("/mnt/flash/boot-config", "w") as f:
+        f.write("SWI=flash:/{{.Filename}}\n")
+{{- end}}
+    status("applying_config")
+    download(CONFIG_URL, "/mnt/flash/startup-config")
+    status("completed")
+except Exception as e:
+    status("failed", str(e))
+    raise`,
+		},
+		{
+			ID:          "cisco-ztp",
+			Name:        "Cisco IOS-XE ZTP Script",
+			Description: "IOS-XE guestshell ztp.py: installs the target image, applies the config and reports status",
+			VendorID:    "cisco",
+			Content: `# ZTP bootstrap script for {{.Hostname}}
+# Generated by ZTP Server
+import json
+import shutil
+import ssl
+import urllib.request
+
+import cli
+
+STATUS_URL = "{{.Bootstrap.StatusURL}}"
+CONFIG_URL = "{{.Bootstrap.ConfigURL}}"
+CA_URL = "{{.Bootstrap.CAURL}}"
+
+
+def status(state, message=""):
+    try:
+        body = json.dumps({"status": state, "message": message}).encode()
+        req = urllib.request.Request(STATUS_URL, data=body, headers={"Content-Type": "application/json"})
+        urllib.request.urlopen(req, timeout=10)
+    except Exception:
+        pass
+
+
+def download(url, path):
+    # HTTPS URLs are signed by the server's own CA if it has one
+    context = None
+    if CA_URL and url.startswith("https:"):
+        context = ssl.create_default_context(cadata=urllib.request.urlopen(CA_URL, timeout=10).read().decode())
+    with urllib.request.urlopen(url, context=context) as resp, open(path, "wb") as f:
+        shutil.copyfileobj(resp, f)
+
+
+try:
+    status("started")
+    status("applying_config")
+    download(CONFIG_URL, "/flash/ztp.cfg")
+    cli.executep("copy flash:ztp.cfg startup-config")
+    cli.executep("copy startup-config running-config")
+{{- with .Image}}
+    status("downloading_image", "{{.Version}}")
+    cli.executep("copy {{.URL}} flash:{{.Filename}}")
+    if "Verified" not in cli.execute("verify /md5 flash:{{.Filename}} {{.MD5}}"):
+        raise Exception("image checksum mismatch")
+    status("installing_image", "{{.Version}}")
+    status("completed")
+    cli.executep("install add file flash:{{.Filename}} activate commit prompt-level none")
+{{- else}}
+    status("completed")
+{{- end}}
+except Exception as e:
+    status("failed", str(e))
+    raise`,
+		},
+		{
+			ID:          "juniper-ztp",
+			Name:        "Juniper Junos ZTP Script",
+			Description: "Junos ZTP shell script: installs the target image, commits the config and reports status",
+			VendorID:    "juniper",
+			Content: `#!/bin/sh
+# ZTP bootstrap script for {{.Hostname}}
+# Generated by ZTP Server
+
+STATUS_URL="{{.Bootstrap.StatusURL}}"
+CONFIG_URL="{{.Bootstrap.ConfigURL}}"
+CA_URL="{{.Bootstrap.CAURL}}"
+
+status() {
+    curl -s -m 10 -H "Content-Type: application/json" \
+        -d "{\"status\": \"$1\", \"message\": \"$2\"}" "$STATUS_URL" >/dev/null 2>&1
+}
+
+fail() {
+    status failed "$1"
+    exit 1
+}
+
+status started
+{{- with .Image}}
+status downloading_image "{{.Version}}"
+fetch -o "/var/tmp/{{.Filename}}" "{{.URL}}" || fail "image download failed"
+[ "$(sha256 -q /var/tmp/{{.Filename}})" = "{{.SHA256}}" ] || fail "image checksum mismatch"
+status installing_image "{{.Version}}"
+cli -c "request system software add /var/tmp/{{.Filename}} no-validate" || fail "image install failed"
+{{- end}}
+status applying_config
+# HTTPS URLs are signed by the server's own CA if it has one
+CA_OPT=""
+if [ -n "$CA_URL" ]; then
+    fetch -o /var/tmp/ztp-ca.crt "$CA_URL" || fail "CA download failed"
+    CA_OPT="--ca-cert=/var/tmp/ztp-ca.crt"
+fi
+fetch $CA_OPT -o /var/tmp/ztp.conf "$CONFIG_URL" || fail "config download failed"
+cli -c "configure private; load override /var/tmp/ztp.conf; commit and-quit" || fail "config commit failed"
+status completed
+{{- with .Image}}
+cli -c "request system reboot"
+{{- end}}`,
 		},
 		{
 			ID:          "generic-switch",
@@ -436,7 +647,11 @@ func (s *Store) migrate() error {
 		config_template TEXT DEFAULT '',
 		ssh_user TEXT DEFAULT '',
 		ssh_pass TEXT DEFAULT '',
+		enable_secret TEXT DEFAULT '',
 		status TEXT DEFAULT 'offline',
+		upgrade_status TEXT DEFAULT '',
+		upgrade_version TEXT DEFAULT '',
+		provisioned_at DATETIME,
 		last_seen DATETIME,
 		last_backup DATETIME,
 		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
@@ -452,6 +667,7 @@ func (s *Store) migrate() error {
 		id INTEGER PRIMARY KEY AUTOINCREMENT,
 		device_mac TEXT NOT NULL,
 		filename TEXT NOT NULL,
+		format TEXT DEFAULT 'text',
 		size INTEGER DEFAULT 0,
 		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
 		FOREIGN KEY (device_mac) REFERENCES devices(mac) ON DELETE CASCADE
@@ -467,6 +683,14 @@ func (s *Store) migrate() error {
 		mac_prefixes TEXT DEFAULT '[]',
 		vendor_class TEXT DEFAULT '',
 		default_template TEXT DEFAULT '',
+		backup_mode TEXT DEFAULT 'exec',
+		prompt_pattern TEXT DEFAULT '',
+		pager_pattern TEXT DEFAULT '',
+		pre_commands TEXT DEFAULT '[]',
+		enable_command TEXT DEFAULT '',
+		netconf_port INTEGER DEFAULT 0,
+		image_boot INTEGER DEFAULT 0,
+		bootstrap_template TEXT DEFAULT '',
 		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
 		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
 	);
@@ -477,6 +701,8 @@ func (s *Store) migrate() error {
 		name TEXT NOT NULL,
 		value TEXT DEFAULT '',
 		type TEXT DEFAULT 'string',
+		sub_options TEXT DEFAULT '[]',
+		enterprise_number INTEGER DEFAULT 0,
 		vendor_id TEXT DEFAULT '',
 		description TEXT DEFAULT '',
 		enabled INTEGER DEFAULT 1,
@@ -506,12 +732,143 @@ func (s *Store) migrate() error {
 		hostname TEXT DEFAULT '',
 		vendor TEXT DEFAULT '',
 		message TEXT DEFAULT '',
+		details TEXT DEFAULT '',
 		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
 	);
 
 	CREATE INDEX IF NOT EXISTS idx_discovery_logs_mac ON discovery_logs(mac);
 	CREATE INDEX IF NOT EXISTS idx_discovery_logs_created ON discovery_logs(created_at DESC);
 
+	CREATE TABLE IF NOT EXISTS pending_devices (
+		mac TEXT PRIMARY KEY,
+		ip TEXT DEFAULT '',
+		hostname TEXT DEFAULT '',
+		vendor TEXT DEFAULT '',
+		status TEXT DEFAULT 'pending',
+		note TEXT DEFAULT '',
+		first_seen DATETIME DEFAULT CURRENT_TIMESTAMP,
+		last_seen DATETIME DEFAULT CURRENT_TIMESTAMP,
+		decided_at DATETIME
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_pending_devices_status ON pending_devices(status);
+
+	CREATE TABLE IF NOT EXISTS jobs (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		type TEXT NOT NULL,
+		status TEXT NOT NULL DEFAULT 'pending',
+		key TEXT DEFAULT '',
+		payload TEXT DEFAULT '',
+		result TEXT DEFAULT '',
+		error TEXT DEFAULT '',
+		attempts INTEGER DEFAULT 0,
+		max_attempts INTEGER DEFAULT 1,
+		run_at DATETIME NOT NULL,
+		started_at DATETIME,
+		finished_at DATETIME,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_jobs_status_run_at ON jobs(status, run_at);
+
+	CREATE TABLE IF NOT EXISTS inventory_sources (
+		id TEXT PRIMARY KEY,
+		name TEXT NOT NULL,
+		type TEXT NOT NULL,
+		enabled INTEGER DEFAULT 1,
+		url TEXT DEFAULT '',
+		token TEXT DEFAULT '',
+		format TEXT DEFAULT '',
+		headers TEXT DEFAULT '{}',
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE TABLE IF NOT EXISTS device_facts (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		device_mac TEXT NOT NULL,
+		driver TEXT DEFAULT '',
+		hostname TEXT DEFAULT '',
+		model TEXT DEFAULT '',
+		serial TEXT DEFAULT '',
+		version TEXT DEFAULT '',
+		uptime TEXT DEFAULT '',
+		interfaces TEXT DEFAULT '[]',
+		neighbors TEXT DEFAULT '[]',
+		serial_mismatch INTEGER DEFAULT 0,
+		collected_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (device_mac) REFERENCES devices(mac) ON DELETE CASCADE
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_device_facts_device ON device_facts(device_mac);
+
+	CREATE TABLE IF NOT EXISTS config_changes (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		device_mac TEXT NOT NULL,
+		template_id TEXT DEFAULT '',
+		config TEXT NOT NULL,
+		confirm_minutes INTEGER DEFAULT 0,
+		status TEXT NOT NULL DEFAULT 'pending',
+		driver TEXT DEFAULT '',
+		output TEXT DEFAULT '',
+		error TEXT DEFAULT '',
+		pre_backup_id INTEGER DEFAULT 0,
+		post_backup_id INTEGER DEFAULT 0,
+		job_id INTEGER DEFAULT 0,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		finished_at DATETIME,
+		FOREIGN KEY (device_mac) REFERENCES devices(mac) ON DELETE CASCADE
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_config_changes_device ON config_changes(device_mac);
+
+	CREATE TABLE IF NOT EXISTS images (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		vendor TEXT NOT NULL,
+		model TEXT DEFAULT '',
+		version TEXT NOT NULL,
+		filename TEXT NOT NULL UNIQUE,
+		size INTEGER DEFAULT 0,
+		sha256 TEXT DEFAULT '',
+		md5 TEXT DEFAULT '',
+		target INTEGER DEFAULT 0,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_images_vendor ON images(vendor, model);
+
+	CREATE TABLE IF NOT EXISTS pnp_sessions (
+		serial TEXT PRIMARY KEY,
+		udi TEXT NOT NULL,
+		pid TEXT DEFAULT '',
+		ip TEXT DEFAULT '',
+		hostname TEXT DEFAULT '',
+		device_mac TEXT DEFAULT '',
+		state TEXT NOT NULL,
+		step INTEGER DEFAULT 0,
+		correlator TEXT DEFAULT '',
+		last_error TEXT DEFAULT '',
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE TABLE IF NOT EXISTS download_tokens (
+		token TEXT PRIMARY KEY,
+		device_mac TEXT NOT NULL,
+		one_time INTEGER DEFAULT 0,
+		expires_at DATETIME,
+		used_at DATETIME,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (device_mac) REFERENCES devices(mac) ON DELETE CASCADE
+	);
+
+	CREATE TABLE IF NOT EXISTS secrets (
+		name TEXT PRIMARY KEY,
+		value TEXT NOT NULL,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+
 	CREATE TABLE IF NOT EXISTS netbox_config (
 		id INTEGER PRIMARY KEY CHECK (id = 1),
 		url TEXT DEFAULT '',
@@ -554,6 +911,39 @@ func (s *Store) migrate() error {
 	// Migration: Add last_error column if it doesn't exist
 	s.db.Exec("ALTER TABLE devices ADD COLUMN last_error TEXT DEFAULT ''")
 
+	// Migration: Add netbox_id column linking devices to their NetBox object
+	s.db.Exec("ALTER TABLE devices ADD COLUMN netbox_id INTEGER DEFAULT 0")
+
+	// Migration: Add NetBox site/role/tenant/platform slugs
+	s.db.Exec("ALTER TABLE devices ADD COLUMN site TEXT DEFAULT ''")
+	s.db.Exec("ALTER TABLE devices ADD COLUMN role TEXT DEFAULT ''")
+	s.db.Exec("ALTER TABLE devices ADD COLUMN tenant TEXT DEFAULT ''")
+	s.db.Exec("ALTER TABLE devices ADD COLUMN platform TEXT DEFAULT ''")
+
+	// Migration: Add enable secret for interactive backups
+	s.db.Exec("ALTER TABLE devices ADD COLUMN enable_secret TEXT DEFAULT ''")
+
+	// Migration: Add image upgrade tracking
+	s.db.Exec("ALTER TABLE devices ADD COLUMN upgrade_status TEXT DEFAULT ''")
+	s.db.Exec("ALTER TABLE devices ADD COLUMN upgrade_version TEXT DEFAULT ''")
+
+	// Migration: Add provisioning time for config download lockout
+	s.db.Exec("ALTER TABLE devices ADD COLUMN provisioned_at DATETIME")
+
+	// Migration: Add backup format to tell NETCONF XML backups from text ones
+	s.db.Exec("ALTER TABLE backups ADD COLUMN format TEXT DEFAULT 'text'")
+
+	// Migration: Add details of device callbacks to the discovery log
+	s.db.Exec("ALTER TABLE discovery_logs ADD COLUMN details TEXT DEFAULT ''")
+
+	// Migration: Add continuous sync columns to netbox_config
+	s.db.Exec("ALTER TABLE netbox_config ADD COLUMN sync_interval INTEGER DEFAULT 300")
+	s.db.Exec("ALTER TABLE netbox_config ADD COLUMN webhook_secret TEXT DEFAULT ''")
+	s.db.Exec("ALTER TABLE netbox_config ADD COLUMN field_owners TEXT DEFAULT '{}'")
+
+	// Migration: Add IPAM prefix for device IP allocation
+	s.db.Exec("ALTER TABLE netbox_config ADD COLUMN prefix_id INTEGER DEFAULT 0")
+
 	// Seed default templates if they don't exist (insert or ignore)
 	defaultTemplates := getDefaultTemplates()
 	for _, t := range defaultTemplates {
@@ -567,33 +957,63 @@ func (s *Store) migrate() error {
 		}
 	}
 
+	// Migration: Add sub-option columns for vendor-encapsulated options
+	s.db.Exec("ALTER TABLE dhcp_options ADD COLUMN sub_options TEXT DEFAULT '[]'")
+	s.db.Exec("ALTER TABLE dhcp_options ADD COLUMN enterprise_number INTEGER DEFAULT 0")
+
 	// Seed default DHCP options if they don't exist (insert or ignore)
 	defaultDhcpOptions := getDefaultDhcpOptions()
 	for _, o := range defaultDhcpOptions {
+		subOptionsJSON := marshalSubOptions(o.SubOptions)
 		// Use INSERT OR IGNORE to only add if not already present
 		_, err := s.db.Exec(`
-			INSERT OR IGNORE INTO dhcp_options (id, option_number, name, value, type, vendor_id, description, enabled, created_at, updated_at)
-			VALUES (?, ?, ?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
-		`, o.ID, o.OptionNumber, o.Name, o.Value, o.Type, o.VendorID, o.Description, boolToInt(o.Enabled))
+			INSERT OR IGNORE INTO dhcp_options (id, option_number, name, value, type, sub_options, vendor_id, description, enabled, created_at, updated_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
+		`, o.ID, o.OptionNumber, o.Name, o.Value, o.Type, subOptionsJSON, o.VendorID, o.Description, boolToInt(o.Enabled))
 		if err != nil {
 			return err
 		}
 	}
 
+	// Migration: Give the OpenGear option 43 default its sub-options if it was seeded empty
+	for _, o := range defaultDhcpOptions {
+		if len(o.SubOptions) == 0 {
+			continue
+		}
+		s.db.Exec(`
+			UPDATE dhcp_options SET sub_options = ? WHERE id = ? AND value = '' AND (sub_options IS NULL OR sub_options = '[]')
+		`, marshalSubOptions(o.SubOptions), o.ID)
+	}
+
 	// Migration: Add columns if they don't exist
 	s.db.Exec("ALTER TABLE vendors ADD COLUMN mac_prefixes TEXT DEFAULT '[]'")
 	s.db.Exec("ALTER TABLE vendors ADD COLUMN vendor_class TEXT DEFAULT ''")
 	s.db.Exec("ALTER TABLE vendors ADD COLUMN default_template TEXT DEFAULT ''")
+	s.db.Exec("ALTER TABLE vendors ADD COLUMN backup_mode TEXT DEFAULT 'exec'")
+	s.db.Exec("ALTER TABLE vendors ADD COLUMN prompt_pattern TEXT DEFAULT ''")
+	s.db.Exec("ALTER TABLE vendors ADD COLUMN pager_pattern TEXT DEFAULT ''")
+	s.db.Exec("ALTER TABLE vendors ADD COLUMN pre_commands TEXT DEFAULT '[]'")
+	s.db.Exec("ALTER TABLE vendors ADD COLUMN enable_command TEXT DEFAULT ''")
+	s.db.Exec("ALTER TABLE vendors ADD COLUMN netconf_port INTEGER DEFAULT 0")
+	s.db.Exec("ALTER TABLE vendors ADD COLUMN image_boot INTEGER DEFAULT 0")
+	s.db.Exec("ALTER TABLE vendors ADD COLUMN bootstrap_template TEXT DEFAULT ''")
+
+	// Migration: Config generation runs through the reloader rather than the job queue;
+	// drop its old jobs, which nothing handles any more
+	s.db.Exec("DELETE FROM jobs WHERE type = 'config.generate'")
 
 	// Seed default vendors if they don't exist (insert or ignore)
 	defaultVendors := getDefaultVendors()
 	for _, v := range defaultVendors {
 		macPrefixesJSON, _ := json.Marshal(v.MacPrefixes)
+		preCommandsJSON := marshalStrings(v.PreCommands)
 		// Use INSERT OR IGNORE to only add if not already present
 		_, err := s.db.Exec(`
-			INSERT OR IGNORE INTO vendors (id, name, backup_command, ssh_port, mac_prefixes, vendor_class, default_template, created_at, updated_at)
-			VALUES (?, ?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
-		`, v.ID, v.Name, v.BackupCommand, v.SSHPort, string(macPrefixesJSON), v.VendorClass, v.DefaultTemplate)
+			INSERT OR IGNORE INTO vendors (id, name, backup_command, ssh_port, mac_prefixes, vendor_class, default_template,
+			                               backup_mode, prompt_pattern, pager_pattern, pre_commands, enable_command, created_at, updated_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
+		`, v.ID, v.Name, v.BackupCommand, v.SSHPort, string(macPrefixesJSON), v.VendorClass, v.DefaultTemplate,
+			v.BackupMode, v.PromptPattern, v.PagerPattern, preCommandsJSON, v.EnableCommand)
 		if err != nil {
 			return err
 		}
@@ -601,75 +1021,159 @@ func (s *Store) migrate() error {
 		s.db.Exec(`
 			UPDATE vendors SET mac_prefixes = ?, vendor_class = ?, default_template = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?
 		`, string(macPrefixesJSON), v.VendorClass, v.DefaultTemplate, v.ID)
+		// Give default vendors created before interactive backups their shell settings,
+		// leaving any the user has configured alone
+		s.db.Exec(`
+			UPDATE vendors SET backup_mode = ?, prompt_pattern = ?, pager_pattern = ?, pre_commands = ?, enable_command = ?
+			WHERE id = ? AND prompt_pattern = '' AND pager_pattern = '' AND pre_commands = '[]' AND enable_command = ''
+		`, v.BackupMode, v.PromptPattern, v.PagerPattern, preCommandsJSON, v.EnableCommand, v.ID)
+	}
+
+	// Migration: Normalize stored MAC addresses to canonical aa:bb:cc:dd:ee:ff form
+	if err := s.normalizeMACs(); err != nil {
+		return fmt.Errorf("failed to normalize MAC addresses: %w", err)
 	}
 
 	return nil
 }
 
-// Device operations
+// normalizeMACs rewrites MAC columns that were stored in a non-canonical notation.
+// Devices whose canonical MAC already exists as another row are left untouched and logged,
+// as are values that can't be parsed at all.
+func (s *Store) normalizeMACs() error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
 
-// ListDevices returns all devices
-func (s *Store) ListDevices() ([]models.Device, error) {
-	rows, err := s.db.Query(`
-		SELECT mac, ip, hostname, vendor, model, serial_number, config_template, ssh_user, ssh_pass,
-		       status, last_seen, last_backup, last_error, created_at, updated_at
-		FROM devices ORDER BY hostname
-	`)
+	deviceMACs, err := queryStrings(tx, "SELECT mac FROM devices")
 	if err != nil {
-		return nil, err
+		return err
 	}
-	defer rows.Close()
 
-	var devices []models.Device
-	for rows.Next() {
-		var d models.Device
-		var lastSeen, lastBackup sql.NullTime
-		var lastError sql.NullString
-		err := rows.Scan(
-			&d.MAC, &d.IP, &d.Hostname, &d.Vendor, &d.Model, &d.SerialNumber, &d.ConfigTemplate,
-			&d.SSHUser, &d.SSHPass, &d.Status,
-			&lastSeen, &lastBackup, &lastError, &d.CreatedAt, &d.UpdatedAt,
-		)
+	existing := make(map[string]bool, len(deviceMACs))
+	for _, mac := range deviceMACs {
+		existing[mac] = true
+	}
+
+	for _, mac := range deviceMACs {
+		canonical, err := utils.ParseMac(mac)
 		if err != nil {
-			return nil, err
+			log.Printf("Warning: device has invalid MAC %q, leaving as-is", mac)
+			continue
 		}
-		if lastSeen.Valid {
-			d.LastSeen = &lastSeen.Time
+		if canonical == mac {
+			continue
 		}
-		if lastBackup.Valid {
-			d.LastBackup = &lastBackup.Time
+		if existing[canonical] {
+			log.Printf("Warning: cannot normalize device MAC %q, %s already exists", mac, canonical)
+			continue
 		}
-		if lastError.Valid {
-			d.LastError = lastError.String
+		if _, err := tx.Exec("UPDATE devices SET mac = ? WHERE mac = ?", canonical, mac); err != nil {
+			return err
 		}
-		devices = append(devices, d)
+		existing[canonical] = true
+		delete(existing, mac)
+		log.Printf("Normalized device MAC %q to %s", mac, canonical)
 	}
 
-	return devices, rows.Err()
+	// Non-key MAC columns can always be rewritten in place
+	if err := normalizeMACColumn(tx, "backups", "device_mac"); err != nil {
+		return err
+	}
+	if err := normalizeMACColumn(tx, "discovery_logs", "mac"); err != nil {
+		return err
+	}
+	if err := normalizeMACColumn(tx, "device_facts", "device_mac"); err != nil {
+		return err
+	}
+	if err := normalizeMACColumn(tx, "config_changes", "device_mac"); err != nil {
+		return err
+	}
+
+	// pending_devices is keyed by MAC: invalid rows are dropped, and a non-canonical
+	// duplicate of an existing canonical entry is discarded in favour of that entry
+	pendingMACs, err := queryStrings(tx, "SELECT mac FROM pending_devices")
+	if err != nil {
+		return err
+	}
+	for _, mac := range pendingMACs {
+		canonical, err := utils.ParseMac(mac)
+		if err == nil && canonical == mac {
+			continue
+		}
+		if err == nil {
+			if _, err := tx.Exec("UPDATE OR IGNORE pending_devices SET mac = ? WHERE mac = ?", canonical, mac); err != nil {
+				return err
+			}
+		}
+		if _, err := tx.Exec("DELETE FROM pending_devices WHERE mac = ?", mac); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
 }
 
-// GetDevice returns a device by MAC address
-func (s *Store) GetDevice(mac string) (*models.Device, error) {
-	var d models.Device
-	var lastSeen, lastBackup sql.NullTime
-	var lastError sql.NullString
+// normalizeMACColumn rewrites every parseable MAC in a non-key column to canonical form
+func normalizeMACColumn(tx *sql.Tx, table, column string) error {
+	macs, err := queryStrings(tx, fmt.Sprintf("SELECT DISTINCT %s FROM %s", column, table))
+	if err != nil {
+		return err
+	}
 
-	err := s.db.QueryRow(`
-		SELECT mac, ip, hostname, vendor, model, serial_number, config_template, ssh_user, ssh_pass,
-		       status, last_seen, last_backup, last_error, created_at, updated_at
-		FROM devices WHERE mac = ?
-	`, mac).Scan(
-		&d.MAC, &d.IP, &d.Hostname, &d.Vendor, &d.Model, &d.SerialNumber, &d.ConfigTemplate,
-		&d.SSHUser, &d.SSHPass, &d.Status,
-		&lastSeen, &lastBackup, &lastError, &d.CreatedAt, &d.UpdatedAt,
-	)
-	if err == sql.ErrNoRows {
-		return nil, nil
+	for _, mac := range macs {
+		canonical, err := utils.ParseMac(mac)
+		if err != nil || canonical == mac {
+			continue
+		}
+		if _, err := tx.Exec(fmt.Sprintf("UPDATE %s SET %s = ? WHERE %s = ?", table, column, column), canonical, mac); err != nil {
+			return err
+		}
 	}
+	return nil
+}
+
+// queryStrings runs a single-column query and collects the values
+func queryStrings(tx *sql.Tx, query string) ([]string, error) {
+	rows, err := tx.Query(query)
 	if err != nil {
 		return nil, err
 	}
+	defer rows.Close()
 
+	var values []string
+	for rows.Next() {
+		var v string
+		if err := rows.Scan(&v); err != nil {
+			return nil, err
+		}
+		values = append(values, v)
+	}
+	return values, rows.Err()
+}
+
+// Device operations
+
+// deviceColumns lists the devices columns read by scanDevice
+const deviceColumns = `mac, ip, hostname, vendor, model, serial_number, config_template, ssh_user, ssh_pass, enable_secret,
+		       status, last_seen, last_backup, last_error, upgrade_status, upgrade_version, provisioned_at, netbox_id, site, role, tenant, platform,
+		       created_at, updated_at`
+
+// scanDevice scans a devices row selected with deviceColumns into a model
+func scanDevice(scanner interface{ Scan(...any) error }) (*models.Device, error) {
+	var d models.Device
+	var lastSeen, lastBackup, provisionedAt sql.NullTime
+	var lastError, upgradeStatus, upgradeVersion, enableSecret, site, role, tenant, platform sql.NullString
+	err := scanner.Scan(
+		&d.MAC, &d.IP, &d.Hostname, &d.Vendor, &d.Model, &d.SerialNumber, &d.ConfigTemplate,
+		&d.SSHUser, &d.SSHPass, &enableSecret, &d.Status,
+		&lastSeen, &lastBackup, &lastError, &upgradeStatus, &upgradeVersion, &provisionedAt, &d.NetBoxID, &site, &role, &tenant, &platform, &d.CreatedAt, &d.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
 	if lastSeen.Valid {
 		d.LastSeen = &lastSeen.Time
 	}
@@ -679,10 +1183,56 @@ func (s *Store) GetDevice(mac string) (*models.Device, error) {
 	if lastError.Valid {
 		d.LastError = lastError.String
 	}
-
+	if provisionedAt.Valid {
+		d.ProvisionedAt = &provisionedAt.Time
+	}
+	d.UpgradeStatus = upgradeStatus.String
+	d.UpgradeVersion = upgradeVersion.String
+	d.EnableSecret = enableSecret.String
+	d.Site = site.String
+	d.Role = role.String
+	d.Tenant = tenant.String
+	d.Platform = platform.String
 	return &d, nil
 }
 
+// ListDevices returns all devices
+func (s *Store) ListDevices() ([]models.Device, error) {
+	rows, err := s.db.Query(`SELECT ` + deviceColumns + ` FROM devices ORDER BY hostname`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var devices []models.Device
+	for rows.Next() {
+		d, err := scanDevice(rows)
+		if err != nil {
+			return nil, err
+		}
+		devices = append(devices, *d)
+	}
+
+	return devices, rows.Err()
+}
+
+// GetDevice returns a device by MAC address
+func (s *Store) GetDevice(mac string) (*models.Device, error) {
+	d, err := scanDevice(s.db.QueryRow(`SELECT `+deviceColumns+` FROM devices WHERE mac = ?`, mac))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// SetDeviceNetBoxID links a device to its NetBox object (0 clears the link)
+func (s *Store) SetDeviceNetBoxID(mac string, netboxID int) error {
+	return s.execWithRowCheck("device", mac, "UPDATE devices SET netbox_id = ? WHERE mac = ?", netboxID, mac)
+}
+
 // CreateDevice creates a new device
 func (s *Store) CreateDevice(d *models.Device) error {
 	now := time.Now()
@@ -691,9 +1241,11 @@ func (s *Store) CreateDevice(d *models.Device) error {
 	d.Status = "offline"
 
 	_, err := s.db.Exec(`
-		INSERT INTO devices (mac, ip, hostname, vendor, model, serial_number, config_template, ssh_user, ssh_pass, status, created_at, updated_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
-	`, d.MAC, d.IP, d.Hostname, d.Vendor, d.Model, d.SerialNumber, d.ConfigTemplate, d.SSHUser, d.SSHPass, d.Status, d.CreatedAt, d.UpdatedAt)
+		INSERT INTO devices (mac, ip, hostname, vendor, model, serial_number, config_template, ssh_user, ssh_pass, enable_secret, status, netbox_id,
+		                     site, role, tenant, platform, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, d.MAC, d.IP, d.Hostname, d.Vendor, d.Model, d.SerialNumber, d.ConfigTemplate, d.SSHUser, d.SSHPass, d.EnableSecret, d.Status, d.NetBoxID,
+		d.Site, d.Role, d.Tenant, d.Platform, d.CreatedAt, d.UpdatedAt)
 
 	return err
 }
@@ -704,9 +1256,10 @@ func (s *Store) UpdateDevice(d *models.Device) error {
 
 	result, err := s.db.Exec(`
 		UPDATE devices SET ip = ?, hostname = ?, vendor = ?, model = ?, serial_number = ?, config_template = ?,
-		       ssh_user = ?, ssh_pass = ?, updated_at = ?
+		       ssh_user = ?, ssh_pass = ?, enable_secret = ?, site = ?, role = ?, tenant = ?, platform = ?, updated_at = ?
 		WHERE mac = ?
-	`, d.IP, d.Hostname, d.Vendor, d.Model, d.SerialNumber, d.ConfigTemplate, d.SSHUser, d.SSHPass, d.UpdatedAt, d.MAC)
+	`, d.IP, d.Hostname, d.Vendor, d.Model, d.SerialNumber, d.ConfigTemplate, d.SSHUser, d.SSHPass, d.EnableSecret,
+		d.Site, d.Role, d.Tenant, d.Platform, d.UpdatedAt, d.MAC)
 	if err != nil {
 		return err
 	}
@@ -767,6 +1320,59 @@ func (s *Store) ClearDeviceError(mac string) error {
 	return s.UpdateDeviceError(mac, "")
 }
 
+// UpdateDeviceUpgrade records a device's image upgrade status and the version it refers to
+func (s *Store) UpdateDeviceUpgrade(mac, status, version string) error {
+	return s.execWithRowCheck("device", mac, `
+		UPDATE devices SET upgrade_status = ?, upgrade_version = ?, updated_at = ?
+		WHERE mac = ?
+	`, status, version, time.Now(), mac)
+}
+
+// SetDeviceProvisioned records that a device has finished provisioning
+func (s *Store) SetDeviceProvisioned(mac string) error {
+	now := time.Now()
+	return s.execWithRowCheck("device", mac, `
+		UPDATE devices SET provisioned_at = ?, updated_at = ?
+		WHERE mac = ?
+	`, now, now, mac)
+}
+
+// ClearDeviceProvisioned forgets that a device was provisioned, lifting the config
+// download lockout
+func (s *Store) ClearDeviceProvisioned(mac string) error {
+	return s.execWithRowCheck("device", mac, `
+		UPDATE devices SET provisioned_at = NULL, updated_at = ?
+		WHERE mac = ?
+	`, time.Now(), mac)
+}
+
+// FindDeviceByIP returns the device with an IP address, or nil if there is none
+func (s *Store) FindDeviceByIP(ip string) (*models.Device, error) {
+	d, err := scanDevice(s.db.QueryRow(`SELECT `+deviceColumns+` FROM devices WHERE ip = ? LIMIT 1`, ip))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// FindDeviceBySerial returns the device with a serial number, or nil if there is none
+func (s *Store) FindDeviceBySerial(serial string) (*models.Device, error) {
+	if serial == "" {
+		return nil, nil
+	}
+	d, err := scanDevice(s.db.QueryRow(`SELECT `+deviceColumns+` FROM devices WHERE serial_number = ? LIMIT 1`, serial))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
 // Settings operations
 
 // GetSettings returns the global settings
@@ -800,10 +1406,13 @@ func (s *Store) UpdateSettings(settings *models.Settings) error {
 
 // CreateBackup records a new backup
 func (s *Store) CreateBackup(b *models.Backup) error {
+	if b.Format == "" {
+		b.Format = models.BackupFormatText
+	}
 	result, err := s.db.Exec(`
-		INSERT INTO backups (device_mac, filename, size, created_at)
-		VALUES (?, ?, ?, ?)
-	`, b.DeviceMAC, b.Filename, b.Size, time.Now())
+		INSERT INTO backups (device_mac, filename, format, size, created_at)
+		VALUES (?, ?, ?, ?, ?)
+	`, b.DeviceMAC, b.Filename, b.Format, b.Size, time.Now())
 	if err != nil {
 		return err
 	}
@@ -816,7 +1425,7 @@ func (s *Store) CreateBackup(b *models.Backup) error {
 // ListBackups returns all backups for a device
 func (s *Store) ListBackups(mac string) ([]models.Backup, error) {
 	rows, err := s.db.Query(`
-		SELECT id, device_mac, filename, size, created_at
+		SELECT id, device_mac, filename, format, size, created_at
 		FROM backups WHERE device_mac = ?
 		ORDER BY created_at DESC
 	`, mac)
@@ -828,7 +1437,7 @@ func (s *Store) ListBackups(mac string) ([]models.Backup, error) {
 	var backups []models.Backup
 	for rows.Next() {
 		var b models.Backup
-		if err := rows.Scan(&b.ID, &b.DeviceMAC, &b.Filename, &b.Size, &b.CreatedAt); err != nil {
+		if err := rows.Scan(&b.ID, &b.DeviceMAC, &b.Filename, &b.Format, &b.Size, &b.CreatedAt); err != nil {
 			return nil, err
 		}
 		backups = append(backups, b)
@@ -841,9 +1450,9 @@ func (s *Store) ListBackups(mac string) ([]models.Backup, error) {
 func (s *Store) GetBackup(id string) (*models.Backup, error) {
 	var b models.Backup
 	err := s.db.QueryRow(`
-		SELECT id, device_mac, filename, size, created_at
+		SELECT id, device_mac, filename, format, size, created_at
 		FROM backups WHERE id = ?
-	`, id).Scan(&b.ID, &b.DeviceMAC, &b.Filename, &b.Size, &b.CreatedAt)
+	`, id).Scan(&b.ID, &b.DeviceMAC, &b.Filename, &b.Format, &b.Size, &b.CreatedAt)
 
 	if err == sql.ErrNoRows {
 		return nil, nil
@@ -859,7 +1468,8 @@ func (s *Store) GetBackup(id string) (*models.Backup, error) {
 // ListVendors returns all vendors with device counts
 func (s *Store) ListVendors() ([]models.Vendor, error) {
 	rows, err := s.db.Query(`
-		SELECT v.id, v.name, v.backup_command, v.ssh_port, v.mac_prefixes, v.vendor_class, v.default_template, v.created_at, v.updated_at,
+		SELECT v.id, v.name, v.backup_command, v.ssh_port, v.mac_prefixes, v.vendor_class, v.default_template,
+		       v.backup_mode, v.prompt_pattern, v.pager_pattern, v.pre_commands, v.enable_command, v.netconf_port, v.image_boot, v.bootstrap_template, v.created_at, v.updated_at,
 		       COALESCE(COUNT(d.mac), 0) as device_count
 		FROM vendors v
 		LEFT JOIN devices d ON d.vendor = v.id
@@ -874,10 +1484,14 @@ func (s *Store) ListVendors() ([]models.Vendor, error) {
 	var vendors []models.Vendor
 	for rows.Next() {
 		var v models.Vendor
-		var macPrefixesJSON string
-		if err := rows.Scan(&v.ID, &v.Name, &v.BackupCommand, &v.SSHPort, &macPrefixesJSON, &v.VendorClass, &v.DefaultTemplate, &v.CreatedAt, &v.UpdatedAt, &v.DeviceCount); err != nil {
+		var macPrefixesJSON, preCommandsJSON string
+		var imageBoot int
+		if err := rows.Scan(&v.ID, &v.Name, &v.BackupCommand, &v.SSHPort, &macPrefixesJSON, &v.VendorClass, &v.DefaultTemplate,
+			&v.BackupMode, &v.PromptPattern, &v.PagerPattern, &preCommandsJSON, &v.EnableCommand, &v.NetconfPort, &imageBoot, &v.BootstrapTemplate, &v.CreatedAt, &v.UpdatedAt, &v.DeviceCount); err != nil {
 			return nil, err
 		}
+		v.PreCommands = unmarshalStrings(preCommandsJSON)
+		v.ImageBoot = imageBoot == 1
 		// Parse mac_prefixes JSON
 		if macPrefixesJSON != "" {
 			json.Unmarshal([]byte(macPrefixesJSON), &v.MacPrefixes)
@@ -894,21 +1508,26 @@ func (s *Store) ListVendors() ([]models.Vendor, error) {
 // GetVendor returns a vendor by ID
 func (s *Store) GetVendor(id string) (*models.Vendor, error) {
 	var v models.Vendor
-	var macPrefixesJSON string
+	var macPrefixesJSON, preCommandsJSON string
+	var imageBoot int
 	err := s.db.QueryRow(`
-		SELECT v.id, v.name, v.backup_command, v.ssh_port, v.mac_prefixes, v.vendor_class, v.default_template, v.created_at, v.updated_at,
+		SELECT v.id, v.name, v.backup_command, v.ssh_port, v.mac_prefixes, v.vendor_class, v.default_template,
+		       v.backup_mode, v.prompt_pattern, v.pager_pattern, v.pre_commands, v.enable_command, v.netconf_port, v.image_boot, v.bootstrap_template, v.created_at, v.updated_at,
 		       COALESCE(COUNT(d.mac), 0) as device_count
 		FROM vendors v
 		LEFT JOIN devices d ON d.vendor = v.id
 		WHERE v.id = ?
 		GROUP BY v.id
-	`, id).Scan(&v.ID, &v.Name, &v.BackupCommand, &v.SSHPort, &macPrefixesJSON, &v.VendorClass, &v.DefaultTemplate, &v.CreatedAt, &v.UpdatedAt, &v.DeviceCount)
+	`, id).Scan(&v.ID, &v.Name, &v.BackupCommand, &v.SSHPort, &macPrefixesJSON, &v.VendorClass, &v.DefaultTemplate,
+		&v.BackupMode, &v.PromptPattern, &v.PagerPattern, &preCommandsJSON, &v.EnableCommand, &v.NetconfPort, &imageBoot, &v.BootstrapTemplate, &v.CreatedAt, &v.UpdatedAt, &v.DeviceCount)
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
 	if err != nil {
 		return nil, err
 	}
+	v.PreCommands = unmarshalStrings(preCommandsJSON)
+	v.ImageBoot = imageBoot == 1
 	// Parse mac_prefixes JSON
 	if macPrefixesJSON != "" {
 		json.Unmarshal([]byte(macPrefixesJSON), &v.MacPrefixes)
@@ -929,11 +1548,21 @@ func (s *Store) CreateVendor(v *models.Vendor) error {
 		v.MacPrefixes = []string{}
 	}
 	macPrefixesJSON, _ := json.Marshal(v.MacPrefixes)
+	if v.BackupMode == "" {
+		v.BackupMode = models.BackupModeExec
+	}
+	if v.PreCommands == nil {
+		v.PreCommands = []string{}
+	}
 
 	_, err := s.db.Exec(`
-		INSERT INTO vendors (id, name, backup_command, ssh_port, mac_prefixes, vendor_class, default_template, created_at, updated_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
-	`, v.ID, v.Name, v.BackupCommand, v.SSHPort, string(macPrefixesJSON), v.VendorClass, v.DefaultTemplate, v.CreatedAt, v.UpdatedAt)
+		INSERT INTO vendors (id, name, backup_command, ssh_port, mac_prefixes, vendor_class, default_template,
+		                     backup_mode, prompt_pattern, pager_pattern, pre_commands, enable_command, netconf_port, image_boot,
+		                     bootstrap_template, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, v.ID, v.Name, v.BackupCommand, v.SSHPort, string(macPrefixesJSON), v.VendorClass, v.DefaultTemplate,
+		v.BackupMode, v.PromptPattern, v.PagerPattern, marshalStrings(v.PreCommands), v.EnableCommand, v.NetconfPort, boolToInt(v.ImageBoot),
+		v.BootstrapTemplate, v.CreatedAt, v.UpdatedAt)
 
 	return err
 }
@@ -946,11 +1575,38 @@ func (s *Store) UpdateVendor(v *models.Vendor) error {
 		v.MacPrefixes = []string{}
 	}
 	macPrefixesJSON, _ := json.Marshal(v.MacPrefixes)
+	if v.BackupMode == "" {
+		v.BackupMode = models.BackupModeExec
+	}
+	if v.PreCommands == nil {
+		v.PreCommands = []string{}
+	}
 
 	return s.execWithRowCheck("vendor", v.ID, `
-		UPDATE vendors SET name = ?, backup_command = ?, ssh_port = ?, mac_prefixes = ?, vendor_class = ?, default_template = ?, updated_at = ?
+		UPDATE vendors SET name = ?, backup_command = ?, ssh_port = ?, mac_prefixes = ?, vendor_class = ?, default_template = ?,
+		       backup_mode = ?, prompt_pattern = ?, pager_pattern = ?, pre_commands = ?, enable_command = ?, netconf_port = ?, image_boot = ?,
+		       bootstrap_template = ?, updated_at = ?
 		WHERE id = ?
-	`, v.Name, v.BackupCommand, v.SSHPort, string(macPrefixesJSON), v.VendorClass, v.DefaultTemplate, v.UpdatedAt, v.ID)
+	`, v.Name, v.BackupCommand, v.SSHPort, string(macPrefixesJSON), v.VendorClass, v.DefaultTemplate,
+		v.BackupMode, v.PromptPattern, v.PagerPattern, marshalStrings(v.PreCommands), v.EnableCommand, v.NetconfPort, boolToInt(v.ImageBoot),
+		v.BootstrapTemplate, v.UpdatedAt, v.ID)
+}
+
+// FindVendorByMAC returns the vendor whose OUI prefixes match the MAC, or nil if none match
+func (s *Store) FindVendorByMAC(mac string) (*models.Vendor, error) {
+	vendors, err := s.ListVendors()
+	if err != nil {
+		return nil, err
+	}
+	mac = strings.ToUpper(mac)
+	for i := range vendors {
+		for _, prefix := range vendors[i].MacPrefixes {
+			if prefix != "" && strings.HasPrefix(mac, strings.ToUpper(prefix)) {
+				return &vendors[i], nil
+			}
+		}
+	}
+	return nil, nil
 }
 
 // DeleteVendor removes a vendor
@@ -958,12 +1614,30 @@ func (s *Store) DeleteVendor(id string) error {
 	return s.execWithRowCheck("vendor", id, "DELETE FROM vendors WHERE id = ?", id)
 }
 
+// marshalStrings encodes a string list for a JSON array column
+func marshalStrings(values []string) string {
+	if len(values) == 0 {
+		return "[]"
+	}
+	data, _ := json.Marshal(values)
+	return string(data)
+}
+
+// unmarshalStrings decodes a JSON array column, returning an empty list if it is unset
+func unmarshalStrings(data string) []string {
+	values := []string{}
+	if data != "" {
+		json.Unmarshal([]byte(data), &values)
+	}
+	return values
+}
+
 // DHCP Option operations
 
 // ListDhcpOptions returns all DHCP options
 func (s *Store) ListDhcpOptions() ([]models.DhcpOption, error) {
 	rows, err := s.db.Query(`
-		SELECT id, option_number, name, value, type, vendor_id, description, enabled, created_at, updated_at
+		SELECT id, option_number, name, value, type, sub_options, enterprise_number, vendor_id, description, enabled, created_at, updated_at
 		FROM dhcp_options
 		ORDER BY option_number, vendor_id
 	`)
@@ -974,13 +1648,11 @@ func (s *Store) ListDhcpOptions() ([]models.DhcpOption, error) {
 
 	var options []models.DhcpOption
 	for rows.Next() {
-		var o models.DhcpOption
-		var enabled int
-		if err := rows.Scan(&o.ID, &o.OptionNumber, &o.Name, &o.Value, &o.Type, &o.VendorID, &o.Description, &enabled, &o.CreatedAt, &o.UpdatedAt); err != nil {
+		o, err := scanDhcpOption(rows)
+		if err != nil {
 			return nil, err
 		}
-		o.Enabled = enabled == 1
-		options = append(options, o)
+		options = append(options, *o)
 	}
 
 	return options, rows.Err()
@@ -988,22 +1660,44 @@ func (s *Store) ListDhcpOptions() ([]models.DhcpOption, error) {
 
 // GetDhcpOption returns a DHCP option by ID
 func (s *Store) GetDhcpOption(id string) (*models.DhcpOption, error) {
-	var o models.DhcpOption
-	var enabled int
-	err := s.db.QueryRow(`
-		SELECT id, option_number, name, value, type, vendor_id, description, enabled, created_at, updated_at
+	o, err := scanDhcpOption(s.db.QueryRow(`
+		SELECT id, option_number, name, value, type, sub_options, enterprise_number, vendor_id, description, enabled, created_at, updated_at
 		FROM dhcp_options WHERE id = ?
-	`, id).Scan(&o.ID, &o.OptionNumber, &o.Name, &o.Value, &o.Type, &o.VendorID, &o.Description, &enabled, &o.CreatedAt, &o.UpdatedAt)
+	`, id))
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
 	if err != nil {
 		return nil, err
 	}
+	return o, nil
+}
+
+// scanDhcpOption scans a dhcp_options row into a model
+func scanDhcpOption(scanner interface{ Scan(...any) error }) (*models.DhcpOption, error) {
+	var o models.DhcpOption
+	var enabled int
+	var subOptionsJSON sql.NullString
+	if err := scanner.Scan(&o.ID, &o.OptionNumber, &o.Name, &o.Value, &o.Type, &subOptionsJSON, &o.EnterpriseNumber, &o.VendorID, &o.Description, &enabled, &o.CreatedAt, &o.UpdatedAt); err != nil {
+		return nil, err
+	}
 	o.Enabled = enabled == 1
+	// Parse sub_options JSON
+	if subOptionsJSON.Valid && subOptionsJSON.String != "" {
+		json.Unmarshal([]byte(subOptionsJSON.String), &o.SubOptions)
+	}
 	return &o, nil
 }
 
+// marshalSubOptions encodes sub-options for the sub_options column
+func marshalSubOptions(subOptions []models.DhcpSubOption) string {
+	if len(subOptions) == 0 {
+		return "[]"
+	}
+	data, _ := json.Marshal(subOptions)
+	return string(data)
+}
+
 // CreateDhcpOption creates a new DHCP option
 func (s *Store) CreateDhcpOption(o *models.DhcpOption) error {
 	now := time.Now()
@@ -1011,9 +1705,9 @@ func (s *Store) CreateDhcpOption(o *models.DhcpOption) error {
 	o.UpdatedAt = now
 
 	_, err := s.db.Exec(`
-		INSERT INTO dhcp_options (id, option_number, name, value, type, vendor_id, description, enabled, created_at, updated_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
-	`, o.ID, o.OptionNumber, o.Name, o.Value, o.Type, o.VendorID, o.Description, boolToInt(o.Enabled), o.CreatedAt, o.UpdatedAt)
+		INSERT INTO dhcp_options (id, option_number, name, value, type, sub_options, enterprise_number, vendor_id, description, enabled, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, o.ID, o.OptionNumber, o.Name, o.Value, o.Type, marshalSubOptions(o.SubOptions), o.EnterpriseNumber, o.VendorID, o.Description, boolToInt(o.Enabled), o.CreatedAt, o.UpdatedAt)
 
 	return err
 }
@@ -1023,9 +1717,9 @@ func (s *Store) UpdateDhcpOption(o *models.DhcpOption) error {
 	o.UpdatedAt = time.Now()
 
 	return s.execWithRowCheck("dhcp option", o.ID, `
-		UPDATE dhcp_options SET option_number = ?, name = ?, value = ?, type = ?, vendor_id = ?, description = ?, enabled = ?, updated_at = ?
+		UPDATE dhcp_options SET option_number = ?, name = ?, value = ?, type = ?, sub_options = ?, enterprise_number = ?, vendor_id = ?, description = ?, enabled = ?, updated_at = ?
 		WHERE id = ?
-	`, o.OptionNumber, o.Name, o.Value, o.Type, o.VendorID, o.Description, boolToInt(o.Enabled), o.UpdatedAt, o.ID)
+	`, o.OptionNumber, o.Name, o.Value, o.Type, marshalSubOptions(o.SubOptions), o.EnterpriseNumber, o.VendorID, o.Description, boolToInt(o.Enabled), o.UpdatedAt, o.ID)
 }
 
 // DeleteDhcpOption removes a DHCP option
@@ -1117,9 +1811,9 @@ func (s *Store) DeleteTemplate(id string) error {
 func (s *Store) CreateDiscoveryLog(log *models.DiscoveryLog) error {
 	log.CreatedAt = time.Now()
 	result, err := s.db.Exec(`
-		INSERT INTO discovery_logs (event_type, mac, ip, hostname, vendor, message, created_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?)
-	`, log.EventType, log.MAC, log.IP, log.Hostname, log.Vendor, log.Message, log.CreatedAt)
+		INSERT INTO discovery_logs (event_type, mac, ip, hostname, vendor, message, details, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`, log.EventType, log.MAC, log.IP, log.Hostname, log.Vendor, log.Message, log.Details, log.CreatedAt)
 	if err != nil {
 		return err
 	}
@@ -1134,7 +1828,7 @@ func (s *Store) ListDiscoveryLogs(limit int) ([]models.DiscoveryLog, error) {
 		limit = 100
 	}
 	rows, err := s.db.Query(`
-		SELECT id, event_type, mac, ip, hostname, vendor, message, created_at
+		SELECT id, event_type, mac, ip, hostname, vendor, message, details, created_at
 		FROM discovery_logs
 		ORDER BY created_at DESC
 		LIMIT ?
@@ -1147,7 +1841,7 @@ func (s *Store) ListDiscoveryLogs(limit int) ([]models.DiscoveryLog, error) {
 	var logs []models.DiscoveryLog
 	for rows.Next() {
 		var l models.DiscoveryLog
-		if err := rows.Scan(&l.ID, &l.EventType, &l.MAC, &l.IP, &l.Hostname, &l.Vendor, &l.Message, &l.CreatedAt); err != nil {
+		if err := rows.Scan(&l.ID, &l.EventType, &l.MAC, &l.IP, &l.Hostname, &l.Vendor, &l.Message, &l.Details, &l.CreatedAt); err != nil {
 			return nil, err
 		}
 		logs = append(logs, l)
@@ -1166,21 +1860,27 @@ func (s *Store) ClearDiscoveryLogs() error {
 
 // NetBoxConfig holds the NetBox integration settings
 type NetBoxConfig struct {
-	URL         string `json:"url"`
-	Token       string `json:"token"`
-	SiteID      int    `json:"site_id"`
-	RoleID      int    `json:"role_id"`
-	SyncEnabled bool   `json:"sync_enabled"`
+	URL           string            `json:"url"`
+	Token         string            `json:"token"`
+	SiteID        int               `json:"site_id"`
+	RoleID        int               `json:"role_id"`
+	SyncEnabled   bool              `json:"sync_enabled"`
+	SyncInterval  int               `json:"sync_interval"`  // Seconds between background reconciles
+	WebhookSecret string            `json:"webhook_secret"` // Shared secret for X-Hook-Signature verification
+	FieldOwners   map[string]string `json:"field_owners"`   // Field name -> "ztp" or "netbox"
+	PrefixID      int               `json:"prefix_id"`      // NetBox prefix new device IPs are allocated from
 }
 
 // GetNetBoxConfig returns the NetBox configuration
 func (s *Store) GetNetBoxConfig() (*NetBoxConfig, error) {
 	var config NetBoxConfig
 	var syncEnabled int
+	var syncInterval, prefixID sql.NullInt64
+	var webhookSecret, fieldOwnersJSON sql.NullString
 	err := s.db.QueryRow(`
-		SELECT url, token, site_id, role_id, sync_enabled
+		SELECT url, token, site_id, role_id, sync_enabled, sync_interval, webhook_secret, field_owners, prefix_id
 		FROM netbox_config WHERE id = 1
-	`).Scan(&config.URL, &config.Token, &config.SiteID, &config.RoleID, &syncEnabled)
+	`).Scan(&config.URL, &config.Token, &config.SiteID, &config.RoleID, &syncEnabled, &syncInterval, &webhookSecret, &fieldOwnersJSON, &prefixID)
 
 	if err == sql.ErrNoRows {
 		// Return empty config if not set
@@ -1191,6 +1891,12 @@ func (s *Store) GetNetBoxConfig() (*NetBoxConfig, error) {
 	}
 
 	config.SyncEnabled = syncEnabled == 1
+	config.SyncInterval = int(syncInterval.Int64)
+	config.PrefixID = int(prefixID.Int64)
+	config.WebhookSecret = webhookSecret.String
+	if fieldOwnersJSON.Valid && fieldOwnersJSON.String != "" {
+		json.Unmarshal([]byte(fieldOwnersJSON.String), &config.FieldOwners)
+	}
 	return &config, nil
 }
 
@@ -1200,13 +1906,20 @@ func (s *Store) SaveNetBoxConfig(config *NetBoxConfig) error {
 	if config.SyncEnabled {
 		syncEnabled = 1
 	}
+	fieldOwners := config.FieldOwners
+	if fieldOwners == nil {
+		fieldOwners = map[string]string{}
+	}
+	fieldOwnersJSON, _ := json.Marshal(fieldOwners)
 
 	// Try to update first
 	result, err := s.db.Exec(`
 		UPDATE netbox_config
-		SET url = ?, token = ?, site_id = ?, role_id = ?, sync_enabled = ?, updated_at = CURRENT_TIMESTAMP
+		SET url = ?, token = ?, site_id = ?, role_id = ?, sync_enabled = ?,
+		    sync_interval = ?, webhook_secret = ?, field_owners = ?, prefix_id = ?, updated_at = CURRENT_TIMESTAMP
 		WHERE id = 1
-	`, config.URL, config.Token, config.SiteID, config.RoleID, syncEnabled)
+	`, config.URL, config.Token, config.SiteID, config.RoleID, syncEnabled,
+		config.SyncInterval, config.WebhookSecret, string(fieldOwnersJSON), config.PrefixID)
 	if err != nil {
 		return err
 	}
@@ -1215,9 +1928,10 @@ func (s *Store) SaveNetBoxConfig(config *NetBoxConfig) error {
 	if rowsAffected == 0 {
 		// Insert if no row exists
 		_, err = s.db.Exec(`
-			INSERT INTO netbox_config (id, url, token, site_id, role_id, sync_enabled)
-			VALUES (1, ?, ?, ?, ?, ?)
-		`, config.URL, config.Token, config.SiteID, config.RoleID, syncEnabled)
+			INSERT INTO netbox_config (id, url, token, site_id, role_id, sync_enabled, sync_interval, webhook_secret, field_owners, prefix_id)
+			VALUES (1, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		`, config.URL, config.Token, config.SiteID, config.RoleID, syncEnabled,
+			config.SyncInterval, config.WebhookSecret, string(fieldOwnersJSON), config.PrefixID)
 		return err
 	}
 
@@ -0,0 +1,89 @@
+package db
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"time"
+)
+
+// Download token operations
+
+// CreateDownloadToken issues a token that lets a device download its config. A ttl of 0
+// gives a token that doesn't expire, and a one-time token is used up by its first
+// download. An unused token of the device with the same options and at least half its
+// lifetime left is handed out again, so rendering a config over and over doesn't pile
+// up tokens. Expired and used tokens are removed along the way.
+func (s *Store) CreateDownloadToken(mac string, ttl time.Duration, oneTime bool) (string, error) {
+	now := time.Now().UTC()
+	if _, err := s.db.Exec("DELETE FROM download_tokens WHERE expires_at < ? OR used_at IS NOT NULL", now); err != nil {
+		return "", err
+	}
+
+	query := `SELECT token FROM download_tokens WHERE device_mac = ? AND one_time = ? AND used_at IS NULL`
+	args := []any{mac, oneTime}
+	if ttl > 0 {
+		query += ` AND expires_at >= ?`
+		args = append(args, now.Add(ttl/2))
+	} else {
+		query += ` AND expires_at IS NULL`
+	}
+	var token string
+	err := s.db.QueryRow(query+` ORDER BY created_at DESC LIMIT 1`, args...).Scan(&token)
+	if err == nil {
+		return token, nil
+	}
+	if err != sql.ErrNoRows {
+		return "", err
+	}
+
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	token = hex.EncodeToString(b)
+
+	var expiresAt sql.NullTime
+	if ttl > 0 {
+		expiresAt = sql.NullTime{Time: now.Add(ttl), Valid: true}
+	}
+	_, err = s.db.Exec(`
+		INSERT INTO download_tokens (token, device_mac, one_time, expires_at, created_at)
+		VALUES (?, ?, ?, ?, ?)
+	`, token, mac, oneTime, expiresAt, now)
+	if err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// UseDownloadToken reports whether token is a valid download token of a device, using
+// it up if it is a one-time token
+func (s *Store) UseDownloadToken(token, mac string) (bool, error) {
+	var oneTime bool
+	var expiresAt, usedAt sql.NullTime
+	err := s.db.QueryRow(`
+		SELECT one_time, expires_at, used_at FROM download_tokens WHERE token = ? AND device_mac = ?
+	`, token, mac).Scan(&oneTime, &expiresAt, &usedAt)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	now := time.Now().UTC()
+	if usedAt.Valid || (expiresAt.Valid && now.After(expiresAt.Time)) {
+		return false, nil
+	}
+	if !oneTime {
+		return true, nil
+	}
+
+	// Only one of concurrent downloads with the same token gets to use it
+	result, err := s.db.Exec("UPDATE download_tokens SET used_at = ? WHERE token = ? AND used_at IS NULL", now, token)
+	if err != nil {
+		return false, err
+	}
+	rows, err := result.RowsAffected()
+	return rows == 1, err
+}
@@ -0,0 +1,87 @@
+package dhcp
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/ztp-server/backend/models"
+)
+
+// configCache holds rendered device configs by MAC. Each config is stored with the key
+// it was rendered for, a hash of its template and template data, so a config is stale
+// as soon as anything that goes into it changes and no explicit invalidation is needed.
+type configCache struct {
+	mu      sync.Mutex
+	entries map[string]cachedConfig
+}
+
+type cachedConfig struct {
+	key    string
+	config []byte
+}
+
+func newConfigCache() *configCache {
+	return &configCache{entries: make(map[string]cachedConfig)}
+}
+
+// get returns a device's cached config if it was rendered for key
+func (c *configCache) get(mac, key string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[mac]
+	if !ok || entry.key != key {
+		return nil, false
+	}
+	return entry.config, true
+}
+
+// put caches a device's config rendered for key
+func (c *configCache) put(mac, key string, config []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[mac] = cachedConfig{key: key, config: config}
+}
+
+// retain drops the configs of devices not in macs
+func (c *configCache) retain(macs map[string]bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for mac := range c.entries {
+		if !macs[mac] {
+			delete(c.entries, mac)
+		}
+	}
+}
+
+// cacheKey returns the key of a config rendered from template content with data
+func cacheKey(content string, data any) (string, error) {
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return "", err
+	}
+	h := sha256.New()
+	h.Write([]byte(content))
+	h.Write([]byte{0})
+	h.Write(encoded)
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// stableDevice returns a copy of a device without the fields that change as it runs:
+// its status, timestamps, last error and upgrade progress. Configs are keyed on it, so
+// a device checking in or being backed up doesn't get its config rendered again.
+func stableDevice(device *models.Device) *models.Device {
+	stable := *device
+	stable.Status = ""
+	stable.LastSeen = nil
+	stable.LastBackup = nil
+	stable.LastError = ""
+	stable.UpgradeStatus = ""
+	stable.UpgradeVersion = ""
+	stable.ProvisionedAt = nil
+	stable.CreatedAt = time.Time{}
+	stable.UpdatedAt = time.Time{}
+	return &stable
+}
@@ -1,7 +1,10 @@
 package dhcp
 
 import (
+	"bytes"
 	"fmt"
+	"io"
+	"log"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -10,8 +13,14 @@ import (
 	"text/template"
 	"time"
 
+	"github.com/ztp-server/backend/bootstrap"
+	"github.com/ztp-server/backend/callback"
 	"github.com/ztp-server/backend/db"
+	"github.com/ztp-server/backend/images"
 	"github.com/ztp-server/backend/models"
+	"github.com/ztp-server/backend/netbox"
+	"github.com/ztp-server/backend/utils"
+	"github.com/ztp-server/backend/validate"
 )
 
 // ConfigManager handles dnsmasq configuration generation
@@ -23,6 +32,12 @@ type ConfigManager struct {
 	dnsmasqPidFile string
 	dhcpInterface  string
 	leasePath      string
+	netboxContext  *netbox.ContextProvider
+	images         *images.Service
+	httpAddr       string
+	tlsAddr        string
+	ownCA          bool
+	configs        *configCache
 }
 
 // NewConfigManager creates a new config manager
@@ -35,9 +50,95 @@ func NewConfigManager(store *db.Store, configPath, tftpDir, templatesDir, pidFil
 		dnsmasqPidFile: pidFile,
 		dhcpInterface:  dhcpInterface,
 		leasePath:      leasePath,
+		netboxContext:  netbox.NewContextProvider(store, netbox.DefaultContextTTL),
+		configs:        newConfigCache(),
 	}
 }
 
+// NetBoxContext returns the provider used to fetch NetBox data for device templates
+func (m *ConfigManager) NetBoxContext() *netbox.ContextProvider {
+	return m.netboxContext
+}
+
+// SetImages sets the image repository that provides target images to device templates
+// and DHCP boot files
+func (m *ConfigManager) SetImages(images *images.Service) {
+	m.images = images
+}
+
+// SetHTTPAddr sets the HTTP server's listen address, used to build the bootstrap script
+// and callback URLs sent in DHCP and given to templates
+func (m *ConfigManager) SetHTTPAddr(listenAddr string) {
+	m.httpAddr = listenAddr
+}
+
+// SetTLS sets the HTTPS listener's address, so that devices are given HTTPS config URLs,
+// and whether its certificates are issued by the server's own CA
+func (m *ConfigManager) SetTLS(tlsAddr string, ownCA bool) {
+	m.tlsAddr = tlsAddr
+	m.ownCA = ownCA
+}
+
+// ConfigURL returns the URL a device downloads its config from, with a new download
+// token if configs require one
+func (m *ConfigManager) ConfigURL(device *models.Device) (string, error) {
+	settings, err := m.store.GetSettings()
+	if err != nil {
+		return "", err
+	}
+	return m.configURL(device, settings, false, nil)
+}
+
+// PreviewContexts returns the bootstrap and callback contexts a template preview sees.
+// Config URLs carry a placeholder rather than a real download token.
+func (m *ConfigManager) PreviewContexts(device *models.Device, settings *models.Settings) (*bootstrap.Context, *callback.Context, error) {
+	return m.contexts(device, settings, true, nil)
+}
+
+// configURL builds a device's config URL, issuing a download token if configs require
+// one and setting issued if it is non-nil. Previews get a placeholder token instead.
+func (m *ConfigManager) configURL(device *models.Device, settings *models.Settings, preview bool, issued *bool) (string, error) {
+	var token string
+	switch {
+	case !settings.ConfigTokenRequired:
+	case preview:
+		token = "preview"
+	default:
+		var err error
+		ttl := time.Duration(settings.ConfigTokenTTL) * time.Minute
+		if token, err = m.store.CreateDownloadToken(device.MAC, ttl, settings.ConfigTokenOneTime); err != nil {
+			return "", fmt.Errorf("failed to issue download token: %w", err)
+		}
+		if issued != nil {
+			*issued = true
+		}
+	}
+	return bootstrap.ConfigURL(device, settings.TFTPServerIP, m.httpAddr, m.tlsAddr, token), nil
+}
+
+// contexts returns the bootstrap and callback contexts of a device. issued is passed on
+// to configURL.
+func (m *ConfigManager) contexts(device *models.Device, settings *models.Settings, preview bool, issued *bool) (*bootstrap.Context, *callback.Context, error) {
+	secret, err := m.store.Secret(callback.SecretName)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get callback secret: %w", err)
+	}
+	callbackContext := callback.NewContext(device, secret, settings.TFTPServerIP, m.httpAddr)
+	bootstrapContext := bootstrap.NewContext(device, settings.TFTPServerIP, m.httpAddr, callbackContext.URL, func() (string, error) {
+		return m.configURL(device, settings, preview, issued)
+	})
+	if m.ownCA {
+		bootstrapContext.CAURL = bootstrap.CAURL(settings.TFTPServerIP, m.httpAddr)
+	}
+	return bootstrapContext, callbackContext, nil
+}
+
+// renderedOption is a DHCP option encoded for a dhcp-option directive
+type renderedOption struct {
+	OptionNumber int
+	Value        string
+}
+
 // dnsmasqTemplate is the template for dnsmasq.conf
 const dnsmasqTemplate = `# Auto-generated by ZTP Server - DO NOT EDIT
 # Generated at: {{.GeneratedAt}}
@@ -55,33 +156,55 @@ dhcp-option=option:router,{{.Settings.DHCPGateway}}
 enable-tftp
 tftp-root={{.TFTPDir}}
 
-# Global DHCP Options (apply to all clients)
+# Rejected devices (blacklisted from receiving ZTP options)
+{{range .RejectedMACs}}
+dhcp-host={{.}},set:ztp-blocked
+{{- end}}
+
+# Global DHCP Options (apply to all clients except rejected devices)
 {{range .GlobalOptions}}
-{{- if .Enabled}}
-dhcp-option={{.OptionNumber}},{{.Value}}
+dhcp-option=tag:!ztp-blocked,{{.OptionNumber}},{{.Value}}
 {{- end}}
-{{end}}
 
-# Per-Device DHCP Options (vendor-specific based on device's vendor assignment)
-{{range .Devices}}
-{{- $mac := .MAC}}
-{{- $vendorOpts := index $.VendorOptions .Vendor}}
-{{- range $vendorOpts}}
-{{- if .Enabled}}
-dhcp-option=tag:{{$mac}},{{.OptionNumber}},{{.Value}}
+# Vendor class matching (DHCP option 60 contains the vendor's class string)
+{{range $id, $class := .VendorClasses}}
+dhcp-vendorclass=set:class-{{$id}},"{{$class}}"
+{{- end}}
+
+# Vendor-specific DHCP Options
+# Registered devices get their assigned vendor's options; unregistered devices and
+# devices without a vendor are matched by vendor class so they can bootstrap and be discovered
+{{range $id, $opts := .VendorOptions}}
+{{- range $opts}}
+dhcp-option=tag:vendor-{{$id}},{{.OptionNumber}},{{.Value}}
+{{- if index $.VendorClasses $id}}
+dhcp-option=tag:class-{{$id}},tag:!ztp-assigned,tag:!ztp-blocked,{{.OptionNumber}},{{.Value}}
 {{- end}}
 {{- end}}
 {{end}}
 
+# Image boot files for vendors that fetch their target image before their config
+{{range $id, $file := .BootImages}}
+dhcp-boot=tag:image-{{$id}},{{$file}}
+{{- end}}
+{{range $id, $file := .VendorBootImages}}
+{{- if index $.VendorClasses $id}}
+dhcp-boot=tag:class-{{$id}},tag:!ztp-assigned,tag:!ztp-blocked,{{$file}}
+{{- end}}
+{{- end}}
+
 # OpenGear ZTP Enrollment Options (vendor-specific options 1-3)
+# Skipped when an enabled OpenGear option 43 already carries them as sub-options
+{{if not .OpenGearEncapsulated}}
 {{if .Settings.OpenGearEnrollURL}}
-dhcp-option=vendor:OpenGear,1,{{.Settings.OpenGearEnrollURL}}
+dhcp-option=tag:!ztp-blocked,vendor:OpenGear,1,{{.Settings.OpenGearEnrollURL}}
 {{end}}
 {{if .Settings.OpenGearEnrollBundle}}
-dhcp-option=vendor:OpenGear,2,{{.Settings.OpenGearEnrollBundle}}
+dhcp-option=tag:!ztp-blocked,vendor:OpenGear,2,{{.Settings.OpenGearEnrollBundle}}
 {{end}}
 {{if .Settings.OpenGearEnrollPassword}}
-dhcp-option=vendor:OpenGear,3,{{.Settings.OpenGearEnrollPassword}}
+dhcp-option=tag:!ztp-blocked,vendor:OpenGear,3,{{.Settings.OpenGearEnrollPassword}}
+{{end}}
 {{end}}
 
 # Lease file for monitoring
@@ -93,8 +216,8 @@ log-queries
 
 # Static DHCP reservations with vendor tags
 {{range .Devices}}
-{{- if .Vendor}}
-dhcp-host={{.MAC}},set:{{.MAC}},{{.IP}},{{.Hostname}}
+{{- if index $.KnownVendors .Vendor}}
+dhcp-host={{.MAC}},set:vendor-{{.Vendor}},set:ztp-assigned{{with index $.DeviceImages .MAC}},set:image-{{.}}{{end}},{{.IP}},{{.Hostname}}
 {{- else}}
 dhcp-host={{.MAC}},{{.IP}},{{.Hostname}}
 {{- end}}
@@ -134,6 +257,22 @@ func (m *ConfigManager) GenerateConfig() error {
 		return fmt.Errorf("failed to get settings: %w", err)
 	}
 
+	// Skip devices whose MAC can't be parsed or whose fields would inject extra
+	// directives - they would produce broken dhcp-host lines
+	valid := devices[:0]
+	for _, device := range devices {
+		if !utils.IsValidMac(device.MAC) {
+			log.Printf("Warning: skipping device %s with invalid MAC %q", device.Hostname, device.MAC)
+			continue
+		}
+		if errs := validate.Reservation(&device); len(errs) > 0 {
+			log.Printf("Warning: skipping device %s: %v", device.MAC, errs)
+			continue
+		}
+		valid = append(valid, device)
+	}
+	devices = valid
+
 	// Clear lease file to force dnsmasq to use new static reservations
 	// This is necessary because dnsmasq honors existing leases over static reservations
 	if err := os.WriteFile(m.leasePath, []byte{}, 0644); err != nil {
@@ -176,27 +315,94 @@ func (m *ConfigManager) generateDnsmasqConfig(devices []models.Device, settings
 		return fmt.Errorf("failed to list DHCP options: %w", err)
 	}
 
-	// Separate global options from vendor-specific options
-	var globalOptions []models.DhcpOption
-	vendorOptions := make(map[string][]models.DhcpOption)
+	vendors, err := m.store.ListVendors()
+	if err != nil {
+		return fmt.Errorf("failed to list vendors: %w", err)
+	}
+
+	// Vendor IDs become dnsmasq tags, so skip any that can't be written safely
+	knownVendors := make(map[string]bool)
+	vendorClasses := make(map[string]string)
+	for _, v := range vendors {
+		if errs := validate.Vendor(&v); len(errs) > 0 {
+			log.Printf("Warning: skipping vendor %q: %v", v.ID, errs)
+			continue
+		}
+		knownVendors[v.ID] = true
+		if v.VendorClass != "" {
+			vendorClasses[v.ID] = v.VendorClass
+		}
+	}
+
+	bootImages, vendorBootImages, deviceImages, err := m.bootImages(devices, vendors, knownVendors)
+	if err != nil {
+		return err
+	}
+
+	// Vendors with a bootstrap template get option 67 pointing at their script instead
+	// of any bootfile option configured for them
+	bootstrapURLs := make(map[string]string)
+	if m.httpAddr != "" && settings.TFTPServerIP != "" {
+		for _, v := range vendors {
+			if v.BootstrapTemplate != "" && knownVendors[v.ID] {
+				bootstrapURLs[v.ID] = bootstrap.ScriptURL(v.ID, settings.TFTPServerIP, m.httpAddr)
+			}
+		}
+	}
+
+	// Encode enabled options and separate global options from vendor-specific options
+	var globalOptions []renderedOption
+	vendorOptions := make(map[string][]renderedOption)
+	openGearEncapsulated := false
 
 	for _, opt := range dhcpOptions {
-		// Substitute variables in the value
-		value := m.substituteOptionVariables(opt.Value, settings)
-		opt.Value = value
+		if !opt.Enabled || (opt.OptionNumber == 67 && bootstrapURLs[opt.VendorID] != "") {
+			continue
+		}
+
+		// Options stored before validation existed may hold values dnsmasq can't parse
+		if errs := validate.DhcpOption(&opt); len(errs) > 0 {
+			log.Printf("Warning: skipping DHCP option %s: %v", opt.ID, errs)
+			continue
+		}
+		data, err := EncodeOption(&opt, settings)
+		if err != nil {
+			log.Printf("Warning: skipping DHCP option %s: %v", opt.ID, err)
+			continue
+		}
+		if data == nil {
+			continue
+		}
 
+		rendered := renderedOption{OptionNumber: opt.OptionNumber, Value: DnsmasqValue(&opt, data)}
 		if opt.VendorID == "" {
-			globalOptions = append(globalOptions, opt)
-		} else {
-			vendorOptions[opt.VendorID] = append(vendorOptions[opt.VendorID], opt)
+			globalOptions = append(globalOptions, rendered)
+		} else if knownVendors[opt.VendorID] {
+			vendorOptions[opt.VendorID] = append(vendorOptions[opt.VendorID], rendered)
 		}
+		if opt.VendorID == "opengear" && opt.OptionNumber == 43 {
+			openGearEncapsulated = true
+		}
+	}
+	for id, scriptURL := range bootstrapURLs {
+		vendorOptions[id] = append(vendorOptions[id], renderedOption{OptionNumber: 67, Value: quoteString(scriptURL)})
 	}
 
-	file, err := os.Create(m.configPath)
+	// Rejected MACs that have since been registered as devices get their normal reservation instead
+	rejectedMACs, err := m.store.ListRejectedMACs()
 	if err != nil {
-		return err
+		return fmt.Errorf("failed to list rejected devices: %w", err)
+	}
+	registered := make(map[string]bool, len(devices))
+	for _, d := range devices {
+		registered[d.MAC] = true
+	}
+	var blocked []string
+	for _, mac := range rejectedMACs {
+		if !registered[mac] {
+			blocked = append(blocked, mac)
+		}
 	}
-	defer file.Close()
 
 	data := struct {
 		GeneratedAt   string
@@ -205,8 +411,17 @@ func (m *ConfigManager) generateDnsmasqConfig(devices []models.Device, settings
 		TFTPDir       string
 		Settings      *models.Settings
 		Devices       []models.Device
-		GlobalOptions []models.DhcpOption
-		VendorOptions map[string][]models.DhcpOption
+		RejectedMACs  []string
+		GlobalOptions []renderedOption
+		VendorOptions map[string][]renderedOption
+		KnownVendors  map[string]bool
+		VendorClasses map[string]string
+
+		BootImages       map[int64]string
+		VendorBootImages map[string]string
+		DeviceImages     map[string]int64
+
+		OpenGearEncapsulated bool
 	}{
 		GeneratedAt:   "auto",
 		Interface:     m.dhcpInterface,
@@ -214,78 +429,281 @@ func (m *ConfigManager) generateDnsmasqConfig(devices []models.Device, settings
 		TFTPDir:       m.tftpDir,
 		Settings:      settings,
 		Devices:       devices,
+		RejectedMACs:  blocked,
 		GlobalOptions: globalOptions,
 		VendorOptions: vendorOptions,
+		KnownVendors:  knownVendors,
+		VendorClasses: vendorClasses,
+
+		BootImages:       bootImages,
+		VendorBootImages: vendorBootImages,
+		DeviceImages:     deviceImages,
+
+		OpenGearEncapsulated: openGearEncapsulated,
 	}
 
-	return tmpl.Execute(file, data)
+	// Write through a rename so dnsmasq never reads a partly written config
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return err
+	}
+	return utils.WriteFileAtomic(m.configPath, buf.Bytes(), 0644)
 }
 
-// substituteOptionVariables replaces variable placeholders in option values
-func (m *ConfigManager) substituteOptionVariables(value string, settings *models.Settings) string {
-	value = strings.ReplaceAll(value, "${tftp_server_ip}", settings.TFTPServerIP)
-	value = strings.ReplaceAll(value, "${dhcp_gateway}", settings.DHCPGateway)
-	return value
+// bootImages finds the target images of vendors that boot them over DHCP. It returns
+// the boot file of each image by ID, the boot file of each vendor's model-independent
+// target for unregistered devices, and the image ID of each registered device.
+func (m *ConfigManager) bootImages(devices []models.Device, vendors []models.Vendor, knownVendors map[string]bool) (map[int64]string, map[string]string, map[string]int64, error) {
+	bootImages := make(map[int64]string)
+	vendorBootImages := make(map[string]string)
+	deviceImages := make(map[string]int64)
+	if m.images == nil {
+		return bootImages, vendorBootImages, deviceImages, nil
+	}
+
+	imageBoot := make(map[string]bool)
+	for _, v := range vendors {
+		if !v.ImageBoot || !knownVendors[v.ID] {
+			continue
+		}
+		imageBoot[v.ID] = true
+		img, err := m.store.GetTargetImage(v.ID, "")
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("failed to get target image: %w", err)
+		}
+		if img != nil {
+			vendorBootImages[v.ID] = images.BootFile(img)
+		}
+	}
+	for i := range devices {
+		if !imageBoot[devices[i].Vendor] {
+			continue
+		}
+		img, err := m.images.Target(&devices[i])
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("failed to get target image: %w", err)
+		}
+		if img != nil {
+			bootImages[img.ID] = images.BootFile(img)
+			deviceImages[devices[i].MAC] = img.ID
+		}
+	}
+	return bootImages, vendorBootImages, deviceImages, nil
 }
 
+// generateDeviceConfigs writes the configs of all devices to the TFTP root for devices
+// that fetch them over TFTP. Only configs whose template, device or settings changed
+// since they were last rendered are rendered and written again, and the configs of
+// deleted devices are removed. With config download protections on, TFTP would get
+// around them, so no configs are kept in the TFTP root at all.
 func (m *ConfigManager) generateDeviceConfigs(devices []models.Device, settings *models.Settings) error {
 	// Ensure TFTP directory exists
 	if err := os.MkdirAll(m.tftpDir, 0755); err != nil {
 		return err
 	}
 
+	persist := !downloadsProtected(settings)
+	current := make(map[string]bool, len(devices))
 	for _, device := range devices {
-		if err := m.generateSingleDeviceConfig(&device, settings); err != nil {
+		current[device.MAC] = true
+		if !persist {
+			continue
+		}
+		if _, err := m.deviceConfig(&device, settings); err != nil {
 			return fmt.Errorf("failed to generate config for %s: %w", device.MAC, err)
 		}
 	}
+	m.configs.retain(current)
 
+	entries, err := os.ReadDir(m.tftpDir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, ".cfg") {
+			continue
+		}
+		mac, err := utils.ParseMac(strings.ReplaceAll(strings.TrimSuffix(name, ".cfg"), "_", ":"))
+		if err != nil || (persist && current[mac]) || utils.MacToFilename(mac)+".cfg" != name {
+			continue
+		}
+		if err := os.Remove(filepath.Join(m.tftpDir, name)); err != nil && !os.IsNotExist(err) {
+			log.Printf("Warning: failed to remove config of %s: %v", mac, err)
+		}
+	}
 	return nil
 }
 
-func (m *ConfigManager) generateSingleDeviceConfig(device *models.Device, settings *models.Settings) error {
-	// Determine which template to use
-	templateContent := defaultDeviceTemplate
-
-	// First try to load from database
-	if device.ConfigTemplate != "" {
-		if dbTemplate, err := m.store.GetTemplate(device.ConfigTemplate); err == nil && dbTemplate != nil {
-			templateContent = dbTemplate.Content
-		} else {
-			// Fallback to file-based template for backwards compatibility
-			customPath := filepath.Join(m.templatesDir, device.ConfigTemplate)
-			if content, err := os.ReadFile(customPath); err == nil {
-				templateContent = string(content)
-			}
+// DeviceConfig returns a device's config, rendering it only if its template, the device
+// or settings changed since it was last rendered. A newly rendered config is also
+// written to the TFTP root, unless config download protections are on.
+func (m *ConfigManager) DeviceConfig(device *models.Device) ([]byte, error) {
+	settings, err := m.store.GetSettings()
+	if err != nil {
+		return nil, err
+	}
+	return m.deviceConfig(device, settings)
+}
+
+// deviceConfig returns a device's config from the cache or renders and persists it.
+// The cache key covers everything the config is rendered from except the device fields
+// that change as it runs, and is worked out before any template data is gathered: NetBox
+// context comes in as the revision of the cached context, so it is only fetched when
+// that has expired. Configs that got a download token aren't cached.
+func (m *ConfigManager) deviceConfig(device *models.Device, settings *models.Settings) ([]byte, error) {
+	content := m.templateContent(device.ConfigTemplate)
+	var image *images.Context
+	if m.images != nil {
+		image = m.images.Context(device, settings)
+	}
+	keyFor := func(netboxRevision string) (string, error) {
+		return cacheKey(content, struct {
+			Device   *models.Device
+			Image    *images.Context
+			NetBox   string
+			Settings *models.Settings
+		}{stableDevice(device), image, netboxRevision, settings})
+	}
+
+	if revision, ok := m.netboxContext.Revision(device.MAC); ok {
+		key, err := keyFor(revision)
+		if err != nil {
+			return nil, err
+		}
+		if config, ok := m.configs.get(device.MAC, key); ok {
+			return config, nil
 		}
 	}
 
-	tmpl, err := template.New("device").Parse(templateContent)
+	data, err := m.templateData(device, settings)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	revision, _ := m.netboxContext.Revision(device.MAC)
+	key, err := keyFor(revision)
+	if err != nil {
+		return nil, err
+	}
+	if config, ok := m.configs.get(device.MAC, key); ok {
+		return config, nil
 	}
 
-	// Generate config filename based on MAC (replace colons with underscores)
-	filename := strings.ReplaceAll(device.MAC, ":", "_") + ".cfg"
-	configPath := filepath.Join(m.tftpDir, filename)
+	// Render before touching the file so a broken template leaves the last good config
+	var buf bytes.Buffer
+	if err := m.execute(&buf, content, data); err != nil {
+		return nil, err
+	}
+	config := buf.Bytes()
+	if !downloadsProtected(settings) {
+		if err := utils.WriteFileAtomic(m.GetConfigPath(device.MAC), config, 0644); err != nil {
+			return nil, err
+		}
+	}
+	if !data.tokenIssued {
+		m.configs.put(device.MAC, key, config)
+	}
+	return config, nil
+}
 
-	file, err := os.Create(configPath)
+// downloadsProtected reports whether any config download protection is on. They only
+// guard /configs, so configs mustn't be left in the TFTP root for anyone to fetch.
+func downloadsProtected(settings *models.Settings) bool {
+	return settings.ConfigTokenRequired || settings.ConfigCheckSourceIP || settings.ConfigLockout || settings.ConfigRequireTLS
+}
+
+// RenderDeviceConfig renders a template for a device, or the device's own template if
+// templateID is empty
+func (m *ConfigManager) RenderDeviceConfig(device *models.Device, templateID string) (string, error) {
+	settings, err := m.store.GetSettings()
+	if err != nil {
+		return "", err
+	}
+	if templateID == "" {
+		templateID = device.ConfigTemplate
+	}
+	var buf bytes.Buffer
+	if err := m.render(&buf, device, settings, m.templateContent(templateID)); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// templateContent returns a template's content, falling back to a file in the
+// templates directory and then to the default template
+func (m *ConfigManager) templateContent(id string) string {
+	if id == "" {
+		return defaultDeviceTemplate
+	}
+	if dbTemplate, err := m.store.GetTemplate(id); err == nil && dbTemplate != nil {
+		return dbTemplate.Content
+	}
+	// Fallback to file-based template for backwards compatibility
+	customPath := filepath.Join(m.templatesDir, id)
+	if content, err := os.ReadFile(customPath); err == nil {
+		return string(content)
+	}
+	return defaultDeviceTemplate
+}
+
+// templateData is what device templates are executed with
+type templateData struct {
+	*models.Device
+	Subnet    string
+	Gateway   string
+	NetBox    *netbox.DeviceContext
+	Image     *images.Context
+	Bootstrap *bootstrap.Context
+	Callback  *callback.Context
+
+	// tokenIssued is set once the template asked for a config URL with a download token
+	tokenIssued bool
+}
+
+// render executes template content for a device
+func (m *ConfigManager) render(w io.Writer, device *models.Device, settings *models.Settings, content string) error {
+	data, err := m.templateData(device, settings)
 	if err != nil {
 		return err
 	}
-	defer file.Close()
+	return m.execute(w, content, data)
+}
 
-	data := struct {
-		*models.Device
-		Subnet  string
-		Gateway string
-	}{
+// execute executes template content with a device's template data
+func (m *ConfigManager) execute(w io.Writer, content string, data *templateData) error {
+	tmpl, err := template.New("device").Parse(content)
+	if err != nil {
+		return err
+	}
+	return tmpl.Execute(w, data)
+}
+
+// templateData collects the data device templates see
+func (m *ConfigManager) templateData(device *models.Device, settings *models.Settings) (*templateData, error) {
+	data := &templateData{
 		Device:  device,
 		Subnet:  settings.DHCPSubnet,
 		Gateway: settings.DHCPGateway,
 	}
 
-	return tmpl.Execute(file, data)
+	// NetBox data is best effort; templates see an empty context if it can't be fetched
+	var err error
+	if data.NetBox, err = m.netboxContext.Get(device); err != nil {
+		log.Printf("Warning: failed to fetch NetBox context for %s: %v", device.MAC, err)
+	}
+
+	// Templates check for a target image with {{with .Image}}
+	if m.images != nil {
+		data.Image = m.images.Context(device, settings)
+	}
+
+	// Configs report back to the device's signed callback URL
+	if m.httpAddr != "" {
+		if data.Bootstrap, data.Callback, err = m.contexts(device, settings, false, &data.tokenIssued); err != nil {
+			return nil, err
+		}
+	}
+	return data, nil
 }
 
 func (m *ConfigManager) reloadDnsmasq() error {
@@ -334,6 +752,6 @@ func (m *ConfigManager) reloadDnsmasq() error {
 
 // GetConfigPath returns the path to a device's config file
 func (m *ConfigManager) GetConfigPath(mac string) string {
-	filename := strings.ReplaceAll(mac, ":", "_") + ".cfg"
+	filename := utils.MacToFilename(mac) + ".cfg"
 	return filepath.Join(m.tftpDir, filename)
 }
@@ -9,6 +9,7 @@ import (
 	"time"
 
 	"github.com/ztp-server/backend/models"
+	"github.com/ztp-server/backend/utils"
 )
 
 // LeaseCallback is a function called when a new lease is detected
@@ -133,7 +134,7 @@ func parseLeaseLine(line string) (*models.Lease, error) {
 
 	lease := &models.Lease{
 		ExpiryTime: expiry,
-		MAC:        strings.ToLower(fields[1]),
+		MAC:        utils.NormalizeMac(fields[1]),
 		IP:         fields[2],
 		Hostname:   fields[3],
 	}
@@ -0,0 +1,281 @@
+package dhcp
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/ztp-server/backend/models"
+	"github.com/ztp-server/backend/validate"
+)
+
+// numberOptionWidths holds the wire size in bytes of standard integer options.
+// Other number options use the smallest width that fits the value.
+var numberOptionWidths = map[int]int{
+	2:  4, // time offset
+	13: 2, // boot file size
+	22: 2, // max datagram reassembly size
+	23: 1, // default IP TTL
+	24: 4, // path MTU aging timeout
+	26: 2, // interface MTU
+	35: 4, // ARP cache timeout
+	37: 1, // TCP default TTL
+	38: 4, // TCP keepalive interval
+	51: 4, // lease time
+	57: 2, // max DHCP message size
+	58: 4, // renewal (T1) time
+	59: 4, // rebinding (T2) time
+}
+
+// textOptions are defined as strings by RFC 2132. An address entered for one of them
+// (e.g. an IP in option 66) is sent as text, as dnsmasq does.
+var textOptions = map[int]bool{
+	12: true, // host name
+	14: true, // merit dump file
+	15: true, // domain name
+	17: true, // root path
+	18: true, // extensions path
+	40: true, // NIS domain
+	56: true, // message
+	60: true, // vendor class identifier
+	64: true, // NIS+ domain
+	66: true, // TFTP server name
+	67: true, // bootfile name
+}
+
+// OptionPreview shows how an option is encoded on the wire and in dnsmasq.conf
+type OptionPreview struct {
+	OptionNumber int                `json:"option_number"`
+	Type         string             `json:"type"`
+	Value        string             `json:"value,omitempty"` // After variable substitution
+	Length       int                `json:"length"`
+	Data         string             `json:"data"`    // Option payload as colon-separated hex
+	Wire         string             `json:"wire"`    // Code, length and payload as sent in the DHCP packet
+	Dnsmasq      string             `json:"dnsmasq"` // Generated dhcp-option directive
+	SubOptions   []SubOptionPreview `json:"sub_options,omitempty"`
+}
+
+// SubOptionPreview shows the encoding of a single TLV inside option 43 or 125
+type SubOptionPreview struct {
+	Code   int    `json:"code"`
+	Name   string `json:"name,omitempty"` // From the vendor schema, if any
+	Value  string `json:"value"`
+	Length int    `json:"length"`
+	Data   string `json:"data"`
+}
+
+// SubstituteVariables replaces the placeholders listed in validate.OptionVariables
+func SubstituteVariables(value string, settings *models.Settings) string {
+	if settings == nil {
+		return value
+	}
+	return strings.NewReplacer(
+		"${tftp_server_ip}", settings.TFTPServerIP,
+		"${dhcp_gateway}", settings.DHCPGateway,
+		"${opengear_enroll_url}", settings.OpenGearEnrollURL,
+		"${opengear_enroll_bundle}", settings.OpenGearEnrollBundle,
+		"${opengear_enroll_password}", settings.OpenGearEnrollPassword,
+	).Replace(value)
+}
+
+// EncodeOption returns the payload bytes of an option after variable substitution.
+// A nil payload means the option has nothing to send and should be left out.
+func EncodeOption(opt *models.DhcpOption, settings *models.Settings) ([]byte, error) {
+	var data []byte
+	if len(opt.SubOptions) > 0 {
+		tlvs, err := encodeSubOptions(opt.SubOptions, settings)
+		if err != nil {
+			return nil, err
+		}
+		if len(tlvs) == 0 {
+			return nil, nil
+		}
+		data = tlvs
+		if opt.OptionNumber == 125 {
+			// RFC 3925: enterprise number, data length, then the TLVs
+			header := make([]byte, 5)
+			binary.BigEndian.PutUint32(header, uint32(opt.EnterpriseNumber))
+			header[4] = byte(len(tlvs))
+			data = append(header, tlvs...)
+		}
+	} else {
+		value := SubstituteVariables(opt.Value, settings)
+		if value == "" {
+			return nil, nil
+		}
+		var err error
+		data, err = encodeValue(valueType(opt), value, numberOptionWidths[opt.OptionNumber])
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if len(data) > 255 {
+		return nil, fmt.Errorf("encoded option is %d bytes, the maximum is 255", len(data))
+	}
+	return data, nil
+}
+
+// DnsmasqValue formats an encoded option for a dhcp-option directive. Strings and IP lists are
+// kept readable, everything else is written as hex so dnsmasq sends exactly the previewed bytes.
+func DnsmasqValue(opt *models.DhcpOption, data []byte) string {
+	if len(opt.SubOptions) == 0 {
+		switch valueType(opt) {
+		case validate.OptionTypeString:
+			return quoteString(string(data))
+		case validate.OptionTypeIP:
+			ips := make([]string, 0, len(data)/4)
+			for i := 0; i+4 <= len(data); i += 4 {
+				ips = append(ips, net.IP(data[i:i+4]).String())
+			}
+			return strings.Join(ips, ",")
+		}
+	}
+	return formatHex(data)
+}
+
+// PreviewOption encodes an option and describes the result for display
+func PreviewOption(opt *models.DhcpOption, settings *models.Settings) (*OptionPreview, error) {
+	data, err := EncodeOption(opt, settings)
+	if err != nil {
+		return nil, err
+	}
+
+	preview := &OptionPreview{
+		OptionNumber: opt.OptionNumber,
+		Type:         valueType(opt),
+		Length:       len(data),
+		Data:         formatHex(data),
+		Wire:         formatHex(append([]byte{byte(opt.OptionNumber), byte(len(data))}, data...)),
+	}
+	if len(opt.SubOptions) == 0 {
+		preview.Value = SubstituteVariables(opt.Value, settings)
+	}
+	if data != nil {
+		preview.Dnsmasq = fmt.Sprintf("dhcp-option=%d,%s", opt.OptionNumber, DnsmasqValue(opt, data))
+	}
+
+	schema := FindVendorOptionSchema(opt.VendorID, opt.OptionNumber)
+	for _, sub := range opt.SubOptions {
+		value := SubstituteVariables(sub.Value, settings)
+		if value == "" {
+			continue
+		}
+		subData, err := encodeValue(sub.Type, value, 0)
+		if err != nil {
+			return nil, err
+		}
+		sp := SubOptionPreview{
+			Code:   sub.Code,
+			Value:  value,
+			Length: len(subData),
+			Data:   formatHex(subData),
+		}
+		if field := schema.SubOption(sub.Code); field != nil {
+			sp.Name = field.Name
+		}
+		preview.SubOptions = append(preview.SubOptions, sp)
+	}
+
+	return preview, nil
+}
+
+// valueType returns the type an option value is encoded as
+func valueType(opt *models.DhcpOption) string {
+	if textOptions[opt.OptionNumber] && opt.Type == validate.OptionTypeIP {
+		return validate.OptionTypeString
+	}
+	return opt.Type
+}
+
+// encodeSubOptions builds the code/length/value list for a vendor-encapsulated option,
+// skipping sub-options whose value is empty after substitution
+func encodeSubOptions(subOptions []models.DhcpSubOption, settings *models.Settings) ([]byte, error) {
+	var out []byte
+	for _, sub := range subOptions {
+		value := SubstituteVariables(sub.Value, settings)
+		if value == "" {
+			continue
+		}
+		data, err := encodeValue(sub.Type, value, 0)
+		if err != nil {
+			return nil, fmt.Errorf("sub-option %d: %w", sub.Code, err)
+		}
+		if len(data) > 255 {
+			return nil, fmt.Errorf("sub-option %d is %d bytes, the maximum is 255", sub.Code, len(data))
+		}
+		out = append(out, byte(sub.Code), byte(len(data)))
+		out = append(out, data...)
+	}
+	return out, nil
+}
+
+// encodeValue converts a substituted value to bytes according to its type.
+// width fixes the size of number values; 0 picks the smallest that fits.
+func encodeValue(optType, value string, width int) ([]byte, error) {
+	switch optType {
+	case validate.OptionTypeString:
+		return []byte(value), nil
+
+	case validate.OptionTypeIP:
+		var out []byte
+		for _, part := range strings.Split(value, ",") {
+			ip := net.ParseIP(strings.TrimSpace(part)).To4()
+			if ip == nil {
+				return nil, fmt.Errorf("%q is not a valid IPv4 address", strings.TrimSpace(part))
+			}
+			out = append(out, ip...)
+		}
+		return out, nil
+
+	case validate.OptionTypeHex:
+		data, err := hex.DecodeString(strings.ReplaceAll(value, ":", ""))
+		if err != nil {
+			return nil, fmt.Errorf("invalid hex value %q", value)
+		}
+		return data, nil
+
+	case validate.OptionTypeNumber:
+		n, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q", value)
+		}
+		if width == 0 {
+			switch {
+			case n <= 0xff:
+				width = 1
+			case n <= 0xffff:
+				width = 2
+			default:
+				width = 4
+			}
+		}
+		if width < 4 && n >= 1<<(8*width) {
+			return nil, fmt.Errorf("%d does not fit in %d bytes", n, width)
+		}
+		buf := make([]byte, 4)
+		binary.BigEndian.PutUint32(buf, uint32(n))
+		return buf[4-width:], nil
+	}
+
+	return nil, fmt.Errorf("unknown option type %q", optType)
+}
+
+// formatHex renders bytes in dnsmasq's colon-separated hex notation
+func formatHex(data []byte) string {
+	parts := make([]string, len(data))
+	for i, b := range data {
+		parts[i] = fmt.Sprintf("%02x", b)
+	}
+	return strings.Join(parts, ":")
+}
+
+// quoteString quotes a string so commas and spaces survive dnsmasq's option parser
+func quoteString(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	return `"` + s + `"`
+}
@@ -0,0 +1,205 @@
+package dhcp
+
+import (
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/ztp-server/backend/models"
+)
+
+const (
+	// DefaultReloadDebounce is how long a regeneration waits for further changes
+	DefaultReloadDebounce = 500 * time.Millisecond
+	// A steady stream of changes delays a regeneration by no more than this
+	reloadMaxDelay = 5 * time.Second
+)
+
+// ErrReloaderStopped is returned by Reload once the reloader has been stopped
+var ErrReloaderStopped = errors.New("config reloader stopped")
+
+// Reloader is the single place configs are regenerated from. Changes request a
+// regeneration, and requests arriving within the debounce interval of each other are
+// coalesced into one. Regenerations never run concurrently, so the dnsmasq config,
+// lease file and dnsmasq process are only touched by one at a time.
+type Reloader struct {
+	generate func() error
+	debounce time.Duration
+	onLive   func(status models.ReloadStatus)
+
+	mu        sync.Mutex
+	done      *sync.Cond
+	requested uint64    // Requests so far
+	taken     uint64    // Requests taken up by the last regeneration to start
+	covered   uint64    // Requests covered by a finished regeneration
+	firstAt   time.Time // When the oldest request not yet taken up was made
+	lastErr   error
+	timer     *time.Timer
+	status    models.ReloadStatus
+	stopped   bool
+
+	wake chan struct{}
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewReloader creates a reloader running generate, usually ConfigManager.GenerateConfig
+func NewReloader(generate func() error, debounce time.Duration) *Reloader {
+	r := &Reloader{
+		generate: generate,
+		debounce: debounce,
+		wake:     make(chan struct{}, 1),
+		stop:     make(chan struct{}),
+	}
+	r.done = sync.NewCond(&r.mu)
+	return r
+}
+
+// SetLiveHook sets a function called after each successful regeneration, once the new
+// config is live
+func (r *Reloader) SetLiveHook(onLive func(status models.ReloadStatus)) {
+	r.onLive = onLive
+}
+
+// Start begins running regenerations
+func (r *Reloader) Start() {
+	r.wg.Add(1)
+	go r.run()
+}
+
+// Stop waits for a running regeneration to finish and stops the reloader. Requests
+// still waiting for their debounce interval are dropped, and Reload calls still waiting
+// return ErrReloaderStopped.
+func (r *Reloader) Stop() {
+	close(r.stop)
+	r.wg.Wait()
+	r.mu.Lock()
+	r.stopped = true
+	if r.timer != nil {
+		r.timer.Stop()
+	}
+	r.done.Broadcast()
+	r.mu.Unlock()
+}
+
+// Request asks for a regeneration without waiting for it. It always returns nil; the
+// error return lets it stand in for the reload functions handlers are given.
+func (r *Reloader) Request() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.request()
+
+	delay := r.debounce
+	if remaining := reloadMaxDelay - time.Since(r.firstAt); remaining < delay {
+		delay = max(remaining, 0)
+	}
+	if r.timer == nil {
+		r.timer = time.AfterFunc(delay, r.poke)
+	} else {
+		r.timer.Reset(delay)
+	}
+	return nil
+}
+
+// Reload regenerates configs now, without waiting for the debounce interval, and
+// returns the error of the regeneration that covered the request. It returns
+// ErrReloaderStopped if the reloader stops before the request is covered.
+func (r *Reloader) Reload() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.stopped {
+		return ErrReloaderStopped
+	}
+	target := r.request()
+	r.poke()
+	for r.covered < target && !r.stopped {
+		r.done.Wait()
+	}
+	if r.covered < target {
+		return ErrReloaderStopped
+	}
+	return r.lastErr
+}
+
+// Status returns the current regeneration status
+func (r *Reloader) Status() models.ReloadStatus {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.status
+}
+
+// request records a request and returns its number. r.mu must be held.
+func (r *Reloader) request() uint64 {
+	if r.requested == r.taken {
+		r.firstAt = time.Now()
+	}
+	r.requested++
+	r.status.Pending = true
+	return r.requested
+}
+
+// poke wakes the run loop
+func (r *Reloader) poke() {
+	select {
+	case r.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (r *Reloader) run() {
+	defer r.wg.Done()
+	for {
+		select {
+		case <-r.wake:
+			r.regenerate()
+		case <-r.stop:
+			return
+		}
+	}
+}
+
+// regenerate runs one regeneration covering all requests made before it started
+func (r *Reloader) regenerate() {
+	r.mu.Lock()
+	if r.covered == r.requested {
+		r.mu.Unlock()
+		return
+	}
+	target := r.requested
+	r.taken = target
+	r.status.Pending = false
+	r.status.Running = true
+	r.mu.Unlock()
+
+	start := time.Now()
+	err := r.generate()
+	finished := time.Now().UTC()
+
+	r.mu.Lock()
+	r.covered = target
+	r.lastErr = err
+	r.status.Running = false
+	r.status.Pending = r.requested > r.covered
+	r.status.LastDurationMS = time.Since(start).Milliseconds()
+	r.status.LastRunAt = &finished
+	r.status.LastError = ""
+	if err != nil {
+		r.status.LastError = err.Error()
+	} else {
+		r.status.Generation++
+		r.status.LiveAt = &finished
+	}
+	status := r.status
+	r.done.Broadcast()
+	r.mu.Unlock()
+
+	if err != nil {
+		log.Printf("Config regeneration failed: %v", err)
+		return
+	}
+	log.Printf("Config generation %d live (took %dms)", status.Generation, status.LastDurationMS)
+	if r.onLive != nil {
+		r.onLive(status)
+	}
+}
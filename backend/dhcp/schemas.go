@@ -0,0 +1,132 @@
+package dhcp
+
+import (
+	"fmt"
+
+	"github.com/ztp-server/backend/models"
+	"github.com/ztp-server/backend/validate"
+)
+
+// VendorOptionSchema describes the sub-options a vendor expects inside option 43 or 125
+type VendorOptionSchema struct {
+	VendorID         string            `json:"vendor_id"`
+	OptionNumber     int               `json:"option_number"`
+	EnterpriseNumber int               `json:"enterprise_number,omitempty"`
+	Name             string            `json:"name"`
+	Description      string            `json:"description,omitempty"`
+	SubOptions       []SubOptionSchema `json:"sub_options"`
+}
+
+// SubOptionSchema describes a single sub-option code
+type SubOptionSchema struct {
+	Code        int    `json:"code"`
+	Name        string `json:"name"`
+	Type        string `json:"type"`
+	Description string `json:"description,omitempty"`
+}
+
+// vendorOptionSchemas lists the known vendor-encapsulated option layouts
+var vendorOptionSchemas = []VendorOptionSchema{
+	{
+		VendorID:     "opengear",
+		OptionNumber: 43,
+		Name:         "OpenGear ZTP enrollment",
+		Description:  "Lighthouse enrollment parameters",
+		SubOptions: []SubOptionSchema{
+			{Code: 1, Name: "Enrollment URL", Type: validate.OptionTypeString, Description: "Lighthouse address to enroll with"},
+			{Code: 2, Name: "Enrollment bundle", Type: validate.OptionTypeString, Description: "Bundle name to enroll into"},
+			{Code: 3, Name: "Enrollment password", Type: validate.OptionTypeString, Description: "Enrollment token"},
+		},
+	},
+	{
+		VendorID:     "juniper",
+		OptionNumber: 43,
+		Name:         "Junos ZTP",
+		Description:  "Image and configuration download parameters",
+		SubOptions: []SubOptionSchema{
+			{Code: 0, Name: "Image file", Type: validate.OptionTypeString, Description: "Software image filename"},
+			{Code: 1, Name: "Config file", Type: validate.OptionTypeString, Description: "Configuration filename"},
+			{Code: 2, Name: "Image file type", Type: validate.OptionTypeString, Description: "\"symlink\" if the image filename is a symbolic link"},
+			{Code: 3, Name: "Transfer mode", Type: validate.OptionTypeString, Description: "http, ftp or tftp"},
+			{Code: 4, Name: "Alternate image file", Type: validate.OptionTypeString, Description: "Alternate software image filename"},
+			{Code: 5, Name: "HTTP port", Type: validate.OptionTypeString, Description: "Port for HTTP transfers"},
+		},
+	},
+	{
+		VendorID:     "cisco",
+		OptionNumber: 43,
+		Name:         "Cisco wireless controller discovery",
+		Description:  "Controller addresses for lightweight access points",
+		SubOptions: []SubOptionSchema{
+			{Code: 241, Name: "Controller addresses", Type: validate.OptionTypeIP, Description: "Comma-separated WLC management IPs"},
+		},
+	},
+}
+
+// VendorOptionSchemas returns all known vendor option schemas
+func VendorOptionSchemas() []VendorOptionSchema {
+	return vendorOptionSchemas
+}
+
+// FindVendorOptionSchema returns the schema for a vendor's option, or nil if there is none
+func FindVendorOptionSchema(vendorID string, optionNumber int) *VendorOptionSchema {
+	for i := range vendorOptionSchemas {
+		if vendorOptionSchemas[i].VendorID == vendorID && vendorOptionSchemas[i].OptionNumber == optionNumber {
+			return &vendorOptionSchemas[i]
+		}
+	}
+	return nil
+}
+
+// SubOption returns the schema entry for a code, or nil if the code is unknown
+func (s *VendorOptionSchema) SubOption(code int) *SubOptionSchema {
+	if s == nil {
+		return nil
+	}
+	for i := range s.SubOptions {
+		if s.SubOptions[i].Code == code {
+			return &s.SubOptions[i]
+		}
+	}
+	return nil
+}
+
+// ValidateOption checks an option before it is stored: field conformance, the vendor schema
+// if one exists, and that it encodes within DHCP size limits. Sub-options without a type
+// take the type from the schema.
+func ValidateOption(opt *models.DhcpOption, settings *models.Settings) validate.Errors {
+	schema := FindVendorOptionSchema(opt.VendorID, opt.OptionNumber)
+	for i := range opt.SubOptions {
+		if field := schema.SubOption(opt.SubOptions[i].Code); field != nil && opt.SubOptions[i].Type == "" {
+			opt.SubOptions[i].Type = field.Type
+		}
+	}
+
+	var errs validate.Errors
+	if schema != nil {
+		for i, sub := range opt.SubOptions {
+			field := schema.SubOption(sub.Code)
+			if field == nil {
+				errs.Add(fmt.Sprintf("sub_options[%d].code", i), "%d is not a %s sub-option", sub.Code, schema.Name)
+			} else if sub.Type != field.Type {
+				errs.Add(fmt.Sprintf("sub_options[%d].type", i), "must be %s for %s", field.Type, field.Name)
+			}
+		}
+		if len(errs) > 0 {
+			return errs
+		}
+	}
+
+	if errs = validate.DhcpOption(opt); len(errs) > 0 {
+		return errs
+	}
+
+	if _, err := EncodeOption(opt, settings); err != nil {
+		field := "value"
+		if len(opt.SubOptions) > 0 {
+			field = "sub_options"
+		}
+		errs.Add(field, "%v", err)
+	}
+	return errs
+}
@@ -0,0 +1,77 @@
+package drivers
+
+import (
+	"fmt"
+	"regexp"
+)
+
+func init() {
+	Register("arista", AristaEOS{})
+}
+
+var (
+	eosHostname = regexp.MustCompile(`(?m)^Hostname:\s*(\S+)`)
+	eosModel    = regexp.MustCompile(`(?m)^Arista (\S+)`)
+	eosSerial   = regexp.MustCompile(`(?m)^Serial number:\s*(\S+)`)
+	eosVersion  = regexp.MustCompile(`(?m)^Software image version:\s*(\S+)`)
+	eosUptime   = regexp.MustCompile(`(?m)^Uptime:\s*(.+)$`)
+)
+
+// AristaEOS drives Arista EOS switches
+type AristaEOS struct {
+	Generic
+}
+
+// Name implements Driver
+func (AristaEOS) Name() string { return "arista-eos" }
+
+// Facts parses show version, show hostname, show interfaces description and show
+// lldp neighbors
+func (AristaEOS) Facts(c *Conn) (*Facts, error) {
+	outputs, err := c.Run("show version", "show hostname", "show interfaces description", "show lldp neighbors")
+	if err != nil {
+		return nil, err
+	}
+	version := outputs[0]
+	return &Facts{
+		Hostname:   match(eosHostname, outputs[1]),
+		Model:      match(eosModel, version),
+		Serial:     match(eosSerial, version),
+		Version:    match(eosVersion, version),
+		Uptime:     match(eosUptime, version),
+		Interfaces: iosInterfaces(outputs[2]),
+		Neighbors: tableNeighbors(parseTable(outputs[3], "Port", "Neighbor Device ID", "Neighbor Port ID"),
+			"Port", "Neighbor Device ID", "Neighbor Port ID"),
+	}, nil
+}
+
+// Uptime reads the uptime line of show version
+func (AristaEOS) Uptime(c *Conn) (string, error) {
+	outputs, err := c.Run("show version | include Uptime")
+	if err != nil {
+		return "", err
+	}
+	return match(eosUptime, outputs[0]), nil
+}
+
+// ApplyConfig enters the lines in configure mode and saves with write memory
+func (AristaEOS) ApplyConfig(c *Conn, config string) (string, error) {
+	return applyLines(c, "configure", iosLines(config), []string{"end", "write memory"}, iosError)
+}
+
+// ApplyConfirmed enters the lines in a config session and commits it with a timer;
+// EOS rolls the session back when the timer expires
+func (AristaEOS) ApplyConfirmed(c *Conn, config, name string, minutes int) (string, error) {
+	timer := fmt.Sprintf("commit timer %02d:%02d:00", minutes/60, minutes%60)
+	return applyLines(c, "configure session "+name, iosLines(config), []string{timer}, iosError)
+}
+
+// Confirm commits the session for good and saves with write memory
+func (AristaEOS) Confirm(c *Conn, name string) (string, error) {
+	return applyLines(c, "configure session "+name+" commit", nil, []string{"write memory"}, iosError)
+}
+
+// Reboot reloads without saving the running config
+func (AristaEOS) Reboot(c *Conn) error {
+	return reload(c, "reload now")
+}
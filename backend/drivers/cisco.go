@@ -0,0 +1,185 @@
+package drivers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/ztp-server/backend/models"
+)
+
+func init() {
+	Register("cisco", CiscoIOS{})
+}
+
+var (
+	iosHostname = regexp.MustCompile(`(?m)^(\S+) uptime is`)
+	iosUptime   = regexp.MustCompile(`(?m)uptime is (.+)$`)
+	iosModel    = regexp.MustCompile(`(?mi)^cisco (\S+) .*(?:processor|bytes of memory)`)
+	iosSerial   = regexp.MustCompile(`(?m)^Processor board ID (\S+)`)
+	iosVersion  = regexp.MustCompile(`(?m)Version ([^,\s]+)`)
+	// iosError matches the "% Invalid input" style errors IOS and EOS print for bad lines
+	iosError = regexp.MustCompile(`(?m)^\s*% ?(Invalid|Incomplete|Ambiguous|Error|Unrecognized).*$`)
+	// iosRevertError also catches the rollback feature being unavailable, e.g. without an
+	// archive path
+	iosRevertError = regexp.MustCompile(`(?m)^\s*% ?(Invalid|Incomplete|Ambiguous|Error|Unrecognized|Archive|Rollback|Revert).*$`)
+
+	reloadSave    = regexp.MustCompile(`(?i)save\?\s*\[yes/no\]:?\s*$`)
+	reloadConfirm = regexp.MustCompile(`(?i)\[confirm\]\s*$`)
+)
+
+// CiscoIOS drives Cisco IOS and IOS-XE devices
+type CiscoIOS struct {
+	Generic
+}
+
+// Name implements Driver
+func (CiscoIOS) Name() string { return "cisco-ios" }
+
+// Facts parses show version, show interfaces description and show lldp neighbors
+func (CiscoIOS) Facts(c *Conn) (*Facts, error) {
+	outputs, err := c.Run("show version", "show interfaces description", "show lldp neighbors")
+	if err != nil {
+		return nil, err
+	}
+	out := outputs[0]
+	return &Facts{
+		Hostname:   match(iosHostname, out),
+		Model:      match(iosModel, out),
+		Serial:     match(iosSerial, out),
+		Version:    match(iosVersion, out),
+		Uptime:     match(iosUptime, out),
+		Interfaces: iosInterfaces(outputs[1]),
+		Neighbors: tableNeighbors(parseTable(outputs[2], "Device ID", "Local Intf", "Port ID"),
+			"Local Intf", "Device ID", "Port ID"),
+	}, nil
+}
+
+// Uptime reads the uptime line of show version
+func (CiscoIOS) Uptime(c *Conn) (string, error) {
+	outputs, err := c.Run("show version | include uptime")
+	if err != nil {
+		return "", err
+	}
+	return match(iosUptime, outputs[0]), nil
+}
+
+// ApplyConfig enters the lines in configure terminal and saves with write memory
+func (CiscoIOS) ApplyConfig(c *Conn, config string) (string, error) {
+	return applyLines(c, "configure terminal", iosLines(config), []string{"end", "write memory"}, iosError)
+}
+
+// ApplyConfirmed enters the lines with configure terminal revert timer, the confirmed
+// change mode of IOS configuration replace and rollback. The device restores its
+// archived config when the timer expires; it needs an archive path configured.
+func (CiscoIOS) ApplyConfirmed(c *Conn, config, name string, minutes int) (string, error) {
+	sh, err := c.Shell()
+	if err != nil {
+		return "", err
+	}
+	defer sh.Close()
+
+	enter := fmt.Sprintf("configure terminal revert timer %d", minutes)
+	commands := append(append([]string{enter}, iosLines(config)...), "end")
+	out, done, err := runLines(sh, commands, iosRevertError)
+	if err != nil && done > 0 {
+		// Entering configure mode armed the timer, so any lines taken are live until it
+		// expires
+		return out, fmt.Errorf("%w: %w", ErrRollbackPending, err)
+	}
+	return out, err
+}
+
+// Confirm stops the revert timer with configure confirm and saves with write memory
+func (CiscoIOS) Confirm(c *Conn, name string) (string, error) {
+	return applyLines(c, "configure confirm", nil, []string{"write memory"}, iosError)
+}
+
+// Reboot reloads without saving the running config
+func (CiscoIOS) Reboot(c *Conn) error {
+	return reload(c, "reload")
+}
+
+// iosLines returns the config lines to enter, without comments or the end line that
+// rendered templates finish with, which would leave config mode early
+func iosLines(config string) []string {
+	var lines []string
+	for _, line := range configLines(config, "!") {
+		if strings.TrimSpace(line) != "end" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// iosInterfaces parses show interfaces description, which IOS and EOS format alike
+func iosInterfaces(out string) []models.DeviceInterface {
+	rows := parseTable(out, "Interface", "Status", "Protocol", "Description")
+	return tableInterfaces(rows, "Interface", "Status", "Description")
+}
+
+// applyLines enters config lines in a shell between the enter and exit commands,
+// stopping at the first line whose output matches errPattern
+func applyLines(c *Conn, enter string, lines, exit []string, errPattern *regexp.Regexp) (string, error) {
+	sh, err := c.Shell()
+	if err != nil {
+		return "", err
+	}
+	defer sh.Close()
+
+	out, _, err := runLines(sh, append(append([]string{enter}, lines...), exit...), errPattern)
+	return out, err
+}
+
+// runLines runs commands in a shell, stopping at the first whose output matches
+// errPattern. It returns the transcript and how many commands succeeded.
+func runLines(sh *Shell, commands []string, errPattern *regexp.Regexp) (string, int, error) {
+	var transcript strings.Builder
+	for i, cmd := range commands {
+		out, err := sh.Run(cmd)
+		transcript.WriteString(out)
+		if err != nil {
+			return transcript.String(), i, fmt.Errorf("%s: %w", cmd, err)
+		}
+		if msg := errPattern.FindString(out); msg != "" {
+			return transcript.String(), i, fmt.Errorf("%s: %s", strings.TrimSpace(cmd), strings.TrimSpace(msg))
+		}
+	}
+	return transcript.String(), len(commands), nil
+}
+
+// reload sends the reload command and answers the save and confirm prompts. The
+// session usually drops once the device starts reloading.
+func reload(c *Conn, command string) error {
+	sh, err := c.Shell()
+	if err != nil {
+		return err
+	}
+	defer sh.Close()
+
+	if err := sh.Send(command); err != nil {
+		return err
+	}
+	for i := 0; i < 3; i++ {
+		line, err := sh.Expect(reloadSave, reloadConfirm, sh.opts.prompt)
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			// The device closing the session is the expected outcome
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		switch {
+		case reloadSave.MatchString(line):
+			sh.Send("no")
+		case reloadConfirm.MatchString(line):
+			sh.Send("")
+			return nil
+		default:
+			return fmt.Errorf("reload was not accepted: %s", strings.TrimSpace(sh.buf.String()))
+		}
+	}
+	return nil
+}
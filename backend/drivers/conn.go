@@ -0,0 +1,190 @@
+package drivers
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+
+	"github.com/ztp-server/backend/models"
+	"github.com/ztp-server/backend/netconf"
+)
+
+// DefaultBackupCommand is run when neither the vendor nor the settings name one
+const DefaultBackupCommand = "show running-config"
+
+// Target is a device to connect to, with credentials resolved from the device and the
+// global defaults
+type Target struct {
+	Host          string
+	Port          int
+	User          string
+	Pass          string
+	EnableSecret  string
+	BackupCommand string
+	NetconfPort   int
+	Vendor        *models.Vendor // nil if the device has no known vendor
+}
+
+// NewTarget resolves a device's connection details. Device credentials override the
+// defaults in settings; the vendor supplies the SSH port and backup command.
+func NewTarget(device *models.Device, vendor *models.Vendor, settings *models.Settings) Target {
+	t := Target{
+		Host:          device.IP,
+		Port:          22,
+		User:          device.SSHUser,
+		Pass:          device.SSHPass,
+		EnableSecret:  device.EnableSecret,
+		BackupCommand: settings.BackupCommand,
+		NetconfPort:   netconf.DefaultPort,
+		Vendor:        vendor,
+	}
+	if t.User == "" {
+		t.User = settings.DefaultSSHUser
+	}
+	if t.Pass == "" {
+		t.Pass = settings.DefaultSSHPass
+	}
+	if t.EnableSecret == "" {
+		t.EnableSecret = settings.DefaultEnableSecret
+	}
+	if vendor != nil {
+		if vendor.SSHPort > 0 {
+			t.Port = vendor.SSHPort
+		}
+		if vendor.BackupCommand != "" {
+			t.BackupCommand = vendor.BackupCommand
+		}
+		if vendor.NetconfPort > 0 {
+			t.NetconfPort = vendor.NetconfPort
+		}
+	}
+	if t.BackupCommand == "" {
+		t.BackupCommand = DefaultBackupCommand
+	}
+	return t
+}
+
+// VendorID returns the ID of the target's vendor, or "" if it has none
+func (t Target) VendorID() string {
+	if t.Vendor == nil {
+		return ""
+	}
+	return t.Vendor.ID
+}
+
+// Interactive reports whether the vendor needs commands run in a PTY shell
+func (t Target) Interactive() bool {
+	return t.Vendor != nil && t.Vendor.BackupMode == models.BackupModeInteractive
+}
+
+// Netconf reports whether the vendor's backups are fetched over NETCONF
+func (t Target) Netconf() bool {
+	return t.Vendor != nil && t.Vendor.BackupMode == models.BackupModeNetconf
+}
+
+// clientConfig returns the SSH client config for password authentication
+func (t Target) clientConfig(timeout time.Duration) *ssh.ClientConfig {
+	return &ssh.ClientConfig{
+		User: t.User,
+		Auth: []ssh.AuthMethod{
+			ssh.Password(t.Pass),
+		},
+		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+		Timeout:         timeout,
+	}
+}
+
+// Conn is an SSH connection to a device
+type Conn struct {
+	Target Target
+	client *ssh.Client
+	stop   func() bool
+}
+
+// Dial opens an SSH connection with password authentication
+func Dial(target Target, timeout time.Duration) (*Conn, error) {
+	return DialContext(context.Background(), target, timeout)
+}
+
+// DialContext opens an SSH connection like Dial and closes it once ctx is done,
+// interrupting the handshake or any command running on it
+func DialContext(ctx context.Context, target Target, timeout time.Duration) (*Conn, error) {
+	addr := net.JoinHostPort(target.Host, strconv.Itoa(target.Port))
+	dialer := net.Dialer{Timeout: timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to dial: %w", err)
+	}
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	c, chans, reqs, err := ssh.NewClientConn(conn, addr, target.clientConfig(timeout))
+	if err != nil {
+		stop()
+		conn.Close()
+		return nil, fmt.Errorf("failed to dial: %w", err)
+	}
+	return &Conn{Target: target, client: ssh.NewClient(c, chans, reqs), stop: stop}, nil
+}
+
+// Close closes the connection
+func (c *Conn) Close() error {
+	c.stop()
+	return c.client.Close()
+}
+
+// Exec runs a command over an exec channel and returns its combined output
+func (c *Conn) Exec(command string) (string, error) {
+	return c.ExecInput(command, "")
+}
+
+// ExecInput runs a command over an exec channel with the given standard input
+func (c *Conn) ExecInput(command, input string) (string, error) {
+	session, err := c.client.NewSession()
+	if err != nil {
+		return "", fmt.Errorf("failed to create session: %w", err)
+	}
+	defer session.Close()
+
+	if input != "" {
+		session.Stdin = strings.NewReader(input)
+	}
+	output, err := session.CombinedOutput(command)
+	if err != nil {
+		return string(output), fmt.Errorf("command failed: %w", err)
+	}
+	return string(output), nil
+}
+
+// Run runs commands the way the vendor expects: in one PTY shell for interactive
+// vendors, otherwise over an exec channel each. It returns each command's output.
+func (c *Conn) Run(commands ...string) ([]string, error) {
+	outputs := make([]string, 0, len(commands))
+	if !c.Target.Interactive() {
+		for _, cmd := range commands {
+			out, err := c.Exec(cmd)
+			if err != nil {
+				return outputs, fmt.Errorf("%s: %w", cmd, err)
+			}
+			outputs = append(outputs, out)
+		}
+		return outputs, nil
+	}
+
+	sh, err := c.Shell()
+	if err != nil {
+		return nil, err
+	}
+	defer sh.Close()
+	for _, cmd := range commands {
+		out, err := sh.Run(cmd)
+		if err != nil {
+			return outputs, fmt.Errorf("%s: %w", cmd, err)
+		}
+		outputs = append(outputs, out)
+	}
+	return outputs, nil
+}
@@ -0,0 +1,99 @@
+package drivers
+
+import (
+	"errors"
+	"sort"
+	"sync"
+
+	"github.com/ztp-server/backend/models"
+)
+
+// ErrNotSupported is returned by drivers that can't perform an operation, e.g.
+// applying config to a Linux host
+var ErrNotSupported = errors.New("operation not supported by this driver")
+
+// ErrRollbackPending is wrapped by ApplyConfirmed errors that happened after the
+// rollback timer was armed. Whatever the device took stays until the timer reverts it.
+var ErrRollbackPending = errors.New("rollback timer already armed")
+
+// Driver knows how to talk to one kind of device. Drivers are selected by vendor ID;
+// devices whose vendor has no driver use Generic, which relies only on the vendor's
+// backup settings.
+type Driver interface {
+	// Name identifies the driver, e.g. cisco-ios
+	Name() string
+	// Backup returns the device's configuration
+	Backup(c *Conn) (string, error)
+	// Facts reads the device's identity, software version, interfaces and LLDP
+	// neighbors
+	Facts(c *Conn) (*Facts, error)
+	// Uptime returns how long the device has been up, as the device reports it
+	Uptime(c *Conn) (string, error)
+	// ApplyConfig merges configuration lines into the running config and saves it,
+	// returning the device's output
+	ApplyConfig(c *Conn, config string) (string, error)
+	// Reboot restarts the device without saving pending changes
+	Reboot(c *Conn) error
+}
+
+// ConfirmedApplier is implemented by drivers that can apply config under a rollback
+// timer: the device reverts the change by itself unless it is confirmed in time, so a
+// change that cuts off management access undoes itself.
+type ConfirmedApplier interface {
+	// ApplyConfirmed applies configuration lines that the device rolls back after the
+	// given number of minutes unless Confirm is called. name identifies the change on
+	// devices that need one, such as EOS config sessions. Errors wrap
+	// ErrRollbackPending if the timer was armed before the change failed.
+	ApplyConfirmed(c *Conn, config, name string, minutes int) (string, error)
+	// Confirm makes a confirmed change permanent and saves it
+	Confirm(c *Conn, name string) (string, error)
+}
+
+// Facts describes a device as it reports itself
+type Facts struct {
+	Hostname   string                   `json:"hostname,omitempty"`
+	Model      string                   `json:"model,omitempty"`
+	Serial     string                   `json:"serial,omitempty"`
+	Version    string                   `json:"version,omitempty"`
+	Uptime     string                   `json:"uptime,omitempty"`
+	Interfaces []models.DeviceInterface `json:"interfaces,omitempty"`
+	Neighbors  []models.LLDPNeighbor    `json:"neighbors,omitempty"`
+}
+
+var (
+	registryMu sync.RWMutex
+	registry   = map[string]Driver{}
+)
+
+// Register makes a driver available for a vendor ID. It panics if the vendor already
+// has a driver.
+func Register(vendorID string, driver Driver) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if _, dup := registry[vendorID]; dup {
+		panic("drivers: driver registered twice for vendor: " + vendorID)
+	}
+	registry[vendorID] = driver
+}
+
+// For returns the driver for a vendor ID, or Generic if none is registered
+func For(vendorID string) Driver {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	if driver, ok := registry[vendorID]; ok {
+		return driver
+	}
+	return Generic{}
+}
+
+// Registered returns the vendor IDs that have a driver, in sorted order
+func Registered() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	ids := make([]string, 0, len(registry))
+	for id := range registry {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
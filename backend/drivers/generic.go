@@ -0,0 +1,83 @@
+package drivers
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Generic drives devices whose vendor has no registered driver. Backups follow the
+// vendor's backup mode and command; uptime is guessed from common commands.
+type Generic struct{}
+
+// Name implements Driver
+func (Generic) Name() string { return "generic" }
+
+// Backup runs the backup command in a PTY shell for interactive vendors, otherwise
+// over an exec channel
+func (Generic) Backup(c *Conn) (string, error) {
+	outputs, err := c.Run(c.Target.BackupCommand)
+	if err != nil {
+		return "", err
+	}
+	return outputs[0], nil
+}
+
+// Facts implements Driver
+func (Generic) Facts(c *Conn) (*Facts, error) {
+	return nil, ErrNotSupported
+}
+
+// Uptime tries the Unix uptime command, then the Cisco-style show version
+func (Generic) Uptime(c *Conn) (string, error) {
+	if out, err := c.Exec("uptime"); err == nil {
+		return strings.TrimSpace(out), nil
+	}
+	out, err := c.Exec("show version | include uptime")
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(out), nil
+}
+
+// ApplyConfig implements Driver
+func (Generic) ApplyConfig(c *Conn, config string) (string, error) {
+	return "", ErrNotSupported
+}
+
+// Reboot implements Driver
+func (Generic) Reboot(c *Conn) error {
+	return ErrNotSupported
+}
+
+// match returns the first submatch of re in s, trimmed, or "" if it doesn't match
+func match(re *regexp.Regexp, s string) string {
+	m := re.FindStringSubmatch(s)
+	if len(m) < 2 {
+		return ""
+	}
+	return strings.TrimSpace(m[1])
+}
+
+// configLines splits config into the lines to enter, dropping blanks and comments
+// that start with any of the given markers
+func configLines(config string, comments ...string) []string {
+	var lines []string
+	for _, line := range strings.Split(config, "\n") {
+		line = strings.TrimRight(line, " \t\r")
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" {
+			continue
+		}
+		comment := false
+		for _, marker := range comments {
+			if strings.HasPrefix(trimmed, marker) {
+				comment = true
+				break
+			}
+		}
+		if !comment {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
@@ -0,0 +1,170 @@
+package drivers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/ztp-server/backend/models"
+)
+
+func init() {
+	Register("juniper", JuniperJunos{})
+}
+
+var (
+	junosHostname = regexp.MustCompile(`(?m)^Hostname:\s*(\S+)`)
+	junosModel    = regexp.MustCompile(`(?m)^Model:\s*(\S+)`)
+	junosVersion  = regexp.MustCompile(`(?m)^(?:Junos:\s*(\S+)|JUNOS .*\[(\S+)\])`)
+	junosUptime   = regexp.MustCompile(`(?m)System booted:.*\((.+) ago\)`)
+	// junosError matches commit and syntax errors
+	junosError = regexp.MustCompile(`(?mi)^\s*(error:.*|syntax error.*|unknown command.*)$`)
+
+	rebootConfirm = regexp.MustCompile(`(?i)\[yes,no\].*$`)
+)
+
+// JuniperJunos drives Juniper Junos devices
+type JuniperJunos struct {
+	Generic
+}
+
+// Name implements Driver
+func (JuniperJunos) Name() string { return "juniper-junos" }
+
+// Facts parses show version, the chassis line of show chassis hardware, the physical
+// interfaces of show interfaces terse and show lldp neighbors
+func (JuniperJunos) Facts(c *Conn) (*Facts, error) {
+	outputs, err := c.Run(
+		"show version",
+		"show chassis hardware | match Chassis",
+		`show system uptime | match "System booted"`,
+		"show interfaces terse",
+		"show lldp neighbors",
+	)
+	if err != nil {
+		return nil, err
+	}
+	version := outputs[0]
+	facts := &Facts{
+		Hostname:   match(junosHostname, version),
+		Model:      match(junosModel, version),
+		Serial:     junosChassisSerial(outputs[1]),
+		Uptime:     match(junosUptime, outputs[2]),
+		Interfaces: junosInterfaces(outputs[3]),
+		Neighbors: tableNeighbors(parseTable(outputs[4], "Local Interface", "Port info", "System Name"),
+			"Local Interface", "System Name", "Port info"),
+	}
+	if m := junosVersion.FindStringSubmatch(version); m != nil {
+		facts.Version = m[1] + m[2]
+	}
+	return facts, nil
+}
+
+// Uptime reads the boot time line of show system uptime
+func (JuniperJunos) Uptime(c *Conn) (string, error) {
+	outputs, err := c.Run(`show system uptime | match "System booted"`)
+	if err != nil {
+		return "", err
+	}
+	return match(junosUptime, outputs[0]), nil
+}
+
+// ApplyConfig loads set-style lines and commits them
+func (JuniperJunos) ApplyConfig(c *Conn, config string) (string, error) {
+	return junosApply(c, configLines(config, "#"), "commit")
+}
+
+// ApplyConfirmed loads set-style lines and commits them with commit confirmed; Junos
+// rolls back to the previous commit when the timer expires
+func (JuniperJunos) ApplyConfirmed(c *Conn, config, name string, minutes int) (string, error) {
+	return junosApply(c, configLines(config, "#"), fmt.Sprintf("commit confirmed %d", minutes))
+}
+
+// Confirm confirms the pending commit with a commit of an empty private candidate
+func (JuniperJunos) Confirm(c *Conn, name string) (string, error) {
+	return junosApply(c, nil, "commit")
+}
+
+// junosApply loads lines into a private candidate and commits it. Unlike the shared
+// candidate, it holds none of other users' uncommitted edits, so only the lines loaded
+// here are committed. After an error the candidate is rolled back before leaving, so
+// nothing half loaded is left behind.
+func junosApply(c *Conn, lines []string, commit string) (string, error) {
+	sh, err := c.Shell()
+	if err != nil {
+		return "", err
+	}
+	defer sh.Close()
+
+	commands := append(append([]string{"configure private"}, lines...), commit, "exit configuration-mode")
+	out, _, err := runLines(sh, commands, junosError)
+	if err != nil {
+		for _, cmd := range []string{"rollback 0", "exit configuration-mode"} {
+			abort, abortErr := sh.Run(cmd)
+			out += abort
+			if abortErr != nil {
+				break
+			}
+		}
+	}
+	return out, err
+}
+
+// Reboot runs request system reboot and confirms it
+func (JuniperJunos) Reboot(c *Conn) error {
+	sh, err := c.Shell()
+	if err != nil {
+		return err
+	}
+	defer sh.Close()
+
+	if err := sh.Send("request system reboot"); err != nil {
+		return err
+	}
+	line, err := sh.Expect(rebootConfirm, sh.opts.prompt)
+	if err != nil {
+		return err
+	}
+	if !rebootConfirm.MatchString(line) {
+		return fmt.Errorf("reboot was not accepted: %s", strings.TrimSpace(sh.buf.String()))
+	}
+	if err := sh.Send("yes"); err != nil {
+		return err
+	}
+	if _, err := sh.Expect(sh.opts.prompt); err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return err
+	}
+	return nil
+}
+
+// junosInterfaces returns the physical interfaces of show interfaces terse, leaving
+// out logical units such as ge-0/0/0.0
+func junosInterfaces(out string) []models.DeviceInterface {
+	var ifaces []models.DeviceInterface
+	for _, row := range parseTable(out, "Interface", "Admin", "Link") {
+		name := row["Interface"]
+		if name == "" || strings.Contains(name, ".") {
+			continue
+		}
+		status := row["Link"]
+		if row["Admin"] == "down" {
+			status = "admin down"
+		}
+		ifaces = append(ifaces, models.DeviceInterface{Name: name, Status: status})
+	}
+	return ifaces
+}
+
+// junosChassisSerial returns the serial number from the "Chassis <serial> <description>"
+// line of show chassis hardware
+func junosChassisSerial(out string) string {
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) >= 2 && fields[0] == "Chassis" {
+			return fields[1]
+		}
+	}
+	return ""
+}
@@ -0,0 +1,76 @@
+package drivers
+
+import (
+	"errors"
+	"io"
+	"regexp"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+)
+
+func init() {
+	Register("linux", Linux{})
+	Register("raspberry-pi", Linux{})
+}
+
+var osPrettyName = regexp.MustCompile(`(?m)^PRETTY_NAME="?([^"\n]+)"?`)
+
+// Linux drives Linux hosts such as Raspberry Pis
+type Linux struct {
+	Generic
+}
+
+// Name implements Driver
+func (Linux) Name() string { return "linux" }
+
+// Facts reads the hostname, os-release, the board or DMI model and serial, links and
+// lldpd's neighbors
+func (Linux) Facts(c *Conn) (*Facts, error) {
+	outputs, err := c.Run(
+		"hostname",
+		"cat /etc/os-release",
+		"cat /sys/firmware/devicetree/base/model 2>/dev/null || cat /sys/class/dmi/id/product_name 2>/dev/null; true",
+		"cat /sys/firmware/devicetree/base/serial-number 2>/dev/null || cat /sys/class/dmi/id/product_serial 2>/dev/null; true",
+		"uptime -p",
+		linuxLinksCommand,
+		lldpctlCommand,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &Facts{
+		Hostname:   strings.TrimSpace(outputs[0]),
+		Version:    match(osPrettyName, outputs[1]),
+		Model:      strings.Trim(strings.TrimSpace(outputs[2]), "\x00"),
+		Serial:     strings.Trim(strings.TrimSpace(outputs[3]), "\x00"),
+		Uptime:     strings.TrimPrefix(strings.TrimSpace(outputs[4]), "up "),
+		Interfaces: parseIPLinks(outputs[5]),
+		Neighbors:  parseLLDPKeyValue(outputs[6]),
+	}, nil
+}
+
+// Uptime runs uptime -p
+func (Linux) Uptime(c *Conn) (string, error) {
+	outputs, err := c.Run("uptime -p")
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimPrefix(strings.TrimSpace(outputs[0]), "up "), nil
+}
+
+// Reboot runs reboot, through sudo if the user isn't root
+func (Linux) Reboot(c *Conn) error {
+	return execReboot(c, "sudo -n reboot || reboot")
+}
+
+// execReboot runs a reboot command over an exec channel. The device often drops the
+// connection before the command reports an exit status, which counts as success.
+func execReboot(c *Conn, command string) error {
+	_, err := c.Exec(command)
+	var missing *ssh.ExitMissingError
+	if errors.As(err, &missing) || errors.Is(err, io.EOF) {
+		return nil
+	}
+	return err
+}
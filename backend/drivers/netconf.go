@@ -0,0 +1,30 @@
+package drivers
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/ztp-server/backend/netconf"
+)
+
+// NetconfBackup fetches the running config with NETCONF <get-config> over the target's
+// netconf subsystem. The result is a standalone XML document whose root is the reply's
+// <data> element, holding the datastore contents. The session is cut off once ctx is
+// done.
+func NetconfBackup(ctx context.Context, target Target, timeout time.Duration) (string, error) {
+	addr := net.JoinHostPort(target.Host, strconv.Itoa(target.NetconfPort))
+	session, err := netconf.Dial(ctx, addr, target.clientConfig(timeout), timeout)
+	if err != nil {
+		return "", err
+	}
+	defer session.Close()
+
+	data, err := session.GetConfig("running")
+	if err != nil {
+		return "", fmt.Errorf("get-config failed: %w", err)
+	}
+	return `<?xml version="1.0" encoding="UTF-8"?>` + "\n" + data + "\n", nil
+}
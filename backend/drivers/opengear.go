@@ -0,0 +1,85 @@
+package drivers
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+func init() {
+	Register("opengear", OpenGear{})
+}
+
+var (
+	// /etc/version reads e.g. "OpenGear/CM71xx Version 4.12.0 ..."
+	ogVersion = regexp.MustCompile(`OpenGear/(\S+) Version (\S+)`)
+	ogUptime  = regexp.MustCompile(`up\s+(.+?),\s+(?:\d+ users?,\s+)?load average`)
+)
+
+// OpenGear drives OpenGear console servers through the config command-line tool
+type OpenGear struct {
+	Generic
+}
+
+// Name implements Driver
+func (OpenGear) Name() string { return "opengear" }
+
+// Facts reads the hostname, /etc/version, the serial number, links and lldpd's
+// neighbors
+func (OpenGear) Facts(c *Conn) (*Facts, error) {
+	outputs, err := c.Run("hostname", "cat /etc/version", "showserial", "uptime", linuxLinksCommand, lldpctlCommand)
+	if err != nil {
+		return nil, err
+	}
+	facts := &Facts{
+		Hostname:   strings.TrimSpace(outputs[0]),
+		Serial:     strings.TrimSpace(outputs[2]),
+		Uptime:     match(ogUptime, outputs[3]),
+		Interfaces: parseIPLinks(outputs[4]),
+		Neighbors:  parseLLDPKeyValue(outputs[5]),
+	}
+	if m := ogVersion.FindStringSubmatch(outputs[1]); m != nil {
+		facts.Model = m[1]
+		facts.Version = m[2]
+	}
+	return facts, nil
+}
+
+// Uptime parses the uptime command
+func (OpenGear) Uptime(c *Conn) (string, error) {
+	outputs, err := c.Run("uptime")
+	if err != nil {
+		return "", err
+	}
+	if up := match(ogUptime, outputs[0]); up != "" {
+		return up, nil
+	}
+	return strings.TrimSpace(outputs[0]), nil
+}
+
+// ApplyConfig sets each config.key=value line with config -s, then applies the
+// changes with config -a
+func (OpenGear) ApplyConfig(c *Conn, config string) (string, error) {
+	var commands []string
+	for _, line := range configLines(config, "#") {
+		line = strings.TrimSpace(line)
+		if !strings.Contains(line, "=") {
+			return "", fmt.Errorf("not a key=value line: %s", line)
+		}
+		commands = append(commands, "config -s "+shellQuote(line))
+	}
+	commands = append(commands, "config -a")
+
+	outputs, err := c.Run(commands...)
+	return strings.Join(outputs, ""), err
+}
+
+// Reboot runs reboot
+func (OpenGear) Reboot(c *Conn) error {
+	return execReboot(c, "reboot")
+}
+
+// shellQuote quotes a string for a POSIX shell
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,193 @@
+package drivers
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/ztp-server/backend/models"
+)
+
+// Commands that list interfaces and LLDP neighbors on Linux-based devices. Both
+// succeed with no output when the tool is missing, so facts collection still works
+// on minimal images.
+const (
+	linuxLinksCommand = "ip -o link show 2>/dev/null; true"
+	lldpctlCommand    = "lldpctl -f keyvalue 2>/dev/null; true"
+)
+
+var headerWord = regexp.MustCompile(`\S+`)
+
+// parseTable splits fixed-width command output into rows keyed by column name. The
+// header is the first line containing every column; each column runs from the start
+// of its name to the next word in the header. Separator lines and "Total ..." footers
+// are skipped, and parsing stops at the first blank line after the rows.
+func parseTable(out string, columns ...string) []map[string]string {
+	lines := strings.Split(strings.ReplaceAll(out, "\r", ""), "\n")
+	header := -1
+	for i, line := range lines {
+		found := true
+		for _, col := range columns {
+			if !strings.Contains(line, col) {
+				found = false
+				break
+			}
+		}
+		if found {
+			header = i
+			break
+		}
+	}
+	if header < 0 {
+		return nil
+	}
+
+	type span struct{ start, end int }
+	words := headerWord.FindAllStringIndex(lines[header], -1)
+	spans := make(map[string]span, len(columns))
+	for _, col := range columns {
+		start := strings.Index(lines[header], col)
+		end := -1
+		for _, w := range words {
+			if w[0] >= start+len(col) {
+				end = w[0]
+				break
+			}
+		}
+		spans[col] = span{start, end}
+	}
+
+	var rows []map[string]string
+	for _, line := range lines[header+1:] {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" {
+			if len(rows) > 0 {
+				break
+			}
+			continue
+		}
+		if strings.Trim(trimmed, "- ") == "" || strings.HasPrefix(trimmed, "Total ") {
+			continue
+		}
+		row := make(map[string]string, len(columns))
+		for col, s := range spans {
+			if s.start >= len(line) {
+				continue
+			}
+			end := s.end
+			if end < 0 || end > len(line) {
+				end = len(line)
+			}
+			row[col] = strings.TrimSpace(line[s.start:end])
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+// parseIPLinks parses ip -o link show, skipping loopbacks
+func parseIPLinks(out string) []models.DeviceInterface {
+	var ifaces []models.DeviceInterface
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 3 || !strings.HasSuffix(fields[0], ":") {
+			continue
+		}
+		iface := models.DeviceInterface{Name: strings.TrimSuffix(fields[1], ":")}
+		if i := strings.Index(iface.Name, "@"); i >= 0 {
+			iface.Name = iface.Name[:i]
+		}
+		loopback := false
+		for i := 2; i < len(fields)-1; i++ {
+			switch {
+			case fields[i] == "state":
+				iface.Status = strings.ToLower(strings.TrimSuffix(fields[i+1], `\`))
+			case fields[i] == "link/loopback":
+				loopback = true
+			case fields[i] == "link/ether":
+				iface.MAC = strings.ToUpper(fields[i+1])
+			}
+		}
+		if !loopback {
+			ifaces = append(ifaces, iface)
+		}
+	}
+	return ifaces
+}
+
+// parseLLDPKeyValue parses lldpctl -f keyvalue, keeping one neighbor per interface
+func parseLLDPKeyValue(out string) []models.LLDPNeighbor {
+	var neighbors []models.LLDPNeighbor
+	index := map[string]int{}
+	descrs := map[string]string{}
+	for _, line := range strings.Split(out, "\n") {
+		key, value, ok := strings.Cut(strings.TrimSpace(line), "=")
+		if !ok || !strings.HasPrefix(key, "lldp.") {
+			continue
+		}
+		key = strings.TrimPrefix(key, "lldp.")
+		var local, field string
+		for _, suffix := range []string{".chassis.name", ".port.ifname", ".port.descr"} {
+			if strings.HasSuffix(key, suffix) {
+				local, field = strings.TrimSuffix(key, suffix), suffix
+				break
+			}
+		}
+		if local == "" {
+			continue
+		}
+		i, seen := index[local]
+		if !seen {
+			i = len(neighbors)
+			index[local] = i
+			neighbors = append(neighbors, models.LLDPNeighbor{LocalInterface: local})
+		}
+		switch field {
+		case ".chassis.name":
+			neighbors[i].RemoteDevice = value
+		case ".port.ifname":
+			neighbors[i].RemoteInterface = value
+		case ".port.descr":
+			descrs[local] = value
+		}
+	}
+	for i, n := range neighbors {
+		if n.RemoteInterface == "" {
+			neighbors[i].RemoteInterface = descrs[n.LocalInterface]
+		}
+	}
+	return neighbors
+}
+
+// tableInterfaces builds interfaces from parseTable rows with the given name, status
+// and description columns
+func tableInterfaces(rows []map[string]string, name, status, description string) []models.DeviceInterface {
+	var ifaces []models.DeviceInterface
+	for _, row := range rows {
+		if row[name] == "" {
+			continue
+		}
+		ifaces = append(ifaces, models.DeviceInterface{
+			Name:        row[name],
+			Status:      row[status],
+			Description: row[description],
+		})
+	}
+	return ifaces
+}
+
+// tableNeighbors builds LLDP neighbors from parseTable rows with the given local
+// interface, remote device and remote interface columns
+func tableNeighbors(rows []map[string]string, local, device, remote string) []models.LLDPNeighbor {
+	var neighbors []models.LLDPNeighbor
+	for _, row := range rows {
+		if row[local] == "" || row[device] == "" {
+			continue
+		}
+		neighbors = append(neighbors, models.LLDPNeighbor{
+			LocalInterface:  row[local],
+			RemoteDevice:    row[device],
+			RemoteInterface: row[remote],
+		})
+	}
+	return neighbors
+}
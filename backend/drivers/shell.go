@@ -0,0 +1,387 @@
+package drivers
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+
+	"github.com/ztp-server/backend/models"
+)
+
+// shellTimeout is how long an interactive session may go without output before it is
+// abandoned
+const shellTimeout = 30 * time.Second
+
+// promptQuiet is how long output must stop after a line that looks like a prompt before
+// it is taken as one, so a prompt-like line in the middle of output doesn't end it
+const promptQuiet = 100 * time.Millisecond
+
+// Parts of a device's own prompt around its host part: what may precede it, as in
+// "<host>" or "[~host]", and what follows it, a mode such as "(config-if)" and the
+// terminator
+const (
+	promptLead = `[<\[~*]*`
+	promptTail = `(\([^()]*\))?\s?[>#$%\]]+\s*$`
+)
+
+var (
+	// defaultPrompt matches the usual "host>", "host#", "user@host:~$" style prompts
+	defaultPrompt = regexp.MustCompile(`[\w.\-@()/:~\[\]]+\s?[>#$%]\s*$`)
+	// defaultPager matches common pagination prompts
+	defaultPager = regexp.MustCompile(`(?i)(-+\s*more\s*-+|<-+\s*more\s*-+>|press any key to continue)`)
+	// promptStart and promptEnd strip a prompt down to its host part
+	promptStart = regexp.MustCompile(`^` + promptLead)
+	promptEnd   = regexp.MustCompile(promptTail)
+	// passwordPrompt matches the enable secret prompt
+	passwordPrompt = regexp.MustCompile(`(?i)(password|secret)\s*:\s*$`)
+	// ansiEscape matches terminal control sequences
+	ansiEscape = regexp.MustCompile(`\x1b(\[[0-9;?]*[A-Za-z]|[()][A-Za-z0-9]|[=>])`)
+)
+
+// ErrShellTimeout is returned when the device stops responding mid-session
+var ErrShellTimeout = errors.New("timed out waiting for the device prompt")
+
+// shellOptions configures an interactive session
+type shellOptions struct {
+	prompt        *regexp.Regexp
+	pager         *regexp.Regexp
+	preCommands   []string
+	enableCommand string
+	enableSecret  string
+	timeout       time.Duration
+	quiet         time.Duration
+}
+
+// newShellOptions builds the session options from a vendor's settings, falling back to
+// the default prompt and pager patterns
+func newShellOptions(vendor *models.Vendor, enableSecret string) (*shellOptions, error) {
+	opts := &shellOptions{
+		prompt:       defaultPrompt,
+		pager:        defaultPager,
+		enableSecret: enableSecret,
+		timeout:      shellTimeout,
+		quiet:        promptQuiet,
+	}
+	if vendor == nil {
+		return opts, nil
+	}
+	if vendor.PromptPattern != "" {
+		re, err := regexp.Compile(vendor.PromptPattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid prompt pattern: %w", err)
+		}
+		opts.prompt = re
+	}
+	if vendor.PagerPattern != "" {
+		re, err := regexp.Compile(vendor.PagerPattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pager pattern: %w", err)
+		}
+		opts.pager = re
+	}
+	opts.preCommands = vendor.PreCommands
+	opts.enableCommand = vendor.EnableCommand
+	return opts, nil
+}
+
+// Shell is a PTY session that runs commands and reads their output until the prompt
+// comes back. Pagination prompts are answered along the way.
+type Shell struct {
+	session *ssh.Session
+	opts    *shellOptions
+	loose   *regexp.Regexp // The vendor's prompt pattern, once opts.prompt is the device's own
+	stdin   io.Writer
+	output  chan []byte
+	done    chan struct{} // Closed when the shell is closed
+	buf     bytes.Buffer
+}
+
+// Shell opens a PTY shell on the connection. It waits for the prompt, escalates to
+// enable mode if the vendor has an enable command and the prompt ends in ">", and runs
+// the vendor's pre-commands. From the first prompt on, only the device's own prompt
+// ends a command's output.
+func (c *Conn) Shell() (*Shell, error) {
+	opts, err := newShellOptions(c.Target.Vendor, c.Target.EnableSecret)
+	if err != nil {
+		return nil, err
+	}
+
+	session, err := c.client.NewSession()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create session: %w", err)
+	}
+
+	modes := ssh.TerminalModes{
+		ssh.ECHO:          0,
+		ssh.TTY_OP_ISPEED: 38400,
+		ssh.TTY_OP_OSPEED: 38400,
+	}
+	if err := session.RequestPty("vt100", 0, 511, modes); err != nil {
+		session.Close()
+		return nil, fmt.Errorf("failed to request PTY: %w", err)
+	}
+	stdin, err := session.StdinPipe()
+	if err != nil {
+		session.Close()
+		return nil, err
+	}
+	stdout, err := session.StdoutPipe()
+	if err != nil {
+		session.Close()
+		return nil, err
+	}
+	if err := session.Shell(); err != nil {
+		session.Close()
+		return nil, fmt.Errorf("failed to start shell: %w", err)
+	}
+
+	sh := &Shell{
+		session: session,
+		opts:    opts,
+		stdin:   stdin,
+		output:  make(chan []byte, 16),
+		done:    make(chan struct{}),
+	}
+	go sh.read(stdout)
+
+	prompt, err := sh.Expect(opts.prompt)
+	if err != nil {
+		sh.Close()
+		return nil, fmt.Errorf("no prompt after login: %w", err)
+	}
+	sh.learnPrompt(prompt)
+
+	if opts.enableCommand != "" && strings.HasSuffix(strings.TrimSpace(prompt), ">") {
+		if err := sh.enable(); err != nil {
+			sh.Close()
+			return nil, err
+		}
+	}
+
+	for _, cmd := range opts.preCommands {
+		if _, err := sh.Run(cmd); err != nil {
+			sh.Close()
+			return nil, fmt.Errorf("%s: %w", cmd, err)
+		}
+	}
+	return sh, nil
+}
+
+// Close ends the session
+func (sh *Shell) Close() error {
+	select {
+	case <-sh.done:
+		return nil
+	default:
+	}
+	fmt.Fprint(sh.stdin, "exit\n")
+	close(sh.done)
+	return sh.session.Close()
+}
+
+// read forwards session output until the session closes
+func (sh *Shell) read(r io.Reader) {
+	defer close(sh.output)
+	for {
+		chunk := make([]byte, 4096)
+		n, err := r.Read(chunk)
+		if n > 0 {
+			select {
+			case sh.output <- chunk[:n]:
+			case <-sh.done:
+				return
+			}
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// Run sends a command and returns its output with the echo, prompt and pagination
+// removed
+func (sh *Shell) Run(command string) (string, error) {
+	if err := sh.Send(command); err != nil {
+		return "", err
+	}
+	if _, err := sh.Expect(sh.opts.prompt); err != nil {
+		return "", err
+	}
+	return cleanOutput(sh.buf.String(), command, sh.opts), nil
+}
+
+// Send writes a line without waiting for a response
+func (sh *Shell) Send(line string) error {
+	_, err := fmt.Fprintf(sh.stdin, "%s\n", line)
+	return err
+}
+
+// enable escalates to privileged mode, answering the secret prompt if one appears
+func (sh *Shell) enable() error {
+	if err := sh.Send(sh.opts.enableCommand); err != nil {
+		return err
+	}
+	line, err := sh.Expect(passwordPrompt, sh.opts.prompt)
+	if err != nil {
+		return fmt.Errorf("enable: %w", err)
+	}
+	if passwordPrompt.MatchString(line) {
+		if sh.opts.enableSecret == "" {
+			return fmt.Errorf("enable: device asked for a secret but none is configured")
+		}
+		if err := sh.Send(sh.opts.enableSecret); err != nil {
+			return err
+		}
+		if line, err = sh.Expect(passwordPrompt, sh.opts.prompt); err != nil {
+			return fmt.Errorf("enable: %w", err)
+		}
+		if passwordPrompt.MatchString(line) {
+			return fmt.Errorf("enable: secret rejected")
+		}
+	}
+	if strings.HasSuffix(strings.TrimSpace(line), ">") {
+		return fmt.Errorf("enable: still in unprivileged mode")
+	}
+	return nil
+}
+
+// learnPrompt narrows the prompt pattern to the device's own prompt: the host part of
+// the prompt it showed, at the start of a line, followed by any mode and terminator, so
+// it still matches after enable or in config mode
+func (sh *Shell) learnPrompt(prompt string) {
+	host := promptEnd.ReplaceAllString(strings.TrimSpace(prompt), "")
+	host = promptStart.ReplaceAllString(host, "")
+	if host == "" {
+		return
+	}
+	if sh.loose == nil {
+		sh.loose = sh.opts.prompt
+	}
+	sh.opts.prompt = regexp.MustCompile(`^` + promptLead + regexp.QuoteMeta(host) + promptTail)
+}
+
+// Expect reads output until its last line matches one of the patterns and no more
+// output follows for a moment, answering pagination prompts along the way, and returns
+// the matching line. Output read since the previous Expect is discarded.
+func (sh *Shell) Expect(patterns ...*regexp.Regexp) (string, error) {
+	sh.buf.Reset()
+	timer := time.NewTimer(sh.opts.timeout)
+	defer timer.Stop()
+	var quiet <-chan time.Time // Set while a matching line waits out the quiet period
+	var matched string
+
+	for {
+		select {
+		case chunk, ok := <-sh.output:
+			if !ok {
+				return "", io.ErrUnexpectedEOF
+			}
+			sh.buf.Write(chunk)
+			if !timer.Stop() {
+				<-timer.C
+			}
+			timer.Reset(sh.opts.timeout)
+			quiet = nil
+		case <-quiet:
+			return matched, nil
+		case <-timer.C:
+			// A change of hostname changes the prompt; pick up the new one
+			if line := lastLine(sh.buf.Bytes()); sh.loose != nil && sh.loose.MatchString(line) && slices.Contains(patterns, sh.opts.prompt) {
+				sh.learnPrompt(line)
+				return line, nil
+			}
+			return "", ErrShellTimeout
+		}
+
+		line := lastLine(sh.buf.Bytes())
+		if sh.opts.pager.MatchString(line) {
+			// Drop the pager prompt and ask for the next page
+			data := sh.buf.Bytes()
+			sh.buf.Truncate(len(data) - len(trailingLine(data)))
+			fmt.Fprint(sh.stdin, " ")
+			continue
+		}
+		for _, re := range patterns {
+			if re.MatchString(line) {
+				matched, quiet = line, time.After(sh.opts.quiet)
+				break
+			}
+		}
+	}
+}
+
+// trailingLine returns the raw bytes after the last newline
+func trailingLine(data []byte) []byte {
+	if i := bytes.LastIndexByte(data, '\n'); i >= 0 {
+		return data[i+1:]
+	}
+	return data
+}
+
+// lastLine returns the text of the last, possibly unterminated, line of output
+func lastLine(data []byte) string {
+	return cleanLine(string(trailingLine(data)))
+}
+
+// cleanLine strips control sequences and applies carriage returns and backspaces the
+// way a terminal would
+func cleanLine(line string) string {
+	line = ansiEscape.ReplaceAllString(line, "")
+	line = strings.TrimRight(line, "\r")
+	if i := strings.LastIndexByte(line, '\r'); i >= 0 {
+		line = line[i+1:]
+	}
+	if strings.IndexByte(line, '\b') >= 0 {
+		var out []rune
+		for _, r := range line {
+			if r == '\b' {
+				if len(out) > 0 {
+					out = out[:len(out)-1]
+				}
+				continue
+			}
+			out = append(out, r)
+		}
+		line = string(out)
+	}
+	return line
+}
+
+// cleanOutput removes the command echo, the trailing prompt and any pager leftovers
+func cleanOutput(raw, command string, opts *shellOptions) string {
+	lines := strings.Split(raw, "\n")
+	for i := range lines {
+		lines[i] = strings.TrimRightFunc(opts.pager.ReplaceAllString(cleanLine(lines[i]), ""), isSpace)
+	}
+
+	// Echo of the command, with or without the prompt in front of it
+	for len(lines) > 0 && strings.TrimSpace(lines[0]) == "" {
+		lines = lines[1:]
+	}
+	if len(lines) > 0 && strings.HasSuffix(strings.TrimSpace(lines[0]), strings.TrimSpace(command)) {
+		lines = lines[1:]
+	}
+	// The prompt that ended the output
+	if len(lines) > 0 && opts.prompt.MatchString(lines[len(lines)-1]) {
+		lines = lines[:len(lines)-1]
+	}
+	for len(lines) > 0 && strings.TrimSpace(lines[len(lines)-1]) == "" {
+		lines = lines[:len(lines)-1]
+	}
+
+	if len(lines) == 0 {
+		return ""
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+func isSpace(r rune) bool {
+	return r == ' ' || r == '\t'
+}
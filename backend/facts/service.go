@@ -0,0 +1,189 @@
+package facts
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/ztp-server/backend/db"
+	"github.com/ztp-server/backend/drivers"
+	"github.com/ztp-server/backend/jobs"
+	"github.com/ztp-server/backend/models"
+	"github.com/ztp-server/backend/ws"
+)
+
+// collectAttempts is how many times a queued collection is tried before it fails
+const collectAttempts = 3
+
+// ErrNoDevice is returned when collecting facts for a device that doesn't exist
+var ErrNoDevice = errors.New("device not found")
+
+// Service collects device facts over SSH through the vendor drivers. Each collection
+// is stored as a snapshot, fills in the device's model and serial number when they are
+// unknown, and raises an alert when the device reports a different serial number than
+// the one it was registered with.
+type Service struct {
+	store *db.Store
+	queue *jobs.Queue
+	hub   *ws.Hub
+
+	onCollected func(device *models.Device, facts *models.DeviceFacts)
+}
+
+// collectPayload is the payload of a facts.collect job
+type collectPayload struct {
+	MAC string `json:"mac"`
+}
+
+// NewService creates a facts service and registers its job handler. Serial mismatches
+// are broadcast to WebSocket clients through hub.
+func NewService(store *db.Store, queue *jobs.Queue, hub *ws.Hub) *Service {
+	s := &Service{store: store, queue: queue, hub: hub}
+	queue.Register(jobs.TypeFactsCollect, 2, s.runJob)
+	return s
+}
+
+// SetCollectedHook sets a function to call after a device's facts have been collected
+// and stored
+func (s *Service) SetCollectedHook(fn func(device *models.Device, facts *models.DeviceFacts)) {
+	s.onCollected = fn
+}
+
+// Queue schedules a facts collection for a device. A collection already queued for the
+// device is reused.
+func (s *Service) Queue(mac string) (*models.Job, error) {
+	return s.queue.Enqueue(jobs.TypeFactsCollect, collectPayload{MAC: mac}, jobs.Options{
+		MaxAttempts: collectAttempts,
+		Key:         mac,
+	})
+}
+
+// OnProvisioned queues a facts collection once a device has come up after ZTP
+func (s *Service) OnProvisioned(device *models.Device) {
+	log.Printf("Queueing facts collection for %s", device.Hostname)
+	if _, err := s.Queue(device.MAC); err != nil {
+		log.Printf("Failed to queue facts collection for %s: %v", device.MAC, err)
+	}
+}
+
+func (s *Service) runJob(ctx context.Context, job *models.Job) (any, error) {
+	var payload collectPayload
+	if err := jobs.Decode(job, &payload); err != nil {
+		return nil, err
+	}
+	facts, err := s.Collect(payload.MAC)
+	if errors.Is(err, ErrNoDevice) || errors.Is(err, drivers.ErrNotSupported) {
+		return nil, jobs.Permanent(err)
+	}
+	return facts, err
+}
+
+// Collect connects to a device, reads its facts and records them
+func (s *Service) Collect(mac string) (*models.DeviceFacts, error) {
+	device, err := s.store.GetDevice(mac)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get device: %w", err)
+	}
+	if device == nil {
+		return nil, fmt.Errorf("%w: %s", ErrNoDevice, mac)
+	}
+	settings, err := s.store.GetSettings()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get settings: %w", err)
+	}
+
+	var vendor *models.Vendor
+	if device.Vendor != "" {
+		vendor, _ = s.store.GetVendor(device.Vendor)
+	}
+	target := drivers.NewTarget(device, vendor, settings)
+	driver := drivers.For(target.VendorID())
+
+	conn, err := drivers.Dial(target, 30*time.Second)
+	if err != nil {
+		return nil, fmt.Errorf("SSH failed: %w", err)
+	}
+	defer conn.Close()
+
+	reported, err := driver.Facts(conn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect facts: %w", err)
+	}
+
+	facts := &models.DeviceFacts{
+		DeviceMAC:  device.MAC,
+		Driver:     driver.Name(),
+		Hostname:   reported.Hostname,
+		Model:      reported.Model,
+		Serial:     reported.Serial,
+		Version:    reported.Version,
+		Uptime:     reported.Uptime,
+		Interfaces: reported.Interfaces,
+		Neighbors:  reported.Neighbors,
+	}
+	facts.SerialMismatch = device.SerialNumber != "" && facts.Serial != "" &&
+		!strings.EqualFold(device.SerialNumber, facts.Serial)
+
+	if err := s.store.CreateDeviceFacts(facts); err != nil {
+		return nil, fmt.Errorf("failed to save facts: %w", err)
+	}
+	s.enrich(device, facts)
+	if s.onCollected != nil {
+		s.onCollected(device, facts)
+	}
+
+	log.Printf("Collected facts for %s using the %s driver: %s %s, %d interfaces, %d LLDP neighbors",
+		device.Hostname, driver.Name(), facts.Model, facts.Version, len(facts.Interfaces), len(facts.Neighbors))
+	return facts, nil
+}
+
+// enrich fills in the device's model and serial number when they are unknown, and
+// alerts on a serial number mismatch. Known values are never overwritten, since they
+// may be owned by NetBox or an inventory source.
+func (s *Service) enrich(device *models.Device, facts *models.DeviceFacts) {
+	if facts.SerialMismatch {
+		s.alertSerialMismatch(device, facts.Serial)
+	}
+
+	model, serial := device.Model, device.SerialNumber
+	if model == "" {
+		model = facts.Model
+	}
+	if serial == "" {
+		serial = facts.Serial
+	}
+	if model == device.Model && serial == device.SerialNumber {
+		return
+	}
+	if err := s.store.UpdateDeviceInventory(device.MAC, model, serial); err != nil {
+		log.Printf("Failed to update inventory for %s: %v", device.MAC, err)
+	}
+}
+
+// alertSerialMismatch flags a device that reports a different serial number than the
+// registered one, which usually means it was racked in the wrong place
+func (s *Service) alertSerialMismatch(device *models.Device, reported string) {
+	msg := fmt.Sprintf("Serial number mismatch: registered %s, device reports %s", device.SerialNumber, reported)
+	log.Printf("Warning: %s (%s): %s", device.Hostname, device.MAC, msg)
+
+	s.store.UpdateDeviceError(device.MAC, msg)
+	s.store.CreateDiscoveryLog(&models.DiscoveryLog{
+		EventType: "serial_mismatch",
+		MAC:       device.MAC,
+		IP:        device.IP,
+		Hostname:  device.Hostname,
+		Vendor:    device.Vendor,
+		Message:   msg,
+	})
+	if s.hub != nil {
+		s.hub.BroadcastSerialMismatch(ws.SerialMismatchPayload{
+			MAC:        device.MAC,
+			Hostname:   device.Hostname,
+			Registered: device.SerialNumber,
+			Reported:   reported,
+		})
+	}
+}
@@ -1,31 +1,31 @@
 package handlers
 
 import (
+	"errors"
+	"net/http"
 	"os"
 	"path/filepath"
 
 	"github.com/gin-gonic/gin"
+	"github.com/ztp-server/backend/backup"
 	"github.com/ztp-server/backend/db"
 	"github.com/ztp-server/backend/models"
 	"github.com/ztp-server/backend/utils"
 )
 
-// BackupTrigger is a function that triggers a backup for a device
-type BackupTrigger func(mac string) error
-
 // BackupHandler handles backup-related HTTP requests
 type BackupHandler struct {
-	store         *db.Store
-	backupTrigger BackupTrigger
-	backupDir     string
+	store     *db.Store
+	backups   *backup.Service
+	backupDir string
 }
 
 // NewBackupHandler creates a new backup handler
-func NewBackupHandler(store *db.Store, trigger BackupTrigger, backupDir string) *BackupHandler {
+func NewBackupHandler(store *db.Store, backups *backup.Service, backupDir string) *BackupHandler {
 	return &BackupHandler{
-		store:         store,
-		backupTrigger: trigger,
-		backupDir:     backupDir,
+		store:     store,
+		backups:   backups,
+		backupDir: backupDir,
 	}
 }
 
@@ -34,6 +34,9 @@ func (h *BackupHandler) RegisterRoutes(r *gin.RouterGroup) {
 	r.POST("/devices/:mac/backup", h.TriggerBackup)
 	r.GET("/devices/:mac/backups", h.ListBackups)
 	r.GET("/backups/:id", h.GetBackup)
+	r.POST("/backups/run", h.StartRun)
+	r.GET("/backups/runs", h.ListRuns)
+	r.GET("/backups/runs/:id", h.GetRun)
 }
 
 // TriggerBackup initiates a manual backup for a device
@@ -48,14 +51,60 @@ func (h *BackupHandler) TriggerBackup(c *gin.Context) {
 		return
 	}
 
-	if h.backupTrigger == nil {
+	if h.backups == nil {
 		errorResponse(c, 500, "backup service not configured")
 		return
 	}
 
-	go h.backupTrigger(mac)
+	job, err := h.backups.QueueBackup(mac, 0)
+	if err != nil {
+		internalError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"message": "backup queued", "job": job})
+}
+
+// StartRun backs up every device matching a filter. Progress is reported through
+// backup_progress WebSocket events and GET /backups/runs/:id.
+func (h *BackupHandler) StartRun(c *gin.Context) {
+	var filter backup.Filter
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&filter); err != nil {
+			badRequest(c, err)
+			return
+		}
+	}
+	for i, mac := range filter.MACs {
+		filter.MACs[i] = utils.NormalizeMac(mac)
+	}
+
+	run, err := h.backups.StartRun(filter)
+	if errors.Is(err, backup.ErrNoDevices) {
+		errorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err != nil {
+		badRequest(c, err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, run)
+}
+
+// ListRuns returns recent bulk backup runs
+func (h *BackupHandler) ListRuns(c *gin.Context) {
+	okList(c, h.backups.ListRuns())
+}
 
-	accepted(c, "backup initiated")
+// GetRun returns the progress of a bulk backup run
+func (h *BackupHandler) GetRun(c *gin.Context) {
+	run := h.backups.GetRun(c.Param("id"))
+	if run == nil {
+		notFound(c, "backup run")
+		return
+	}
+	ok(c, run)
 }
 
 // ListBackups returns all backups for a device
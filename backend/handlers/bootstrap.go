@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ztp-server/backend/bootstrap"
+	"github.com/ztp-server/backend/db"
+	"github.com/ztp-server/backend/models"
+	"github.com/ztp-server/backend/ws"
+)
+
+// BootstrapHandler serves vendor bootstrap scripts. Scripts report their progress to
+// the device's callback URL, see CallbackHandler.
+type BootstrapHandler struct {
+	store  *db.Store
+	hub    *ws.Hub
+	render func(device *models.Device, templateID string) (string, error)
+}
+
+// NewBootstrapHandler creates a new bootstrap handler. render renders a template for a
+// device.
+func NewBootstrapHandler(store *db.Store, hub *ws.Hub, render func(*models.Device, string) (string, error)) *BootstrapHandler {
+	return &BootstrapHandler{store: store, hub: hub, render: render}
+}
+
+// RegisterRoutes registers the bootstrap script route
+func (h *BootstrapHandler) RegisterRoutes(router *gin.Engine) {
+	router.GET(bootstrap.Path+"/:vendor", h.ServeScript)
+}
+
+// ServeScript renders the vendor's bootstrap script for the device requesting it. The
+// script URL is the same for every device of a vendor, so the device is found by the
+// IP address it was reserved, as seen on the connection.
+func (h *BootstrapHandler) ServeScript(c *gin.Context) {
+	vendor, err := h.store.GetVendor(c.Param("vendor"))
+	if err != nil {
+		c.String(http.StatusInternalServerError, "Internal error")
+		return
+	}
+	if vendor == nil || vendor.BootstrapTemplate == "" {
+		c.String(http.StatusNotFound, "Bootstrap script not found")
+		return
+	}
+	tmpl, err := h.store.GetTemplate(vendor.BootstrapTemplate)
+	if err != nil {
+		c.String(http.StatusInternalServerError, "Internal error")
+		return
+	}
+	if tmpl == nil {
+		log.Printf("Warning: vendor %s has unknown bootstrap template %q", vendor.ID, vendor.BootstrapTemplate)
+		c.String(http.StatusNotFound, "Bootstrap script not found")
+		return
+	}
+
+	clientIP := c.RemoteIP()
+	device, err := h.store.FindDeviceByIP(clientIP)
+	if err != nil {
+		c.String(http.StatusInternalServerError, "Internal error")
+		return
+	}
+	if device == nil {
+		c.String(http.StatusNotFound, "Device not registered")
+		return
+	}
+
+	// Scripts can carry a download token, so they get the same checks as configs
+	settings, err := h.store.GetSettings()
+	if err != nil {
+		c.String(http.StatusInternalServerError, "Internal error")
+		return
+	}
+	if reason := bootstrap.CheckConfigURL(device, settings, clientIP); reason != "" {
+		log.Printf("Refused bootstrap script to %s: %s", clientIP, reason)
+		c.String(http.StatusForbidden, reason)
+		return
+	}
+
+	script, err := h.render(device, tmpl.ID)
+	if err != nil {
+		log.Printf("Failed to render bootstrap script for %s: %v", device.MAC, err)
+		c.String(http.StatusInternalServerError, "Failed to render bootstrap script")
+		return
+	}
+
+	filename := strings.TrimPrefix(bootstrap.Path, "/") + "/" + vendor.ID
+	h.hub.BroadcastConfigPulled(device.MAC, clientIP, device.Hostname, filename, "http")
+	log.Printf("Bootstrap script pulled via HTTP: %s by %s", filename, clientIP)
+
+	c.String(http.StatusOK, script)
+}
@@ -0,0 +1,158 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ztp-server/backend/callback"
+	"github.com/ztp-server/backend/db"
+	"github.com/ztp-server/backend/models"
+	"github.com/ztp-server/backend/utils"
+	"github.com/ztp-server/backend/ws"
+)
+
+// CallbackHandler records the provisioning status devices report to their signed
+// callback URL
+type CallbackHandler struct {
+	store  *db.Store
+	hub    *ws.Hub
+	backup func(mac string) error
+}
+
+// NewCallbackHandler creates a new callback handler. backup queues an immediate backup
+// of a device, run once it reports it has applied its config.
+func NewCallbackHandler(store *db.Store, hub *ws.Hub, backup func(mac string) error) *CallbackHandler {
+	return &CallbackHandler{store: store, hub: hub, backup: backup}
+}
+
+// RegisterRoutes registers the callback routes. GET is accepted as well as POST for
+// devices that can only fetch URLs.
+func (h *CallbackHandler) RegisterRoutes(router *gin.Engine) {
+	router.GET(callback.Path+"/:mac", h.Report)
+	router.POST(callback.Path+"/:mac", h.Report)
+}
+
+// Report records a provisioning status reported by a device. The status, message and
+// an optional JSON payload are POSTed as JSON, or status and message are sent as query
+// parameters. The token comes from the token query parameter or the X-ZTP-Token header.
+func (h *CallbackHandler) Report(c *gin.Context) {
+	mac, err := utils.ParseMac(strings.ReplaceAll(c.Param("mac"), "_", ":"))
+	if err != nil {
+		badRequest(c, err)
+		return
+	}
+
+	secret, err := h.store.Secret(callback.SecretName)
+	if err != nil {
+		internalError(c, err)
+		return
+	}
+	token := c.Query("token")
+	if token == "" {
+		token = c.GetHeader("X-ZTP-Token")
+	}
+	if !callback.Verify(secret, mac, token) {
+		errorResponse(c, http.StatusUnauthorized, "invalid callback token")
+		return
+	}
+
+	var req struct {
+		Status  string          `json:"status"`
+		Message string          `json:"message"`
+		Payload json.RawMessage `json:"payload"`
+	}
+	if c.Request.Method == http.MethodPost && c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			badRequest(c, err)
+			return
+		}
+		if string(req.Payload) == "null" {
+			req.Payload = nil
+		}
+	}
+	if req.Status == "" {
+		req.Status = c.Query("status")
+	}
+	if req.Message == "" {
+		req.Message = c.Query("message")
+	}
+	switch req.Status {
+	case models.ProvisionStarted, models.ProvisionDownloadingImage, models.ProvisionInstallingImage,
+		models.ProvisionApplyingConfig, models.ProvisionCompleted, models.ProvisionFailed:
+	case "":
+		errorResponse(c, http.StatusBadRequest, "status is required")
+		return
+	default:
+		errorResponse(c, http.StatusBadRequest, "unknown status "+req.Status)
+		return
+	}
+
+	device, err := h.store.GetDevice(mac)
+	if err != nil {
+		internalError(c, err)
+		return
+	}
+	if device == nil {
+		notFound(c, "device")
+		return
+	}
+
+	switch req.Status {
+	case models.ProvisionStarted:
+		err = h.store.UpdateDeviceStatus(mac, "provisioning")
+	case models.ProvisionCompleted:
+		// The post-provisioning backup marks the device online and collects its facts
+		if err = h.store.UpdateDeviceStatus(mac, "provisioning"); err == nil {
+			err = h.store.ClearDeviceError(mac)
+		}
+		if err == nil {
+			err = h.store.SetDeviceProvisioned(mac)
+		}
+	case models.ProvisionFailed:
+		msg := "Provisioning failed"
+		if req.Message != "" {
+			msg += ": " + req.Message
+		}
+		err = h.store.UpdateDeviceError(mac, msg)
+	}
+	if err != nil {
+		internalError(c, err)
+		return
+	}
+
+	clientIP := c.RemoteIP()
+	message := "Provisioning " + strings.ReplaceAll(req.Status, "_", " ")
+	if req.Message != "" {
+		message += ": " + req.Message
+	}
+	h.store.CreateDiscoveryLog(&models.DiscoveryLog{
+		EventType: "provision_" + req.Status,
+		MAC:       mac,
+		IP:        clientIP,
+		Hostname:  device.Hostname,
+		Vendor:    device.Vendor,
+		Message:   message,
+		Details:   string(req.Payload),
+	})
+	h.hub.BroadcastProvisionStatus(ws.ProvisionStatusPayload{
+		MAC:      mac,
+		IP:       clientIP,
+		Hostname: device.Hostname,
+		Status:   req.Status,
+		Message:  req.Message,
+		Payload:  req.Payload,
+	})
+	log.Printf("Provisioning status from %s (%s): %s", device.Hostname, clientIP, req.Status)
+
+	// Back the device up now rather than waiting out the backup delay
+	if req.Status == models.ProvisionCompleted && h.backup != nil {
+		if err := h.backup(mac); err != nil {
+			log.Printf("Failed to queue post-provisioning backup for %s: %v", mac, err)
+		}
+	}
+
+	noContent(c)
+}
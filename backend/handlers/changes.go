@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ztp-server/backend/changes"
+	"github.com/ztp-server/backend/db"
+	"github.com/ztp-server/backend/utils"
+)
+
+// ChangeHandler handles config change HTTP requests
+type ChangeHandler struct {
+	store   *db.Store
+	changes *changes.Service
+}
+
+// NewChangeHandler creates a new config change handler
+func NewChangeHandler(store *db.Store, changes *changes.Service) *ChangeHandler {
+	return &ChangeHandler{store: store, changes: changes}
+}
+
+// RegisterRoutes registers the config change routes
+func (h *ChangeHandler) RegisterRoutes(r *gin.RouterGroup) {
+	r.POST("/devices/:mac/apply", h.Apply)
+	r.GET("/devices/:mac/changes", h.List)
+	r.GET("/changes/:id", h.Get)
+}
+
+// Apply queues a config snippet or rendered template to be pushed to a device
+func (h *ChangeHandler) Apply(c *gin.Context) {
+	mac := utils.NormalizeMac(c.Param("mac"))
+	device, err := h.store.GetDevice(mac)
+	if err != nil {
+		internalError(c, err)
+		return
+	}
+	if device == nil {
+		notFound(c, "device")
+		return
+	}
+
+	var req changes.Request
+	if err := c.ShouldBindJSON(&req); err != nil {
+		badRequest(c, err)
+		return
+	}
+
+	change, err := h.changes.Apply(device, req)
+	var reqErr *changes.RequestError
+	if errors.As(err, &reqErr) {
+		badRequest(c, err)
+		return
+	}
+	if err != nil {
+		internalError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"message": "config change queued", "change": change})
+}
+
+// List returns a device's config changes, newest first
+func (h *ChangeHandler) List(c *gin.Context) {
+	mac := utils.NormalizeMac(c.Param("mac"))
+	list, err := h.store.ListConfigChanges(mac)
+	if err != nil {
+		internalError(c, err)
+		return
+	}
+	okList(c, list)
+}
+
+// Get returns a single config change
+func (h *ChangeHandler) Get(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, "invalid change ID")
+		return
+	}
+	change, err := h.store.GetConfigChange(id)
+	if err != nil {
+		internalError(c, err)
+		return
+	}
+	if change == nil {
+		notFound(c, "config change")
+		return
+	}
+	ok(c, change)
+}
@@ -8,6 +8,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/ztp-server/backend/db"
+	"github.com/ztp-server/backend/models"
 	"github.com/ztp-server/backend/utils"
 	"github.com/ztp-server/backend/ws"
 )
@@ -17,14 +18,17 @@ type ConfigServerHandler struct {
 	store   *db.Store
 	hub     *ws.Hub
 	tftpDir string
+	render  func(device *models.Device) ([]byte, error)
 }
 
-// NewConfigServerHandler creates a new config server handler
-func NewConfigServerHandler(store *db.Store, hub *ws.Hub, tftpDir string) *ConfigServerHandler {
+// NewConfigServerHandler creates a new config server handler. Registered devices'
+// configs are rendered by render when requested; other files are served from tftpDir.
+func NewConfigServerHandler(store *db.Store, hub *ws.Hub, tftpDir string, render func(device *models.Device) ([]byte, error)) *ConfigServerHandler {
 	return &ConfigServerHandler{
 		store:   store,
 		hub:     hub,
 		tftpDir: tftpDir,
+		render:  render,
 	}
 }
 
@@ -46,35 +50,98 @@ func (h *ConfigServerHandler) ServeConfig(c *gin.Context) {
 
 	configPath := filepath.Join(h.tftpDir, filename)
 
-	// Check if file exists
-	if _, err := os.Stat(configPath); os.IsNotExist(err) {
-		c.String(404, "Config not found")
-		return
-	}
-
 	// Extract MAC from filename (format: aa_bb_cc_dd_ee_ff.cfg)
 	mac := ""
 	hostname := ""
-	clientIP := c.ClientIP()
+	// The connection's address, not one a client can claim in X-Forwarded-For
+	clientIP := c.RemoteIP()
+	var device *models.Device
 
 	if strings.HasSuffix(filename, ".cfg") {
 		macPart := strings.TrimSuffix(filename, ".cfg")
-		mac = strings.ReplaceAll(macPart, "_", ":")
-		mac = utils.NormalizeMac(mac)
+		if parsed, err := utils.ParseMac(strings.ReplaceAll(macPart, "_", ":")); err == nil {
+			mac = parsed
 
-		// Look up device info
-		device, err := h.store.GetDevice(mac)
-		if err == nil && device != nil {
-			hostname = device.Hostname
+			// Look up device info
+			device, err = h.store.GetDevice(mac)
+			if err == nil && device != nil {
+				hostname = device.Hostname
+			}
 		}
 	}
 
-	// Broadcast config pulled event
-	if mac != "" {
-		h.hub.BroadcastConfigPulled(mac, clientIP, hostname, filename, "http")
-		log.Printf("Config pulled via HTTP: %s by %s", filename, clientIP)
+	// Other files must exist in the TFTP root; device configs are rendered below
+	if device == nil {
+		if _, err := os.Stat(configPath); os.IsNotExist(err) {
+			c.String(404, "Config not found")
+			return
+		}
+	}
+
+	settings, err := h.store.GetSettings()
+	if err != nil {
+		c.String(500, "Internal error")
+		return
+	}
+	if status, reason := h.checkAccess(c, device, settings); status != 0 {
+		log.Printf("Refused config %s to %s: %s", filename, clientIP, reason)
+		c.String(status, reason)
+		return
+	}
+
+	if device == nil {
+		c.File(configPath)
+		return
+	}
+
+	// Render the config for this request so it reflects the current template, device and
+	// settings, falling back to the last written file if rendering fails
+	config, err := h.render(device)
+	if err != nil {
+		log.Printf("Failed to render config for %s: %v", device.MAC, err)
+		if config, err = os.ReadFile(configPath); err != nil {
+			c.String(500, "Failed to render config")
+			return
+		}
 	}
 
-	// Serve the file
-	c.File(configPath)
+	h.hub.BroadcastConfigPulled(mac, clientIP, hostname, filename, "http")
+	log.Printf("Config pulled via HTTP: %s by %s", filename, clientIP)
+	c.Data(200, "text/plain; charset=utf-8", config)
+}
+
+// checkAccess applies the config download protections enabled in settings. It returns
+// the status to refuse the download with and why, or 0 if the download is allowed.
+// Files that aren't a registered device's config are refused once any protection is on.
+func (h *ConfigServerHandler) checkAccess(c *gin.Context, device *models.Device, settings *models.Settings) (int, string) {
+	if settings.ConfigRequireTLS && c.Request.TLS == nil {
+		return 403, "Configs are only served over HTTPS"
+	}
+	if !settings.ConfigTokenRequired && !settings.ConfigCheckSourceIP && !settings.ConfigLockout {
+		return 0, ""
+	}
+	if device == nil {
+		return 403, "Forbidden"
+	}
+	if settings.ConfigLockout && device.ProvisionedAt != nil {
+		return 403, "Device already provisioned"
+	}
+	if settings.ConfigCheckSourceIP && c.RemoteIP() != device.IP {
+		return 403, "Source address does not match the device's reservation"
+	}
+	if settings.ConfigTokenRequired {
+		token := c.Query("token")
+		if token == "" {
+			return 401, "Download token required"
+		}
+		valid, err := h.store.UseDownloadToken(token, device.MAC)
+		if err != nil {
+			log.Printf("Failed to check download token for %s: %v", device.MAC, err)
+			return 500, "Internal error"
+		}
+		if !valid {
+			return 401, "Invalid or expired download token"
+		}
+	}
+	return 0, ""
 }
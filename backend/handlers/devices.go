@@ -1,35 +1,36 @@
 package handlers
 
 import (
-	"bytes"
+	"errors"
 	"fmt"
-	"net"
-	"os"
+	"net/http"
 	"os/exec"
-	"path/filepath"
 	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/ztp-server/backend/db"
+	"github.com/ztp-server/backend/drivers"
 	"github.com/ztp-server/backend/models"
+	"github.com/ztp-server/backend/netbox"
 	"github.com/ztp-server/backend/utils"
-	"golang.org/x/crypto/ssh"
+	"github.com/ztp-server/backend/validate"
 )
 
 // DeviceHandler handles device-related HTTP requests
 type DeviceHandler struct {
 	store        *db.Store
 	configReload func() error
-	tftpDir      string
+	render       func(device *models.Device) ([]byte, error)
 }
 
-// NewDeviceHandler creates a new device handler
-func NewDeviceHandler(store *db.Store, configReload func() error, tftpDir string) *DeviceHandler {
+// NewDeviceHandler creates a new device handler. render returns a device's config, the
+// same one /configs serves it.
+func NewDeviceHandler(store *db.Store, configReload func() error, render func(device *models.Device) ([]byte, error)) *DeviceHandler {
 	return &DeviceHandler{
 		store:        store,
 		configReload: configReload,
-		tftpDir:      tftpDir,
+		render:       render,
 	}
 }
 
@@ -41,7 +42,10 @@ func (h *DeviceHandler) RegisterRoutes(r *gin.RouterGroup) {
 	r.PUT("/devices/:mac", h.Update)
 	r.DELETE("/devices/:mac", h.Delete)
 	r.POST("/devices/:mac/connect", h.Connect)
+	r.POST("/devices/:mac/reboot", h.Reboot)
+	r.POST("/devices/:mac/reprovision", h.Reprovision)
 	r.GET("/devices/:mac/config", h.GetConfig)
+	r.GET("/drivers", h.ListDrivers)
 }
 
 // ConnectResult represents the result of a device connectivity check
@@ -61,6 +65,7 @@ type PingResult struct {
 // SSHResult represents the SSH connection result
 type SSHResult struct {
 	Connected bool   `json:"connected"`
+	Driver    string `json:"driver,omitempty"`
 	Uptime    string `json:"uptime,omitempty"`
 	Error     string `json:"error,omitempty"`
 }
@@ -80,19 +85,10 @@ func (h *DeviceHandler) Connect(c *gin.Context) {
 		return
 	}
 
-	// Get default SSH credentials if device doesn't have them
-	sshUser := device.SSHUser
-	sshPass := device.SSHPass
-	if sshUser == "" || sshPass == "" {
-		settings, err := h.store.GetSettings()
-		if err == nil && settings != nil {
-			if sshUser == "" {
-				sshUser = settings.DefaultSSHUser
-			}
-			if sshPass == "" {
-				sshPass = settings.DefaultSSHPass
-			}
-		}
+	target, err := h.target(device)
+	if err != nil {
+		internalError(c, err)
+		return
 	}
 
 	result := ConnectResult{}
@@ -101,8 +97,8 @@ func (h *DeviceHandler) Connect(c *gin.Context) {
 	result.Ping = h.pingDevice(device.IP)
 
 	// SSH check (only if ping succeeded or we want to try anyway)
-	if sshUser != "" && sshPass != "" {
-		result.SSH = h.sshConnect(device.IP, sshUser, sshPass)
+	if target.User != "" && target.Pass != "" {
+		result.SSH = h.sshConnect(target)
 	} else {
 		result.SSH = SSHResult{
 			Connected: false,
@@ -120,7 +116,7 @@ func (h *DeviceHandler) Connect(c *gin.Context) {
 	ok(c, result)
 }
 
-// GetConfig returns the generated configuration for a device
+// GetConfig returns the configuration of a device, as /configs serves it
 func (h *DeviceHandler) GetConfig(c *gin.Context) {
 	mac := utils.NormalizeMac(c.Param("mac"))
 
@@ -135,31 +131,16 @@ func (h *DeviceHandler) GetConfig(c *gin.Context) {
 		return
 	}
 
-	// Build config file path
-	filename := strings.ReplaceAll(mac, ":", "_") + ".cfg"
-	configPath := filepath.Join(h.tftpDir, filename)
-
-	// Read config file
-	content, err := os.ReadFile(configPath)
+	content, err := h.render(device)
 	if err != nil {
-		if os.IsNotExist(err) {
-			ok(c, gin.H{
-				"mac":      mac,
-				"hostname": device.Hostname,
-				"filename": filename,
-				"content":  "",
-				"exists":   false,
-			})
-			return
-		}
-		internalError(c, err)
+		internalError(c, fmt.Errorf("failed to render config: %w", err))
 		return
 	}
 
 	ok(c, gin.H{
 		"mac":      mac,
 		"hostname": device.Hostname,
-		"filename": filename,
+		"filename": utils.MacToFilename(mac) + ".cfg",
 		"content":  string(content),
 		"exists":   true,
 	})
@@ -200,66 +181,121 @@ func (h *DeviceHandler) pingDevice(ip string) PingResult {
 	}
 }
 
-func (h *DeviceHandler) sshConnect(ip, user, pass string) SSHResult {
-	config := &ssh.ClientConfig{
-		User: user,
-		Auth: []ssh.AuthMethod{
-			ssh.Password(pass),
-		},
-		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
-		Timeout:         10 * time.Second,
-	}
+func (h *DeviceHandler) sshConnect(target drivers.Target) SSHResult {
+	driver := drivers.For(target.VendorID())
 
-	// Try to connect
-	addr := net.JoinHostPort(ip, "22")
-	client, err := ssh.Dial("tcp", addr, config)
+	conn, err := drivers.Dial(target, 10*time.Second)
 	if err != nil {
 		return SSHResult{
 			Connected: false,
+			Driver:    driver.Name(),
 			Error:     fmt.Sprintf("SSH connection failed: %v", err),
 		}
 	}
-	defer client.Close()
+	defer conn.Close()
 
-	// Create session to run uptime
-	session, err := client.NewSession()
+	uptime, err := driver.Uptime(conn)
+	if err != nil || uptime == "" {
+		uptime = "Connected (uptime command not available)"
+	}
+	return SSHResult{
+		Connected: true,
+		Driver:    driver.Name(),
+		Uptime:    uptime,
+	}
+}
+
+// Reboot restarts a device through its vendor driver
+func (h *DeviceHandler) Reboot(c *gin.Context) {
+	mac := utils.NormalizeMac(c.Param("mac"))
+
+	device, err := h.store.GetDevice(mac)
 	if err != nil {
-		return SSHResult{
-			Connected: true,
-			Error:     fmt.Sprintf("Failed to create session: %v", err),
-		}
+		internalError(c, err)
+		return
+	}
+	if device == nil {
+		notFound(c, "device")
+		return
 	}
-	defer session.Close()
 
-	// Run uptime command
-	var stdout bytes.Buffer
-	session.Stdout = &stdout
-	err = session.Run("uptime")
+	target, err := h.target(device)
 	if err != nil {
-		// Try alternative uptime commands for network devices
-		session2, _ := client.NewSession()
-		if session2 != nil {
-			defer session2.Close()
-			var stdout2 bytes.Buffer
-			session2.Stdout = &stdout2
-			// Try "show version" for Cisco-like devices
-			if session2.Run("show version | include uptime") == nil {
-				return SSHResult{
-					Connected: true,
-					Uptime:    strings.TrimSpace(stdout2.String()),
-				}
-			}
-		}
-		return SSHResult{
-			Connected: true,
-			Uptime:    "Connected (uptime command not available)",
+		internalError(c, err)
+		return
+	}
+	driver := drivers.For(target.VendorID())
+
+	conn, err := drivers.Dial(target, 10*time.Second)
+	if err != nil {
+		errorResponse(c, http.StatusBadGateway, fmt.Sprintf("SSH connection failed: %v", err))
+		return
+	}
+	defer conn.Close()
+
+	if err := driver.Reboot(conn); errors.Is(err, drivers.ErrNotSupported) {
+		errorResponse(c, http.StatusBadRequest, fmt.Sprintf("the %s driver can't reboot devices", driver.Name()))
+		return
+	} else if err != nil {
+		errorResponse(c, http.StatusBadGateway, err.Error())
+		return
+	}
+
+	h.store.UpdateDeviceStatus(mac, "offline")
+	accepted(c, "reboot initiated")
+}
+
+// Reprovision lifts the config download lockout of a provisioned device so it can
+// fetch its config again
+func (h *DeviceHandler) Reprovision(c *gin.Context) {
+	mac := utils.NormalizeMac(c.Param("mac"))
+	if err := h.store.ClearDeviceProvisioned(mac); handleError(c, err, true) {
+		return
+	}
+	message(c, "device can be provisioned again")
+}
+
+// DriverInfo describes the driver a vendor's devices use
+type DriverInfo struct {
+	Vendor string `json:"vendor"`
+	Driver string `json:"driver"`
+}
+
+// ListDrivers returns the driver each vendor uses, followed by the drivers registered
+// for vendor IDs that don't exist yet
+func (h *DeviceHandler) ListDrivers(c *gin.Context) {
+	vendors, err := h.store.ListVendors()
+	if err != nil {
+		internalError(c, err)
+		return
+	}
+
+	infos := []DriverInfo{}
+	seen := make(map[string]bool)
+	for _, v := range vendors {
+		infos = append(infos, DriverInfo{Vendor: v.ID, Driver: drivers.For(v.ID).Name()})
+		seen[v.ID] = true
+	}
+	for _, id := range drivers.Registered() {
+		if !seen[id] {
+			infos = append(infos, DriverInfo{Vendor: id, Driver: drivers.For(id).Name()})
 		}
 	}
+	okList(c, infos)
+}
 
-	return SSHResult{
-		Connected: true,
-		Uptime:    strings.TrimSpace(stdout.String()),
+// target resolves a device's SSH connection details from the device, its vendor and
+// the default credentials
+func (h *DeviceHandler) target(device *models.Device) (drivers.Target, error) {
+	settings, err := h.store.GetSettings()
+	if err != nil {
+		return drivers.Target{}, err
+	}
+	var vendor *models.Vendor
+	if device.Vendor != "" {
+		vendor, _ = h.store.GetVendor(device.Vendor)
 	}
+	return drivers.NewTarget(device, vendor, settings), nil
 }
 
 // List returns all devices
@@ -303,12 +339,17 @@ func (h *DeviceHandler) Create(c *gin.Context) {
 		return
 	}
 
-	device.MAC = utils.NormalizeMac(device.MAC)
+	if device.MAC == "" || device.Hostname == "" {
+		errorResponse(c, 400, "mac and hostname are required")
+		return
+	}
 
-	if device.MAC == "" || device.IP == "" || device.Hostname == "" {
-		errorResponse(c, 400, "mac, ip, and hostname are required")
+	mac, err := utils.ParseMac(device.MAC)
+	if err != nil {
+		validationFailed(c, validate.Errors{{Field: "mac", Message: err.Error()}})
 		return
 	}
+	device.MAC = mac
 
 	// Check for duplicate
 	existing, _ := h.store.GetDevice(device.MAC)
@@ -317,7 +358,28 @@ func (h *DeviceHandler) Create(c *gin.Context) {
 		return
 	}
 
+	// Without an IP, draw one from the NetBox prefix
+	release := func() {}
+	if device.IP == "" {
+		release, err = allocateDeviceIP(h.store, &device)
+		if errors.Is(err, netbox.ErrNoPrefix) {
+			errorResponse(c, 400, "ip is required unless a NetBox prefix is configured")
+			return
+		}
+		if err != nil {
+			errorResponse(c, http.StatusBadGateway, err.Error())
+			return
+		}
+	}
+
+	if errs := h.validateDevice(&device); len(errs) > 0 {
+		release()
+		validationFailed(c, errs)
+		return
+	}
+
 	if err := h.store.CreateDevice(&device); err != nil {
+		release()
 		internalError(c, err)
 		return
 	}
@@ -338,6 +400,11 @@ func (h *DeviceHandler) Update(c *gin.Context) {
 
 	device.MAC = mac
 
+	if errs := h.validateDevice(&device); len(errs) > 0 {
+		validationFailed(c, errs)
+		return
+	}
+
 	if err := h.store.UpdateDevice(&device); handleError(c, err, true) {
 		return
 	}
@@ -358,8 +425,15 @@ func (h *DeviceHandler) Delete(c *gin.Context) {
 	noContent(c)
 }
 
+// validateDevice checks the device against the current DHCP settings
+func (h *DeviceHandler) validateDevice(device *models.Device) validate.Errors {
+	// Without settings the subnet check is skipped rather than failing the request
+	settings, _ := h.store.GetSettings()
+	return validate.Device(device, settings)
+}
+
 func (h *DeviceHandler) triggerReload() {
 	if h.configReload != nil {
-		go h.configReload()
+		h.configReload()
 	}
 }
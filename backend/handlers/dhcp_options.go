@@ -3,7 +3,9 @@ package handlers
 import (
 	"github.com/gin-gonic/gin"
 	"github.com/ztp-server/backend/db"
+	"github.com/ztp-server/backend/dhcp"
 	"github.com/ztp-server/backend/models"
+	"github.com/ztp-server/backend/validate"
 )
 
 // DhcpOptionHandler handles DHCP option-related HTTP requests
@@ -24,7 +26,10 @@ func NewDhcpOptionHandler(store *db.Store, configReload func() error) *DhcpOptio
 func (h *DhcpOptionHandler) RegisterRoutes(r *gin.RouterGroup) {
 	r.GET("/dhcp-options", h.List)
 	r.GET("/dhcp-options/defaults", h.ListDefaults)
+	r.GET("/dhcp-options/schemas", h.ListSchemas)
 	r.GET("/dhcp-options/:id", h.Get)
+	r.GET("/dhcp-options/:id/preview", h.Preview)
+	r.POST("/dhcp-options/preview", h.PreviewUnsaved)
 	r.POST("/dhcp-options", h.Create)
 	r.PUT("/dhcp-options/:id", h.Update)
 	r.DELETE("/dhcp-options/:id", h.Delete)
@@ -46,6 +51,11 @@ func (h *DhcpOptionHandler) ListDefaults(c *gin.Context) {
 	okList(c, defaults)
 }
 
+// ListSchemas returns the known vendor sub-option layouts for options 43 and 125
+func (h *DhcpOptionHandler) ListSchemas(c *gin.Context) {
+	okList(c, dhcp.VendorOptionSchemas())
+}
+
 // Get returns a single DHCP option by ID
 func (h *DhcpOptionHandler) Get(c *gin.Context) {
 	id := c.Param("id")
@@ -79,7 +89,12 @@ func (h *DhcpOptionHandler) Create(c *gin.Context) {
 
 	// Set defaults
 	if option.Type == "" {
-		option.Type = "string"
+		option.Type = validate.OptionTypeString
+	}
+
+	if errs := dhcp.ValidateOption(&option, h.settings()); len(errs) > 0 {
+		validationFailed(c, errs)
+		return
 	}
 
 	// Check for duplicate
@@ -109,6 +124,14 @@ func (h *DhcpOptionHandler) Update(c *gin.Context) {
 	}
 
 	option.ID = id
+	if option.Type == "" {
+		option.Type = validate.OptionTypeString
+	}
+
+	if errs := dhcp.ValidateOption(&option, h.settings()); len(errs) > 0 {
+		validationFailed(c, errs)
+		return
+	}
 
 	if err := h.store.UpdateDhcpOption(&option); handleError(c, err, true) {
 		return
@@ -130,8 +153,63 @@ func (h *DhcpOptionHandler) Delete(c *gin.Context) {
 	noContent(c)
 }
 
+// Preview shows the wire encoding of a stored DHCP option
+func (h *DhcpOptionHandler) Preview(c *gin.Context) {
+	id := c.Param("id")
+
+	option, err := h.store.GetDhcpOption(id)
+	if err != nil {
+		internalError(c, err)
+		return
+	}
+
+	if option == nil {
+		notFound(c, "dhcp option")
+		return
+	}
+
+	h.preview(c, option)
+}
+
+// PreviewUnsaved shows the wire encoding of an option before it is saved
+func (h *DhcpOptionHandler) PreviewUnsaved(c *gin.Context) {
+	var option models.DhcpOption
+	if err := c.ShouldBindJSON(&option); err != nil {
+		badRequest(c, err)
+		return
+	}
+
+	if option.Type == "" {
+		option.Type = validate.OptionTypeString
+	}
+
+	h.preview(c, &option)
+}
+
+func (h *DhcpOptionHandler) preview(c *gin.Context, option *models.DhcpOption) {
+	settings := h.settings()
+	if errs := dhcp.ValidateOption(option, settings); len(errs) > 0 {
+		validationFailed(c, errs)
+		return
+	}
+
+	preview, err := dhcp.PreviewOption(option, settings)
+	if err != nil {
+		badRequest(c, err)
+		return
+	}
+
+	ok(c, preview)
+}
+
+// settings returns the current settings for variable substitution, or nil if unavailable
+func (h *DhcpOptionHandler) settings() *models.Settings {
+	settings, _ := h.store.GetSettings()
+	return settings
+}
+
 func (h *DhcpOptionHandler) triggerReload() {
 	if h.configReload != nil {
-		go h.configReload()
+		h.configReload()
 	}
 }
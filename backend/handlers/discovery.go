@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"bufio"
+	"errors"
 	"os"
 	"strconv"
 	"strings"
@@ -10,6 +11,8 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/ztp-server/backend/db"
 	"github.com/ztp-server/backend/models"
+	"github.com/ztp-server/backend/utils"
+	"github.com/ztp-server/backend/validate"
 )
 
 // DiscoveredDevice represents a device found in DHCP leases but not yet configured
@@ -24,17 +27,19 @@ type DiscoveredDevice struct {
 
 // DiscoveryHandler handles device discovery from DHCP leases
 type DiscoveryHandler struct {
-	store         *db.Store
-	leasePath     string
-	clearKnownFn  func()
+	store        *db.Store
+	leasePath    string
+	clearKnownFn func()
+	configReload func() error
 }
 
 // NewDiscoveryHandler creates a new discovery handler
-func NewDiscoveryHandler(store *db.Store, leasePath string, clearKnownFn func()) *DiscoveryHandler {
+func NewDiscoveryHandler(store *db.Store, leasePath string, clearKnownFn func(), configReload func() error) *DiscoveryHandler {
 	return &DiscoveryHandler{
 		store:        store,
 		leasePath:    leasePath,
 		clearKnownFn: clearKnownFn,
+		configReload: configReload,
 	}
 }
 
@@ -45,6 +50,11 @@ func (h *DiscoveryHandler) RegisterRoutes(r *gin.RouterGroup) {
 	r.GET("/discovery/logs", h.ListLogs)
 	r.POST("/discovery/clear", h.ClearKnown)
 	r.DELETE("/discovery/logs", h.ClearLogs)
+	r.GET("/discovery/pending", h.ListPending)
+	r.POST("/discovery/pending/approve", h.BulkApprove)
+	r.POST("/discovery/pending/:mac/approve", h.Approve)
+	r.POST("/discovery/pending/:mac/reject", h.Reject)
+	r.DELETE("/discovery/pending/:mac", h.DeletePending)
 }
 
 // ClearKnown clears the known MACs so all current leases will trigger notifications
@@ -74,13 +84,13 @@ func (h *DiscoveryHandler) List(c *gin.Context) {
 	// Build a set of known MACs
 	knownMACs := make(map[string]bool)
 	for _, d := range devices {
-		knownMACs[strings.ToLower(d.MAC)] = true
+		knownMACs[utils.NormalizeMac(d.MAC)] = true
 	}
 
 	// Filter leases to only include unknown devices
 	var discovered []DiscoveredDevice
 	for _, lease := range leases {
-		mac := strings.ToLower(lease.MAC)
+		mac := utils.NormalizeMac(lease.MAC)
 		if !knownMACs[mac] {
 			discovered = append(discovered, leaseToDiscovered(lease, true))
 		}
@@ -172,7 +182,7 @@ func (h *DiscoveryHandler) parseLeaseLine(line string) (*Lease, error) {
 
 	lease := &Lease{
 		ExpiryTime: expiry,
-		MAC:        strings.ToLower(fields[1]),
+		MAC:        utils.NormalizeMac(fields[1]),
 		IP:         fields[2],
 		Hostname:   fields[3],
 	}
@@ -218,3 +228,276 @@ func (h *DiscoveryHandler) LogDiscoveryEvent(eventType, mac, ip, hostname, vendo
 	}
 	h.store.CreateDiscoveryLog(log)
 }
+
+// ApproveRequest holds the operator-assigned details for adopting a pending device.
+// Empty fields fall back to what was observed in the DHCP lease and the vendor defaults.
+type ApproveRequest struct {
+	MAC            string `json:"mac"`
+	IP             string `json:"ip"`
+	Hostname       string `json:"hostname"`
+	Vendor         string `json:"vendor"`
+	Model          string `json:"model"`
+	SerialNumber   string `json:"serial_number"`
+	ConfigTemplate string `json:"config_template"`
+	SSHUser        string `json:"ssh_user"`
+	SSHPass        string `json:"ssh_pass"`
+	AllocateIP     bool   `json:"allocate_ip"` // Draw the IP from the NetBox prefix instead of the lease
+}
+
+// ApproveResult reports the outcome of approving a single device in a bulk request
+type ApproveResult struct {
+	MAC     string         `json:"mac"`
+	Success bool           `json:"success"`
+	Device  *models.Device `json:"device,omitempty"`
+	Error   string         `json:"error,omitempty"`
+}
+
+var errDeviceExists = errors.New("device with this MAC already exists")
+
+// ListPending returns devices in the approval queue (status=pending by default, status=all for every entry)
+func (h *DiscoveryHandler) ListPending(c *gin.Context) {
+	status := c.DefaultQuery("status", models.PendingStatusPending)
+	if status == "all" {
+		status = ""
+	}
+
+	pending, err := h.store.ListPendingDevices(status)
+	if err != nil {
+		internalError(c, err)
+		return
+	}
+	okList(c, pending)
+}
+
+// Approve adopts a single pending device, creating it with the assigned hostname/template/IP
+func (h *DiscoveryHandler) Approve(c *gin.Context) {
+	mac, err := utils.ParseMac(c.Param("mac"))
+	if err != nil {
+		badRequest(c, err)
+		return
+	}
+
+	var req ApproveRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			badRequest(c, err)
+			return
+		}
+	}
+
+	pending, err := h.store.GetPendingDevice(mac)
+	if err != nil {
+		internalError(c, err)
+		return
+	}
+	if pending == nil {
+		notFound(c, "pending device")
+		return
+	}
+
+	device, err := h.approvePending(pending, req)
+	if errors.Is(err, errDeviceExists) {
+		conflict(c, err.Error())
+		return
+	}
+	var errs validate.Errors
+	if errors.As(err, &errs) {
+		validationFailed(c, errs)
+		return
+	}
+	if err != nil {
+		badRequest(c, err)
+		return
+	}
+
+	h.triggerReload()
+	created(c, device)
+}
+
+// BulkApprove adopts several pending devices and regenerates the config once at the end
+func (h *DiscoveryHandler) BulkApprove(c *gin.Context) {
+	var req struct {
+		Devices []ApproveRequest `json:"devices"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		badRequest(c, err)
+		return
+	}
+	if len(req.Devices) == 0 {
+		errorResponse(c, 400, "devices is required")
+		return
+	}
+
+	results := make([]ApproveResult, 0, len(req.Devices))
+	approved := 0
+	for _, item := range req.Devices {
+		mac, err := utils.ParseMac(item.MAC)
+		if err != nil {
+			results = append(results, ApproveResult{MAC: item.MAC, Error: err.Error()})
+			continue
+		}
+		result := ApproveResult{MAC: mac}
+
+		pending, err := h.store.GetPendingDevice(mac)
+		switch {
+		case err != nil:
+			result.Error = err.Error()
+		case pending == nil:
+			result.Error = "pending device not found"
+		default:
+			device, err := h.approvePending(pending, item)
+			if err != nil {
+				result.Error = err.Error()
+			} else {
+				result.Success = true
+				result.Device = device
+				approved++
+			}
+		}
+		results = append(results, result)
+	}
+
+	if approved > 0 {
+		h.triggerReload()
+	}
+
+	ok(c, gin.H{
+		"approved": approved,
+		"failed":   len(results) - approved,
+		"results":  results,
+	})
+}
+
+// Reject blacklists a pending device so it no longer receives ZTP options
+func (h *DiscoveryHandler) Reject(c *gin.Context) {
+	mac, err := utils.ParseMac(c.Param("mac"))
+	if err != nil {
+		badRequest(c, err)
+		return
+	}
+
+	var req struct {
+		Note string `json:"note"`
+	}
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			badRequest(c, err)
+			return
+		}
+	}
+
+	pending, err := h.store.GetPendingDevice(mac)
+	if err != nil {
+		internalError(c, err)
+		return
+	}
+	if pending == nil {
+		notFound(c, "pending device")
+		return
+	}
+
+	if err := h.store.SetPendingDeviceStatus(mac, models.PendingStatusRejected, req.Note); err != nil {
+		internalError(c, err)
+		return
+	}
+
+	h.LogDiscoveryEvent("rejected", mac, pending.IP, pending.Hostname, pending.Vendor, "Device rejected from ZTP")
+	h.triggerReload()
+	message(c, "device rejected")
+}
+
+// DeletePending removes a device from the queue, lifting any rejection.
+// The device re-enters the queue as pending the next time it requests a lease.
+func (h *DiscoveryHandler) DeletePending(c *gin.Context) {
+	mac, err := utils.ParseMac(c.Param("mac"))
+	if err != nil {
+		badRequest(c, err)
+		return
+	}
+
+	pending, err := h.store.GetPendingDevice(mac)
+	if err != nil {
+		internalError(c, err)
+		return
+	}
+	if pending == nil {
+		notFound(c, "pending device")
+		return
+	}
+
+	if err := h.store.DeletePendingDevice(mac); handleError(c, err, true) {
+		return
+	}
+
+	if pending.Status == models.PendingStatusRejected {
+		h.triggerReload()
+	}
+	noContent(c)
+}
+
+// approvePending creates a device from a pending entry and marks the entry approved
+func (h *DiscoveryHandler) approvePending(pending *models.PendingDevice, req ApproveRequest) (*models.Device, error) {
+	existing, err := h.store.GetDevice(pending.MAC)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return nil, errDeviceExists
+	}
+
+	device := &models.Device{
+		MAC:            pending.MAC,
+		IP:             req.IP,
+		Hostname:       req.Hostname,
+		Vendor:         req.Vendor,
+		Model:          req.Model,
+		SerialNumber:   req.SerialNumber,
+		ConfigTemplate: req.ConfigTemplate,
+		SSHUser:        req.SSHUser,
+		SSHPass:        req.SSHPass,
+	}
+	if device.IP == "" {
+		device.IP = pending.IP
+	}
+	if device.Hostname == "" {
+		device.Hostname = pending.Hostname
+	}
+	if device.Vendor == "" {
+		device.Vendor = pending.Vendor
+	}
+	if device.ConfigTemplate == "" && device.Vendor != "" {
+		if vendor, err := h.store.GetVendor(device.Vendor); err == nil && vendor != nil {
+			device.ConfigTemplate = vendor.DefaultTemplate
+		}
+	}
+
+	release := func() {}
+	if req.AllocateIP && req.IP == "" {
+		if release, err = allocateDeviceIP(h.store, device); err != nil {
+			return nil, err
+		}
+	}
+
+	settings, _ := h.store.GetSettings()
+	if errs := validate.Device(device, settings); len(errs) > 0 {
+		release()
+		return nil, errs
+	}
+
+	if err := h.store.CreateDevice(device); err != nil {
+		release()
+		return nil, err
+	}
+	if err := h.store.SetPendingDeviceStatus(pending.MAC, models.PendingStatusApproved, ""); err != nil {
+		return nil, err
+	}
+
+	h.LogDiscoveryEvent("added", device.MAC, device.IP, device.Hostname, device.Vendor, "Device approved from pending queue")
+	return device, nil
+}
+
+func (h *DiscoveryHandler) triggerReload() {
+	if h.configReload != nil {
+		h.configReload()
+	}
+}
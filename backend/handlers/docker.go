@@ -15,6 +15,7 @@ import (
 	"github.com/docker/docker/api/types/network"
 	"github.com/docker/docker/client"
 	"github.com/gin-gonic/gin"
+	"github.com/ztp-server/backend/utils"
 )
 
 type DockerHandler struct {
@@ -146,6 +147,13 @@ func (h *DockerHandler) Spawn(c *gin.Context) {
 	mac := req.MAC
 	if mac == "" {
 		mac = generateMAC()
+	} else {
+		parsed, err := utils.ParseMac(mac)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		mac = parsed
 	}
 	timestamp := time.Now().Unix()
 	hostname := req.Hostname
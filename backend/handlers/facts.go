@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ztp-server/backend/db"
+	"github.com/ztp-server/backend/facts"
+	"github.com/ztp-server/backend/utils"
+)
+
+// FactsHandler handles device facts HTTP requests
+type FactsHandler struct {
+	store *db.Store
+	facts *facts.Service
+}
+
+// NewFactsHandler creates a new facts handler
+func NewFactsHandler(store *db.Store, facts *facts.Service) *FactsHandler {
+	return &FactsHandler{store: store, facts: facts}
+}
+
+// RegisterRoutes registers the facts routes
+func (h *FactsHandler) RegisterRoutes(r *gin.RouterGroup) {
+	r.GET("/devices/:mac/facts", h.Get)
+	r.GET("/devices/:mac/facts/history", h.History)
+	r.POST("/devices/:mac/facts", h.Collect)
+}
+
+// Get returns the most recently collected facts for a device
+func (h *FactsHandler) Get(c *gin.Context) {
+	mac := utils.NormalizeMac(c.Param("mac"))
+	if !h.requireDevice(c, mac) {
+		return
+	}
+
+	f, err := h.store.GetLatestDeviceFacts(mac)
+	if err != nil {
+		internalError(c, err)
+		return
+	}
+	if f == nil {
+		notFound(c, "facts")
+		return
+	}
+	ok(c, f)
+}
+
+// History returns a device's facts snapshots, newest first
+func (h *FactsHandler) History(c *gin.Context) {
+	mac := utils.NormalizeMac(c.Param("mac"))
+	if !h.requireDevice(c, mac) {
+		return
+	}
+
+	limit := 0
+	if l := c.Query("limit"); l != "" {
+		n, err := strconv.Atoi(l)
+		if err != nil || n < 1 {
+			errorResponse(c, http.StatusBadRequest, "limit must be a positive number")
+			return
+		}
+		limit = n
+	}
+
+	list, err := h.store.ListDeviceFacts(mac, limit)
+	if err != nil {
+		internalError(c, err)
+		return
+	}
+	okList(c, list)
+}
+
+// Collect queues a facts collection for a device
+func (h *FactsHandler) Collect(c *gin.Context) {
+	mac := utils.NormalizeMac(c.Param("mac"))
+	if !h.requireDevice(c, mac) {
+		return
+	}
+
+	job, err := h.facts.Queue(mac)
+	if err != nil {
+		internalError(c, err)
+		return
+	}
+	c.JSON(http.StatusAccepted, gin.H{"message": "facts collection queued", "job": job})
+}
+
+// requireDevice sends a 404 and returns false if the device doesn't exist
+func (h *FactsHandler) requireDevice(c *gin.Context, mac string) bool {
+	device, err := h.store.GetDevice(mac)
+	if err != nil {
+		internalError(c, err)
+		return false
+	}
+	if device == nil {
+		notFound(c, "device")
+		return false
+	}
+	return true
+}
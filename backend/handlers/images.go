@@ -0,0 +1,214 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ztp-server/backend/db"
+	"github.com/ztp-server/backend/images"
+	"github.com/ztp-server/backend/models"
+	"github.com/ztp-server/backend/validate"
+)
+
+// ImageHandler handles image repository HTTP requests
+type ImageHandler struct {
+	store        *db.Store
+	images       *images.Service
+	configReload func() error
+}
+
+// NewImageHandler creates a new image handler
+func NewImageHandler(store *db.Store, images *images.Service, configReload func() error) *ImageHandler {
+	return &ImageHandler{store: store, images: images, configReload: configReload}
+}
+
+// RegisterRoutes registers the image repository API routes
+func (h *ImageHandler) RegisterRoutes(r *gin.RouterGroup) {
+	r.GET("/images", h.List)
+	r.GET("/images/:id", h.Get)
+	r.POST("/images", h.Upload)
+	r.PUT("/images/:id", h.Update)
+	r.DELETE("/images/:id", h.Delete)
+}
+
+// RegisterFileRoutes registers the route devices download images from
+func (h *ImageHandler) RegisterFileRoutes(router *gin.Engine) {
+	router.GET("/"+images.Dir+"/:filename", h.ServeImage)
+	router.HEAD("/"+images.Dir+"/:filename", h.ServeImage)
+}
+
+// List returns the images in the repository, optionally for one vendor
+func (h *ImageHandler) List(c *gin.Context) {
+	list, err := h.store.ListImages(c.Query("vendor"))
+	if err != nil {
+		internalError(c, err)
+		return
+	}
+	okList(c, list)
+}
+
+// Get returns a single image
+func (h *ImageHandler) Get(c *gin.Context) {
+	img, found := h.requireImage(c)
+	if !found {
+		return
+	}
+	ok(c, img)
+}
+
+// Upload stores an image sent as multipart form data: the file plus vendor, model,
+// version and target fields
+func (h *ImageHandler) Upload(c *gin.Context) {
+	file, err := c.FormFile("file")
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, "file is required")
+		return
+	}
+	target, _ := strconv.ParseBool(c.PostForm("target"))
+	img := models.Image{
+		Vendor:   c.PostForm("vendor"),
+		Model:    c.PostForm("model"),
+		Version:  c.PostForm("version"),
+		Filename: file.Filename,
+		Target:   target,
+	}
+	if !h.validate(c, &img) {
+		return
+	}
+
+	src, err := file.Open()
+	if err != nil {
+		internalError(c, err)
+		return
+	}
+	defer src.Close()
+
+	if err := h.images.Upload(&img, src); err != nil {
+		if errors.Is(err, images.ErrExists) {
+			conflict(c, err.Error())
+			return
+		}
+		internalError(c, err)
+		return
+	}
+
+	if img.Target {
+		h.triggerReload()
+	}
+	created(c, img)
+}
+
+// Update changes an image's vendor, model, version or target flag. The file itself
+// can't be replaced; upload a new image instead.
+func (h *ImageHandler) Update(c *gin.Context) {
+	img, found := h.requireImage(c)
+	if !found {
+		return
+	}
+
+	var req struct {
+		Vendor  string `json:"vendor"`
+		Model   string `json:"model"`
+		Version string `json:"version"`
+		Target  bool   `json:"target"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		badRequest(c, err)
+		return
+	}
+	img.Vendor, img.Model, img.Version, img.Target = req.Vendor, req.Model, req.Version, req.Target
+	if !h.validate(c, img) {
+		return
+	}
+
+	if err := h.store.UpdateImage(img); handleError(c, err, true) {
+		return
+	}
+
+	h.triggerReload()
+	ok(c, img)
+}
+
+// Delete removes an image and its file
+func (h *ImageHandler) Delete(c *gin.Context) {
+	img, found := h.requireImage(c)
+	if !found {
+		return
+	}
+
+	if err := h.images.Delete(img); handleError(c, err, true) {
+		return
+	}
+
+	if img.Target {
+		h.triggerReload()
+	}
+	noContent(c)
+}
+
+// ServeImage serves an image file to a device and tracks its upgrade
+func (h *ImageHandler) ServeImage(c *gin.Context) {
+	img, err := h.store.GetImageByFilename(c.Param("filename"))
+	if err != nil {
+		c.String(http.StatusInternalServerError, "Internal error")
+		return
+	}
+	if img == nil {
+		c.String(http.StatusNotFound, "Image not found")
+		return
+	}
+
+	if c.Request.Method == http.MethodGet {
+		h.images.OnDownload(c.RemoteIP(), img)
+	}
+	c.File(h.images.Path(img.Filename))
+}
+
+// validate checks an image's metadata and that its vendor exists, sending a 400 and
+// returning false if not
+func (h *ImageHandler) validate(c *gin.Context, img *models.Image) bool {
+	errs := validate.Image(img)
+	if validate.VendorID(img.Vendor) {
+		vendor, err := h.store.GetVendor(img.Vendor)
+		if err != nil {
+			internalError(c, err)
+			return false
+		}
+		if vendor == nil {
+			errs.Add("vendor", "unknown vendor %q", img.Vendor)
+		}
+	}
+	if len(errs) > 0 {
+		validationFailed(c, errs)
+		return false
+	}
+	return true
+}
+
+// requireImage looks up the image in the id parameter, sending a 400 or 404 and
+// returning false if it can't be found
+func (h *ImageHandler) requireImage(c *gin.Context) (*models.Image, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, "invalid image ID")
+		return nil, false
+	}
+	img, err := h.store.GetImage(id)
+	if err != nil {
+		internalError(c, err)
+		return nil, false
+	}
+	if img == nil {
+		notFound(c, "image")
+		return nil, false
+	}
+	return img, true
+}
+
+func (h *ImageHandler) triggerReload() {
+	if h.configReload != nil {
+		h.configReload()
+	}
+}
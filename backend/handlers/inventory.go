@@ -0,0 +1,339 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ztp-server/backend/db"
+	"github.com/ztp-server/backend/inventory"
+	"github.com/ztp-server/backend/jobs"
+	"github.com/ztp-server/backend/models"
+	"github.com/ztp-server/backend/utils"
+	"github.com/ztp-server/backend/validate"
+)
+
+// InventoryHandler handles inventory source HTTP requests
+type InventoryHandler struct {
+	store        *db.Store
+	configReload func() error
+	queue        *jobs.Queue
+}
+
+// NewInventoryHandler creates a new inventory handler
+func NewInventoryHandler(store *db.Store, configReload func() error, queue *jobs.Queue) *InventoryHandler {
+	return &InventoryHandler{
+		store:        store,
+		configReload: configReload,
+		queue:        queue,
+	}
+}
+
+// RegisterRoutes registers all inventory routes
+func (h *InventoryHandler) RegisterRoutes(r *gin.RouterGroup) {
+	r.GET("/inventory/types", h.ListTypes)
+	r.GET("/inventory/sources", h.List)
+	r.GET("/inventory/sources/:id", h.Get)
+	r.POST("/inventory/sources", h.Create)
+	r.PUT("/inventory/sources/:id", h.Update)
+	r.DELETE("/inventory/sources/:id", h.Delete)
+	r.GET("/inventory/sources/:id/check", h.Check)
+	r.GET("/inventory/sources/:id/devices", h.ListDevices)
+	r.GET("/inventory/sources/:id/vendors", h.ListVendors)
+	r.GET("/inventory/sources/:id/sites", h.ListSites)
+	r.POST("/inventory/sync", h.Sync)
+}
+
+// ListTypes returns the registered source types
+func (h *InventoryHandler) ListTypes(c *gin.Context) {
+	okList(c, inventory.Types())
+}
+
+// List returns the built-in NetBox source and all configured sources
+func (h *InventoryHandler) List(c *gin.Context) {
+	sources, err := inventory.Sources(h.store)
+	if err != nil {
+		internalError(c, err)
+		return
+	}
+	for i := range sources {
+		sources[i].Token = maskSecret(sources[i].Token)
+	}
+	okList(c, sources)
+}
+
+// Get returns a single source by ID
+func (h *InventoryHandler) Get(c *gin.Context) {
+	src, ok := h.lookup(c)
+	if !ok {
+		return
+	}
+	src.Token = maskSecret(src.Token)
+	c.JSON(http.StatusOK, src)
+}
+
+// Create adds a new inventory source
+func (h *InventoryHandler) Create(c *gin.Context) {
+	var src models.InventorySource
+	if err := c.ShouldBindJSON(&src); err != nil {
+		badRequest(c, err)
+		return
+	}
+	src.Builtin = false
+
+	if src.ID == inventory.BuiltinID {
+		conflict(c, "the built-in NetBox source is configured via /api/netbox/config")
+		return
+	}
+	if !h.validate(c, &src) {
+		return
+	}
+
+	existing, _ := h.store.GetInventorySource(src.ID)
+	if existing != nil {
+		conflict(c, "inventory source with this ID already exists")
+		return
+	}
+
+	if err := h.store.CreateInventorySource(&src); err != nil {
+		internalError(c, err)
+		return
+	}
+
+	src.Token = maskSecret(src.Token)
+	created(c, src)
+}
+
+// Update modifies an inventory source. An omitted token keeps the stored one.
+func (h *InventoryHandler) Update(c *gin.Context) {
+	id := c.Param("id")
+	if id == inventory.BuiltinID {
+		errorResponse(c, http.StatusBadRequest, "the built-in NetBox source is configured via /api/netbox/config")
+		return
+	}
+
+	var src models.InventorySource
+	if err := c.ShouldBindJSON(&src); err != nil {
+		badRequest(c, err)
+		return
+	}
+	src.ID = id
+	src.Builtin = false
+
+	existing, err := h.store.GetInventorySource(id)
+	if err != nil {
+		internalError(c, err)
+		return
+	}
+	if existing == nil {
+		notFound(c, "inventory source")
+		return
+	}
+	if src.Token == "" {
+		src.Token = existing.Token
+	}
+	if !h.validate(c, &src) {
+		return
+	}
+
+	if err := h.store.UpdateInventorySource(&src); handleError(c, err, true) {
+		return
+	}
+
+	src.CreatedAt = existing.CreatedAt
+	src.Token = maskSecret(src.Token)
+	ok(c, src)
+}
+
+// Delete removes an inventory source
+func (h *InventoryHandler) Delete(c *gin.Context) {
+	id := c.Param("id")
+	if id == inventory.BuiltinID {
+		errorResponse(c, http.StatusBadRequest, "the built-in NetBox source can't be deleted")
+		return
+	}
+
+	if err := h.store.DeleteInventorySource(id); handleError(c, err, true) {
+		return
+	}
+	noContent(c)
+}
+
+// Check tests connectivity to a source
+func (h *InventoryHandler) Check(c *gin.Context) {
+	source, ok := h.open(c)
+	if !ok {
+		return
+	}
+
+	if err := source.Check(); err != nil {
+		c.JSON(http.StatusOK, gin.H{"connected": false, "error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"connected": true})
+}
+
+// ListDevices returns the devices a source would provide, converted to ZTP devices
+func (h *InventoryHandler) ListDevices(c *gin.Context) {
+	source, ok := h.open(c)
+	if !ok {
+		return
+	}
+
+	records, err := source.ListDevices()
+	if err != nil {
+		errorResponse(c, http.StatusBadGateway, err.Error())
+		return
+	}
+	okList(c, records)
+}
+
+// ListVendors returns a source's manufacturers as ZTP vendors
+func (h *InventoryHandler) ListVendors(c *gin.Context) {
+	source, ok := h.open(c)
+	if !ok {
+		return
+	}
+
+	vendors, err := source.ListVendors()
+	if err != nil {
+		errorResponse(c, http.StatusBadGateway, err.Error())
+		return
+	}
+	okList(c, vendors)
+}
+
+// ListSites returns a source's sites
+func (h *InventoryHandler) ListSites(c *gin.Context) {
+	source, ok := h.open(c)
+	if !ok {
+		return
+	}
+
+	sites, err := source.ListSites()
+	if err != nil {
+		errorResponse(c, http.StatusBadGateway, err.Error())
+		return
+	}
+	okList(c, sites)
+}
+
+// InventorySyncRequest selects the source and direction of a sync
+type InventorySyncRequest struct {
+	inventory.SyncRequest
+	Async bool `json:"async"` // Queue the sync as a job and return it immediately
+}
+
+// Sync pulls devices from or pushes devices to a source, either now or as a queued job
+func (h *InventoryHandler) Sync(c *gin.Context) {
+	var req InventorySyncRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		badRequest(c, err)
+		return
+	}
+	if req.Direction != inventory.DirectionPull && req.Direction != inventory.DirectionPush {
+		errorResponse(c, http.StatusBadRequest, "direction must be pull or push")
+		return
+	}
+	if req.DryRun && req.Direction == inventory.DirectionPush {
+		errorResponse(c, http.StatusBadRequest, "dry_run is only supported for pull")
+		return
+	}
+	for i, mac := range req.MACs {
+		req.MACs[i] = utils.NormalizeMac(mac)
+	}
+
+	if req.Async {
+		job, err := h.queue.Enqueue(jobs.TypeInventorySync, req.SyncRequest, jobs.Options{})
+		if err != nil {
+			internalError(c, err)
+			return
+		}
+		c.JSON(http.StatusAccepted, gin.H{"message": "Sync queued", "job": job})
+		return
+	}
+
+	result, err := inventory.Sync(h.store, req.SyncRequest)
+	switch {
+	case errors.Is(err, inventory.ErrSourceNotFound):
+		notFound(c, "inventory source")
+		return
+	case errors.Is(err, inventory.ErrSourceUnusable), errors.Is(err, inventory.ErrNotSupported):
+		errorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	case err != nil:
+		errorResponse(c, http.StatusBadGateway, err.Error())
+		return
+	}
+	if result.Changed {
+		h.triggerReload()
+	}
+
+	if result.Direction == inventory.DirectionPush {
+		c.JSON(http.StatusOK, gin.H{
+			"message": "Sync push completed",
+			"source":  result.Source,
+			"result":  result.Result,
+		})
+		return
+	}
+
+	message := "Sync pull completed"
+	if result.DryRun {
+		message = "Sync pull preview"
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message": message,
+		"source":  result.Source,
+		"dry_run": result.DryRun,
+		"summary": result.Summary,
+		"devices": result.Devices,
+	})
+}
+
+// lookup loads the source named in the URL, sending a 404 if it doesn't exist
+func (h *InventoryHandler) lookup(c *gin.Context) (*models.InventorySource, bool) {
+	src, err := inventory.Get(h.store, c.Param("id"))
+	if err != nil {
+		internalError(c, err)
+		return nil, false
+	}
+	if src == nil {
+		notFound(c, "inventory source")
+		return nil, false
+	}
+	return src, true
+}
+
+// open loads and opens the source named in the URL
+func (h *InventoryHandler) open(c *gin.Context) (inventory.Source, bool) {
+	src, ok := h.lookup(c)
+	if !ok {
+		return nil, false
+	}
+	source, err := inventory.Open(src, h.store)
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, err.Error())
+		return nil, false
+	}
+	return source, true
+}
+
+// validate checks a source's configuration, sending the errors if it is invalid
+func (h *InventoryHandler) validate(c *gin.Context, src *models.InventorySource) bool {
+	errs := validate.InventorySource(src)
+	if !inventory.Supported(src.Type) {
+		errs.Add("type", "must be a registered source type")
+	}
+	if len(errs) > 0 {
+		validationFailed(c, errs)
+		return false
+	}
+	return true
+}
+
+func (h *InventoryHandler) triggerReload() {
+	if h.configReload != nil {
+		h.configReload()
+	}
+}
@@ -0,0 +1,147 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ztp-server/backend/db"
+	"github.com/ztp-server/backend/jobs"
+	"github.com/ztp-server/backend/models"
+)
+
+// JobHandler handles job queue HTTP requests
+type JobHandler struct {
+	store *db.Store
+	queue *jobs.Queue
+}
+
+// NewJobHandler creates a new job handler
+func NewJobHandler(store *db.Store, queue *jobs.Queue) *JobHandler {
+	return &JobHandler{
+		store: store,
+		queue: queue,
+	}
+}
+
+// RegisterRoutes registers all job routes
+func (h *JobHandler) RegisterRoutes(r *gin.RouterGroup) {
+	r.GET("/jobs", h.List)
+	r.GET("/jobs/types", h.ListTypes)
+	r.GET("/jobs/:id", h.Get)
+	r.POST("/jobs/:id/cancel", h.Cancel)
+	r.POST("/jobs/:id/retry", h.Retry)
+	r.DELETE("/jobs/:id", h.Delete)
+}
+
+// List returns jobs, newest first, optionally filtered by type and status
+func (h *JobHandler) List(c *gin.Context) {
+	filter := db.JobFilter{
+		Type:   c.Query("type"),
+		Status: c.Query("status"),
+		Limit:  100,
+	}
+	if limit := c.Query("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 1 {
+			errorResponse(c, http.StatusBadRequest, "limit must be a positive number")
+			return
+		}
+		filter.Limit = n
+	}
+
+	list, err := h.store.ListJobs(filter)
+	if err != nil {
+		internalError(c, err)
+		return
+	}
+	okList(c, list)
+}
+
+// ListTypes returns the job types the queue can run
+func (h *JobHandler) ListTypes(c *gin.Context) {
+	okList(c, h.queue.Types())
+}
+
+// Get returns a single job
+func (h *JobHandler) Get(c *gin.Context) {
+	job, found := h.lookup(c)
+	if !found {
+		return
+	}
+	ok(c, job)
+}
+
+// Cancel cancels a pending job or interrupts a running one
+func (h *JobHandler) Cancel(c *gin.Context) {
+	job, found := h.lookup(c)
+	if !found {
+		return
+	}
+
+	if err := h.queue.Cancel(job.ID); err != nil {
+		if errors.Is(err, jobs.ErrNotRunning) {
+			conflict(c, err.Error())
+			return
+		}
+		internalError(c, err)
+		return
+	}
+	message(c, "job cancelled")
+}
+
+// Retry queues a failed or cancelled job again
+func (h *JobHandler) Retry(c *gin.Context) {
+	job, found := h.lookup(c)
+	if !found {
+		return
+	}
+
+	if job.Status != models.JobFailed && job.Status != models.JobCancelled {
+		conflict(c, "only failed or cancelled jobs can be retried")
+		return
+	}
+	if err := h.queue.Retry(job.ID); err != nil {
+		internalError(c, err)
+		return
+	}
+	accepted(c, "job queued")
+}
+
+// Delete removes a finished job
+func (h *JobHandler) Delete(c *gin.Context) {
+	job, found := h.lookup(c)
+	if !found {
+		return
+	}
+
+	if job.Status == models.JobPending || job.Status == models.JobRunning {
+		conflict(c, "cancel the job before deleting it")
+		return
+	}
+	if err := h.store.DeleteJob(job.ID); handleError(c, err, true) {
+		return
+	}
+	noContent(c)
+}
+
+// lookup loads the job named in the URL, sending an error response if it can't
+func (h *JobHandler) lookup(c *gin.Context) (*models.Job, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, "invalid job ID")
+		return nil, false
+	}
+
+	job, err := h.store.GetJob(id)
+	if err != nil {
+		internalError(c, err)
+		return nil, false
+	}
+	if job == nil {
+		notFound(c, "job")
+		return nil, false
+	}
+	return job, true
+}
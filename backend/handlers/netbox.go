@@ -1,24 +1,38 @@
 package handlers
 
 import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
 	"net/http"
+	"strconv"
 	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/ztp-server/backend/db"
+	"github.com/ztp-server/backend/inventory"
 	"github.com/ztp-server/backend/models"
 	"github.com/ztp-server/backend/netbox"
+	"github.com/ztp-server/backend/utils"
+	"github.com/ztp-server/backend/validate"
 )
 
 // NetBoxHandler handles NetBox-related HTTP requests
 type NetBoxHandler struct {
-	store *db.Store
+	store         *db.Store
+	configReload  func() error
+	reconciler    *netbox.Reconciler
+	netboxContext *netbox.ContextProvider
 }
 
 // NewNetBoxHandler creates a new NetBox handler
-func NewNetBoxHandler(store *db.Store) *NetBoxHandler {
+func NewNetBoxHandler(store *db.Store, configReload func() error, reconciler *netbox.Reconciler, netboxContext *netbox.ContextProvider) *NetBoxHandler {
 	return &NetBoxHandler{
-		store: store,
+		store:         store,
+		configReload:  configReload,
+		reconciler:    reconciler,
+		netboxContext: netboxContext,
 	}
 }
 
@@ -31,18 +45,30 @@ func (h *NetBoxHandler) RegisterRoutes(r *gin.RouterGroup) {
 	r.POST("/netbox/sync/pull", h.SyncPull)
 	r.POST("/netbox/sync/vendors/push", h.SyncVendorsPush)
 	r.POST("/netbox/sync/vendors/pull", h.SyncVendorsPull)
+	r.GET("/netbox/sync/status", h.SyncStatus)
+	r.POST("/netbox/sync/run", h.SyncRun)
+	r.POST("/netbox/webhook", h.Webhook)
 	r.GET("/netbox/manufacturers", h.ListManufacturers)
 	r.GET("/netbox/sites", h.ListSites)
 	r.GET("/netbox/device-roles", h.ListDeviceRoles)
+	r.GET("/netbox/tenants", h.ListTenants)
+	r.GET("/netbox/platforms", h.ListPlatforms)
+	r.GET("/netbox/prefixes", h.ListPrefixes)
+	r.GET("/netbox/prefixes/:id/available-ips", h.ListAvailableIPs)
+	r.GET("/netbox/context/:mac", h.GetDeviceContext)
 }
 
 // NetBoxConfig represents the NetBox configuration
 type NetBoxConfig struct {
-	URL         string `json:"url"`
-	Token       string `json:"token"`
-	SiteID      int    `json:"site_id"`
-	RoleID      int    `json:"role_id"`
-	SyncEnabled bool   `json:"sync_enabled"`
+	URL           string            `json:"url"`
+	Token         string            `json:"token"`
+	SiteID        int               `json:"site_id"`
+	RoleID        int               `json:"role_id"`
+	SyncEnabled   bool              `json:"sync_enabled"`
+	SyncInterval  int               `json:"sync_interval"`
+	WebhookSecret string            `json:"webhook_secret"`
+	FieldOwners   map[string]string `json:"field_owners"`
+	PrefixID      int               `json:"prefix_id"`
 }
 
 // Status checks NetBox connectivity
@@ -89,22 +115,23 @@ func (h *NetBoxHandler) GetConfig(c *gin.Context) {
 		return
 	}
 
-	// Don't expose the full token
-	maskedToken := ""
-	if config.Token != "" {
-		if len(config.Token) > 8 {
-			maskedToken = config.Token[:4] + "..." + config.Token[len(config.Token)-4:]
-		} else {
-			maskedToken = "****"
-		}
+	// Resolve defaults so the UI shows the effective owner of every field
+	owners := make(map[string]string, len(netbox.DefaultFieldOwners))
+	for field := range netbox.DefaultFieldOwners {
+		owners[field] = netbox.FieldOwner(config.FieldOwners, field)
 	}
 
+	// Don't expose the full token or webhook secret
 	c.JSON(http.StatusOK, gin.H{
-		"url":          config.URL,
-		"token":        maskedToken,
-		"site_id":      config.SiteID,
-		"role_id":      config.RoleID,
-		"sync_enabled": config.SyncEnabled,
+		"url":            config.URL,
+		"token":          maskSecret(config.Token),
+		"site_id":        config.SiteID,
+		"role_id":        config.RoleID,
+		"sync_enabled":   config.SyncEnabled,
+		"sync_interval":  config.SyncInterval,
+		"webhook_secret": maskSecret(config.WebhookSecret),
+		"field_owners":   owners,
+		"prefix_id":      config.PrefixID,
 	})
 }
 
@@ -116,18 +143,46 @@ func (h *NetBoxHandler) UpdateConfig(c *gin.Context) {
 		return
 	}
 
-	// Get existing config to preserve token if not provided
+	var errs validate.Errors
+	if config.SyncInterval < 0 {
+		errs.Add("sync_interval", "must not be negative")
+	}
+	for field, owner := range config.FieldOwners {
+		if _, ok := netbox.DefaultFieldOwners[field]; !ok {
+			errs.Add("field_owners."+field, "is not a synced field")
+		} else if owner != netbox.OwnerZTP && owner != netbox.OwnerNetBox {
+			errs.Add("field_owners."+field, "must be %q or %q", netbox.OwnerZTP, netbox.OwnerNetBox)
+		}
+	}
+	if len(errs) > 0 {
+		validationFailed(c, errs)
+		return
+	}
+
+	// Get existing config to preserve secrets if not provided
 	existing, _ := h.store.GetNetBoxConfig()
-	if config.Token == "" && existing != nil {
-		config.Token = existing.Token
+	if existing != nil {
+		if config.Token == "" {
+			config.Token = existing.Token
+		}
+		if config.WebhookSecret == "" {
+			config.WebhookSecret = existing.WebhookSecret
+		}
+		if config.FieldOwners == nil {
+			config.FieldOwners = existing.FieldOwners
+		}
 	}
 
 	if err := h.store.SaveNetBoxConfig(&db.NetBoxConfig{
-		URL:         config.URL,
-		Token:       config.Token,
-		SiteID:      config.SiteID,
-		RoleID:      config.RoleID,
-		SyncEnabled: config.SyncEnabled,
+		URL:           config.URL,
+		Token:         config.Token,
+		SiteID:        config.SiteID,
+		RoleID:        config.RoleID,
+		SyncEnabled:   config.SyncEnabled,
+		SyncInterval:  config.SyncInterval,
+		WebhookSecret: config.WebhookSecret,
+		FieldOwners:   config.FieldOwners,
+		PrefixID:      config.PrefixID,
 	}); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -136,6 +191,66 @@ func (h *NetBoxHandler) UpdateConfig(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "NetBox configuration updated"})
 }
 
+// SyncStatus returns the state of the background reconciler
+func (h *NetBoxHandler) SyncStatus(c *gin.Context) {
+	c.JSON(http.StatusOK, h.reconciler.Status())
+}
+
+// SyncRun runs a full reconcile immediately and returns its result
+func (h *NetBoxHandler) SyncRun(c *gin.Context) {
+	result, err := h.reconciler.RunOnce("manual")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Reconcile completed",
+		"result":  result,
+	})
+}
+
+// Webhook receives NetBox change notifications and schedules a reconcile.
+// Requests must be signed with the configured webhook secret.
+func (h *NetBoxHandler) Webhook(c *gin.Context) {
+	config, err := h.store.GetNetBoxConfig()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if config.WebhookSecret == "" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "NetBox webhook secret not configured"})
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, 1<<20))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !netbox.VerifyWebhookSignature(config.WebhookSecret, body, c.GetHeader("X-Hook-Signature")) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid webhook signature"})
+		return
+	}
+
+	var event netbox.WebhookEvent
+	if err := json.Unmarshal(body, &event); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Template context may come from any NetBox object, so drop it whatever changed
+	h.netboxContext.Invalidate()
+
+	if !config.SyncEnabled || !netbox.RelevantWebhookModels[event.Model] {
+		c.JSON(http.StatusOK, gin.H{"message": "Event ignored"})
+		return
+	}
+
+	h.reconciler.Trigger(fmt.Sprintf("webhook:%s.%s", event.Model, event.Event))
+	c.JSON(http.StatusAccepted, gin.H{"message": "Reconcile scheduled"})
+}
+
 // SyncPush pushes devices from ZTP to NetBox
 func (h *NetBoxHandler) SyncPush(c *gin.Context) {
 	config, err := h.store.GetNetBoxConfig()
@@ -165,6 +280,9 @@ func (h *NetBoxHandler) SyncPush(c *gin.Context) {
 	if config.RoleID > 0 {
 		sync.DefaultRoleID = config.RoleID
 	}
+	if settings, err := h.store.GetSettings(); err == nil {
+		sync.PrefixLength = netbox.PrefixLength(settings)
+	}
 
 	result := sync.PushDevices(devices, vendors)
 
@@ -174,7 +292,13 @@ func (h *NetBoxHandler) SyncPush(c *gin.Context) {
 	})
 }
 
-// SyncPull pulls devices from NetBox to ZTP
+// SyncPullRequest selects what a pull does. An empty body applies every device.
+type SyncPullRequest struct {
+	DryRun bool     `json:"dry_run"` // Only report what would change
+	MACs   []string `json:"macs"`    // Apply only these devices
+}
+
+// SyncPull pulls devices from NetBox to ZTP, creating new devices and updating known ones
 func (h *NetBoxHandler) SyncPull(c *gin.Context) {
 	config, err := h.store.GetNetBoxConfig()
 	if err != nil || config.URL == "" || config.Token == "" {
@@ -182,39 +306,45 @@ func (h *NetBoxHandler) SyncPull(c *gin.Context) {
 		return
 	}
 
-	sync := netbox.NewSyncService(config.URL, config.Token)
+	var req SyncPullRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	for i, mac := range req.MACs {
+		req.MACs[i] = utils.NormalizeMac(mac)
+	}
 
-	// Pull devices from NetBox
-	devices, result, err := sync.PullDevices()
+	devices, err := h.store.ListDevices()
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	settings, _ := h.store.GetSettings()
 
-	// Import devices into ZTP (skip existing)
-	imported := 0
-	skipped := 0
-	for _, device := range devices {
-		// Check if device already exists
-		existing, _ := h.store.GetDevice(device.MAC)
-		if existing != nil {
-			skipped++
-			continue
-		}
+	sync := netbox.NewSyncService(config.URL, config.Token)
+	records, err := sync.PullRecords()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	items := inventory.PlanPull(records, devices, settings)
 
-		// Create device in ZTP
-		if err := h.store.CreateDevice(&device); err != nil {
-			result.Errors = append(result.Errors, device.Hostname+": "+err.Error())
-			continue
+	message := "Sync pull preview"
+	if !req.DryRun {
+		message = "Sync pull completed"
+		if inventory.ApplyPull(h.store, items, req.MACs) {
+			h.triggerReload()
 		}
-		imported++
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"message":  "Sync pull completed",
-		"imported": imported,
-		"skipped":  skipped,
-		"result":   result,
+		"message": message,
+		"dry_run": req.DryRun,
+		"summary": inventory.SummarizePull(items),
+		"devices": items,
 	})
 }
 
@@ -272,6 +402,115 @@ func (h *NetBoxHandler) ListDeviceRoles(c *gin.Context) {
 	c.JSON(http.StatusOK, roles)
 }
 
+// GetDeviceContext returns the NetBox data a device's config template sees as .NetBox
+func (h *NetBoxHandler) GetDeviceContext(c *gin.Context) {
+	device, err := h.store.GetDevice(utils.NormalizeMac(c.Param("mac")))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if device == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "device not found"})
+		return
+	}
+
+	if c.Query("refresh") == "true" {
+		h.netboxContext.Invalidate()
+	}
+	ctx, err := h.netboxContext.Get(device)
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, ctx)
+}
+
+// ListTenants lists tenants from NetBox
+func (h *NetBoxHandler) ListTenants(c *gin.Context) {
+	config, err := h.store.GetNetBoxConfig()
+	if err != nil || config.URL == "" || config.Token == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "NetBox not configured"})
+		return
+	}
+
+	sync := netbox.NewSyncService(config.URL, config.Token)
+	tenants, err := sync.Tenants.ListAll()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, tenants)
+}
+
+// ListPlatforms lists platforms from NetBox
+func (h *NetBoxHandler) ListPlatforms(c *gin.Context) {
+	config, err := h.store.GetNetBoxConfig()
+	if err != nil || config.URL == "" || config.Token == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "NetBox not configured"})
+		return
+	}
+
+	sync := netbox.NewSyncService(config.URL, config.Token)
+	platforms, err := sync.Platforms.ListAll()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, platforms)
+}
+
+// ListPrefixes lists IPAM prefixes from NetBox
+func (h *NetBoxHandler) ListPrefixes(c *gin.Context) {
+	config, err := h.store.GetNetBoxConfig()
+	if err != nil || config.URL == "" || config.Token == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "NetBox not configured"})
+		return
+	}
+
+	sync := netbox.NewSyncService(config.URL, config.Token)
+	prefixes, err := sync.Prefixes.ListAll()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, prefixes)
+}
+
+// ListAvailableIPs lists the next free addresses in a NetBox prefix
+func (h *NetBoxHandler) ListAvailableIPs(c *gin.Context) {
+	config, err := h.store.GetNetBoxConfig()
+	if err != nil || config.URL == "" || config.Token == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "NetBox not configured"})
+		return
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid prefix id"})
+		return
+	}
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	if limit <= 0 {
+		limit = 10
+	}
+
+	sync := netbox.NewSyncService(config.URL, config.Token)
+	ips, err := sync.Prefixes.AvailableIPs(id, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if ips == nil {
+		ips = []netbox.AvailableIP{}
+	}
+
+	c.JSON(http.StatusOK, ips)
+}
+
 // SyncVendorsPush pushes local vendors to NetBox as manufacturers
 func (h *NetBoxHandler) SyncVendorsPush(c *gin.Context) {
 	config, err := h.store.GetNetBoxConfig()
@@ -368,46 +607,7 @@ func (h *NetBoxHandler) SyncVendorsPull(c *gin.Context) {
 	errors := []string{}
 
 	for _, mfr := range manufacturers {
-		// Extract custom fields
-		macPrefixes := []string{}
-		backupCommand := "show running-config"
-		sshPort := 22
-		vendorClass := ""
-		defaultTemplate := ""
-
-		if mfr.CustomFields != nil {
-			if v, ok := mfr.CustomFields["mac_prefixes"].(string); ok && v != "" {
-				// Split comma-separated prefixes
-				for _, p := range strings.Split(v, ",") {
-					p = strings.TrimSpace(p)
-					if p != "" {
-						macPrefixes = append(macPrefixes, p)
-					}
-				}
-			}
-			if v, ok := mfr.CustomFields["backup_command"].(string); ok && v != "" {
-				backupCommand = v
-			}
-			if v, ok := mfr.CustomFields["ssh_port"].(float64); ok {
-				sshPort = int(v)
-			}
-			if v, ok := mfr.CustomFields["vendor_class"].(string); ok && v != "" {
-				vendorClass = v
-			}
-			if v, ok := mfr.CustomFields["default_template"].(string); ok && v != "" {
-				defaultTemplate = v
-			}
-		}
-
-		vendor := &models.Vendor{
-			ID:              mfr.Slug,
-			Name:            mfr.Name,
-			MacPrefixes:     macPrefixes,
-			BackupCommand:   backupCommand,
-			SSHPort:         sshPort,
-			VendorClass:     vendorClass,
-			DefaultTemplate: defaultTemplate,
-		}
+		vendor := netbox.VendorFromManufacturer(&mfr)
 
 		// Check if vendor exists
 		existing, _ := h.store.GetVendor(mfr.Slug)
@@ -437,3 +637,39 @@ func (h *NetBoxHandler) SyncVendorsPull(c *gin.Context) {
 		},
 	})
 }
+
+func (h *NetBoxHandler) triggerReload() {
+	if h.configReload != nil {
+		h.configReload()
+	}
+}
+
+// maskSecret hides all but the ends of a credential
+func maskSecret(secret string) string {
+	if secret == "" {
+		return ""
+	}
+	if len(secret) > 8 {
+		return secret[:4] + "..." + secret[len(secret)-4:]
+	}
+	return "****"
+}
+
+// allocateDeviceIP fills in a device's IP from the configured NetBox prefix. The returned
+// func releases the address again if the device ends up not being created.
+func allocateDeviceIP(store *db.Store, device *models.Device) (func(), error) {
+	config, err := store.GetNetBoxConfig()
+	if err != nil {
+		return nil, err
+	}
+	ip, err := netbox.AllocateDeviceIP(config, device.Hostname)
+	if err != nil {
+		return nil, err
+	}
+	device.IP = netbox.HostAddress(ip.Address)
+	return func() {
+		if err := netbox.ReleaseDeviceIP(config, ip); err != nil {
+			log.Printf("[netbox] Warning: failed to release allocated IP %s: %v", ip.Address, err)
+		}
+	}, nil
+}
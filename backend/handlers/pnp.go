@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"encoding/xml"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ztp-server/backend/db"
+	"github.com/ztp-server/backend/pnp"
+)
+
+// PnPHandler serves the Cisco Plug and Play protocol and its session API
+type PnPHandler struct {
+	store  *db.Store
+	server *pnp.Server
+}
+
+// NewPnPHandler creates a new PnP handler
+func NewPnPHandler(store *db.Store, server *pnp.Server) *PnPHandler {
+	return &PnPHandler{store: store, server: server}
+}
+
+// RegisterRoutes registers the PnP session API routes
+func (h *PnPHandler) RegisterRoutes(r *gin.RouterGroup) {
+	r.GET("/pnp/sessions", h.ListSessions)
+	r.DELETE("/pnp/sessions/:serial", h.ResetSession)
+}
+
+// RegisterProtocolRoutes registers the routes PnP agents talk to
+func (h *PnPHandler) RegisterProtocolRoutes(router *gin.Engine) {
+	router.GET("/pnp/HELLO", h.Hello)
+	router.POST("/pnp/WORK-REQUEST", h.WorkRequest)
+	router.POST("/pnp/WORK-RESPONSE", h.WorkResponse)
+}
+
+// ListSessions returns all PnP sessions
+func (h *PnPHandler) ListSessions(c *gin.Context) {
+	sessions, err := h.store.ListPnPSessions()
+	if err != nil {
+		internalError(c, err)
+		return
+	}
+	okList(c, sessions)
+}
+
+// ResetSession deletes a PnP session so the agent is provisioned again from the start
+func (h *PnPHandler) ResetSession(c *gin.Context) {
+	if err := h.store.DeletePnPSession(c.Param("serial")); handleError(c, err, true) {
+		return
+	}
+	noContent(c)
+}
+
+// Hello answers an agent's discovery probe
+func (h *PnPHandler) Hello(c *gin.Context) {
+	c.Status(http.StatusOK)
+}
+
+// WorkRequest hands an agent its next work item
+func (h *PnPHandler) WorkRequest(c *gin.Context) {
+	h.serve(c, h.server.WorkRequest)
+}
+
+// WorkResponse records an agent's work item result
+func (h *PnPHandler) WorkResponse(c *gin.Context) {
+	h.serve(c, h.server.WorkResponse)
+}
+
+// serve decodes an agent's message, passes it to the PnP server and sends the reply
+func (h *PnPHandler) serve(c *gin.Context, handle func(ip string, msg *pnp.Message) ([]byte, error)) {
+	var msg pnp.Message
+	if err := xml.NewDecoder(c.Request.Body).Decode(&msg); err != nil {
+		c.String(http.StatusBadRequest, "Invalid PnP message")
+		return
+	}
+	reply, err := handle(c.RemoteIP(), &msg)
+	if err != nil {
+		log.Printf("PnP request from %s failed: %v", c.RemoteIP(), err)
+		c.String(http.StatusInternalServerError, "PnP request failed")
+		return
+	}
+	c.Data(http.StatusOK, "application/xml; charset=utf-8", reply)
+}
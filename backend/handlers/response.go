@@ -5,6 +5,7 @@ import (
 	"strings"
 
 	"github.com/gin-gonic/gin"
+	"github.com/ztp-server/backend/validate"
 )
 
 // Error responses
@@ -24,6 +25,11 @@ func notFound(c *gin.Context, resource string) {
 	errorResponse(c, http.StatusNotFound, resource+" not found")
 }
 
+// validationFailed sends the field errors so clients can highlight individual inputs
+func validationFailed(c *gin.Context, errs validate.Errors) {
+	c.JSON(http.StatusBadRequest, gin.H{"error": errs.Error(), "fields": errs})
+}
+
 func conflict(c *gin.Context, message string) {
 	errorResponse(c, http.StatusConflict, message)
 }
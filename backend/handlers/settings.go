@@ -5,20 +5,22 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/ztp-server/backend/db"
+	"github.com/ztp-server/backend/dhcp"
 	"github.com/ztp-server/backend/models"
+	"github.com/ztp-server/backend/validate"
 )
 
 // SettingsHandler handles settings-related HTTP requests
 type SettingsHandler struct {
-	store        *db.Store
-	configReload func() error
+	store    *db.Store
+	reloader *dhcp.Reloader
 }
 
 // NewSettingsHandler creates a new settings handler
-func NewSettingsHandler(store *db.Store, configReload func() error) *SettingsHandler {
+func NewSettingsHandler(store *db.Store, reloader *dhcp.Reloader) *SettingsHandler {
 	return &SettingsHandler{
-		store:        store,
-		configReload: configReload,
+		store:    store,
+		reloader: reloader,
 	}
 }
 
@@ -27,6 +29,7 @@ func (h *SettingsHandler) RegisterRoutes(r *gin.RouterGroup) {
 	r.GET("/settings", h.Get)
 	r.PUT("/settings", h.Update)
 	r.POST("/reload", h.Reload)
+	r.GET("/reload/status", h.ReloadStatus)
 	r.GET("/network/addresses", h.GetLocalAddresses)
 }
 
@@ -49,23 +52,23 @@ func (h *SettingsHandler) Update(c *gin.Context) {
 		return
 	}
 
+	if errs := validate.Settings(&settings); len(errs) > 0 {
+		validationFailed(c, errs)
+		return
+	}
+
 	if err := h.store.UpdateSettings(&settings); err != nil {
 		internalError(c, err)
 		return
 	}
 
-	h.triggerReload()
+	h.reloader.Request()
 	ok(c, settings)
 }
 
-// Reload triggers a manual config regeneration
+// Reload regenerates the config right away and waits for it to go live
 func (h *SettingsHandler) Reload(c *gin.Context) {
-	if h.configReload == nil {
-		errorResponse(c, 500, "config reload not configured")
-		return
-	}
-
-	if err := h.configReload(); err != nil {
+	if err := h.reloader.Reload(); err != nil {
 		internalError(c, err)
 		return
 	}
@@ -73,10 +76,9 @@ func (h *SettingsHandler) Reload(c *gin.Context) {
 	message(c, "configuration reloaded")
 }
 
-func (h *SettingsHandler) triggerReload() {
-	if h.configReload != nil {
-		go h.configReload()
-	}
+// ReloadStatus returns the status of config regeneration
+func (h *SettingsHandler) ReloadStatus(c *gin.Context) {
+	ok(c, h.reloader.Status())
 }
 
 // NetworkInterface represents a network interface with its addresses
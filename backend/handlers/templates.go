@@ -7,21 +7,33 @@ import (
 	"text/template"
 
 	"github.com/gin-gonic/gin"
+	"github.com/ztp-server/backend/bootstrap"
+	"github.com/ztp-server/backend/callback"
 	"github.com/ztp-server/backend/db"
+	"github.com/ztp-server/backend/images"
 	"github.com/ztp-server/backend/models"
+	"github.com/ztp-server/backend/netbox"
 )
 
 // TemplateHandler handles template-related HTTP requests
 type TemplateHandler struct {
-	store        *db.Store
-	configReload func() error
+	store         *db.Store
+	configReload  func() error
+	netboxContext *netbox.ContextProvider
+	images        *images.Service
+	contexts      func(*models.Device, *models.Settings) (*bootstrap.Context, *callback.Context, error)
 }
 
-// NewTemplateHandler creates a new template handler
-func NewTemplateHandler(store *db.Store, configReload func() error) *TemplateHandler {
+// NewTemplateHandler creates a new template handler. contexts returns the bootstrap and
+// callback contexts previews see.
+func NewTemplateHandler(store *db.Store, configReload func() error, netboxContext *netbox.ContextProvider, images *images.Service,
+	contexts func(*models.Device, *models.Settings) (*bootstrap.Context, *callback.Context, error)) *TemplateHandler {
 	return &TemplateHandler{
-		store:        store,
-		configReload: configReload,
+		store:         store,
+		configReload:  configReload,
+		netboxContext: netboxContext,
+		images:        images,
+		contexts:      contexts,
 	}
 }
 
@@ -161,9 +173,11 @@ func (h *TemplateHandler) Preview(c *gin.Context) {
 	id := c.Param("id")
 
 	var previewData struct {
-		Device  models.Device   `json:"device"`
-		Subnet  string          `json:"subnet"`
-		Gateway string          `json:"gateway"`
+		Device  models.Device         `json:"device"`
+		Subnet  string                `json:"subnet"`
+		Gateway string                `json:"gateway"`
+		NetBox  *netbox.DeviceContext `json:"netbox"` // Sample NetBox data; fetched for the device if omitted
+		Image   *images.Context       `json:"image"`  // Sample target image; looked up for the device if omitted
 	}
 
 	if err := c.ShouldBindJSON(&previewData); err != nil {
@@ -189,14 +203,51 @@ func (h *TemplateHandler) Preview(c *gin.Context) {
 		return
 	}
 
+	netboxContext := previewData.NetBox
+	if netboxContext == nil && previewData.Device.MAC != "" && h.netboxContext != nil {
+		netboxContext, _ = h.netboxContext.Get(&previewData.Device)
+	}
+	if netboxContext == nil {
+		netboxContext = netbox.EmptyDeviceContext()
+	}
+	if netboxContext.InterfaceMap == nil {
+		netboxContext.InterfaceMap = map[string]netbox.InterfaceContext{}
+		for _, iface := range netboxContext.Interfaces {
+			netboxContext.InterfaceMap[iface.Name] = iface
+		}
+	}
+
+	settings, err := h.store.GetSettings()
+	if err != nil {
+		internalError(c, err)
+		return
+	}
+	image := previewData.Image
+	if image == nil && h.images != nil {
+		image = h.images.Context(&previewData.Device, settings)
+	}
+	bootstrapContext, callbackContext, err := h.contexts(&previewData.Device, settings)
+	if err != nil {
+		internalError(c, err)
+		return
+	}
+
 	data := struct {
 		*models.Device
-		Subnet  string
-		Gateway string
+		Subnet    string
+		Gateway   string
+		NetBox    *netbox.DeviceContext
+		Image     *images.Context
+		Bootstrap *bootstrap.Context
+		Callback  *callback.Context
 	}{
-		Device:  &previewData.Device,
-		Subnet:  previewData.Subnet,
-		Gateway: previewData.Gateway,
+		Device:    &previewData.Device,
+		Subnet:    previewData.Subnet,
+		Gateway:   previewData.Gateway,
+		NetBox:    netboxContext,
+		Image:     image,
+		Bootstrap: bootstrapContext,
+		Callback:  callbackContext,
 	}
 
 	var buf bytes.Buffer
@@ -216,6 +267,29 @@ func (h *TemplateHandler) GetVariables(c *gin.Context) {
 		{"name": "Hostname", "description": "Device hostname", "example": "switch-01"},
 		{"name": "Vendor", "description": "Device vendor", "example": "cisco"},
 		{"name": "SerialNumber", "description": "Device serial number", "example": "SN12345"},
+		{"name": "Site", "description": "NetBox site slug", "example": "dc1"},
+		{"name": "Role", "description": "NetBox device role slug", "example": "access-switch"},
+		{"name": "Tenant", "description": "NetBox tenant slug", "example": "customer-a"},
+		{"name": "Platform", "description": "NetBox platform slug", "example": "ios"},
+		{"name": "NetBox.ConfigContext", "description": "Rendered NetBox config context (map)", "example": "{{index .NetBox.ConfigContext \"ntp_servers\"}}"},
+		{"name": "NetBox.CustomFields", "description": "NetBox device custom fields (map)", "example": "{{.NetBox.CustomFields.asset_owner}}"},
+		{"name": "NetBox.Site", "description": "NetBox site: Name, Slug, Region, Facility, TimeZone, PhysicalAddress, CustomFields", "example": "{{.NetBox.Site.TimeZone}}"},
+		{"name": "NetBox.Interfaces", "description": "NetBox interfaces: Name, Type, Enabled, MAC, MTU, Description, Addresses", "example": "{{range .NetBox.Interfaces}}{{.Name}} {{end}}"},
+		{"name": "NetBox.PrimaryIP", "description": "NetBox primary IPv4 with mask", "example": "172.30.0.99/24"},
+		{"name": "NetBox.Found", "description": "Whether NetBox data is available for the device", "example": "{{if .NetBox.Found}}...{{end}}"},
+		{"name": "Image.URL", "description": "HTTP URL of the device's target image, if its vendor and model have one", "example": "{{with .Image}}copy {{.URL}} flash:{{end}}"},
+		{"name": "Image.TFTPURL", "description": "TFTP URL of the target image", "example": "tftp://172.30.0.2/images/c2960x-15.2.7.E3.bin"},
+		{"name": "Image.Filename", "description": "Target image file name", "example": "c2960x-15.2.7.E3.bin"},
+		{"name": "Image.Version", "description": "Target image version", "example": "15.2(7)E3"},
+		{"name": "Image.SHA256", "description": "SHA-256 checksum of the target image", "example": "{{.Image.SHA256}}"},
+		{"name": "Image.MD5", "description": "MD5 checksum of the target image", "example": "{{.Image.MD5}}"},
+		{"name": "Image.Size", "description": "Target image size in bytes", "example": "{{.Image.Size}}"},
+		{"name": "Bootstrap.ConfigURL", "description": "URL of the device's generated config, for bootstrap scripts; HTTPS and with a download token if enabled", "example": "https://172.30.0.2:8443/configs/02_42_ac_1e_00_99.cfg?token=..."},
+		{"name": "Bootstrap.CAURL", "description": "URL of the server's own CA certificate, empty unless it issues its own HTTPS certificates", "example": "http://172.30.0.2:8080/ca.crt"},
+		{"name": "Bootstrap.StatusURL", "description": "URL bootstrap scripts POST {\"status\": ..., \"message\": ...} to, the same as Callback.URL", "example": "http://172.30.0.2:8080/callback/02_42_ac_1e_00_99?token=..."},
+		{"name": "Bootstrap.ScriptURL", "description": "URL of the vendor's bootstrap script, sent in DHCP option 67", "example": "http://172.30.0.2:8080/bootstrap/arista"},
+		{"name": "Callback.URL", "description": "Signed URL the device reports provisioning status to, by POST or GET with status and message", "example": "http://172.30.0.2:8080/callback/02_42_ac_1e_00_99?token=..."},
+		{"name": "Callback.Token", "description": "Callback token alone, for the X-ZTP-Token header", "example": "{{.Callback.Token}}"},
 		{"name": "Subnet", "description": "Network subnet mask", "example": "255.255.255.0"},
 		{"name": "Gateway", "description": "Default gateway", "example": "172.30.0.1"},
 		{"name": "SSHUser", "description": "SSH username (if set)", "example": "admin"},
@@ -226,7 +300,7 @@ func (h *TemplateHandler) GetVariables(c *gin.Context) {
 
 func (h *TemplateHandler) triggerReload() {
 	if h.configReload != nil {
-		go h.configReload()
+		h.configReload()
 	}
 }
 
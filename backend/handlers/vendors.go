@@ -4,17 +4,20 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/ztp-server/backend/db"
 	"github.com/ztp-server/backend/models"
+	"github.com/ztp-server/backend/validate"
 )
 
 // VendorHandler handles vendor-related HTTP requests
 type VendorHandler struct {
-	store *db.Store
+	store        *db.Store
+	configReload func() error
 }
 
 // NewVendorHandler creates a new vendor handler
-func NewVendorHandler(store *db.Store) *VendorHandler {
+func NewVendorHandler(store *db.Store, configReload func() error) *VendorHandler {
 	return &VendorHandler{
-		store: store,
+		store:        store,
+		configReload: configReload,
 	}
 }
 
@@ -83,6 +86,10 @@ func (h *VendorHandler) Create(c *gin.Context) {
 		vendor.SSHPort = 22
 	}
 
+	if !h.validate(c, &vendor) {
+		return
+	}
+
 	// Check for duplicate
 	existing, _ := h.store.GetVendor(vendor.ID)
 	if existing != nil {
@@ -95,6 +102,7 @@ func (h *VendorHandler) Create(c *gin.Context) {
 		return
 	}
 
+	h.triggerReload()
 	created(c, vendor)
 }
 
@@ -110,10 +118,15 @@ func (h *VendorHandler) Update(c *gin.Context) {
 
 	vendor.ID = id
 
+	if !h.validate(c, &vendor) {
+		return
+	}
+
 	if err := h.store.UpdateVendor(&vendor); handleError(c, err, true) {
 		return
 	}
 
+	h.triggerReload()
 	ok(c, vendor)
 }
 
@@ -125,5 +138,33 @@ func (h *VendorHandler) Delete(c *gin.Context) {
 		return
 	}
 
+	h.triggerReload()
 	noContent(c)
 }
+
+// validate checks a vendor and that its bootstrap template exists, sending a 400 and
+// returning false if not
+func (h *VendorHandler) validate(c *gin.Context, vendor *models.Vendor) bool {
+	errs := validate.Vendor(vendor)
+	if vendor.BootstrapTemplate != "" {
+		tmpl, err := h.store.GetTemplate(vendor.BootstrapTemplate)
+		if err != nil {
+			internalError(c, err)
+			return false
+		}
+		if tmpl == nil {
+			errs.Add("bootstrap_template", "unknown template %q", vendor.BootstrapTemplate)
+		}
+	}
+	if len(errs) > 0 {
+		validationFailed(c, errs)
+		return false
+	}
+	return true
+}
+
+func (h *VendorHandler) triggerReload() {
+	if h.configReload != nil {
+		h.configReload()
+	}
+}
@@ -0,0 +1,209 @@
+// Package images is the firmware and OS image repository. Images are stored in a
+// directory under the TFTP root, so dnsmasq serves them over TFTP, and are also served
+// over HTTP. Each vendor, optionally narrowed to a model, can have a target image that
+// its devices are upgraded to during ZTP.
+package images
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/ztp-server/backend/db"
+	"github.com/ztp-server/backend/models"
+	"github.com/ztp-server/backend/utils"
+)
+
+// Dir is the directory under the TFTP root that images are stored in
+const Dir = "images"
+
+// ErrExists is returned when uploading an image whose filename is already taken
+var ErrExists = errors.New("an image with this filename already exists")
+
+// Context is the target image as seen by device config templates
+type Context struct {
+	Version  string
+	Filename string
+	Size     int64
+	SHA256   string
+	MD5      string
+	URL      string // HTTP download URL
+	TFTPPath string // Path relative to the TFTP root, e.g. images/<filename>
+	TFTPURL  string // tftp://<server>/images/<filename>
+}
+
+// Service manages the image repository and tracks device upgrades
+type Service struct {
+	store      *db.Store
+	dir        string
+	listenAddr string
+}
+
+// NewService creates an image service storing images under tftpDir. listenAddr is the
+// HTTP server's listen address, used to build download URLs.
+func NewService(store *db.Store, tftpDir, listenAddr string) *Service {
+	return &Service{store: store, dir: filepath.Join(tftpDir, Dir), listenAddr: listenAddr}
+}
+
+// Path returns the file an image is stored in
+func (s *Service) Path(filename string) string {
+	return filepath.Join(s.dir, filename)
+}
+
+// Upload stores an image's contents and records it, computing its size and checksums.
+// The metadata must already be validated.
+func (s *Service) Upload(img *models.Image, r io.Reader) error {
+	existing, err := s.store.GetImageByFilename(img.Filename)
+	if err != nil {
+		return err
+	}
+	dest := s.Path(img.Filename)
+	if _, err := os.Stat(dest); existing != nil || err == nil {
+		return ErrExists
+	}
+	if err := os.MkdirAll(s.dir, 0755); err != nil {
+		return err
+	}
+
+	// Write to a temporary file first so a failed upload never leaves a partial image
+	// where devices can fetch it
+	tmp, err := os.CreateTemp(s.dir, ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	sha, sum := sha256.New(), md5.New()
+	size, err := io.Copy(io.MultiWriter(tmp, sha, sum), r)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to store image: %w", err)
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), dest); err != nil {
+		return err
+	}
+
+	img.Size = size
+	img.SHA256 = hex.EncodeToString(sha.Sum(nil))
+	img.MD5 = hex.EncodeToString(sum.Sum(nil))
+	if err := s.store.CreateImage(img); err != nil {
+		os.Remove(dest)
+		return err
+	}
+	log.Printf("Stored image %s (%s %s, %d bytes)", img.Filename, img.Vendor, img.Version, img.Size)
+	return nil
+}
+
+// Delete removes an image and its file
+func (s *Service) Delete(img *models.Image) error {
+	if err := s.store.DeleteImage(img.ID); err != nil {
+		return err
+	}
+	if err := os.Remove(s.Path(img.Filename)); err != nil && !os.IsNotExist(err) {
+		log.Printf("Warning: failed to remove image file %s: %v", img.Filename, err)
+	}
+	return nil
+}
+
+// Target returns the image a device should be upgraded to, or nil if its vendor and
+// model have none
+func (s *Service) Target(device *models.Device) (*models.Image, error) {
+	if device.Vendor == "" {
+		return nil, nil
+	}
+	return s.store.GetTargetImage(device.Vendor, device.Model)
+}
+
+// BootFile returns an image's path relative to the TFTP root, as sent in DHCP
+func BootFile(img *models.Image) string {
+	return path.Join(Dir, img.Filename)
+}
+
+// Context returns the device's target image for config templates, or nil if it has none
+func (s *Service) Context(device *models.Device, settings *models.Settings) *Context {
+	img, err := s.Target(device)
+	if err != nil {
+		log.Printf("Warning: failed to get target image for %s: %v", device.MAC, err)
+		return nil
+	}
+	if img == nil {
+		return nil
+	}
+
+	host := settings.TFTPServerIP
+	return &Context{
+		Version:  img.Version,
+		Filename: img.Filename,
+		Size:     img.Size,
+		SHA256:   img.SHA256,
+		MD5:      img.MD5,
+		URL:      (&url.URL{Scheme: "http", Host: utils.HTTPHost(host, s.listenAddr), Path: "/" + BootFile(img)}).String(),
+		TFTPPath: BootFile(img),
+		TFTPURL:  (&url.URL{Scheme: "tftp", Host: host, Path: "/" + BootFile(img)}).String(),
+	}
+}
+
+// OnDownload marks the upgrade of the device at ip as under way once it fetches an image
+func (s *Service) OnDownload(ip string, img *models.Image) {
+	device, err := s.store.FindDeviceByIP(ip)
+	if err != nil || device == nil {
+		return
+	}
+	log.Printf("Image %s downloaded by %s (%s)", img.Filename, device.Hostname, ip)
+	if err := s.store.UpdateDeviceUpgrade(device.MAC, models.UpgradeDownloading, img.Version); err != nil {
+		log.Printf("Failed to update upgrade status for %s: %v", device.MAC, err)
+	}
+}
+
+// OnFactsCollected compares the version a device reports with its target image. A
+// device that fetched the image but still runs another version has failed to upgrade.
+func (s *Service) OnFactsCollected(device *models.Device, facts *models.DeviceFacts) {
+	if facts.Version == "" {
+		return
+	}
+	img, err := s.Target(device)
+	if err != nil {
+		log.Printf("Warning: failed to get target image for %s: %v", device.MAC, err)
+		return
+	}
+	if img == nil {
+		return
+	}
+
+	status := models.UpgradePending
+	switch {
+	case versionMatches(facts.Version, img.Version):
+		status = models.UpgradeUpgraded
+	case device.UpgradeStatus == models.UpgradeDownloading && device.UpgradeVersion == img.Version:
+		status = models.UpgradeFailed
+	}
+	if status == device.UpgradeStatus && img.Version == device.UpgradeVersion {
+		return
+	}
+	if status == models.UpgradeFailed {
+		log.Printf("Warning: %s fetched image %s but reports version %s", device.Hostname, img.Version, facts.Version)
+	}
+	if err := s.store.UpdateDeviceUpgrade(device.MAC, status, img.Version); err != nil {
+		log.Printf("Failed to update upgrade status for %s: %v", device.MAC, err)
+	}
+}
+
+// versionMatches reports whether a reported version is the target version. Devices
+// often decorate the version, e.g. "Version 15.2(7)E3," or "JUNOS 21.4R3-S4.9".
+func versionMatches(reported, target string) bool {
+	return strings.Contains(strings.ToLower(reported), strings.ToLower(strings.TrimSpace(target)))
+}
@@ -0,0 +1,225 @@
+package inventory
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/ztp-server/backend/db"
+	"github.com/ztp-server/backend/models"
+	"github.com/ztp-server/backend/utils"
+)
+
+// HTTP source formats
+const (
+	FormatJSON = "json"
+	FormatCSV  = "csv"
+)
+
+func init() {
+	Register(models.InventoryHTTP, openHTTP)
+}
+
+// httpSource reads devices from a JSON or CSV document served over HTTP. Columns and
+// keys use the ZTP device field names (mac, ip, hostname, vendor, model, serial_number,
+// site, role, tenant, platform), plus an optional id. Vendor, site, role, tenant and
+// platform names are slugified. JSON may be an array of devices or an object with a
+// "devices" array. The source is read-only.
+type httpSource struct {
+	url    string
+	format string
+	client *client
+}
+
+func openHTTP(config *models.InventorySource, store *db.Store) (Source, error) {
+	if config.URL == "" {
+		return nil, fmt.Errorf("URL is required")
+	}
+	format := config.Format
+	if format == "" {
+		format = FormatJSON
+	}
+	if format != FormatJSON && format != FormatCSV {
+		return nil, fmt.Errorf("unsupported format: %s", format)
+	}
+
+	headers := make(map[string]string, len(config.Headers)+1)
+	for key, value := range config.Headers {
+		headers[key] = value
+	}
+	if config.Token != "" {
+		headers["Authorization"] = "Bearer " + config.Token
+	}
+	return &httpSource{url: config.URL, format: format, client: newClient(headers)}, nil
+}
+
+// rows fetches the document and returns one field map per device
+func (h *httpSource) rows() ([]map[string]string, error) {
+	body, err := h.client.get(h.url)
+	if err != nil {
+		return nil, err
+	}
+	if h.format == FormatCSV {
+		return parseCSV(body)
+	}
+	return parseJSON(body)
+}
+
+func parseJSON(body []byte) ([]map[string]string, error) {
+	var entries []map[string]any
+	if err := json.Unmarshal(body, &entries); err != nil {
+		var wrapped struct {
+			Devices []map[string]any `json:"devices"`
+		}
+		if err := json.Unmarshal(body, &wrapped); err != nil {
+			return nil, fmt.Errorf("failed to decode JSON: %w", err)
+		}
+		entries = wrapped.Devices
+	}
+
+	rows := make([]map[string]string, 0, len(entries))
+	for _, entry := range entries {
+		row := make(map[string]string, len(entry))
+		for key, value := range entry {
+			if value != nil {
+				row[strings.ToLower(key)] = strings.TrimSpace(fmt.Sprint(value))
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+func parseCSV(body []byte) ([]map[string]string, error) {
+	records, err := csv.NewReader(bytes.NewReader(body)).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse CSV: %w", err)
+	}
+	if len(records) == 0 {
+		return nil, nil
+	}
+
+	header := records[0]
+	rows := make([]map[string]string, 0, len(records)-1)
+	for _, record := range records[1:] {
+		row := make(map[string]string, len(header))
+		for i, column := range header {
+			if i < len(record) {
+				row[strings.ToLower(strings.TrimSpace(column))] = strings.TrimSpace(record[i])
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// Check fetches and parses the document
+func (h *httpSource) Check() error {
+	_, err := h.rows()
+	return err
+}
+
+// ListDevices returns the document's devices
+func (h *httpSource) ListDevices() ([]Record, error) {
+	rows, err := h.rows()
+	if err != nil {
+		return nil, err
+	}
+
+	records := make([]Record, 0, len(rows))
+	for i, row := range rows {
+		record := Record{ExternalID: row["id"], Name: row["hostname"]}
+		if record.ExternalID == "" {
+			record.ExternalID = fmt.Sprintf("row-%d", i+1)
+		}
+
+		mac := row["mac"]
+		if mac != "" {
+			mac, record.Err = utils.ParseMac(mac)
+		}
+		if record.Err == nil {
+			record.Device = &models.Device{
+				MAC:          mac,
+				IP:           row["ip"],
+				Hostname:     row["hostname"],
+				Vendor:       slugOf(row["vendor"]),
+				Model:        row["model"],
+				SerialNumber: row["serial_number"],
+				Site:         slugOf(row["site"]),
+				Role:         slugOf(row["role"]),
+				Tenant:       slugOf(row["tenant"]),
+				Platform:     slugOf(row["platform"]),
+				Status:       "offline",
+			}
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+// PushDevices is not supported for HTTP sources
+func (h *httpSource) PushDevices(devices []models.Device, vendors []models.Vendor) (*Result, error) {
+	return nil, ErrNotSupported
+}
+
+// ListVendors returns the distinct vendors named in the document
+func (h *httpSource) ListVendors() ([]models.Vendor, error) {
+	names, err := h.distinct("vendor")
+	if err != nil {
+		return nil, err
+	}
+	vendors := make([]models.Vendor, 0, len(names))
+	for _, name := range names {
+		vendors = append(vendors, models.Vendor{
+			ID:            Slugify(name),
+			Name:          name,
+			MacPrefixes:   []string{},
+			BackupCommand: "show running-config",
+			SSHPort:       22,
+		})
+	}
+	return vendors, nil
+}
+
+// ListSites returns the distinct sites named in the document
+func (h *httpSource) ListSites() ([]Site, error) {
+	names, err := h.distinct("site")
+	if err != nil {
+		return nil, err
+	}
+	sites := make([]Site, 0, len(names))
+	for _, name := range names {
+		slug := Slugify(name)
+		sites = append(sites, Site{ID: slug, Name: name, Slug: slug})
+	}
+	return sites, nil
+}
+
+// slugOf slugifies a name, leaving empty values empty
+func slugOf(name string) string {
+	if name == "" {
+		return ""
+	}
+	return Slugify(name)
+}
+
+// distinct returns the sorted non-empty values of a field
+func (h *httpSource) distinct(field string) ([]string, error) {
+	rows, err := h.rows()
+	if err != nil {
+		return nil, err
+	}
+	seen := map[string]bool{}
+	var values []string
+	for _, row := range rows {
+		if v := row[field]; v != "" && !seen[v] {
+			seen[v] = true
+			values = append(values, v)
+		}
+	}
+	sort.Strings(values)
+	return values, nil
+}
@@ -0,0 +1,250 @@
+package inventory
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/ztp-server/backend/db"
+	"github.com/ztp-server/backend/models"
+	"github.com/ztp-server/backend/utils"
+)
+
+func init() {
+	Register(models.InventoryNautobot, openNautobot)
+}
+
+// nautobot reads devices from a Nautobot 2.x instance. Nautobot is pull-only; pushing
+// devices returns ErrNotSupported.
+type nautobot struct {
+	baseURL string
+	client  *client
+}
+
+// nautobotRef is a nested object reference. With depth=1 it carries the object's name.
+type nautobotRef struct {
+	ID      string `json:"id"`
+	Name    string `json:"name"`
+	Display string `json:"display"`
+}
+
+type nautobotDevice struct {
+	ID         string       `json:"id"`
+	Name       string       `json:"name"`
+	Serial     string       `json:"serial"`
+	Status     *nautobotRef `json:"status"`
+	Role       *nautobotRef `json:"role"`
+	Tenant     *nautobotRef `json:"tenant"`
+	Platform   *nautobotRef `json:"platform"`
+	Location   *nautobotRef `json:"location"`
+	DeviceType *struct {
+		Manufacturer *nautobotRef `json:"manufacturer"`
+	} `json:"device_type"`
+	PrimaryIP4 *struct {
+		Address string `json:"address"`
+	} `json:"primary_ip4"`
+	CustomFields map[string]any `json:"custom_fields"`
+}
+
+type nautobotInterface struct {
+	Name       string       `json:"name"`
+	MacAddress string       `json:"mac_address"`
+	Device     *nautobotRef `json:"device"`
+}
+
+type nautobotLocation struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+func openNautobot(config *models.InventorySource, store *db.Store) (Source, error) {
+	if config.URL == "" || config.Token == "" {
+		return nil, fmt.Errorf("Nautobot URL and token are required")
+	}
+	return &nautobot{
+		baseURL: strings.TrimSuffix(config.URL, "/"),
+		client: newClient(map[string]string{
+			"Authorization": "Token " + config.Token,
+			"Accept":        "application/json",
+		}),
+	}, nil
+}
+
+// list follows Nautobot's pagination and decodes every result into out
+func (n *nautobot) list(path string, out func(json.RawMessage) error) error {
+	next := n.baseURL + path
+	for next != "" {
+		body, err := n.client.get(next)
+		if err != nil {
+			return err
+		}
+		var page struct {
+			Next    *string           `json:"next"`
+			Results []json.RawMessage `json:"results"`
+		}
+		if err := json.Unmarshal(body, &page); err != nil {
+			return fmt.Errorf("failed to decode response: %w", err)
+		}
+		for _, raw := range page.Results {
+			if err := out(raw); err != nil {
+				return fmt.Errorf("failed to decode result: %w", err)
+			}
+		}
+		next = ""
+		if page.Next != nil {
+			next = *page.Next
+		}
+	}
+	return nil
+}
+
+// Check tests the connection to Nautobot
+func (n *nautobot) Check() error {
+	_, err := n.client.get(n.baseURL + "/api/status/")
+	return err
+}
+
+// ListDevices returns all Nautobot devices as ZTP devices. The MAC is taken from the
+// device's management interface, any interface with a MAC, or a mac_address custom field.
+func (n *nautobot) ListDevices() ([]Record, error) {
+	manufacturers := map[string]string{} // ID -> name
+	if err := n.list("/api/dcim/manufacturers/?limit=100", func(raw json.RawMessage) error {
+		var ref nautobotRef
+		if err := json.Unmarshal(raw, &ref); err != nil {
+			return err
+		}
+		manufacturers[ref.ID] = ref.Name
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("failed to list Nautobot manufacturers: %w", err)
+	}
+
+	macs := map[string]string{} // Device ID -> MAC
+	if err := n.list("/api/dcim/interfaces/?limit=100", func(raw json.RawMessage) error {
+		var iface nautobotInterface
+		if err := json.Unmarshal(raw, &iface); err != nil {
+			return err
+		}
+		if iface.Device == nil || iface.MacAddress == "" {
+			return nil
+		}
+		if _, ok := macs[iface.Device.ID]; !ok || isManagementInterface(iface.Name) {
+			macs[iface.Device.ID] = iface.MacAddress
+		}
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("failed to list Nautobot interfaces: %w", err)
+	}
+
+	var records []Record
+	if err := n.list("/api/dcim/devices/?limit=100&depth=1", func(raw json.RawMessage) error {
+		var d nautobotDevice
+		if err := json.Unmarshal(raw, &d); err != nil {
+			return err
+		}
+		device, err := n.convert(&d, macs[d.ID], manufacturers)
+		records = append(records, Record{ExternalID: d.ID, Name: d.Name, Device: device, Err: err})
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("failed to list Nautobot devices: %w", err)
+	}
+	return records, nil
+}
+
+// convert maps a Nautobot device to a ZTP device
+func (n *nautobot) convert(d *nautobotDevice, mac string, manufacturers map[string]string) (*models.Device, error) {
+	if mac == "" {
+		if v, ok := d.CustomFields["mac_address"].(string); ok {
+			mac = v
+		}
+	}
+	if mac != "" {
+		parsed, err := utils.ParseMac(mac)
+		if err != nil {
+			return nil, err
+		}
+		mac = parsed
+	}
+
+	device := &models.Device{
+		MAC:          mac,
+		Hostname:     d.Name,
+		SerialNumber: d.Serial,
+		Status:       "offline",
+	}
+	if d.PrimaryIP4 != nil {
+		device.IP = strings.Split(d.PrimaryIP4.Address, "/")[0]
+	}
+	if d.Status != nil {
+		switch strings.ToLower(d.Status.Name) {
+		case "active":
+			device.Status = "online"
+		case "staged":
+			device.Status = "provisioning"
+		}
+	}
+	if d.DeviceType != nil && d.DeviceType.Manufacturer != nil {
+		if name := manufacturers[d.DeviceType.Manufacturer.ID]; name != "" {
+			device.Vendor = Slugify(name)
+		}
+	}
+	device.Site = refSlug(d.Location)
+	device.Role = refSlug(d.Role)
+	device.Tenant = refSlug(d.Tenant)
+	device.Platform = refSlug(d.Platform)
+	return device, nil
+}
+
+// PushDevices is not supported for Nautobot
+func (n *nautobot) PushDevices(devices []models.Device, vendors []models.Vendor) (*Result, error) {
+	return nil, ErrNotSupported
+}
+
+// ListVendors returns Nautobot manufacturers as ZTP vendors
+func (n *nautobot) ListVendors() ([]models.Vendor, error) {
+	var vendors []models.Vendor
+	err := n.list("/api/dcim/manufacturers/?limit=100", func(raw json.RawMessage) error {
+		var ref nautobotRef
+		if err := json.Unmarshal(raw, &ref); err != nil {
+			return err
+		}
+		vendors = append(vendors, models.Vendor{
+			ID:            Slugify(ref.Name),
+			Name:          ref.Name,
+			MacPrefixes:   []string{},
+			BackupCommand: "show running-config",
+			SSHPort:       22,
+		})
+		return nil
+	})
+	return vendors, err
+}
+
+// ListSites returns Nautobot locations
+func (n *nautobot) ListSites() ([]Site, error) {
+	var sites []Site
+	err := n.list("/api/dcim/locations/?limit=100", func(raw json.RawMessage) error {
+		var loc nautobotLocation
+		if err := json.Unmarshal(raw, &loc); err != nil {
+			return err
+		}
+		sites = append(sites, Site{ID: loc.ID, Name: loc.Name, Slug: Slugify(loc.Name), Description: loc.Description})
+		return nil
+	})
+	return sites, err
+}
+
+// refSlug returns the slug of a nested reference's name, or "" if unset
+func refSlug(ref *nautobotRef) string {
+	if ref == nil || ref.Name == "" {
+		return ""
+	}
+	return Slugify(ref.Name)
+}
+
+// isManagementInterface reports whether an interface name looks like a management port
+func isManagementInterface(name string) bool {
+	name = strings.ToLower(name)
+	return strings.HasPrefix(name, "mgmt") || strings.HasPrefix(name, "management") || strings.HasPrefix(name, "ma1") || name == "fxp0"
+}
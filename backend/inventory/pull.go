@@ -0,0 +1,244 @@
+package inventory
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/ztp-server/backend/db"
+	"github.com/ztp-server/backend/models"
+	"github.com/ztp-server/backend/validate"
+)
+
+// Pull plan actions
+const (
+	PullCreate    = "create"
+	PullUpdate    = "update"
+	PullUnchanged = "unchanged"
+	PullConflict  = "conflict" // Would clash with another ZTP device; never applied
+	PullSkip      = "skip"     // Can't be imported, e.g. no MAC or invalid data
+)
+
+// PullChange is a single field a pull would change
+type PullChange struct {
+	Field    string `json:"field"`
+	Current  string `json:"current"`
+	Incoming string `json:"incoming"`
+}
+
+// PullItem describes what pulling one source device would do
+type PullItem struct {
+	ExternalID string       `json:"external_id"`         // The device's ID in the source
+	NetBoxID   int          `json:"netbox_id,omitempty"` // Set for NetBox sources
+	MAC        string       `json:"mac,omitempty"`
+	Hostname   string       `json:"hostname"`
+	Action     string       `json:"action"`
+	Reason     string       `json:"reason,omitempty"` // Why a device conflicts or is skipped
+	Changes    []PullChange `json:"changes,omitempty"`
+	Applied    bool         `json:"applied"`
+	Error      string       `json:"error,omitempty"`
+
+	device *models.Device // Device as it will be stored
+}
+
+// PullSummary counts pull items by action
+type PullSummary struct {
+	Created   int `json:"created"`
+	Updated   int `json:"updated"`
+	Unchanged int `json:"unchanged"`
+	Conflicts int `json:"conflicts"`
+	Skipped   int `json:"skipped"`
+	Applied   int `json:"applied"`
+	Failed    int `json:"failed"`
+}
+
+// PlanPull compares the records read from a source with the ZTP inventory and describes
+// the result of pulling each, without changing anything. Existing devices are matched by
+// MAC; fields the source leaves empty keep their ZTP value, and ZTP-only fields such as
+// the config template and SSH credentials are never touched.
+func PlanPull(records []Record, devices []models.Device, settings *models.Settings) []PullItem {
+	byMAC := make(map[string]*models.Device, len(devices))
+	hostnames := make(map[string]string, len(devices)) // hostname -> MAC
+	ips := make(map[string]string, len(devices))       // IP -> MAC
+	for i := range devices {
+		byMAC[devices[i].MAC] = &devices[i]
+		hostnames[devices[i].Hostname] = devices[i].MAC
+		ips[devices[i].IP] = devices[i].MAC
+	}
+
+	items := make([]PullItem, 0, len(records))
+	seen := make(map[string]string) // MAC -> external ID that claimed it first
+	for _, record := range records {
+		item := PullItem{ExternalID: record.ExternalID, Hostname: record.Name}
+
+		if record.Err != nil || record.Device == nil {
+			item.Action, item.Reason = PullSkip, "device could not be read"
+			if record.Err != nil {
+				item.Reason = record.Err.Error()
+			}
+			items = append(items, item)
+			continue
+		}
+		incoming := record.Device
+		item.NetBoxID = incoming.NetBoxID
+		item.MAC = incoming.MAC
+		if incoming.MAC == "" {
+			item.Action, item.Reason = PullSkip, "no MAC address in source"
+			items = append(items, item)
+			continue
+		}
+		if other, dup := seen[incoming.MAC]; dup {
+			item.Action, item.Reason = PullConflict, fmt.Sprintf("MAC also used by source device %s", other)
+			items = append(items, item)
+			continue
+		}
+		seen[incoming.MAC] = record.ExternalID
+
+		existing := byMAC[incoming.MAC]
+		var device models.Device
+		if existing != nil {
+			device = *existing
+			item.Changes = mergePulled(&device, incoming)
+			if incoming.NetBoxID != 0 {
+				device.NetBoxID = incoming.NetBoxID
+			}
+		} else {
+			device = *incoming
+		}
+
+		switch {
+		case existing != nil && incoming.NetBoxID != 0 && existing.NetBoxID != 0 && existing.NetBoxID != incoming.NetBoxID:
+			item.Action, item.Reason = PullConflict, fmt.Sprintf("ZTP device is linked to NetBox device %d", existing.NetBoxID)
+		case hostnames[device.Hostname] != "" && hostnames[device.Hostname] != device.MAC:
+			item.Action, item.Reason = PullConflict, fmt.Sprintf("hostname %s is used by %s", device.Hostname, hostnames[device.Hostname])
+		case device.IP != "" && ips[device.IP] != "" && ips[device.IP] != device.MAC:
+			item.Action, item.Reason = PullConflict, fmt.Sprintf("IP %s is used by %s", device.IP, ips[device.IP])
+		default:
+			if errs := validate.Device(&device, settings); len(errs) > 0 {
+				item.Action, item.Reason = PullSkip, errs.Error()
+			} else if existing == nil {
+				item.Action = PullCreate
+			} else if len(item.Changes) > 0 || existing.NetBoxID != device.NetBoxID {
+				item.Action = PullUpdate
+			} else {
+				item.Action = PullUnchanged
+			}
+		}
+
+		if item.Action == PullCreate || item.Action == PullUpdate {
+			// Later source devices must not claim the same hostname or IP
+			hostnames[device.Hostname] = device.MAC
+			ips[device.IP] = device.MAC
+		}
+		item.device = &device
+		items = append(items, item)
+	}
+
+	return items
+}
+
+// ApplyPull stores the create and update items of a plan. If macs is non-empty only those
+// devices are applied. It reports whether any ZTP device changed.
+func ApplyPull(store *db.Store, items []PullItem, macs []string) bool {
+	selected := make(map[string]bool, len(macs))
+	for _, mac := range macs {
+		selected[mac] = true
+	}
+
+	changed := false
+	for i := range items {
+		item := &items[i]
+		if item.Action != PullCreate && item.Action != PullUpdate {
+			continue
+		}
+		if len(selected) > 0 && !selected[item.MAC] {
+			continue
+		}
+
+		var err error
+		if item.Action == PullCreate {
+			err = store.CreateDevice(item.device)
+		} else if err = store.UpdateDevice(item.device); err == nil && item.NetBoxID != 0 {
+			err = store.SetDeviceNetBoxID(item.MAC, item.NetBoxID)
+		}
+		if err != nil {
+			item.Error = err.Error()
+			continue
+		}
+		item.Applied = true
+		changed = true
+	}
+	return changed
+}
+
+// SummarizePull counts a plan's items by action and outcome
+func SummarizePull(items []PullItem) PullSummary {
+	var summary PullSummary
+	for _, item := range items {
+		switch item.Action {
+		case PullCreate:
+			summary.Created++
+		case PullUpdate:
+			summary.Updated++
+		case PullUnchanged:
+			summary.Unchanged++
+		case PullConflict:
+			summary.Conflicts++
+		case PullSkip:
+			summary.Skipped++
+		}
+		if item.Applied {
+			summary.Applied++
+		}
+		if item.Error != "" {
+			summary.Failed++
+		}
+	}
+	return summary
+}
+
+// mergePulled copies the non-empty source values onto a ZTP device and lists what changed
+func mergePulled(device, incoming *models.Device) []PullChange {
+	var changes []PullChange
+	for _, f := range []struct {
+		field    string
+		current  *string
+		incoming string
+	}{
+		{"ip", &device.IP, incoming.IP},
+		{"hostname", &device.Hostname, incoming.Hostname},
+		{"serial_number", &device.SerialNumber, incoming.SerialNumber},
+		{"vendor", &device.Vendor, incoming.Vendor},
+		{"site", &device.Site, incoming.Site},
+		{"role", &device.Role, incoming.Role},
+		{"tenant", &device.Tenant, incoming.Tenant},
+		{"platform", &device.Platform, incoming.Platform},
+	} {
+		if f.incoming == "" || f.incoming == *f.current {
+			continue
+		}
+		if f.field == "vendor" && Slugify(f.incoming) == Slugify(*f.current) {
+			continue
+		}
+		changes = append(changes, PullChange{Field: f.field, Current: *f.current, Incoming: f.incoming})
+		*f.current = f.incoming
+	}
+	return changes
+}
+
+var (
+	slugInvalid = regexp.MustCompile(`[^a-z0-9-]+`)
+	slugDashes  = regexp.MustCompile(`-+`)
+)
+
+// Slugify converts a name to the lowercase slug form used for vendors, sites and roles
+func Slugify(s string) string {
+	s = strings.ToLower(s)
+	s = slugInvalid.ReplaceAllString(s, "-")
+	s = slugDashes.ReplaceAllString(s, "-")
+	s = strings.Trim(s, "-")
+	if s == "" {
+		s = "unknown"
+	}
+	return s
+}
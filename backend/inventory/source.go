@@ -0,0 +1,146 @@
+package inventory
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/ztp-server/backend/db"
+	"github.com/ztp-server/backend/models"
+)
+
+// BuiltinID is the ID of the NetBox source backed by the NetBox integration settings
+const BuiltinID = "netbox"
+
+// ErrNotSupported is returned by sources that can't perform an operation, e.g. pushing
+// to a read-only HTTP export
+var ErrNotSupported = errors.New("operation not supported by this inventory source")
+
+// Source is an external inventory (the InventorySource interface) that ZTP devices can
+// be pulled from and pushed to. NetBox is the first implementation; Nautobot and generic
+// JSON/CSV-over-HTTP sources are built in, and further types register via Register.
+type Source interface {
+	// Check tests connectivity and credentials
+	Check() error
+	// ListDevices returns every device the source knows, converted to ZTP devices
+	ListDevices() ([]Record, error)
+	// PushDevices creates or updates the given ZTP devices in the source
+	PushDevices(devices []models.Device, vendors []models.Vendor) (*Result, error)
+	// ListVendors returns the source's manufacturers as ZTP vendors
+	ListVendors() ([]models.Vendor, error)
+	// ListSites returns the source's sites or locations
+	ListSites() ([]Site, error)
+}
+
+// Record is one device read from a source. Err is set if the device couldn't be
+// converted, in which case Device may be nil.
+type Record struct {
+	ExternalID string         `json:"external_id"` // The device's ID in the source
+	Name       string         `json:"name"`
+	Device     *models.Device `json:"device,omitempty"`
+	Err        error          `json:"-"`
+}
+
+// Site is a site or location in a source
+type Site struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Slug        string `json:"slug"`
+	Description string `json:"description,omitempty"`
+}
+
+// Result contains the results of a push
+type Result struct {
+	Created int      `json:"created"`
+	Updated int      `json:"updated"`
+	Skipped int      `json:"skipped"`
+	Errors  []string `json:"errors,omitempty"`
+}
+
+// Factory opens a source from its configuration
+type Factory func(config *models.InventorySource, store *db.Store) (Source, error)
+
+var (
+	factoriesMu sync.RWMutex
+	factories   = map[string]Factory{}
+)
+
+// Register makes a source type available. It panics if the type is registered twice.
+func Register(sourceType string, factory Factory) {
+	factoriesMu.Lock()
+	defer factoriesMu.Unlock()
+	if _, dup := factories[sourceType]; dup {
+		panic("inventory: source type registered twice: " + sourceType)
+	}
+	factories[sourceType] = factory
+}
+
+// Types returns the registered source types in sorted order
+func Types() []string {
+	factoriesMu.RLock()
+	defer factoriesMu.RUnlock()
+	types := make([]string, 0, len(factories))
+	for t := range factories {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+	return types
+}
+
+// Supported reports whether a source type is registered
+func Supported(sourceType string) bool {
+	factoriesMu.RLock()
+	defer factoriesMu.RUnlock()
+	_, ok := factories[sourceType]
+	return ok
+}
+
+// Open creates a source from its configuration
+func Open(config *models.InventorySource, store *db.Store) (Source, error) {
+	factoriesMu.RLock()
+	factory, ok := factories[config.Type]
+	factoriesMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown inventory source type: %s", config.Type)
+	}
+	return factory(config, store)
+}
+
+// Builtin returns the NetBox source configured through the NetBox integration settings
+func Builtin(store *db.Store) (*models.InventorySource, error) {
+	config, err := store.GetNetBoxConfig()
+	if err != nil {
+		return nil, err
+	}
+	return &models.InventorySource{
+		ID:      BuiltinID,
+		Name:    "NetBox",
+		Type:    models.InventoryNetBox,
+		Enabled: config.URL != "" && config.Token != "",
+		URL:     config.URL,
+		Token:   config.Token,
+		Builtin: true,
+	}, nil
+}
+
+// Sources returns the built-in NetBox source followed by the configured sources
+func Sources(store *db.Store) ([]models.InventorySource, error) {
+	builtin, err := Builtin(store)
+	if err != nil {
+		return nil, err
+	}
+	configured, err := store.ListInventorySources()
+	if err != nil {
+		return nil, err
+	}
+	return append([]models.InventorySource{*builtin}, configured...), nil
+}
+
+// Get returns a source's configuration by ID, or nil if it doesn't exist
+func Get(store *db.Store, id string) (*models.InventorySource, error) {
+	if id == BuiltinID {
+		return Builtin(store)
+	}
+	return store.GetInventorySource(id)
+}
@@ -0,0 +1,137 @@
+package inventory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/ztp-server/backend/db"
+	"github.com/ztp-server/backend/jobs"
+	"github.com/ztp-server/backend/models"
+)
+
+// Sync directions
+const (
+	DirectionPull = "pull"
+	DirectionPush = "push"
+)
+
+var (
+	// ErrSourceNotFound is returned when syncing with an unknown source
+	ErrSourceNotFound = errors.New("inventory source not found")
+	// ErrSourceUnusable is returned when a source is disabled or can't be opened
+	ErrSourceUnusable = errors.New("inventory source unusable")
+)
+
+// SyncRequest selects the source and direction of a sync
+type SyncRequest struct {
+	Source    string   `json:"source"`    // Source ID, BuiltinID if empty
+	Direction string   `json:"direction"` // pull or push
+	DryRun    bool     `json:"dry_run"`   // Pull only: report what would change
+	MACs      []string `json:"macs"`      // Only these devices
+}
+
+// SyncResult is the outcome of a sync. Pulls fill in the plan, pushes the push result.
+type SyncResult struct {
+	Source    string       `json:"source"`
+	Direction string       `json:"direction"`
+	DryRun    bool         `json:"dry_run"`
+	Summary   *PullSummary `json:"summary,omitempty"`
+	Devices   []PullItem   `json:"devices,omitempty"`
+	Result    *Result      `json:"result,omitempty"`
+	Changed   bool         `json:"-"` // ZTP devices were modified
+}
+
+// Sync pulls devices from or pushes devices to a source. Pulls use PlanPull, so they
+// are idempotent and can be previewed with DryRun.
+func Sync(store *db.Store, req SyncRequest) (*SyncResult, error) {
+	if req.Source == "" {
+		req.Source = BuiltinID
+	}
+	if req.Direction != DirectionPull && req.Direction != DirectionPush {
+		return nil, fmt.Errorf("direction must be pull or push")
+	}
+
+	src, err := Get(store, req.Source)
+	if err != nil {
+		return nil, err
+	}
+	if src == nil {
+		return nil, ErrSourceNotFound
+	}
+	if !src.Enabled {
+		return nil, fmt.Errorf("%w: %s is disabled", ErrSourceUnusable, src.ID)
+	}
+	source, err := Open(src, store)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrSourceUnusable, err)
+	}
+
+	devices, err := store.ListDevices()
+	if err != nil {
+		return nil, err
+	}
+
+	result := &SyncResult{Source: src.ID, Direction: req.Direction, DryRun: req.DryRun}
+	if req.Direction == DirectionPush {
+		vendors, err := store.ListVendors()
+		if err != nil {
+			return nil, err
+		}
+		result.Result, err = source.PushDevices(selectDevices(devices, req.MACs), vendors)
+		return result, err
+	}
+
+	records, err := source.ListDevices()
+	if err != nil {
+		return nil, err
+	}
+	settings, _ := store.GetSettings()
+	result.Devices = PlanPull(records, devices, settings)
+	if !req.DryRun {
+		result.Changed = ApplyPull(store, result.Devices, req.MACs)
+	}
+	summary := SummarizePull(result.Devices)
+	result.Summary = &summary
+	return result, nil
+}
+
+// RegisterJobs registers the inventory.sync job handler. onChange is called when a
+// pull modified ZTP devices.
+func RegisterJobs(queue *jobs.Queue, store *db.Store, onChange func() error) {
+	queue.Register(jobs.TypeInventorySync, 1, func(ctx context.Context, job *models.Job) (any, error) {
+		var req SyncRequest
+		if err := jobs.Decode(job, &req); err != nil {
+			return nil, err
+		}
+		result, err := Sync(store, req)
+		if errors.Is(err, ErrSourceNotFound) || errors.Is(err, ErrSourceUnusable) || errors.Is(err, ErrNotSupported) {
+			return nil, jobs.Permanent(err)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if result.Changed && onChange != nil {
+			onChange()
+		}
+		return result, nil
+	})
+}
+
+// selectDevices returns the devices with the given MACs, or all devices if macs is empty
+func selectDevices(devices []models.Device, macs []string) []models.Device {
+	if len(macs) == 0 {
+		return devices
+	}
+	selected := make(map[string]bool, len(macs))
+	for _, mac := range macs {
+		selected[mac] = true
+	}
+	var filtered []models.Device
+	for _, device := range devices {
+		if selected[device.MAC] {
+			filtered = append(filtered, device)
+		}
+	}
+	return filtered
+}
@@ -0,0 +1,414 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ztp-server/backend/db"
+	"github.com/ztp-server/backend/models"
+)
+
+// Job types
+const (
+	TypeBackup        = "backup"
+	TypeNetBoxSync    = "netbox.sync"
+	TypeInventorySync = "inventory.sync"
+	TypeFactsCollect  = "facts.collect"
+	TypeConfigApply   = "config.apply"
+)
+
+const (
+	pollInterval   = time.Second
+	retryBaseDelay = 10 * time.Second
+	retryMaxDelay  = 10 * time.Minute
+	retention      = 7 * 24 * time.Hour // Finished jobs are pruned after this long
+)
+
+// Handler runs a job. The returned value is stored as the job's JSON result. The context
+// is cancelled when the job is cancelled or the server shuts down.
+type Handler func(ctx context.Context, job *models.Job) (any, error)
+
+// Options control how a job is queued
+type Options struct {
+	Delay       time.Duration // Run no earlier than this from now
+	MaxAttempts int           // Attempts before the job fails, 1 if unset
+	Key         string        // Coalesce with a pending job of the same type and key
+}
+
+// ErrNotRunning is returned when cancelling a job that is neither pending nor running
+var ErrNotRunning = errors.New("job is not pending or running")
+
+type permanentError struct{ err error }
+
+func (e permanentError) Error() string { return e.err.Error() }
+func (e permanentError) Unwrap() error { return e.err }
+
+// Permanent marks an error as not worth retrying
+func Permanent(err error) error {
+	return permanentError{err}
+}
+
+type deferError struct{ delay time.Duration }
+
+func (e deferError) Error() string { return fmt.Sprintf("deferred for %s", e.delay) }
+
+// Defer puts a job back in the queue to run after the delay, without counting the
+// attempt. Handlers use it when a resource limit stops the job from starting now.
+func Defer(delay time.Duration) error {
+	return deferError{delay}
+}
+
+type worker struct {
+	handler     Handler
+	concurrency int
+	active      int
+	resumable   bool
+	interrupted func(job *models.Job)
+}
+
+type runningJob struct {
+	cancel    context.CancelFunc
+	cancelled bool // By request, as opposed to shutdown
+}
+
+// Queue runs jobs stored in SQLite, so delayed and queued work survives restarts. Each
+// job type has its own handler and concurrency limit; failed jobs are retried with
+// exponential backoff until they run out of attempts.
+type Queue struct {
+	store *db.Store
+	wake  chan struct{}
+	stop  chan struct{}
+	wg    sync.WaitGroup
+	ctx   context.Context
+	halt  context.CancelFunc
+
+	mu      sync.Mutex // Guards the fields below and serializes enqueues
+	workers map[string]*worker
+	running map[int64]*runningJob
+}
+
+// NewQueue creates a new job queue. Register handlers before calling Start.
+func NewQueue(store *db.Store) *Queue {
+	ctx, halt := context.WithCancel(context.Background())
+	return &Queue{
+		store:   store,
+		wake:    make(chan struct{}, 1),
+		stop:    make(chan struct{}),
+		ctx:     ctx,
+		halt:    halt,
+		workers: make(map[string]*worker),
+		running: make(map[int64]*runningJob),
+	}
+}
+
+// Register sets the handler and the number of jobs of a type that may run at once
+func (q *Queue) Register(jobType string, concurrency int, handler Handler) {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.workers[jobType] = &worker{handler: handler, concurrency: concurrency, resumable: true}
+}
+
+// NotResumable marks a registered job type as unsafe to run twice, such as one that
+// changes a device. Its jobs interrupted by a shutdown or crash fail instead of running
+// again, and interrupted, if set, is called with each one left running at the last
+// shutdown so its owner can record the outcome.
+func (q *Queue) NotResumable(jobType string, interrupted func(job *models.Job)) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if w, ok := q.workers[jobType]; ok {
+		w.resumable = false
+		w.interrupted = interrupted
+	}
+}
+
+// SetConcurrency changes how many jobs of a registered type may run at once. Running
+// jobs above a lowered limit are left to finish.
+func (q *Queue) SetConcurrency(jobType string, concurrency int) {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	q.mu.Lock()
+	w, ok := q.workers[jobType]
+	if ok {
+		w.concurrency = concurrency
+	}
+	q.mu.Unlock()
+	if ok {
+		q.poke()
+	}
+}
+
+// Types returns the registered job types
+func (q *Queue) Types() []string {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	types := make([]string, 0, len(q.workers))
+	for t := range q.workers {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+	return types
+}
+
+// Start requeues jobs interrupted by the last shutdown and begins dispatching. Those
+// of non-resumable types fail instead.
+func (q *Queue) Start() {
+	q.failInterrupted()
+	if n, err := q.store.RequeueRunningJobs(); err != nil {
+		log.Printf("[jobs] Failed to requeue interrupted jobs: %v", err)
+	} else if n > 0 {
+		log.Printf("[jobs] Requeued %d interrupted job(s)", n)
+	}
+	q.prune()
+
+	q.wg.Add(1)
+	go q.run()
+	log.Println("[jobs] Queue started")
+}
+
+// Stop stops dispatching, interrupts running jobs and waits for them. Interrupted jobs
+// run again on the next start, unless their type is not resumable.
+func (q *Queue) Stop() {
+	close(q.stop)
+	q.halt()
+	q.wg.Wait()
+	log.Println("[jobs] Queue stopped")
+}
+
+// Enqueue adds a job. The payload is stored as JSON and passed back to the handler.
+func (q *Queue) Enqueue(jobType string, payload any, opts Options) (*models.Job, error) {
+	job := &models.Job{
+		Type:        jobType,
+		Key:         opts.Key,
+		MaxAttempts: opts.MaxAttempts,
+		RunAt:       time.Now().Add(opts.Delay),
+	}
+	if payload != nil {
+		data, err := json.Marshal(payload)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode payload: %w", err)
+		}
+		job.Payload = data
+	}
+
+	q.mu.Lock()
+	job, err := q.store.CreateJob(job)
+	q.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	q.poke()
+	return job, nil
+}
+
+// Cancel cancels a pending job, or interrupts a running one
+func (q *Queue) Cancel(id int64) error {
+	cancelled, err := q.store.CancelPendingJob(id)
+	if err != nil || cancelled {
+		return err
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	r, ok := q.running[id]
+	if !ok {
+		return ErrNotRunning
+	}
+	r.cancelled = true
+	r.cancel()
+	return nil
+}
+
+// Retry queues a failed or cancelled job again
+func (q *Queue) Retry(id int64) error {
+	ok, err := q.store.RetryJob(id)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("only failed or cancelled jobs can be retried")
+	}
+	q.poke()
+	return nil
+}
+
+// poke wakes the dispatcher without waiting for the next poll
+func (q *Queue) poke() {
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (q *Queue) run() {
+	defer q.wg.Done()
+
+	poll := time.NewTicker(pollInterval)
+	defer poll.Stop()
+	prune := time.NewTicker(time.Hour)
+	defer prune.Stop()
+
+	for {
+		q.dispatch()
+		select {
+		case <-q.wake:
+		case <-poll.C:
+		case <-prune.C:
+			q.prune()
+		case <-q.stop:
+			return
+		}
+	}
+}
+
+// dispatch starts due jobs for every type with a free slot
+func (q *Queue) dispatch() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for jobType, w := range q.workers {
+		for w.active < w.concurrency {
+			job, err := q.store.ClaimJob(jobType)
+			if err != nil {
+				log.Printf("[jobs] Failed to claim %s job: %v", jobType, err)
+				break
+			}
+			if job == nil {
+				break
+			}
+
+			ctx, cancel := context.WithCancel(q.ctx)
+			q.running[job.ID] = &runningJob{cancel: cancel}
+			w.active++
+			q.wg.Add(1)
+			go q.execute(ctx, w, job)
+		}
+	}
+}
+
+func (q *Queue) execute(ctx context.Context, w *worker, job *models.Job) {
+	defer q.wg.Done()
+
+	result, err := q.call(ctx, w.handler, job)
+
+	q.mu.Lock()
+	r := q.running[job.ID]
+	delete(q.running, job.ID)
+	w.active--
+	q.mu.Unlock()
+	r.cancel()
+
+	q.finish(w, job, r.cancelled, result, err)
+	q.poke()
+}
+
+// call runs the handler, turning a panic into an error
+func (q *Queue) call(ctx context.Context, handler Handler, job *models.Job) (result any, err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = Permanent(fmt.Errorf("panic: %v", p))
+		}
+	}()
+	return handler(ctx, job)
+}
+
+// finish records a job's outcome, scheduling a retry if attempts remain
+func (q *Queue) finish(w *worker, job *models.Job, cancelled bool, result any, err error) {
+	var storeErr error
+	var deferred deferError
+	switch {
+	case cancelled:
+		storeErr = q.store.FinishJob(job.ID, models.JobCancelled, nil, "cancelled")
+	case errors.As(err, &deferred):
+		storeErr = q.store.RescheduleJob(job.ID, time.Now().Add(deferred.delay), job.Error, true)
+	case err != nil && q.ctx.Err() != nil && w.resumable:
+		// Interrupted by shutdown; run again after restart
+		storeErr = q.store.RescheduleJob(job.ID, time.Now(), err.Error(), true)
+	case err != nil:
+		var permanent permanentError
+		if job.Attempts < job.MaxAttempts && !errors.As(err, &permanent) {
+			delay := backoff(job.Attempts)
+			log.Printf("[jobs] %s job %d failed (attempt %d/%d), retrying in %s: %v", job.Type, job.ID, job.Attempts, job.MaxAttempts, delay, err)
+			storeErr = q.store.RescheduleJob(job.ID, time.Now().Add(delay), err.Error(), false)
+		} else {
+			log.Printf("[jobs] %s job %d failed: %v", job.Type, job.ID, err)
+			storeErr = q.store.FinishJob(job.ID, models.JobFailed, nil, err.Error())
+		}
+	default:
+		var data []byte
+		if result != nil {
+			data, _ = json.Marshal(result)
+		}
+		storeErr = q.store.FinishJob(job.ID, models.JobSucceeded, data, "")
+	}
+	if storeErr != nil {
+		log.Printf("[jobs] Failed to record result of job %d: %v", job.ID, storeErr)
+	}
+}
+
+// failInterrupted fails the jobs of non-resumable types left running by the last process
+func (q *Queue) failInterrupted() {
+	q.mu.Lock()
+	workers := make(map[string]*worker)
+	for t, w := range q.workers {
+		if !w.resumable {
+			workers[t] = w
+		}
+	}
+	q.mu.Unlock()
+
+	for t, w := range workers {
+		jobs, err := q.store.FailRunningJobs(t, "interrupted by a server restart")
+		if err != nil {
+			log.Printf("[jobs] Failed to fail interrupted %s jobs: %v", t, err)
+			continue
+		}
+		for i := range jobs {
+			log.Printf("[jobs] %s job %d was interrupted by a restart and won't run again", t, jobs[i].ID)
+			if w.interrupted != nil {
+				w.interrupted(&jobs[i])
+			}
+		}
+	}
+}
+
+func (q *Queue) prune() {
+	if n, err := q.store.PruneJobs(time.Now().Add(-retention)); err != nil {
+		log.Printf("[jobs] Failed to prune jobs: %v", err)
+	} else if n > 0 {
+		log.Printf("[jobs] Pruned %d finished job(s)", n)
+	}
+}
+
+// backoff returns the delay before retrying after the given attempt
+func backoff(attempt int) time.Duration {
+	delay := retryBaseDelay
+	for i := 1; i < attempt && delay < retryMaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > retryMaxDelay {
+		delay = retryMaxDelay
+	}
+	return delay
+}
+
+// Decode unmarshals a job's payload
+func Decode(job *models.Job, v any) error {
+	if len(job.Payload) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(job.Payload, v); err != nil {
+		return Permanent(fmt.Errorf("invalid payload: %w", err))
+	}
+	return nil
+}
@@ -1,8 +1,10 @@
 package main
 
 import (
+	"crypto/tls"
 	"fmt"
 	"log"
+	"net/http"
 	"os"
 	"os/exec"
 	"os/signal"
@@ -12,11 +14,20 @@ import (
 	"github.com/gin-gonic/gin"
 
 	"github.com/ztp-server/backend/backup"
+	"github.com/ztp-server/backend/bootstrap"
+	"github.com/ztp-server/backend/certs"
+	"github.com/ztp-server/backend/changes"
 	"github.com/ztp-server/backend/config"
 	"github.com/ztp-server/backend/db"
 	"github.com/ztp-server/backend/dhcp"
+	"github.com/ztp-server/backend/facts"
 	"github.com/ztp-server/backend/handlers"
+	"github.com/ztp-server/backend/images"
+	"github.com/ztp-server/backend/inventory"
+	"github.com/ztp-server/backend/jobs"
 	"github.com/ztp-server/backend/models"
+	"github.com/ztp-server/backend/netbox"
+	"github.com/ztp-server/backend/pnp"
 	"github.com/ztp-server/backend/status"
 	"github.com/ztp-server/backend/ws"
 )
@@ -34,15 +45,58 @@ func main() {
 	// Initialize DHCP config manager
 	configMgr := dhcp.NewConfigManager(store, cfg.DnsmasqConfig, cfg.TFTPDir, cfg.TemplatesDir, cfg.DnsmasqPID, cfg.DHCPInterface, cfg.LeasePath)
 
-	// Initialize backup service
-	backupSvc := backup.NewService(store, cfg.BackupDir)
-	backupSvc.Start()
-	defer backupSvc.Stop()
+	// Image repository, served from the TFTP root and over HTTP
+	imageSvc := images.NewService(store, cfg.TFTPDir, cfg.ListenAddr)
+	configMgr.SetImages(imageSvc)
+	configMgr.SetHTTPAddr(cfg.ListenAddr)
+
+	// Optional HTTPS listener, so configs and their secrets aren't sent in the clear
+	var tlsConfig *tls.Config
+	var authority *certs.Authority
+	if cfg.TLSEnabled() {
+		if cfg.TLSSelfSigned {
+			authority, err = certs.NewAuthority(cfg.TLSDir)
+			if err == nil {
+				tlsConfig = authority.TLSConfig()
+			}
+		} else {
+			tlsConfig, err = certs.Load(cfg.TLSCertFile, cfg.TLSKeyFile)
+		}
+		if err != nil {
+			log.Fatalf("Failed to set up TLS: %v", err)
+		}
+		configMgr.SetTLS(cfg.TLSListenAddr, authority != nil)
+	}
 
 	// Initialize WebSocket hub for real-time notifications
 	wsHub := ws.NewHub()
 	go wsHub.Run()
 
+	// All config regeneration goes through the reloader, which coalesces bursts of changes
+	// into one regeneration and never runs two at once
+	reloader := dhcp.NewReloader(configMgr.GenerateConfig, dhcp.DefaultReloadDebounce)
+	reloader.SetLiveHook(func(status models.ReloadStatus) {
+		wsHub.BroadcastConfigReloaded(ws.ConfigReloadedPayload{Generation: status.Generation, DurationMS: status.LastDurationMS})
+	})
+	reloader.Start()
+	defer reloader.Stop()
+	reloadConfig := reloader.Request
+
+	// Initialize the persistent job queue
+	jobQueue := jobs.NewQueue(store)
+	inventory.RegisterJobs(jobQueue, store, reloadConfig)
+
+	// Initialize backup service
+	backupSvc := backup.NewService(store, cfg.BackupDir, jobQueue, wsHub)
+
+	// Collect device facts once provisioned devices come up
+	factsSvc := facts.NewService(store, jobQueue, wsHub)
+	backupSvc.SetProvisionedHook(factsSvc.OnProvisioned)
+	factsSvc.SetCollectedHook(imageSvc.OnFactsCollected)
+
+	// Push day-2 config changes through the vendor drivers
+	changeSvc := changes.NewService(store, backupSvc, jobQueue, configMgr.RenderDeviceConfig)
+
 	// Create WebSocket callback for lease notifications
 	wsLeaseCallback := func(lease *models.Lease) {
 		wsHub.BroadcastDeviceDiscovered(lease.MAC, lease.IP, lease.Hostname, "")
@@ -54,15 +108,38 @@ func main() {
 		device, _ := store.GetDevice(lease.MAC)
 		eventType := "discovered"
 		message := "New device detected via DHCP"
+		vendorID := ""
 		if device != nil {
 			eventType = "lease_renewed"
 			message = "DHCP lease renewed for configured device"
+			vendorID = device.Vendor
+		} else {
+			// Unknown devices land in the approval queue
+			if vendor, _ := store.FindVendorByMAC(lease.MAC); vendor != nil {
+				vendorID = vendor.ID
+			}
+			hostname := lease.Hostname
+			if hostname == "*" {
+				hostname = ""
+			}
+			if pending, _ := store.GetPendingDevice(lease.MAC); pending != nil && pending.Status == models.PendingStatusRejected {
+				message = "DHCP lease renewed for rejected device"
+			}
+			if err := store.UpsertPendingDevice(&models.PendingDevice{
+				MAC:      lease.MAC,
+				IP:       lease.IP,
+				Hostname: hostname,
+				Vendor:   vendorID,
+			}); err != nil {
+				log.Printf("Failed to queue pending device %s: %v", lease.MAC, err)
+			}
 		}
 		logEntry := &models.DiscoveryLog{
 			EventType: eventType,
 			MAC:       lease.MAC,
 			IP:        lease.IP,
 			Hostname:  lease.Hostname,
+			Vendor:    vendorID,
 			Message:   message,
 		}
 		store.CreateDiscoveryLog(logEntry)
@@ -78,8 +155,17 @@ func main() {
 	statusChecker.Start()
 	defer statusChecker.Stop()
 
+	// Initialize NetBox reconciler to keep devices in sync on an interval and on webhooks
+	netboxReconciler := netbox.NewReconciler(store, reloadConfig, jobQueue)
+	netboxReconciler.Start()
+	defer netboxReconciler.Stop()
+
+	// Start running jobs once every job type has its handler
+	jobQueue.Start()
+	defer jobQueue.Stop()
+
 	// Generate initial config
-	if err := configMgr.GenerateConfig(); err != nil {
+	if err := reloader.Reload(); err != nil {
 		log.Printf("Warning: failed to generate initial config: %v", err)
 	}
 
@@ -88,21 +174,36 @@ func main() {
 		log.Printf("Warning: failed to start dnsmasq: %v", err)
 	}
 
+	imageHandler := handlers.NewImageHandler(store, imageSvc, reloadConfig)
+
+	// Cisco Plug and Play server for IOS-XE agents
+	pnpHandler := handlers.NewPnPHandler(store, pnp.NewServer(store, imageSvc, configMgr.RenderDeviceConfig, configMgr.ConfigURL))
+
 	// Setup router
 	router := gin.Default()
+	// Devices are identified by their address, so never take it from X-Forwarded-For
+	if err := router.SetTrustedProxies(nil); err != nil {
+		log.Fatalf("Failed to set trusted proxies: %v", err)
+	}
 	router.Use(corsMiddleware())
 
 	// API routes
 	api := router.Group("/api")
 	{
-		handlers.NewDeviceHandler(store, configMgr.GenerateConfig, cfg.TFTPDir).RegisterRoutes(api)
-		handlers.NewSettingsHandler(store, configMgr.GenerateConfig).RegisterRoutes(api)
-		handlers.NewBackupHandler(store, backupSvc.TriggerBackup, cfg.BackupDir).RegisterRoutes(api)
-		handlers.NewVendorHandler(store).RegisterRoutes(api)
-		handlers.NewDhcpOptionHandler(store, configMgr.GenerateConfig).RegisterRoutes(api)
-		handlers.NewTemplateHandler(store, configMgr.GenerateConfig).RegisterRoutes(api)
-		handlers.NewDiscoveryHandler(store, cfg.LeasePath, leaseWatcher.ClearKnownMACs).RegisterRoutes(api)
-		handlers.NewNetBoxHandler(store).RegisterRoutes(api)
+		handlers.NewDeviceHandler(store, reloadConfig, configMgr.DeviceConfig).RegisterRoutes(api)
+		handlers.NewSettingsHandler(store, reloader).RegisterRoutes(api)
+		handlers.NewBackupHandler(store, backupSvc, cfg.BackupDir).RegisterRoutes(api)
+		handlers.NewVendorHandler(store, reloadConfig).RegisterRoutes(api)
+		handlers.NewDhcpOptionHandler(store, reloadConfig).RegisterRoutes(api)
+		handlers.NewTemplateHandler(store, reloadConfig, configMgr.NetBoxContext(), imageSvc, configMgr.PreviewContexts).RegisterRoutes(api)
+		handlers.NewDiscoveryHandler(store, cfg.LeasePath, leaseWatcher.ClearKnownMACs, reloadConfig).RegisterRoutes(api)
+		handlers.NewNetBoxHandler(store, reloadConfig, netboxReconciler, configMgr.NetBoxContext()).RegisterRoutes(api)
+		handlers.NewInventoryHandler(store, reloadConfig, jobQueue).RegisterRoutes(api)
+		handlers.NewJobHandler(store, jobQueue).RegisterRoutes(api)
+		handlers.NewFactsHandler(store, factsSvc).RegisterRoutes(api)
+		handlers.NewChangeHandler(store, changeSvc).RegisterRoutes(api)
+		imageHandler.RegisterRoutes(api)
+		pnpHandler.RegisterRoutes(api)
 
 		// WebSocket handler for real-time notifications
 		ws.NewHandler(wsHub).RegisterRoutes(api)
@@ -116,7 +217,26 @@ func main() {
 	}
 
 	// HTTP config server - serves generated device configs with WebSocket notifications
-	handlers.NewConfigServerHandler(store, wsHub, cfg.TFTPDir).RegisterRoutes(router)
+	handlers.NewConfigServerHandler(store, wsHub, cfg.TFTPDir, configMgr.DeviceConfig).RegisterRoutes(router)
+
+	// Bootstrap scripts for vendor-native ZTP
+	handlers.NewBootstrapHandler(store, wsHub, configMgr.RenderDeviceConfig).RegisterRoutes(router)
+
+	// Signed per-device callbacks reporting provisioning status
+	handlers.NewCallbackHandler(store, wsHub, backupSvc.TriggerBackup).RegisterRoutes(router)
+
+	// Cisco PnP protocol endpoints
+	pnpHandler.RegisterProtocolRoutes(router)
+
+	// HTTP image server - serves repository images and tracks device upgrades
+	imageHandler.RegisterFileRoutes(router)
+
+	// The self-signed CA, for devices and scripts to trust the HTTPS listener
+	if authority != nil {
+		router.GET(bootstrap.CAPath, func(c *gin.Context) {
+			c.Data(http.StatusOK, "application/x-pem-file", authority.CertPEM())
+		})
+	}
 
 	// Serve static frontend files
 	router.Static("/assets", cfg.FrontendDir+"/assets")
@@ -132,6 +252,15 @@ func main() {
 			log.Fatalf("Server failed: %v", err)
 		}
 	}()
+	if tlsConfig != nil {
+		go func() {
+			log.Printf("Starting ZTP HTTPS server on %s", cfg.TLSListenAddr)
+			server := &http.Server{Addr: cfg.TLSListenAddr, Handler: router, TLSConfig: tlsConfig}
+			if err := server.ListenAndServeTLS("", ""); err != nil {
+				log.Fatalf("HTTPS server failed: %v", err)
+			}
+		}()
+	}
 
 	// Wait for shutdown signal
 	quit := make(chan os.Signal, 1)
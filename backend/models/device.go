@@ -1,6 +1,9 @@
 package models
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+)
 
 // Device represents a network device managed by the ZTP server
 type Device struct {
@@ -13,10 +16,19 @@ type Device struct {
 	ConfigTemplate string     `json:"config_template"`
 	SSHUser        string     `json:"ssh_user,omitempty"`
 	SSHPass        string     `json:"ssh_pass,omitempty"`
+	EnableSecret   string     `json:"enable_secret,omitempty"` // Enable-mode secret for interactive backups
 	Status         string     `json:"status"` // online, offline, provisioning
 	LastSeen       *time.Time `json:"last_seen,omitempty"`
 	LastBackup     *time.Time `json:"last_backup,omitempty"`
 	LastError      string     `json:"last_error,omitempty"` // Last error message from backup/provisioning
+	UpgradeStatus  string     `json:"upgrade_status,omitempty"`  // Image upgrade step: pending, downloading, upgraded, failed
+	UpgradeVersion string     `json:"upgrade_version,omitempty"` // Image version the upgrade status refers to
+	ProvisionedAt  *time.Time `json:"provisioned_at,omitempty"`  // When the device last finished provisioning
+	NetBoxID       int        `json:"netbox_id,omitempty"`  // Linked NetBox device, set by sync
+	Site           string     `json:"site,omitempty"`       // NetBox site slug
+	Role           string     `json:"role,omitempty"`       // NetBox device role slug
+	Tenant         string     `json:"tenant,omitempty"`     // NetBox tenant slug
+	Platform       string     `json:"platform,omitempty"`   // NetBox platform slug
 	CreatedAt      time.Time  `json:"created_at"`
 	UpdatedAt      time.Time  `json:"updated_at"`
 }
@@ -36,6 +48,22 @@ type Settings struct {
 	OpenGearEnrollURL      string `json:"opengear_enroll_url"`
 	OpenGearEnrollBundle   string `json:"opengear_enroll_bundle"`
 	OpenGearEnrollPassword string `json:"opengear_enroll_password"`
+	// Backup worker pool; 0 uses the default worker count or disables a limit
+	BackupWorkers     int `json:"backup_workers"`      // Backups running at once
+	BackupVendorLimit int `json:"backup_vendor_limit"` // Backups running at once per vendor
+	BackupSubnetLimit int `json:"backup_subnet_limit"` // Backups running at once per /24
+	BackupRateLimit   int `json:"backup_rate_limit"`   // Backups started per minute
+	// Enable-mode secret for interactive backups of devices without their own
+	DefaultEnableSecret string `json:"default_enable_secret"`
+	// Template rendered for Cisco PnP devices and run line by line as exec commands
+	PnPExecTemplate string `json:"pnp_exec_template"`
+	// Config download protection for /configs
+	ConfigTokenRequired bool `json:"config_token_required"`  // Downloads need a token from {{.Bootstrap.ConfigURL}}
+	ConfigTokenTTL      int  `json:"config_token_ttl"`       // Minutes a download token is valid, 0 for no expiry
+	ConfigTokenOneTime  bool `json:"config_token_one_time"`  // Tokens are used up by their first download
+	ConfigCheckSourceIP bool `json:"config_check_source_ip"` // Configs are only served to the device's reserved IP
+	ConfigLockout       bool `json:"config_lockout"`         // Configs are refused once a device has been provisioned
+	ConfigRequireTLS    bool `json:"config_require_tls"`     // Configs are refused over plain HTTP
 }
 
 // Backup represents a config backup record
@@ -43,6 +71,7 @@ type Backup struct {
 	ID        int64     `json:"id"`
 	DeviceMAC string    `json:"device_mac"`
 	Filename  string    `json:"filename"`
+	Format    string    `json:"format"` // text, or xml for NETCONF backups
 	Size      int64     `json:"size"`
 	CreatedAt time.Time `json:"created_at"`
 }
@@ -58,30 +87,47 @@ type Lease struct {
 
 // Vendor represents a network device vendor configuration
 type Vendor struct {
-	ID              string    `json:"id"`
-	Name            string    `json:"name"`
-	BackupCommand   string    `json:"backup_command"`
-	SSHPort         int       `json:"ssh_port"`
-	MacPrefixes     []string  `json:"mac_prefixes"`      // OUI prefixes for MAC address lookup
-	VendorClass     string    `json:"vendor_class"`      // DHCP Option 60 vendor class identifier
-	DefaultTemplate string    `json:"default_template"`  // Default template ID for this vendor
-	DeviceCount     int       `json:"device_count,omitempty"` // Computed field
-	CreatedAt       time.Time `json:"created_at"`
-	UpdatedAt       time.Time `json:"updated_at"`
+	ID                string    `json:"id"`
+	Name              string    `json:"name"`
+	BackupCommand     string    `json:"backup_command"`
+	SSHPort           int       `json:"ssh_port"`
+	MacPrefixes       []string  `json:"mac_prefixes"`       // OUI prefixes for MAC address lookup
+	VendorClass       string    `json:"vendor_class"`       // DHCP Option 60 vendor class identifier
+	DefaultTemplate   string    `json:"default_template"`   // Default template ID for this vendor
+	BackupMode        string    `json:"backup_mode"`        // exec, interactive or netconf
+	PromptPattern     string    `json:"prompt_pattern"`     // Interactive: regex matching the CLI prompt
+	PagerPattern      string    `json:"pager_pattern"`      // Interactive: regex matching a pagination prompt such as --More--
+	PreCommands       []string  `json:"pre_commands"`       // Interactive: run before the backup command, e.g. terminal length 0
+	EnableCommand     string    `json:"enable_command"`     // Interactive: privilege escalation command, e.g. enable
+	NetconfPort       int       `json:"netconf_port"`       // NETCONF: SSH port of the netconf subsystem, 830 if unset
+	ImageBoot         bool      `json:"image_boot"`         // Send the target image as the DHCP boot file
+	BootstrapTemplate string    `json:"bootstrap_template"` // Template served as the ZTP bootstrap script, DHCP option 67 points to it
+	DeviceCount       int       `json:"device_count,omitempty"` // Computed field
+	CreatedAt         time.Time `json:"created_at"`
+	UpdatedAt         time.Time `json:"updated_at"`
 }
 
 // DhcpOption represents a DHCP option configuration
 type DhcpOption struct {
-	ID           string    `json:"id"`
-	OptionNumber int       `json:"option_number"`
-	Name         string    `json:"name"`
-	Value        string    `json:"value"`
-	Type         string    `json:"type"` // string, ip, hex, number
-	VendorID     string    `json:"vendor_id,omitempty"`
-	Description  string    `json:"description,omitempty"`
-	Enabled      bool      `json:"enabled"`
-	CreatedAt    time.Time `json:"created_at"`
-	UpdatedAt    time.Time `json:"updated_at"`
+	ID               string          `json:"id"`
+	OptionNumber     int             `json:"option_number"`
+	Name             string          `json:"name"`
+	Value            string          `json:"value"`
+	Type             string          `json:"type"`                        // string, ip, hex, number
+	SubOptions       []DhcpSubOption `json:"sub_options,omitempty"`       // TLV-encoded payload for options 43 and 125
+	EnterpriseNumber int             `json:"enterprise_number,omitempty"` // IANA enterprise number, option 125 only
+	VendorID         string          `json:"vendor_id,omitempty"`
+	Description      string          `json:"description,omitempty"`
+	Enabled          bool            `json:"enabled"`
+	CreatedAt        time.Time       `json:"created_at"`
+	UpdatedAt        time.Time       `json:"updated_at"`
+}
+
+// DhcpSubOption is a single code/value entry inside a vendor-encapsulated option
+type DhcpSubOption struct {
+	Code  int    `json:"code"`
+	Type  string `json:"type"` // string, ip, hex, number
+	Value string `json:"value"`
 }
 
 // Template represents a configuration template
@@ -105,9 +151,220 @@ type DiscoveryLog struct {
 	Hostname  string    `json:"hostname,omitempty"`
 	Vendor    string    `json:"vendor,omitempty"`
 	Message   string    `json:"message,omitempty"`
+	Details   string    `json:"details,omitempty"` // JSON payload a device sent with its callback
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// PendingDevice represents an unknown device waiting for operator approval
+type PendingDevice struct {
+	MAC       string     `json:"mac"`
+	IP        string     `json:"ip"`
+	Hostname  string     `json:"hostname,omitempty"`
+	Vendor    string     `json:"vendor,omitempty"` // Detected from MAC OUI prefix
+	Status    string     `json:"status"`           // pending, approved, rejected
+	Note      string     `json:"note,omitempty"`
+	FirstSeen time.Time  `json:"first_seen"`
+	LastSeen  time.Time  `json:"last_seen"`
+	DecidedAt *time.Time `json:"decided_at,omitempty"`
+}
+
+// DeviceFacts is a snapshot of what a device reported about itself
+type DeviceFacts struct {
+	ID             int64             `json:"id"`
+	DeviceMAC      string            `json:"device_mac"`
+	Driver         string            `json:"driver"`
+	Hostname       string            `json:"hostname,omitempty"`
+	Model          string            `json:"model,omitempty"`
+	Serial         string            `json:"serial,omitempty"`
+	Version        string            `json:"version,omitempty"`
+	Uptime         string            `json:"uptime,omitempty"`
+	Interfaces     []DeviceInterface `json:"interfaces"`
+	Neighbors      []LLDPNeighbor    `json:"neighbors"`
+	SerialMismatch bool              `json:"serial_mismatch"` // Reported serial differs from the registered one
+	CollectedAt    time.Time         `json:"collected_at"`
+}
+
+// DeviceInterface is an interface reported by a device
+type DeviceInterface struct {
+	Name        string `json:"name"`
+	Status      string `json:"status,omitempty"`
+	MAC         string `json:"mac,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// LLDPNeighbor is a device seen over LLDP on one of a device's interfaces
+type LLDPNeighbor struct {
+	LocalInterface  string `json:"local_interface"`
+	RemoteDevice    string `json:"remote_device"`
+	RemoteInterface string `json:"remote_interface,omitempty"`
+}
+
+// Vendor backup modes
+const (
+	BackupModeExec        = "exec"        // Run the backup command over an SSH exec channel
+	BackupModeInteractive = "interactive" // Drive a PTY shell, handling prompts and pagination
+	BackupModeNetconf     = "netconf"     // Fetch the running config as XML with NETCONF <get-config>
+)
+
+// Backup file formats
+const (
+	BackupFormatText = "text"
+	BackupFormatXML  = "xml"
+)
+
+// Pending device statuses
+const (
+	PendingStatusPending  = "pending"
+	PendingStatusApproved = "approved"
+	PendingStatusRejected = "rejected"
+)
+
+// InventorySource is an external inventory that devices can be synced with
+type InventorySource struct {
+	ID        string            `json:"id"`
+	Name      string            `json:"name"`
+	Type      string            `json:"type"` // netbox, nautobot, http
+	Enabled   bool              `json:"enabled"`
+	URL       string            `json:"url"`
+	Token     string            `json:"token,omitempty"`
+	Format    string            `json:"format,omitempty"`  // http sources: json or csv
+	Headers   map[string]string `json:"headers,omitempty"` // http sources: extra request headers
+	Builtin   bool              `json:"builtin"`           // The NetBox integration, configured via /api/netbox/config
+	CreatedAt time.Time         `json:"created_at"`
+	UpdatedAt time.Time         `json:"updated_at"`
+}
+
+// Inventory source types
+const (
+	InventoryNetBox   = "netbox"
+	InventoryNautobot = "nautobot"
+	InventoryHTTP     = "http"
+)
+
+// Job is a unit of background work in the persistent job queue
+type Job struct {
+	ID          int64           `json:"id"`
+	Type        string          `json:"type"` // backup, netbox.sync, config.generate, inventory.sync
+	Status      string          `json:"status"`
+	Key         string          `json:"key,omitempty"` // Pending jobs with the same type and key are coalesced
+	Payload     json.RawMessage `json:"payload,omitempty"`
+	Result      json.RawMessage `json:"result,omitempty"`
+	Error       string          `json:"error,omitempty"`
+	Attempts    int             `json:"attempts"`
+	MaxAttempts int             `json:"max_attempts"`
+	RunAt       time.Time       `json:"run_at"` // Not started before this time
+	StartedAt   *time.Time      `json:"started_at,omitempty"`
+	FinishedAt  *time.Time      `json:"finished_at,omitempty"`
+	CreatedAt   time.Time       `json:"created_at"`
+	UpdatedAt   time.Time       `json:"updated_at"`
+}
+
+// Job statuses
+const (
+	JobPending   = "pending"
+	JobRunning   = "running"
+	JobSucceeded = "succeeded"
+	JobFailed    = "failed"
+	JobCancelled = "cancelled"
+)
+
+// ReloadStatus describes the regeneration of the dnsmasq and device configs
+type ReloadStatus struct {
+	Generation     uint64     `json:"generation"` // Successful regenerations since the server started
+	Pending        bool       `json:"pending"`    // Changes are waiting for a regeneration
+	Running        bool       `json:"running"`
+	LastError      string     `json:"last_error,omitempty"` // Error of the last regeneration, empty if it succeeded
+	LastDurationMS int64      `json:"last_duration_ms"`
+	LastRunAt      *time.Time `json:"last_run_at,omitempty"` // When the last regeneration finished
+	LiveAt         *time.Time `json:"live_at,omitempty"`     // When the current generation went live
+}
+
+// ConfigChange records a configuration change pushed to a live device
+type ConfigChange struct {
+	ID             int64      `json:"id"`
+	DeviceMAC      string     `json:"device_mac"`
+	TemplateID     string     `json:"template_id,omitempty"` // Template the config was rendered from, empty for snippets
+	Config         string     `json:"config"`
+	ConfirmMinutes int        `json:"confirm_minutes"`        // Rollback timer; 0 applies without commit-confirm
+	Status         string     `json:"status"`
+	Driver         string     `json:"driver,omitempty"`
+	Output         string     `json:"output,omitempty"`       // Device output while applying and confirming
+	Error          string     `json:"error,omitempty"`
+	PreBackupID    int64      `json:"pre_backup_id,omitempty"`
+	PostBackupID   int64      `json:"post_backup_id,omitempty"`
+	JobID          int64      `json:"job_id,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	FinishedAt     *time.Time `json:"finished_at,omitempty"`
+}
+
+// Config change statuses
+const (
+	ChangePending    = "pending"
+	ChangeRunning    = "running"
+	ChangeApplied    = "applied"     // Applied by a driver without commit-confirm
+	ChangeConfirmed  = "confirmed"   // Applied with a rollback timer, then confirmed
+	ChangeRolledBack = "rolled_back" // Device unreachable after the change; left to roll back
+	ChangeFailed     = "failed"
+)
+
+// Image is a firmware or OS image in the image repository
+type Image struct {
+	ID        int64     `json:"id"`
+	Vendor    string    `json:"vendor"`
+	Model     string    `json:"model,omitempty"` // Empty for every model of the vendor
+	Version   string    `json:"version"`
+	Filename  string    `json:"filename"`
+	Size      int64     `json:"size"`
+	SHA256    string    `json:"sha256"`
+	MD5       string    `json:"md5"`
+	Target    bool      `json:"target"` // Devices of the vendor and model are upgraded to this image
 	CreatedAt time.Time `json:"created_at"`
 }
 
+// Device image upgrade statuses
+const (
+	UpgradePending     = "pending"     // Device reported a version other than the target
+	UpgradeDownloading = "downloading" // Device fetched the target image
+	UpgradeUpgraded    = "upgraded"    // Device reported the target version
+	UpgradeFailed      = "failed"      // Device fetched the image but still reports another version
+)
+
+// Provisioning statuses devices and bootstrap scripts report to the callback endpoint
+const (
+	ProvisionStarted          = "started"
+	ProvisionDownloadingImage = "downloading_image"
+	ProvisionInstallingImage  = "installing_image"
+	ProvisionApplyingConfig   = "applying_config"
+	ProvisionCompleted        = "completed"
+	ProvisionFailed           = "failed"
+)
+
+// PnPSession tracks a Cisco Plug and Play agent through its provisioning work items
+type PnPSession struct {
+	Serial     string    `json:"serial"` // Serial number from the UDI, identifies the agent
+	UDI        string    `json:"udi"`    // PID:<pid>,VID:<vid>,SN:<serial>
+	PID        string    `json:"pid,omitempty"`
+	IP         string    `json:"ip"`
+	Hostname   string    `json:"hostname,omitempty"`   // Hostname the agent reported
+	DeviceMAC  string    `json:"device_mac,omitempty"` // Registered device, empty until one matches
+	State      string    `json:"state"`
+	Step       int       `json:"step"`                 // cli_exec: index of the command being run
+	Correlator string    `json:"correlator,omitempty"` // Work item awaiting a response
+	LastError  string    `json:"last_error,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"` // Last contact from the agent
+}
+
+// PnP session states, in the order work items are handed out
+const (
+	PnPUnknown       = "unknown"        // No registered device has the agent's serial or IP
+	PnPImageInstall  = "image_install"  // Installing the device's target image
+	PnPConfigUpgrade = "config_upgrade" // Applying the rendered config to the running config
+	PnPCLIExec       = "cli_exec"       // Running exec commands, ending with write memory
+	PnPCompleted     = "completed"
+	PnPFailed        = "failed"
+)
+
 // DefaultSettings returns settings with sensible defaults
 func DefaultSettings() Settings {
 	return Settings{
@@ -120,5 +377,6 @@ func DefaultSettings() Settings {
 		DHCPSubnet:      "255.255.255.0",
 		DHCPGateway:     "172.30.0.1",
 		TFTPServerIP:    "172.30.0.2",
+		BackupWorkers:   4,
 	}
 }
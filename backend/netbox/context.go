@@ -0,0 +1,320 @@
+package netbox
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/ztp-server/backend/db"
+	"github.com/ztp-server/backend/models"
+)
+
+// DefaultContextTTL is how long fetched device context is reused before NetBox is asked again
+const DefaultContextTTL = 5 * time.Minute
+
+const (
+	// Context is fetched while configs render, so a slow NetBox must not hold them up
+	contextTimeout = 5 * time.Second
+	// After a failed fetch NetBox isn't asked again for this long
+	contextRetryAfter = 30 * time.Second
+)
+
+// DeviceContext is the NetBox data exposed to config templates as .NetBox. It is never
+// nil when passed to a template, so {{.NetBox.ConfigContext.ntp_servers}} renders as
+// empty rather than failing for devices NetBox doesn't know.
+type DeviceContext struct {
+	Found         bool                        `json:"found"` // False if NetBox is unconfigured or has no matching device
+	ID            int                         `json:"id,omitempty"`
+	Name          string                      `json:"name,omitempty"`
+	Status        string                      `json:"status,omitempty"`
+	Role          string                      `json:"role,omitempty"`
+	Tenant        string                      `json:"tenant,omitempty"`
+	Platform      string                      `json:"platform,omitempty"`
+	PrimaryIP     string                      `json:"primary_ip,omitempty"` // With mask, e.g. 10.0.0.5/24
+	ConfigContext map[string]any              `json:"config_context"`
+	CustomFields  map[string]any              `json:"custom_fields"`
+	Site          SiteContext                 `json:"site"`
+	Interfaces    []InterfaceContext          `json:"interfaces"`
+	InterfaceMap  map[string]InterfaceContext `json:"-"` // Interfaces by name, for {{index .NetBox.InterfaceMap "mgmt0"}}
+}
+
+// SiteContext is the site data available to templates
+type SiteContext struct {
+	Name            string         `json:"name,omitempty"`
+	Slug            string         `json:"slug,omitempty"`
+	Region          string         `json:"region,omitempty"`
+	Facility        string         `json:"facility,omitempty"`
+	TimeZone        string         `json:"time_zone,omitempty"`
+	PhysicalAddress string         `json:"physical_address,omitempty"`
+	Description     string         `json:"description,omitempty"`
+	CustomFields    map[string]any `json:"custom_fields"`
+}
+
+// InterfaceContext is the interface data available to templates
+type InterfaceContext struct {
+	Name        string   `json:"name"`
+	Type        string   `json:"type,omitempty"`
+	Enabled     bool     `json:"enabled"`
+	MAC         string   `json:"mac,omitempty"`
+	MTU         int      `json:"mtu,omitempty"`
+	Description string   `json:"description,omitempty"`
+	Addresses   []string `json:"addresses,omitempty"` // With mask
+}
+
+// EmptyDeviceContext returns a context for devices without NetBox data
+func EmptyDeviceContext() *DeviceContext {
+	return &DeviceContext{
+		ConfigContext: map[string]any{},
+		CustomFields:  map[string]any{},
+		Site:          SiteContext{CustomFields: map[string]any{}},
+		Interfaces:    []InterfaceContext{},
+		InterfaceMap:  map[string]InterfaceContext{},
+	}
+}
+
+type contextEntry struct {
+	ctx       *DeviceContext
+	fetchedAt time.Time
+	revision  string // Hash of ctx, for device entries
+}
+
+type contextFailure struct {
+	err      error
+	failedAt time.Time
+}
+
+// ContextProvider fetches and caches NetBox context for template rendering. Entries are
+// kept for the TTL and, if a refresh fails, served stale rather than failing the render.
+// Failures are remembered for a short while: a device whose fetch failed isn't fetched
+// again until then, and while NetBox can't be reached no device is, so an outage
+// doesn't stall every render for the length of a request timeout.
+type ContextProvider struct {
+	store *db.Store
+	ttl   time.Duration
+
+	mu          sync.Mutex
+	cache       map[string]contextEntry   // Keyed by device MAC
+	sites       map[int]contextEntry      // Site data shared between devices, keyed by site ID
+	failures    map[string]contextFailure // Failed fetches, keyed by device MAC
+	unreachable contextFailure            // Last fetch that couldn't reach NetBox at all
+}
+
+// NewContextProvider creates a new context provider
+func NewContextProvider(store *db.Store, ttl time.Duration) *ContextProvider {
+	if ttl <= 0 {
+		ttl = DefaultContextTTL
+	}
+	return &ContextProvider{
+		store:    store,
+		ttl:      ttl,
+		cache:    make(map[string]contextEntry),
+		sites:    make(map[int]contextEntry),
+		failures: make(map[string]contextFailure),
+	}
+}
+
+// Invalidate drops all cached context, e.g. after a NetBox webhook
+func (p *ContextProvider) Invalidate() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.cache = make(map[string]contextEntry)
+	p.sites = make(map[int]contextEntry)
+	p.failures = make(map[string]contextFailure)
+	p.unreachable = contextFailure{}
+}
+
+// Revision identifies the context Get would return for a device from the cache,
+// without going to NetBox. ok is false if no context is cached for the device or it is
+// older than the TTL, so Get would fetch it.
+func (p *ContextProvider) Revision(mac string) (revision string, ok bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	entry, cached := p.cache[mac]
+	if !cached || time.Since(entry.fetchedAt) >= p.ttl {
+		return "", false
+	}
+	return entry.revision, true
+}
+
+// Get returns the NetBox context for a device. It never returns nil; when NetBox is not
+// configured or the device can't be found an empty context is returned.
+func (p *ContextProvider) Get(device *models.Device) (*DeviceContext, error) {
+	p.mu.Lock()
+	entry, cached := p.cache[device.MAC]
+	failure, failed := p.failures[device.MAC]
+	if p.unreachable.err != nil && (!failed || p.unreachable.failedAt.After(failure.failedAt)) {
+		failure, failed = p.unreachable, true
+	}
+	p.mu.Unlock()
+	if cached && time.Since(entry.fetchedAt) < p.ttl {
+		return entry.ctx, nil
+	}
+	if failed && time.Since(failure.failedAt) < contextRetryAfter {
+		if cached {
+			return entry.ctx, nil
+		}
+		return EmptyDeviceContext(), failure.err
+	}
+
+	config, err := p.store.GetNetBoxConfig()
+	if err != nil {
+		return EmptyDeviceContext(), err
+	}
+	if config.URL == "" || config.Token == "" {
+		return EmptyDeviceContext(), nil
+	}
+
+	s := NewSyncService(config.URL, config.Token)
+	s.client.SetTimeout(contextTimeout)
+	ctx, err := p.fetch(s, device)
+	if err != nil {
+		p.mu.Lock()
+		failure := contextFailure{err: err, failedAt: time.Now()}
+		p.failures[device.MAC] = failure
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			p.unreachable = failure
+		}
+		p.mu.Unlock()
+		if cached {
+			log.Printf("[netbox] Warning: using cached context for %s: %v", device.MAC, err)
+			return entry.ctx, nil
+		}
+		return EmptyDeviceContext(), err
+	}
+
+	encoded, _ := json.Marshal(ctx)
+	sum := sha256.Sum256(encoded)
+	p.mu.Lock()
+	p.cache[device.MAC] = contextEntry{ctx: ctx, fetchedAt: time.Now(), revision: hex.EncodeToString(sum[:])}
+	delete(p.failures, device.MAC)
+	p.unreachable = contextFailure{}
+	p.mu.Unlock()
+	return ctx, nil
+}
+
+// fetch builds a device's context from NetBox. The NetBox device is found by the linked
+// ID, falling back to the interface carrying the device's MAC.
+func (p *ContextProvider) fetch(s *SyncService, device *models.Device) (*DeviceContext, error) {
+	id := device.NetBoxID
+	if id == 0 && device.MAC != "" {
+		iface, err := s.Interfaces.GetByMac(device.MAC)
+		if err != nil {
+			return nil, fmt.Errorf("failed to look up interface: %w", err)
+		}
+		if iface != nil {
+			id = iface.Device.ID
+		}
+	}
+	if id == 0 {
+		return EmptyDeviceContext(), nil
+	}
+
+	nb, err := s.Devices.Get(id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get device %d: %w", id, err)
+	}
+
+	ctx := EmptyDeviceContext()
+	ctx.Found = true
+	ctx.ID = nb.ID
+	ctx.Name = nb.Name
+	ctx.Status = nb.Status.Value
+	ctx.Role = nb.Role.Slug
+	if nb.Tenant != nil {
+		ctx.Tenant = nb.Tenant.Slug
+	}
+	if nb.Platform != nil {
+		ctx.Platform = nb.Platform.Slug
+	}
+	if nb.PrimaryIP4 != nil {
+		ctx.PrimaryIP = nb.PrimaryIP4.Address
+	}
+	if nb.ConfigContext != nil {
+		ctx.ConfigContext = nb.ConfigContext
+	}
+	if nb.CustomFields != nil {
+		ctx.CustomFields = nb.CustomFields
+	}
+
+	site, err := p.site(s, nb.Site.ID)
+	if err != nil {
+		return nil, err
+	}
+	ctx.Site = site
+
+	interfaces, err := s.Interfaces.ListByDevice(nb.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list interfaces: %w", err)
+	}
+	addresses, err := s.IPAddresses.ListByDevice(nb.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list IP addresses: %w", err)
+	}
+	byInterface := make(map[int][]string)
+	for _, ip := range addresses {
+		if ip.AssignedObjectType == "dcim.interface" {
+			byInterface[ip.AssignedObjectID] = append(byInterface[ip.AssignedObjectID], ip.Address)
+		}
+	}
+	for _, iface := range interfaces {
+		ic := InterfaceContext{
+			Name:        iface.Name,
+			Type:        iface.Type.Value,
+			Enabled:     iface.Enabled,
+			MAC:         iface.MacAddress,
+			MTU:         iface.MTU,
+			Description: iface.Description,
+			Addresses:   byInterface[iface.ID],
+		}
+		ctx.Interfaces = append(ctx.Interfaces, ic)
+		ctx.InterfaceMap[iface.Name] = ic
+	}
+
+	return ctx, nil
+}
+
+// site returns the context for a site, cached separately since many devices share one
+func (p *ContextProvider) site(s *SyncService, id int) (SiteContext, error) {
+	if id == 0 {
+		return SiteContext{CustomFields: map[string]any{}}, nil
+	}
+
+	p.mu.Lock()
+	entry, ok := p.sites[id]
+	p.mu.Unlock()
+	if ok && time.Since(entry.fetchedAt) < p.ttl {
+		return entry.ctx.Site, nil
+	}
+
+	site, err := s.Sites.Get(id)
+	if err != nil {
+		return SiteContext{}, fmt.Errorf("failed to get site %d: %w", id, err)
+	}
+	sc := SiteContext{
+		Name:            site.Name,
+		Slug:            site.Slug,
+		Facility:        site.Facility,
+		TimeZone:        site.TimeZone,
+		PhysicalAddress: site.PhysicalAddress,
+		Description:     site.Description,
+		CustomFields:    site.CustomFields,
+	}
+	if site.Region != nil {
+		sc.Region = site.Region.Slug
+	}
+	if sc.CustomFields == nil {
+		sc.CustomFields = map[string]any{}
+	}
+
+	p.mu.Lock()
+	p.sites[id] = contextEntry{ctx: &DeviceContext{Site: sc}, fetchedAt: time.Now()}
+	p.mu.Unlock()
+	return sc, nil
+}
@@ -0,0 +1,178 @@
+package netbox
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/ztp-server/backend/db"
+	"github.com/ztp-server/backend/models"
+)
+
+// stubNetBox serves the NetBox API endpoints the context provider uses for one device
+func stubNetBox(t *testing.T, requests *int32) *httptest.Server {
+	t.Helper()
+	routes := map[string]string{
+		"/api/dcim/interfaces/?mac_address=aa%3Abb%3Acc%3Add%3Aee%3Aff": `{"count": 1, "results": [{"id": 11, "device": {"id": 7}, "name": "mgmt0"}]}`,
+		"/api/dcim/devices/7/": `{"id": 7, "name": "leaf1", "status": {"value": "active"}, "role": {"id": 2, "name": "Leaf", "slug": "leaf"},
+			"site": {"id": 3, "name": "DC1", "slug": "dc1"}, "platform": {"id": 4, "slug": "eos"}, "primary_ip4": {"id": 21, "address": "10.0.0.5/24"},
+			"config_context": {"ntp_servers": ["10.0.0.1"]}, "custom_fields": {"rack_unit": 12}}`,
+		"/api/dcim/sites/3/": `{"id": 3, "name": "DC1", "slug": "dc1", "region": {"id": 1, "slug": "eu"}, "time_zone": "Europe/Berlin"}`,
+		"/api/dcim/interfaces/?device_id=7&limit=100": `{"count": 1, "results": [{"id": 11, "device": {"id": 7}, "name": "mgmt0",
+			"type": {"value": "1000base-t"}, "enabled": true, "mac_address": "AA:BB:CC:DD:EE:FF"}]}`,
+		"/api/ipam/ip-addresses/?device_id=7&limit=1000": `{"count": 1, "results": [{"id": 21, "address": "10.0.0.5/24",
+			"assigned_object_type": "dcim.interface", "assigned_object_id": 11}]}`,
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(requests, 1)
+		if r.Header.Get("Authorization") != "Token secret" {
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"detail": "Invalid token"}`))
+			return
+		}
+		body, ok := routes[r.URL.RequestURI()]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"detail": "Not found."}`))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func newTestProvider(t *testing.T, url string) *ContextProvider {
+	t.Helper()
+	store, err := db.New(filepath.Join(t.TempDir(), "ztp.db"))
+	if err != nil {
+		t.Fatalf("failed to open store: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	if err := store.SaveNetBoxConfig(&db.NetBoxConfig{URL: url, Token: "secret"}); err != nil {
+		t.Fatalf("failed to save NetBox config: %v", err)
+	}
+	return NewContextProvider(store, DefaultContextTTL)
+}
+
+func TestClientReportsAPIErrors(t *testing.T) {
+	var requests int32
+	server := stubNetBox(t, &requests)
+
+	err := NewClient(server.URL+"/", "wrong").Get("/api/dcim/devices/7/", &Device{})
+	if err == nil || !strings.Contains(err.Error(), "Invalid token") {
+		t.Fatalf("expected the API error detail, got %v", err)
+	}
+
+	var device Device
+	if err := NewClient(server.URL+"/", "secret").Get("/api/dcim/devices/7/", &device); err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if device.Name != "leaf1" || device.Site.ID != 3 || device.PrimaryIP4 == nil {
+		t.Fatalf("device decoded wrong: %+v", device)
+	}
+}
+
+func TestContextProviderFetchesDevice(t *testing.T) {
+	var requests int32
+	server := stubNetBox(t, &requests)
+	p := newTestProvider(t, server.URL)
+
+	device := &models.Device{MAC: "aa:bb:cc:dd:ee:ff"}
+	if _, ok := p.Revision(device.MAC); ok {
+		t.Fatal("revision reported before anything was fetched")
+	}
+	ctx, err := p.Get(device)
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if !ctx.Found || ctx.Name != "leaf1" || ctx.Role != "leaf" || ctx.Platform != "eos" || ctx.PrimaryIP != "10.0.0.5/24" {
+		t.Fatalf("unexpected context: %+v", ctx)
+	}
+	if ctx.Site.Region != "eu" || ctx.Site.TimeZone != "Europe/Berlin" {
+		t.Fatalf("unexpected site: %+v", ctx.Site)
+	}
+	mgmt, ok := ctx.InterfaceMap["mgmt0"]
+	if !ok || len(mgmt.Addresses) != 1 || mgmt.Addresses[0] != "10.0.0.5/24" {
+		t.Fatalf("unexpected interfaces: %+v", ctx.Interfaces)
+	}
+	if servers, _ := ctx.ConfigContext["ntp_servers"].([]any); len(servers) != 1 {
+		t.Fatalf("unexpected config context: %v", ctx.ConfigContext)
+	}
+
+	revision, ok := p.Revision(device.MAC)
+	if !ok || revision == "" {
+		t.Fatal("no revision for the cached context")
+	}
+
+	// Served from the cache until the TTL runs out
+	fetched := atomic.LoadInt32(&requests)
+	if _, err := p.Get(device); err != nil {
+		t.Fatalf("cached Get failed: %v", err)
+	}
+	if n := atomic.LoadInt32(&requests); n != fetched {
+		t.Fatalf("cached Get made %d requests", n-fetched)
+	}
+
+	// Refetching the same data keeps the revision
+	p.Invalidate()
+	if _, ok := p.Revision(device.MAC); ok {
+		t.Fatal("revision reported after Invalidate")
+	}
+	if _, err := p.Get(device); err != nil {
+		t.Fatalf("Get after Invalidate failed: %v", err)
+	}
+	if again, _ := p.Revision(device.MAC); again != revision {
+		t.Fatalf("revision changed from %s to %s for the same data", revision, again)
+	}
+}
+
+func TestContextProviderRemembersFailures(t *testing.T) {
+	var requests int32
+	server := stubNetBox(t, &requests)
+	p := newTestProvider(t, server.URL)
+
+	// Linked to a NetBox device the stub doesn't know, so the fetch fails with a 404
+	device := &models.Device{MAC: "aa:bb:cc:dd:ee:01", NetBoxID: 99}
+	if _, err := p.Get(device); err == nil {
+		t.Fatal("expected an error for a missing device")
+	}
+	failed := atomic.LoadInt32(&requests)
+	ctx, err := p.Get(device)
+	if err == nil || ctx == nil || ctx.Found {
+		t.Fatalf("expected the remembered error and an empty context, got %+v, %v", ctx, err)
+	}
+	if n := atomic.LoadInt32(&requests); n != failed {
+		t.Fatalf("retried a failed fetch right away (%d requests)", n-failed)
+	}
+
+	// Other devices are still fetched while NetBox is reachable
+	if _, err := p.Get(&models.Device{MAC: "aa:bb:cc:dd:ee:ff"}); err != nil {
+		t.Fatalf("Get of another device failed: %v", err)
+	}
+}
+
+func TestContextProviderBacksOffWhileUnreachable(t *testing.T) {
+	var requests int32
+	server := stubNetBox(t, &requests)
+	p := newTestProvider(t, server.URL)
+	server.Close()
+
+	if _, err := p.Get(&models.Device{MAC: "aa:bb:cc:dd:ee:01"}); err == nil {
+		t.Fatal("expected an error with NetBox down")
+	}
+	// A different device isn't fetched either until the retry interval has passed
+	if _, err := p.Get(&models.Device{MAC: "aa:bb:cc:dd:ee:02"}); err == nil {
+		t.Fatal("expected the remembered outage")
+	}
+	p.mu.Lock()
+	_, tried := p.failures["aa:bb:cc:dd:ee:02"]
+	p.mu.Unlock()
+	if tried {
+		t.Fatal("fetched a device while NetBox was known to be unreachable")
+	}
+}
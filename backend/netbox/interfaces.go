@@ -86,6 +86,18 @@ func (s *IPAddressService) List(params map[string]string) (*PaginatedResponse[IP
 	return &result, err
 }
 
+// ListByDevice returns all IP addresses assigned to a device's interfaces
+func (s *IPAddressService) ListByDevice(deviceID int) ([]IPAddress, error) {
+	result, err := s.List(map[string]string{
+		"device_id": strconv.Itoa(deviceID),
+		"limit":     "1000",
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result.Results, nil
+}
+
 // Get returns a single IP address by ID
 func (s *IPAddressService) Get(id int) (*IPAddress, error) {
 	var result IPAddress
@@ -143,3 +155,11 @@ func (s *IPAddressService) AssignToInterface(address string, interfaceID int) (*
 		AssignedObjectID:   interfaceID,
 	})
 }
+
+// PartialUpdate partially updates an IP address
+func (s *IPAddressService) PartialUpdate(id int, ip *IPAddressUpdate) (*IPAddress, error) {
+	var result IPAddress
+	path := fmt.Sprintf("/api/ipam/ip-addresses/%d/", id)
+	err := s.client.Patch(path, ip, &result)
+	return &result, err
+}
@@ -0,0 +1,61 @@
+package netbox
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/ztp-server/backend/db"
+	"github.com/ztp-server/backend/models"
+	"github.com/ztp-server/backend/validate"
+)
+
+// ErrNoPrefix is returned when IP allocation is requested without a configured prefix,
+// including when NetBox itself isn't configured
+var ErrNoPrefix = errors.New("no NetBox prefix configured for IP allocation")
+
+// AllocateDeviceIP reserves the next available address in the configured NetBox prefix
+// for a device. The address is created unassigned and gets attached to the device's
+// management interface when the device is pushed. Call ReleaseDeviceIP if the device
+// ends up not being created.
+func AllocateDeviceIP(config *db.NetBoxConfig, hostname string) (*IPAddress, error) {
+	if config.URL == "" || config.Token == "" || config.PrefixID == 0 {
+		return nil, ErrNoPrefix
+	}
+
+	s := NewSyncService(config.URL, config.Token)
+	ip, err := s.Prefixes.AllocateIP(config.PrefixID, &AvailableIPRequest{
+		Status:      "active",
+		DNSName:     hostname,
+		Description: "Allocated by ZTP server",
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to allocate IP from prefix %d: %w", config.PrefixID, err)
+	}
+	return ip, nil
+}
+
+// ReleaseDeviceIP deletes an address allocated by AllocateDeviceIP
+func ReleaseDeviceIP(config *db.NetBoxConfig, ip *IPAddress) error {
+	s := NewSyncService(config.URL, config.Token)
+	return s.IPAddresses.Delete(ip.ID)
+}
+
+// HostAddress strips the mask from a NetBox address such as "10.0.0.5/24"
+func HostAddress(address string) string {
+	return strings.Split(address, "/")[0]
+}
+
+// PrefixLength returns the mask length of the DHCP subnet, used for addresses created in
+// NetBox. It returns 0 if the settings don't describe a valid subnet.
+func PrefixLength(settings *models.Settings) int {
+	if settings == nil {
+		return 0
+	}
+	subnet := validate.Subnet(settings)
+	if subnet == nil {
+		return 0
+	}
+	ones, _ := subnet.Mask.Size()
+	return ones
+}
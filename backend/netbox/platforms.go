@@ -0,0 +1,65 @@
+package netbox
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// PlatformService handles platform-related API operations
+type PlatformService struct {
+	client *Client
+}
+
+// NewPlatformService creates a new platform service
+func NewPlatformService(client *Client) *PlatformService {
+	return &PlatformService{client: client}
+}
+
+// List returns a paginated list of platforms
+func (s *PlatformService) List(params map[string]string) (*PaginatedResponse[Platform], error) {
+	var result PaginatedResponse[Platform]
+	path := "/api/dcim/platforms/" + BuildQuery(params)
+	err := s.client.Get(path, &result)
+	return &result, err
+}
+
+// ListAll returns all platforms
+func (s *PlatformService) ListAll() ([]Platform, error) {
+	var all []Platform
+	params := map[string]string{"limit": "100"}
+	offset := 0
+
+	for {
+		params["offset"] = strconv.Itoa(offset)
+		result, err := s.List(params)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, result.Results...)
+		if result.Next == "" || len(result.Results) == 0 {
+			break
+		}
+		offset += len(result.Results)
+	}
+	return all, nil
+}
+
+// Get returns a single platform by ID
+func (s *PlatformService) Get(id int) (*Platform, error) {
+	var result Platform
+	path := fmt.Sprintf("/api/dcim/platforms/%d/", id)
+	err := s.client.Get(path, &result)
+	return &result, err
+}
+
+// GetBySlug returns a platform by slug
+func (s *PlatformService) GetBySlug(slug string) (*Platform, error) {
+	result, err := s.List(map[string]string{"slug": slug})
+	if err != nil {
+		return nil, err
+	}
+	if len(result.Results) == 0 {
+		return nil, nil
+	}
+	return &result.Results[0], nil
+}
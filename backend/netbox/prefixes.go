@@ -0,0 +1,71 @@
+package netbox
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// PrefixService handles prefix-related API operations
+type PrefixService struct {
+	client *Client
+}
+
+// NewPrefixService creates a new prefix service
+func NewPrefixService(client *Client) *PrefixService {
+	return &PrefixService{client: client}
+}
+
+// List returns a paginated list of prefixes
+func (s *PrefixService) List(params map[string]string) (*PaginatedResponse[Prefix], error) {
+	var result PaginatedResponse[Prefix]
+	path := "/api/ipam/prefixes/" + BuildQuery(params)
+	err := s.client.Get(path, &result)
+	return &result, err
+}
+
+// ListAll returns all prefixes
+func (s *PrefixService) ListAll() ([]Prefix, error) {
+	var all []Prefix
+	params := map[string]string{"limit": "100"}
+	offset := 0
+
+	for {
+		params["offset"] = strconv.Itoa(offset)
+		result, err := s.List(params)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, result.Results...)
+		if result.Next == "" || len(result.Results) == 0 {
+			break
+		}
+		offset += len(result.Results)
+	}
+	return all, nil
+}
+
+// Get returns a single prefix by ID
+func (s *PrefixService) Get(id int) (*Prefix, error) {
+	var result Prefix
+	path := fmt.Sprintf("/api/ipam/prefixes/%d/", id)
+	err := s.client.Get(path, &result)
+	return &result, err
+}
+
+// AvailableIPs returns up to limit unallocated addresses in a prefix
+func (s *PrefixService) AvailableIPs(id, limit int) ([]AvailableIP, error) {
+	var result []AvailableIP
+	path := fmt.Sprintf("/api/ipam/prefixes/%d/available-ips/", id) + BuildQuery(map[string]string{
+		"limit": strconv.Itoa(limit),
+	})
+	err := s.client.Get(path, &result)
+	return result, err
+}
+
+// AllocateIP creates an IP address from the next available address in a prefix
+func (s *PrefixService) AllocateIP(id int, req *AvailableIPRequest) (*IPAddress, error) {
+	var result IPAddress
+	path := fmt.Sprintf("/api/ipam/prefixes/%d/available-ips/", id)
+	err := s.client.Post(path, req, &result)
+	return &result, err
+}
@@ -0,0 +1,561 @@
+package netbox
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ztp-server/backend/db"
+	"github.com/ztp-server/backend/jobs"
+	"github.com/ztp-server/backend/models"
+	"github.com/ztp-server/backend/validate"
+)
+
+// Fields whose source of truth can be configured per field
+const (
+	FieldHostname = "hostname"
+	FieldIP       = "ip"
+	FieldSerial   = "serial_number"
+	FieldVendor   = "vendor"
+	FieldSite     = "site"
+	FieldRole     = "role"
+	FieldTenant   = "tenant"
+	FieldPlatform = "platform"
+)
+
+// Sources of truth for a synced field
+const (
+	OwnerZTP    = "ztp"
+	OwnerNetBox = "netbox"
+)
+
+// DefaultFieldOwners is used for fields without a configured owner. Device status is
+// always owned by ZTP since it comes from the status checker.
+var DefaultFieldOwners = map[string]string{
+	FieldHostname: OwnerNetBox,
+	FieldIP:       OwnerNetBox,
+	FieldSerial:   OwnerNetBox,
+	FieldVendor:   OwnerNetBox,
+	FieldSite:     OwnerNetBox,
+	FieldRole:     OwnerNetBox,
+	FieldTenant:   OwnerNetBox,
+	FieldPlatform: OwnerNetBox,
+}
+
+// DefaultSyncInterval is used when no interval is configured
+const DefaultSyncInterval = 300 * time.Second
+
+// minSyncInterval keeps a misconfigured interval from hammering NetBox
+const minSyncInterval = 30 * time.Second
+
+// ReconcileResult summarizes a reconcile run
+type ReconcileResult struct {
+	Trigger       string    `json:"trigger"` // interval, webhook, manual
+	StartedAt     time.Time `json:"started_at"`
+	FinishedAt    time.Time `json:"finished_at"`
+	Linked        int       `json:"linked"`         // Existing devices matched by MAC
+	CreatedZTP    int       `json:"created_ztp"`    // NetBox devices imported into ZTP
+	CreatedNetBox int       `json:"created_netbox"` // ZTP devices created in NetBox
+	UpdatedZTP    int       `json:"updated_ztp"`    // ZTP devices updated from NetBox
+	UpdatedNetBox int       `json:"updated_netbox"` // NetBox devices updated from ZTP
+	Unchanged     int       `json:"unchanged"`
+	Errors        []string  `json:"errors,omitempty"`
+}
+
+// ReconcileStatus describes the reconciler state
+type ReconcileStatus struct {
+	Enabled    bool             `json:"enabled"`
+	Interval   int              `json:"interval"` // seconds
+	Running    bool             `json:"running"`
+	LastRun    *time.Time       `json:"last_run,omitempty"`
+	LastResult *ReconcileResult `json:"last_result,omitempty"`
+	LastError  string           `json:"last_error,omitempty"`
+}
+
+// Reconciler keeps ZTP devices and NetBox in sync in the background. It runs on the
+// configured interval while sync is enabled, and whenever a NetBox webhook arrives.
+// Runs are queued as netbox.sync jobs, one at a time.
+type Reconciler struct {
+	store    *db.Store
+	onChange func() error // Called after ZTP devices were changed, e.g. to regenerate configs
+	queue    *jobs.Queue
+	stop     chan struct{}
+	wg       sync.WaitGroup
+
+	runMu      sync.Mutex // Held for the duration of a run
+	mu         sync.Mutex // Guards the fields below
+	running    bool
+	lastRun    *time.Time
+	lastResult *ReconcileResult
+	lastError  string
+}
+
+// reconcilePayload is the payload of a netbox.sync job
+type reconcilePayload struct {
+	Reason string `json:"reason"`
+}
+
+// NewReconciler creates a new reconciler and registers its job handler
+func NewReconciler(store *db.Store, onChange func() error, queue *jobs.Queue) *Reconciler {
+	r := &Reconciler{
+		store:    store,
+		onChange: onChange,
+		queue:    queue,
+		stop:     make(chan struct{}),
+	}
+	queue.Register(jobs.TypeNetBoxSync, 1, r.runJob)
+	return r
+}
+
+// Start begins background reconciling
+func (r *Reconciler) Start() {
+	r.wg.Add(1)
+	go r.run()
+	log.Println("[netbox] Reconciler started")
+}
+
+// Stop stops background reconciling
+func (r *Reconciler) Stop() {
+	close(r.stop)
+	r.wg.Wait()
+	log.Println("[netbox] Reconciler stopped")
+}
+
+// Trigger queues a reconcile to run as soon as possible. Requests arriving while one is
+// already queued are coalesced.
+func (r *Reconciler) Trigger(reason string) {
+	if _, err := r.queue.Enqueue(jobs.TypeNetBoxSync, reconcilePayload{Reason: reason}, jobs.Options{Key: "reconcile"}); err != nil {
+		log.Printf("[netbox] Failed to queue reconcile: %v", err)
+	}
+}
+
+// Status returns the current reconciler state
+func (r *Reconciler) Status() ReconcileStatus {
+	status := ReconcileStatus{Interval: int(DefaultSyncInterval.Seconds())}
+	if config, err := r.store.GetNetBoxConfig(); err == nil {
+		status.Enabled = config.SyncEnabled
+		status.Interval = int(syncInterval(config).Seconds())
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	status.Running = r.running
+	status.LastRun = r.lastRun
+	status.LastResult = r.lastResult
+	status.LastError = r.lastError
+	return status
+}
+
+func (r *Reconciler) run() {
+	defer r.wg.Done()
+
+	for {
+		interval := DefaultSyncInterval
+		if config, err := r.store.GetNetBoxConfig(); err == nil {
+			interval = syncInterval(config)
+		}
+
+		select {
+		case <-time.After(interval):
+			if r.enabled() {
+				r.Trigger("interval")
+			}
+		case <-r.stop:
+			return
+		}
+	}
+}
+
+// enabled reports whether background sync is switched on and configured
+func (r *Reconciler) enabled() bool {
+	config, err := r.store.GetNetBoxConfig()
+	return err == nil && config.SyncEnabled && config.URL != "" && config.Token != ""
+}
+
+// runJob runs a queued reconcile, skipping it if sync was disabled in the meantime
+func (r *Reconciler) runJob(ctx context.Context, job *models.Job) (any, error) {
+	var payload reconcilePayload
+	if err := jobs.Decode(job, &payload); err != nil {
+		return nil, err
+	}
+	if !r.enabled() {
+		return nil, nil
+	}
+
+	result, err := r.RunOnce(payload.Reason)
+	if err != nil {
+		log.Printf("[netbox] Reconcile failed: %v", err)
+		return nil, err
+	}
+	return result, nil
+}
+
+// RunOnce performs a full reconcile now, regardless of whether background sync is enabled
+func (r *Reconciler) RunOnce(reason string) (*ReconcileResult, error) {
+	r.runMu.Lock()
+	defer r.runMu.Unlock()
+
+	r.mu.Lock()
+	r.running = true
+	r.mu.Unlock()
+
+	result, err := r.reconcile(reason)
+
+	r.mu.Lock()
+	now := time.Now()
+	r.running = false
+	r.lastRun = &now
+	r.lastResult = result
+	r.lastError = ""
+	if err != nil {
+		r.lastError = err.Error()
+	}
+	r.mu.Unlock()
+
+	if err == nil {
+		log.Printf("[netbox] Reconcile (%s): linked=%d created_ztp=%d created_netbox=%d updated_ztp=%d updated_netbox=%d errors=%d",
+			reason, result.Linked, result.CreatedZTP, result.CreatedNetBox, result.UpdatedZTP, result.UpdatedNetBox, len(result.Errors))
+	}
+	return result, err
+}
+
+func (r *Reconciler) reconcile(reason string) (*ReconcileResult, error) {
+	result := &ReconcileResult{Trigger: reason, StartedAt: time.Now()}
+	defer func() { result.FinishedAt = time.Now() }()
+
+	config, err := r.store.GetNetBoxConfig()
+	if err != nil {
+		return result, err
+	}
+	if config.URL == "" || config.Token == "" {
+		return result, fmt.Errorf("NetBox not configured")
+	}
+
+	s := NewSyncService(config.URL, config.Token)
+	s.DefaultSiteID = config.SiteID
+	s.DefaultRoleID = config.RoleID
+	if err := s.EnsurePrerequisites(); err != nil {
+		return result, err
+	}
+	if settings, err := r.store.GetSettings(); err == nil {
+		s.PrefixLength = PrefixLength(settings)
+	}
+
+	devices, err := r.store.ListDevices()
+	if err != nil {
+		return result, err
+	}
+	vendors, err := r.store.ListVendors()
+	if err != nil {
+		return result, err
+	}
+	nbDevices, err := s.Devices.ListAll(nil)
+	if err != nil {
+		return result, fmt.Errorf("failed to list NetBox devices: %w", err)
+	}
+
+	// Index NetBox devices by ID and by MAC
+	byID := make(map[int]*Device, len(nbDevices))
+	byMAC := make(map[string]*Device)
+	pulled := make(map[int]*models.Device)
+	for i := range nbDevices {
+		nb := &nbDevices[i]
+		byID[nb.ID] = nb
+		device, err := s.PullDevice(nb)
+		if err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("%s: %v", nb.Name, err))
+			continue
+		}
+		pulled[nb.ID] = device
+		if device.MAC != "" {
+			byMAC[device.MAC] = nb
+		}
+	}
+
+	changed := false
+	seen := make(map[int]bool)
+	for i := range devices {
+		device := &devices[i]
+
+		nb := byID[device.NetBoxID]
+		if nb == nil {
+			// Not linked yet, or the linked object was deleted in NetBox
+			nb = byMAC[device.MAC]
+			if nb != nil {
+				result.Linked++
+			}
+		}
+
+		if nb == nil {
+			created, err := s.PushDevice(device, vendors)
+			if err != nil {
+				result.Errors = append(result.Errors, fmt.Sprintf("%s: %v", device.Hostname, err))
+				continue
+			}
+			if err := r.store.SetDeviceNetBoxID(device.MAC, created.ID); err != nil {
+				result.Errors = append(result.Errors, fmt.Sprintf("%s: %v", device.Hostname, err))
+			}
+			seen[created.ID] = true
+			result.CreatedNetBox++
+			continue
+		}
+
+		seen[nb.ID] = true
+		if device.NetBoxID != nb.ID {
+			if err := r.store.SetDeviceNetBoxID(device.MAC, nb.ID); err != nil {
+				result.Errors = append(result.Errors, fmt.Sprintf("%s: %v", device.Hostname, err))
+				continue
+			}
+		}
+
+		remote := pulled[nb.ID]
+		if remote == nil {
+			continue
+		}
+		updatedZTP, updatedNetBox, err := r.reconcileDevice(s, config, device, nb, remote, vendors)
+		if err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("%s: %v", device.Hostname, err))
+			continue
+		}
+		if updatedZTP {
+			result.UpdatedZTP++
+			changed = true
+		}
+		if updatedNetBox {
+			result.UpdatedNetBox++
+		}
+		if !updatedZTP && !updatedNetBox {
+			result.Unchanged++
+		}
+	}
+
+	// Import NetBox devices that ZTP doesn't know. Devices ZTP created (ztp_managed) are
+	// skipped so that deleting a device in ZTP doesn't bring it back.
+	for i := range nbDevices {
+		nb := &nbDevices[i]
+		remote := pulled[nb.ID]
+		if seen[nb.ID] || remote == nil || remote.MAC == "" || isZTPManaged(nb) {
+			continue
+		}
+		if errs := validate.Device(remote, nil); len(errs) > 0 {
+			result.Errors = append(result.Errors, fmt.Sprintf("%s: %v", nb.Name, errs))
+			continue
+		}
+		remote.NetBoxID = nb.ID
+		if err := r.store.CreateDevice(remote); err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("%s: %v", nb.Name, err))
+			continue
+		}
+		result.CreatedZTP++
+		changed = true
+	}
+
+	if changed && r.onChange != nil {
+		if err := r.onChange(); err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("config regeneration failed: %v", err))
+		}
+	}
+
+	return result, nil
+}
+
+// reconcileDevice resolves differences between a linked device pair, field by field,
+// in favour of each field's configured owner
+func (r *Reconciler) reconcileDevice(s *SyncService, config *db.NetBoxConfig, device *models.Device, nb *Device, remote *models.Device, vendors []models.Vendor) (bool, bool, error) {
+	local := *device
+	update := &DeviceUpdate{}
+	pushNetBox := false
+	var newVendor *models.Vendor
+
+	if device.Hostname != remote.Hostname && remote.Hostname != "" {
+		if FieldOwner(config.FieldOwners, FieldHostname) == OwnerNetBox {
+			local.Hostname = remote.Hostname
+		} else {
+			update.Name = device.Hostname
+			pushNetBox = true
+		}
+	}
+
+	if device.SerialNumber != remote.SerialNumber {
+		if FieldOwner(config.FieldOwners, FieldSerial) == OwnerNetBox {
+			local.SerialNumber = remote.SerialNumber
+		} else if device.SerialNumber != "" {
+			update.Serial = device.SerialNumber
+			pushNetBox = true
+		}
+	}
+
+	// A device without a primary IP in NetBox gets the ZTP address whoever owns the field
+	pushIP := false
+	if device.IP != remote.IP {
+		if remote.IP != "" && FieldOwner(config.FieldOwners, FieldIP) == OwnerNetBox {
+			local.IP = remote.IP
+		} else {
+			pushIP = true
+		}
+	}
+
+	if slugify(device.Vendor) != slugify(remote.Vendor) && !(device.Vendor == "" && remote.Vendor == "") {
+		if FieldOwner(config.FieldOwners, FieldVendor) == OwnerNetBox {
+			if remote.Vendor != "" {
+				local.Vendor = remote.Vendor
+			}
+		} else if device.Vendor != "" {
+			for i := range vendors {
+				if vendors[i].ID == device.Vendor {
+					newVendor = &vendors[i]
+					break
+				}
+			}
+		}
+	}
+	if newVendor != nil {
+		manufacturer, err := s.EnsureManufacturer(newVendor)
+		if err != nil {
+			return false, false, fmt.Errorf("failed to ensure manufacturer: %w", err)
+		}
+		deviceType, err := s.EnsureDeviceType(manufacturer.ID, newVendor.ID, newVendor.Name)
+		if err != nil {
+			return false, false, fmt.Errorf("failed to ensure device type: %w", err)
+		}
+		update.DeviceType = deviceType.ID
+		pushNetBox = true
+	}
+
+	// Site, role, tenant and platform are slugs on both sides. A ZTP-owned value is only
+	// pushed when set, since NetBox requires a site and role.
+	pushPlacement := false
+	for _, f := range []struct {
+		field         string
+		local, remote *string
+		value         string
+	}{
+		{FieldSite, &local.Site, &remote.Site, device.Site},
+		{FieldRole, &local.Role, &remote.Role, device.Role},
+		{FieldTenant, &local.Tenant, &remote.Tenant, device.Tenant},
+		{FieldPlatform, &local.Platform, &remote.Platform, device.Platform},
+	} {
+		if f.value == *f.remote {
+			continue
+		}
+		if FieldOwner(config.FieldOwners, f.field) == OwnerNetBox {
+			*f.local = *f.remote
+		} else if f.value != "" {
+			pushPlacement = true
+		}
+	}
+	if pushPlacement {
+		placement, err := s.resolvePlacement(device)
+		if err != nil {
+			return false, false, err
+		}
+		if FieldOwner(config.FieldOwners, FieldSite) == OwnerZTP {
+			update.Site = placement.Site
+		}
+		if FieldOwner(config.FieldOwners, FieldRole) == OwnerZTP {
+			update.Role = placement.Role
+		}
+		if FieldOwner(config.FieldOwners, FieldTenant) == OwnerZTP {
+			update.Tenant = placement.Tenant
+		}
+		if FieldOwner(config.FieldOwners, FieldPlatform) == OwnerZTP {
+			update.Platform = placement.Platform
+		}
+		pushNetBox = true
+	}
+
+	// Status is always pushed from ZTP
+	if status := mapStatusToNetBox(device.Status); status != nb.Status.Value {
+		update.Status = status
+		pushNetBox = true
+	}
+
+	updatedZTP := local.Hostname != device.Hostname || local.IP != device.IP ||
+		local.SerialNumber != device.SerialNumber || local.Vendor != device.Vendor ||
+		local.Site != device.Site || local.Role != device.Role ||
+		local.Tenant != device.Tenant || local.Platform != device.Platform
+	if updatedZTP {
+		if errs := validate.Device(&local, nil); len(errs) > 0 {
+			return false, false, errs
+		}
+		if err := r.store.UpdateDevice(&local); err != nil {
+			return false, false, err
+		}
+	}
+
+	if pushNetBox {
+		if _, err := s.Devices.PartialUpdate(nb.ID, update); err != nil {
+			return updatedZTP, false, fmt.Errorf("failed to update NetBox device: %w", err)
+		}
+	}
+	if pushIP {
+		if err := s.AssignPrimaryIP(nb, device); err != nil {
+			return updatedZTP, pushNetBox, err
+		}
+		pushNetBox = true
+	}
+
+	return updatedZTP, pushNetBox, nil
+}
+
+// FieldOwner returns the source of truth for a field, falling back to DefaultFieldOwners
+func FieldOwner(owners map[string]string, field string) string {
+	if owner, ok := owners[field]; ok && (owner == OwnerZTP || owner == OwnerNetBox) {
+		return owner
+	}
+	return DefaultFieldOwners[field]
+}
+
+// VerifyWebhookSignature checks a NetBox X-Hook-Signature header, which is the hex
+// HMAC-SHA512 of the request body keyed with the webhook secret
+func VerifyWebhookSignature(secret string, body []byte, signature string) bool {
+	if secret == "" || signature == "" {
+		return false
+	}
+	expected, err := hex.DecodeString(strings.TrimSpace(signature))
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(sha512.New, []byte(secret))
+	mac.Write(body)
+	return hmac.Equal(mac.Sum(nil), expected)
+}
+
+// WebhookEvent is the payload NetBox sends for object changes
+type WebhookEvent struct {
+	Event     string         `json:"event"` // created, updated, deleted
+	Timestamp string         `json:"timestamp"`
+	Model     string         `json:"model"` // device, interface, ipaddress, ...
+	Username  string         `json:"username"`
+	RequestID string         `json:"request_id"`
+	Data      map[string]any `json:"data"`
+}
+
+// RelevantWebhookModels lists the NetBox models whose changes affect ZTP devices
+var RelevantWebhookModels = map[string]bool{
+	"device":    true,
+	"interface": true,
+	"ipaddress": true,
+}
+
+func syncInterval(config *db.NetBoxConfig) time.Duration {
+	if config.SyncInterval <= 0 {
+		return DefaultSyncInterval
+	}
+	interval := time.Duration(config.SyncInterval) * time.Second
+	if interval < minSyncInterval {
+		return minSyncInterval
+	}
+	return interval
+}
+
+func isZTPManaged(nb *Device) bool {
+	managed, _ := nb.CustomFields["ztp_managed"].(bool)
+	return managed
+}
@@ -0,0 +1,93 @@
+package netbox
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/ztp-server/backend/db"
+	"github.com/ztp-server/backend/inventory"
+	"github.com/ztp-server/backend/models"
+)
+
+func init() {
+	inventory.Register(models.InventoryNetBox, OpenSource)
+}
+
+// Source exposes NetBox as an inventory source
+type Source struct {
+	sync *SyncService
+}
+
+// OpenSource creates a NetBox inventory source. The built-in source uses the default
+// site, role and DHCP prefix length from the NetBox integration settings.
+func OpenSource(config *models.InventorySource, store *db.Store) (inventory.Source, error) {
+	if config.URL == "" || config.Token == "" {
+		return nil, fmt.Errorf("NetBox not configured")
+	}
+
+	s := NewSyncService(config.URL, config.Token)
+	if config.Builtin {
+		nbConfig, err := store.GetNetBoxConfig()
+		if err != nil {
+			return nil, err
+		}
+		s.DefaultSiteID = nbConfig.SiteID
+		s.DefaultRoleID = nbConfig.RoleID
+	}
+	if settings, err := store.GetSettings(); err == nil {
+		s.PrefixLength = PrefixLength(settings)
+	}
+	return &Source{sync: s}, nil
+}
+
+// Check tests the connection to NetBox
+func (src *Source) Check() error {
+	return src.sync.CheckConnection()
+}
+
+// ListDevices returns all NetBox devices as ZTP devices
+func (src *Source) ListDevices() ([]inventory.Record, error) {
+	return src.sync.PullRecords()
+}
+
+// PushDevices creates or updates ZTP devices in NetBox
+func (src *Source) PushDevices(devices []models.Device, vendors []models.Vendor) (*inventory.Result, error) {
+	result := src.sync.PushDevices(devices, vendors)
+	return &inventory.Result{
+		Created: result.Created,
+		Updated: result.Updated,
+		Skipped: result.Skipped,
+		Errors:  result.Errors,
+	}, nil
+}
+
+// ListVendors returns NetBox manufacturers as ZTP vendors
+func (src *Source) ListVendors() ([]models.Vendor, error) {
+	manufacturers, err := src.sync.Manufacturers.ListAll()
+	if err != nil {
+		return nil, err
+	}
+	vendors := make([]models.Vendor, 0, len(manufacturers))
+	for i := range manufacturers {
+		vendors = append(vendors, *VendorFromManufacturer(&manufacturers[i]))
+	}
+	return vendors, nil
+}
+
+// ListSites returns NetBox sites
+func (src *Source) ListSites() ([]inventory.Site, error) {
+	sites, err := src.sync.Sites.ListAll()
+	if err != nil {
+		return nil, err
+	}
+	result := make([]inventory.Site, 0, len(sites))
+	for _, site := range sites {
+		result = append(result, inventory.Site{
+			ID:          strconv.Itoa(site.ID),
+			Name:        site.Name,
+			Slug:        site.Slug,
+			Description: site.Description,
+		})
+	}
+	return result, nil
+}
@@ -3,10 +3,12 @@ package netbox
 import (
 	"fmt"
 	"log"
-	"regexp"
+	"strconv"
 	"strings"
 
+	"github.com/ztp-server/backend/inventory"
 	"github.com/ztp-server/backend/models"
+	"github.com/ztp-server/backend/utils"
 )
 
 // SyncService handles bidirectional sync between ZTP server and NetBox
@@ -19,10 +21,19 @@ type SyncService struct {
 	Sites         *SiteService
 	Interfaces    *InterfaceService
 	IPAddresses   *IPAddressService
+	Prefixes      *PrefixService
+	Tenants       *TenantService
+	Platforms     *PlatformService
 
-	// Default IDs for creating devices
+	// Default IDs for creating devices. EnsurePrerequisites fills in whichever is unset.
 	DefaultSiteID int
 	DefaultRoleID int
+
+	// Mask length for IP addresses created in NetBox, /32 if unset
+	PrefixLength int
+
+	// Slug to ID lookups, keyed by "kind:slug"
+	slugIDs map[string]int
 }
 
 // NewSyncService creates a new sync service
@@ -37,6 +48,10 @@ func NewSyncService(baseURL, token string) *SyncService {
 		Sites:         NewSiteService(client),
 		Interfaces:    NewInterfaceService(client),
 		IPAddresses:   NewIPAddressService(client),
+		Prefixes:      NewPrefixService(client),
+		Tenants:       NewTenantService(client),
+		Platforms:     NewPlatformService(client),
+		slugIDs:       map[string]int{},
 	}
 }
 
@@ -53,27 +68,105 @@ type SyncResult struct {
 	Errors    []string `json:"errors,omitempty"`
 }
 
-// EnsurePrerequisites ensures required NetBox objects exist
+// EnsurePrerequisites ensures required NetBox objects exist. A configured default site
+// or role is kept; otherwise the built-in "ZTP Lab" site and "Network Device" role are used.
 func (s *SyncService) EnsurePrerequisites() error {
 	// Ensure default site exists
-	site, err := s.Sites.GetOrCreate("ZTP Lab", "ztp-lab")
-	if err != nil {
-		return fmt.Errorf("failed to create default site: %w", err)
+	if s.DefaultSiteID == 0 {
+		site, err := s.Sites.GetOrCreate("ZTP Lab", "ztp-lab")
+		if err != nil {
+			return fmt.Errorf("failed to create default site: %w", err)
+		}
+		s.DefaultSiteID = site.ID
+		log.Printf("[netbox] Using site: %s (ID: %d)", site.Name, site.ID)
 	}
-	s.DefaultSiteID = site.ID
-	log.Printf("[netbox] Using site: %s (ID: %d)", site.Name, site.ID)
 
 	// Ensure default role exists
-	role, err := s.DeviceRoles.GetOrCreate("Network Device", "network-device", "2196f3")
-	if err != nil {
-		return fmt.Errorf("failed to create default role: %w", err)
+	if s.DefaultRoleID == 0 {
+		role, err := s.DeviceRoles.GetOrCreate("Network Device", "network-device", "2196f3")
+		if err != nil {
+			return fmt.Errorf("failed to create default role: %w", err)
+		}
+		s.DefaultRoleID = role.ID
+		log.Printf("[netbox] Using device role: %s (ID: %d)", role.Name, role.ID)
 	}
-	s.DefaultRoleID = role.ID
-	log.Printf("[netbox] Using device role: %s (ID: %d)", role.Name, role.ID)
 
 	return nil
 }
 
+// devicePlacement holds the NetBox IDs of a device's site, role, tenant and platform.
+// Fields the ZTP device leaves empty are 0.
+type devicePlacement struct {
+	Site     int
+	Role     int
+	Tenant   int
+	Platform int
+}
+
+// resolvePlacement looks up the NetBox IDs for the slugs set on a ZTP device
+func (s *SyncService) resolvePlacement(device *models.Device) (*devicePlacement, error) {
+	var p devicePlacement
+	var err error
+	if p.Site, err = s.lookupSlug("site", device.Site, func(slug string) (int, error) {
+		site, err := s.Sites.GetBySlug(slug)
+		if err != nil || site == nil {
+			return 0, err
+		}
+		return site.ID, nil
+	}); err != nil {
+		return nil, err
+	}
+	if p.Role, err = s.lookupSlug("role", device.Role, func(slug string) (int, error) {
+		role, err := s.DeviceRoles.GetBySlug(slug)
+		if err != nil || role == nil {
+			return 0, err
+		}
+		return role.ID, nil
+	}); err != nil {
+		return nil, err
+	}
+	if p.Tenant, err = s.lookupSlug("tenant", device.Tenant, func(slug string) (int, error) {
+		tenant, err := s.Tenants.GetBySlug(slug)
+		if err != nil || tenant == nil {
+			return 0, err
+		}
+		return tenant.ID, nil
+	}); err != nil {
+		return nil, err
+	}
+	if p.Platform, err = s.lookupSlug("platform", device.Platform, func(slug string) (int, error) {
+		platform, err := s.Platforms.GetBySlug(slug)
+		if err != nil || platform == nil {
+			return 0, err
+		}
+		return platform.ID, nil
+	}); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// lookupSlug resolves a slug to an object ID, caching the result for the life of the
+// service. Unknown slugs are an error rather than being created.
+func (s *SyncService) lookupSlug(kind, slug string, find func(string) (int, error)) (int, error) {
+	if slug == "" {
+		return 0, nil
+	}
+	key := kind + ":" + slug
+	if id, ok := s.slugIDs[key]; ok {
+		return id, nil
+	}
+	id, err := find(slug)
+	if err != nil {
+		return 0, fmt.Errorf("failed to look up %s %q: %w", kind, slug, err)
+	}
+	if id == 0 {
+		return 0, fmt.Errorf("%s %q not found in NetBox", kind, slug)
+	}
+	s.slugIDs[key] = id
+	return id, nil
+}
+
 // EnsureManufacturer ensures a manufacturer exists in NetBox
 func (s *SyncService) EnsureManufacturer(vendor *models.Vendor) (*Manufacturer, error) {
 	slug := slugify(vendor.ID)
@@ -132,6 +225,11 @@ func (s *SyncService) PushDevice(device *models.Device, vendors []models.Vendor)
 		deviceTypeID = deviceType.ID
 	}
 
+	placement, err := s.resolvePlacement(device)
+	if err != nil {
+		return nil, err
+	}
+
 	// Check if device already exists by name
 	existing, err := s.Devices.GetByName(device.Hostname)
 	if err != nil {
@@ -144,22 +242,41 @@ func (s *SyncService) PushDevice(device *models.Device, vendors []models.Vendor)
 	if existing != nil {
 		// Update existing device
 		update := &DeviceUpdate{
-			Status: status,
-			Serial: device.SerialNumber,
+			Status:   status,
+			Serial:   device.SerialNumber,
+			Site:     placement.Site,
+			Role:     placement.Role,
+			Tenant:   placement.Tenant,
+			Platform: placement.Platform,
 			CustomFields: map[string]any{
 				"mac_address": device.MAC,
 				"ztp_managed": true,
 			},
 		}
-		return s.Devices.PartialUpdate(existing.ID, update)
+		updated, err := s.Devices.PartialUpdate(existing.ID, update)
+		if err != nil {
+			return nil, err
+		}
+		if err := s.AssignPrimaryIP(updated, device); err != nil {
+			log.Printf("[netbox] Warning: failed to assign IP for device %s: %v", device.Hostname, err)
+		}
+		return updated, nil
 	}
 
-	// Create new device
+	// Create new device, placing it in the defaults unless the device names its own
+	if placement.Site == 0 {
+		placement.Site = s.DefaultSiteID
+	}
+	if placement.Role == 0 {
+		placement.Role = s.DefaultRoleID
+	}
 	create := &DeviceCreate{
 		Name:       device.Hostname,
 		DeviceType: deviceTypeID,
-		Role:       s.DefaultRoleID,
-		Site:       s.DefaultSiteID,
+		Role:       placement.Role,
+		Site:       placement.Site,
+		Tenant:     placement.Tenant,
+		Platform:   placement.Platform,
 		Status:     status,
 		Serial:     device.SerialNumber,
 		CustomFields: map[string]any{
@@ -187,9 +304,92 @@ func (s *SyncService) PushDevice(device *models.Device, vendors []models.Vendor)
 		}
 	}
 
+	if err := s.AssignPrimaryIP(nbDevice, device); err != nil {
+		log.Printf("[netbox] Warning: failed to assign IP for device %s: %v", device.Hostname, err)
+	}
+
 	return nbDevice, nil
 }
 
+// AssignPrimaryIP makes the ZTP device's IP the primary IPv4 address of its NetBox device.
+// The address is attached to the management interface, which is created if missing. An
+// existing IPAM entry for the address, such as one allocated from a prefix, is reused.
+func (s *SyncService) AssignPrimaryIP(nbDevice *Device, device *models.Device) error {
+	if device.IP == "" {
+		return nil
+	}
+	if nbDevice.PrimaryIP4 != nil && HostAddress(nbDevice.PrimaryIP4.Address) == device.IP {
+		return nil
+	}
+
+	iface, err := s.managementInterface(nbDevice.ID, device.MAC)
+	if err != nil {
+		return fmt.Errorf("failed to ensure management interface: %w", err)
+	}
+
+	ip, err := s.IPAddresses.GetByAddress(device.IP)
+	if err != nil {
+		return fmt.Errorf("failed to look up IP address: %w", err)
+	}
+	if ip == nil {
+		prefixLength := s.PrefixLength
+		if prefixLength == 0 {
+			prefixLength = 32
+		}
+		ip, err = s.IPAddresses.Create(&IPAddressCreate{
+			Address:            fmt.Sprintf("%s/%d", device.IP, prefixLength),
+			Status:             "active",
+			AssignedObjectType: "dcim.interface",
+			AssignedObjectID:   iface.ID,
+			DNSName:            device.Hostname,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to create IP address: %w", err)
+		}
+	} else if ip.AssignedObjectType != "dcim.interface" || ip.AssignedObjectID != iface.ID {
+		ip, err = s.IPAddresses.PartialUpdate(ip.ID, &IPAddressUpdate{
+			Status:             "active",
+			AssignedObjectType: "dcim.interface",
+			AssignedObjectID:   iface.ID,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to assign IP address: %w", err)
+		}
+	}
+
+	if _, err := s.Devices.PartialUpdate(nbDevice.ID, &DeviceUpdate{PrimaryIP4: ip.ID}); err != nil {
+		return fmt.Errorf("failed to set primary IP: %w", err)
+	}
+	return nil
+}
+
+// managementInterface returns the device's mgmt0 interface, or the interface carrying
+// its MAC, creating mgmt0 if neither exists
+func (s *SyncService) managementInterface(deviceID int, mac string) (*Interface, error) {
+	interfaces, err := s.Interfaces.ListByDevice(deviceID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range interfaces {
+		if interfaces[i].Name == "mgmt0" {
+			return &interfaces[i], nil
+		}
+	}
+	for i := range interfaces {
+		if mac != "" && strings.EqualFold(interfaces[i].MacAddress, mac) {
+			return &interfaces[i], nil
+		}
+	}
+
+	return s.Interfaces.Create(&InterfaceCreate{
+		Device:     deviceID,
+		Name:       "mgmt0",
+		Type:       InterfaceTypeEnum.Ethernet1G,
+		Enabled:    true,
+		MacAddress: mac,
+	})
+}
+
 // PushDevices pushes multiple ZTP devices to NetBox
 func (s *SyncService) PushDevices(devices []models.Device, vendors []models.Vendor) *SyncResult {
 	result := &SyncResult{}
@@ -239,6 +439,14 @@ func (s *SyncService) PullDevice(nbDevice *Device) (*models.Device, error) {
 		}
 	}
 
+	if mac != "" {
+		parsed, err := utils.ParseMac(mac)
+		if err != nil {
+			return nil, err
+		}
+		mac = parsed
+	}
+
 	// Get IP from primary_ip4
 	ip := ""
 	if nbDevice.PrimaryIP4 != nil {
@@ -258,6 +466,15 @@ func (s *SyncService) PullDevice(nbDevice *Device) (*models.Device, error) {
 		Vendor:       vendor,
 		SerialNumber: nbDevice.Serial,
 		Status:       mapStatusFromNetBox(nbDevice.Status.Value),
+		NetBoxID:     nbDevice.ID,
+		Site:         nbDevice.Site.Slug,
+		Role:         nbDevice.Role.Slug,
+	}
+	if nbDevice.Tenant != nil {
+		device.Tenant = nbDevice.Tenant.Slug
+	}
+	if nbDevice.Platform != nil {
+		device.Platform = nbDevice.Platform.Slug
 	}
 
 	return device, nil
@@ -293,6 +510,64 @@ func (s *SyncService) PullDevices() ([]models.Device, *SyncResult, error) {
 	return devices, result, nil
 }
 
+// PullRecords converts every NetBox device to a ZTP device for pull planning
+func (s *SyncService) PullRecords() ([]inventory.Record, error) {
+	nbDevices, err := s.Devices.ListAll(nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list NetBox devices: %w", err)
+	}
+
+	records := make([]inventory.Record, 0, len(nbDevices))
+	for i := range nbDevices {
+		device, err := s.PullDevice(&nbDevices[i])
+		records = append(records, inventory.Record{
+			ExternalID: strconv.Itoa(nbDevices[i].ID),
+			Name:       nbDevices[i].Name,
+			Device:     device,
+			Err:        err,
+		})
+	}
+	return records, nil
+}
+
+// VendorFromManufacturer converts a NetBox manufacturer to a ZTP vendor. Vendor settings
+// come from the manufacturer's custom fields, with the usual defaults.
+func VendorFromManufacturer(mfr *Manufacturer) *models.Vendor {
+	vendor := &models.Vendor{
+		ID:            mfr.Slug,
+		Name:          mfr.Name,
+		MacPrefixes:   []string{},
+		BackupCommand: "show running-config",
+		SSHPort:       22,
+	}
+
+	if mfr.CustomFields != nil {
+		if v, ok := mfr.CustomFields["mac_prefixes"].(string); ok && v != "" {
+			// Split comma-separated prefixes
+			for _, p := range strings.Split(v, ",") {
+				p = strings.TrimSpace(p)
+				if p != "" {
+					vendor.MacPrefixes = append(vendor.MacPrefixes, p)
+				}
+			}
+		}
+		if v, ok := mfr.CustomFields["backup_command"].(string); ok && v != "" {
+			vendor.BackupCommand = v
+		}
+		if v, ok := mfr.CustomFields["ssh_port"].(float64); ok {
+			vendor.SSHPort = int(v)
+		}
+		if v, ok := mfr.CustomFields["vendor_class"].(string); ok && v != "" {
+			vendor.VendorClass = v
+		}
+		if v, ok := mfr.CustomFields["default_template"].(string); ok && v != "" {
+			vendor.DefaultTemplate = v
+		}
+	}
+
+	return vendor
+}
+
 // SyncVendors syncs ZTP vendors to NetBox manufacturers
 func (s *SyncService) SyncVendors(vendors []models.Vendor) *SyncResult {
 	result := &SyncResult{}
@@ -319,14 +594,7 @@ func (s *SyncService) SyncVendors(vendors []models.Vendor) *SyncResult {
 // Helper functions
 
 func slugify(s string) string {
-	s = strings.ToLower(s)
-	s = regexp.MustCompile(`[^a-z0-9-]+`).ReplaceAllString(s, "-")
-	s = regexp.MustCompile(`-+`).ReplaceAllString(s, "-")
-	s = strings.Trim(s, "-")
-	if s == "" {
-		s = "unknown"
-	}
-	return s
+	return inventory.Slugify(s)
 }
 
 func mapStatusToNetBox(ztpStatus string) string {
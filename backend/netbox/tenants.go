@@ -0,0 +1,65 @@
+package netbox
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// TenantService handles tenant-related API operations
+type TenantService struct {
+	client *Client
+}
+
+// NewTenantService creates a new tenant service
+func NewTenantService(client *Client) *TenantService {
+	return &TenantService{client: client}
+}
+
+// List returns a paginated list of tenants
+func (s *TenantService) List(params map[string]string) (*PaginatedResponse[Tenant], error) {
+	var result PaginatedResponse[Tenant]
+	path := "/api/tenancy/tenants/" + BuildQuery(params)
+	err := s.client.Get(path, &result)
+	return &result, err
+}
+
+// ListAll returns all tenants
+func (s *TenantService) ListAll() ([]Tenant, error) {
+	var all []Tenant
+	params := map[string]string{"limit": "100"}
+	offset := 0
+
+	for {
+		params["offset"] = strconv.Itoa(offset)
+		result, err := s.List(params)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, result.Results...)
+		if result.Next == "" || len(result.Results) == 0 {
+			break
+		}
+		offset += len(result.Results)
+	}
+	return all, nil
+}
+
+// Get returns a single tenant by ID
+func (s *TenantService) Get(id int) (*Tenant, error) {
+	var result Tenant
+	path := fmt.Sprintf("/api/tenancy/tenants/%d/", id)
+	err := s.client.Get(path, &result)
+	return &result, err
+}
+
+// GetBySlug returns a tenant by slug
+func (s *TenantService) GetBySlug(slug string) (*Tenant, error) {
+	result, err := s.List(map[string]string{"slug": slug})
+	if err != nil {
+		return nil, err
+	}
+	if len(result.Results) == 0 {
+		return nil, nil
+	}
+	return &result.Results[0], nil
+}
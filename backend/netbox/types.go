@@ -127,15 +127,21 @@ type DeviceTypeCreate struct {
 
 // Site represents a NetBox site
 type Site struct {
-	ID          int         `json:"id,omitempty"`
-	URL         string      `json:"url,omitempty"`
-	Display     string      `json:"display,omitempty"`
-	Name        string      `json:"name"`
-	Slug        string      `json:"slug"`
-	Status      interface{} `json:"status,omitempty"`
-	Description string      `json:"description,omitempty"`
-	Created     time.Time   `json:"created,omitempty"`
-	LastUpdated time.Time   `json:"last_updated,omitempty"`
+	ID              int            `json:"id,omitempty"`
+	URL             string         `json:"url,omitempty"`
+	Display         string         `json:"display,omitempty"`
+	Name            string         `json:"name"`
+	Slug            string         `json:"slug"`
+	Status          interface{}    `json:"status,omitempty"`
+	Region          *NestedRef     `json:"region,omitempty"`
+	Tenant          *NestedRef     `json:"tenant,omitempty"`
+	Facility        string         `json:"facility,omitempty"`
+	TimeZone        string         `json:"time_zone,omitempty"`
+	PhysicalAddress string         `json:"physical_address,omitempty"`
+	Description     string         `json:"description,omitempty"`
+	CustomFields    map[string]any `json:"custom_fields,omitempty"`
+	Created         time.Time      `json:"created,omitempty"`
+	LastUpdated     time.Time      `json:"last_updated,omitempty"`
 }
 
 // SiteCreate is used to create a site
@@ -169,24 +175,52 @@ type DeviceRoleCreate struct {
 	Description string `json:"description,omitempty"`
 }
 
+// Tenant represents a NetBox tenant
+type Tenant struct {
+	ID          int       `json:"id,omitempty"`
+	URL         string    `json:"url,omitempty"`
+	Display     string    `json:"display,omitempty"`
+	Name        string    `json:"name"`
+	Slug        string    `json:"slug"`
+	Description string    `json:"description,omitempty"`
+	Created     time.Time `json:"created,omitempty"`
+	LastUpdated time.Time `json:"last_updated,omitempty"`
+}
+
+// Platform represents a NetBox platform (device OS)
+type Platform struct {
+	ID           int        `json:"id,omitempty"`
+	URL          string     `json:"url,omitempty"`
+	Display      string     `json:"display,omitempty"`
+	Name         string     `json:"name"`
+	Slug         string     `json:"slug"`
+	Manufacturer *NestedRef `json:"manufacturer,omitempty"`
+	Description  string     `json:"description,omitempty"`
+	Created      time.Time  `json:"created,omitempty"`
+	LastUpdated  time.Time  `json:"last_updated,omitempty"`
+}
+
 // Device represents a NetBox device
 type Device struct {
-	ID           int              `json:"id,omitempty"`
-	URL          string           `json:"url,omitempty"`
-	Display      string           `json:"display,omitempty"`
-	Name         string           `json:"name"`
-	DeviceType   NestedDeviceType `json:"device_type"`
-	Role         NestedDeviceRole `json:"role"`
-	Site         NestedSite       `json:"site"`
-	Status       StatusChoice     `json:"status"`
-	Serial       string           `json:"serial,omitempty"`
-	AssetTag     string           `json:"asset_tag,omitempty"`
-	PrimaryIP4   *NestedIPAddress `json:"primary_ip4,omitempty"`
-	PrimaryIP6   *NestedIPAddress `json:"primary_ip6,omitempty"`
-	Comments     string           `json:"comments,omitempty"`
-	CustomFields map[string]any   `json:"custom_fields,omitempty"`
-	Created      time.Time        `json:"created,omitempty"`
-	LastUpdated  time.Time        `json:"last_updated,omitempty"`
+	ID            int              `json:"id,omitempty"`
+	URL           string           `json:"url,omitempty"`
+	Display       string           `json:"display,omitempty"`
+	Name          string           `json:"name"`
+	DeviceType    NestedDeviceType `json:"device_type"`
+	Role          NestedDeviceRole `json:"role"`
+	Site          NestedSite       `json:"site"`
+	Tenant        *NestedRef       `json:"tenant,omitempty"`
+	Platform      *NestedRef       `json:"platform,omitempty"`
+	Status        StatusChoice     `json:"status"`
+	Serial        string           `json:"serial,omitempty"`
+	AssetTag      string           `json:"asset_tag,omitempty"`
+	PrimaryIP4    *NestedIPAddress `json:"primary_ip4,omitempty"`
+	PrimaryIP6    *NestedIPAddress `json:"primary_ip6,omitempty"`
+	Comments      string           `json:"comments,omitempty"`
+	ConfigContext map[string]any   `json:"config_context,omitempty"`
+	CustomFields  map[string]any   `json:"custom_fields,omitempty"`
+	Created       time.Time        `json:"created,omitempty"`
+	LastUpdated   time.Time        `json:"last_updated,omitempty"`
 }
 
 // DeviceCreate is used to create a device
@@ -195,6 +229,8 @@ type DeviceCreate struct {
 	DeviceType   int            `json:"device_type"`
 	Role         int            `json:"role"`
 	Site         int            `json:"site"`
+	Tenant       int            `json:"tenant,omitempty"`
+	Platform     int            `json:"platform,omitempty"`
 	Status       string         `json:"status,omitempty"`
 	Serial       string         `json:"serial,omitempty"`
 	AssetTag     string         `json:"asset_tag,omitempty"`
@@ -208,10 +244,13 @@ type DeviceUpdate struct {
 	DeviceType   int            `json:"device_type,omitempty"`
 	Role         int            `json:"role,omitempty"`
 	Site         int            `json:"site,omitempty"`
+	Tenant       int            `json:"tenant,omitempty"`
+	Platform     int            `json:"platform,omitempty"`
 	Status       string         `json:"status,omitempty"`
 	Serial       string         `json:"serial,omitempty"`
 	AssetTag     string         `json:"asset_tag,omitempty"`
 	Comments     string         `json:"comments,omitempty"`
+	PrimaryIP4   int            `json:"primary_ip4,omitempty"`
 	CustomFields map[string]any `json:"custom_fields,omitempty"`
 }
 
@@ -225,6 +264,7 @@ type Interface struct {
 	Type        StatusChoice `json:"type"`
 	Enabled     bool         `json:"enabled"`
 	MacAddress  string       `json:"mac_address,omitempty"`
+	MTU         int          `json:"mtu,omitempty"`
 	Description string       `json:"description,omitempty"`
 }
 
@@ -240,13 +280,16 @@ type InterfaceCreate struct {
 
 // IPAddress represents a NetBox IP address
 type IPAddress struct {
-	ID          int          `json:"id,omitempty"`
-	URL         string       `json:"url,omitempty"`
-	Display     string       `json:"display,omitempty"`
-	Address     string       `json:"address"`
-	Status      StatusChoice `json:"status"`
-	AssignedObj interface{}  `json:"assigned_object,omitempty"`
-	Description string       `json:"description,omitempty"`
+	ID                 int          `json:"id,omitempty"`
+	URL                string       `json:"url,omitempty"`
+	Display            string       `json:"display,omitempty"`
+	Address            string       `json:"address"`
+	Status             StatusChoice `json:"status"`
+	AssignedObjectType string       `json:"assigned_object_type,omitempty"`
+	AssignedObjectID   int          `json:"assigned_object_id,omitempty"`
+	AssignedObj        interface{}  `json:"assigned_object,omitempty"`
+	DNSName            string       `json:"dns_name,omitempty"`
+	Description        string       `json:"description,omitempty"`
 }
 
 // IPAddressCreate is used to create an IP address
@@ -255,9 +298,45 @@ type IPAddressCreate struct {
 	Status               string `json:"status,omitempty"`
 	AssignedObjectType   string `json:"assigned_object_type,omitempty"`
 	AssignedObjectID     int    `json:"assigned_object_id,omitempty"`
+	DNSName              string `json:"dns_name,omitempty"`
 	Description          string `json:"description,omitempty"`
 }
 
+// IPAddressUpdate is used for partial updates of an IP address
+type IPAddressUpdate struct {
+	Status             string `json:"status,omitempty"`
+	AssignedObjectType string `json:"assigned_object_type,omitempty"`
+	AssignedObjectID   int    `json:"assigned_object_id,omitempty"`
+	DNSName            string `json:"dns_name,omitempty"`
+	Description        string `json:"description,omitempty"`
+}
+
+// Prefix represents a NetBox IPAM prefix
+type Prefix struct {
+	ID          int          `json:"id,omitempty"`
+	URL         string       `json:"url,omitempty"`
+	Display     string       `json:"display,omitempty"`
+	Family      StatusChoice `json:"family"`
+	Prefix      string       `json:"prefix"`
+	Status      StatusChoice `json:"status"`
+	Site        *NestedSite  `json:"site,omitempty"`
+	IsPool      bool         `json:"is_pool"`
+	Description string       `json:"description,omitempty"`
+}
+
+// AvailableIP is an unallocated address within a prefix
+type AvailableIP struct {
+	Family  int    `json:"family"`
+	Address string `json:"address"`
+}
+
+// AvailableIPRequest is used to allocate the next available address in a prefix
+type AvailableIPRequest struct {
+	Status      string `json:"status,omitempty"`
+	DNSName     string `json:"dns_name,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
 // APIError represents a NetBox API error response
 type APIError struct {
 	Detail string            `json:"detail,omitempty"`
@@ -0,0 +1,396 @@
+// Package netconf is a minimal NETCONF client (RFC 6241) covering what backups need:
+// the hello exchange, <get-config> and <close-session>. Sessions run over any
+// io.ReadWriteCloser, normally the "netconf" SSH subsystem (RFC 6242).
+package netconf
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// NETCONF base capabilities. Base 1.1 switches the session to chunked framing once
+// both peers advertise it.
+const (
+	CapBase10 = "urn:ietf:params:netconf:base:1.0"
+	CapBase11 = "urn:ietf:params:netconf:base:1.1"
+
+	namespace = "urn:ietf:params:xml:ns:netconf:base:1.0"
+	// endOfMessage delimits messages in base 1.0 framing
+	endOfMessage = "]]>]]>"
+)
+
+// ErrTimeout is returned when the server doesn't answer in time. The session is
+// unusable afterwards.
+var ErrTimeout = errors.New("timed out waiting for NETCONF reply")
+
+// RPCError is an <rpc-error> returned by the server
+type RPCError struct {
+	Type     string `xml:"error-type"`
+	Tag      string `xml:"error-tag"`
+	Severity string `xml:"error-severity"`
+	Message  string `xml:"error-message"`
+}
+
+func (e *RPCError) Error() string {
+	msg := strings.TrimSpace(e.Message)
+	if msg == "" {
+		msg = e.Tag
+	}
+	return fmt.Sprintf("NETCONF %s error: %s", e.Type, msg)
+}
+
+// Session is a NETCONF session. It is not safe for concurrent use.
+type Session struct {
+	ID           string   // Session ID assigned by the server
+	Capabilities []string // Capabilities the server advertised
+
+	transport io.ReadWriteCloser
+	reader    *bufio.Reader
+	timeout   time.Duration
+	chunked   bool
+	messageID int
+	closeOnce sync.Once
+}
+
+// NewSession exchanges hello messages over the transport. Each exchange, including the
+// hello, fails with ErrTimeout if the server takes longer than timeout; 0 waits forever.
+func NewSession(transport io.ReadWriteCloser, timeout time.Duration) (*Session, error) {
+	s := &Session{
+		transport: transport,
+		reader:    bufio.NewReader(transport),
+		timeout:   timeout,
+	}
+
+	hello := `<?xml version="1.0" encoding="UTF-8"?><hello xmlns="` + namespace + `"><capabilities>` +
+		`<capability>` + CapBase10 + `</capability><capability>` + CapBase11 + `</capability>` +
+		`</capabilities></hello>`
+	reply, err := s.exchange([]byte(hello))
+	if err != nil {
+		s.abort()
+		return nil, fmt.Errorf("hello failed: %w", err)
+	}
+
+	var serverHello struct {
+		XMLName      xml.Name `xml:"hello"`
+		Capabilities []string `xml:"capabilities>capability"`
+		SessionID    string   `xml:"session-id"`
+	}
+	if err := xml.Unmarshal(reply, &serverHello); err != nil {
+		s.abort()
+		return nil, fmt.Errorf("invalid hello from server: %w", err)
+	}
+	for _, c := range serverHello.Capabilities {
+		c = strings.TrimSpace(c)
+		s.Capabilities = append(s.Capabilities, c)
+		if c == CapBase11 {
+			s.chunked = true
+		}
+	}
+	s.ID = strings.TrimSpace(serverHello.SessionID)
+	return s, nil
+}
+
+// HasCapability reports whether the server advertised a capability. Parameters after
+// "?" in the advertised URI are ignored.
+func (s *Session) HasCapability(uri string) bool {
+	for _, c := range s.Capabilities {
+		if c == uri || strings.HasPrefix(c, uri+"?") {
+			return true
+		}
+	}
+	return false
+}
+
+// GetConfig returns the <data> element of a <get-config> reply for a datastore such as
+// running. Namespaces declared on the enclosing <rpc-reply> are declared again on the
+// element, so prefixed names inside it, like Junos's junos:changed-seconds attributes,
+// stay bound when it is stored on its own.
+func (s *Session) GetConfig(source string) (string, error) {
+	reply, err := s.RPC("<get-config><source><" + source + "/></source></get-config>")
+	if err != nil {
+		return "", err
+	}
+	data, err := extractElement(reply, "data")
+	if err != nil {
+		return "", fmt.Errorf("invalid get-config reply: %w", err)
+	}
+	return string(data), nil
+}
+
+// extractElement returns the first element with the given local name in a document,
+// byte for byte, with the namespace declarations in scope at the element added to its
+// start tag
+func extractElement(doc []byte, local string) ([]byte, error) {
+	dec := xml.NewDecoder(bytes.NewReader(doc))
+	var scope [][]xml.Attr // Attributes of the enclosing elements, outermost first
+	for {
+		start := dec.InputOffset()
+		tok, err := dec.RawToken()
+		if err == io.EOF {
+			return nil, fmt.Errorf("no <%s> element", local)
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if t.Name.Local != local {
+				scope = append(scope, t.Attr)
+				continue
+			}
+			end, err := elementEnd(dec)
+			if err != nil {
+				return nil, err
+			}
+			return withDeclarations(doc[start:end], t, scope), nil
+		case xml.EndElement:
+			if len(scope) > 0 {
+				scope = scope[:len(scope)-1]
+			}
+		}
+	}
+}
+
+// elementEnd reads up to the end of the element whose start tag was just read and
+// returns the offset after it
+func elementEnd(dec *xml.Decoder) (int, error) {
+	for depth := 1; depth > 0; {
+		tok, err := dec.RawToken()
+		if err != nil {
+			return 0, err
+		}
+		switch tok.(type) {
+		case xml.StartElement:
+			depth++
+		case xml.EndElement:
+			depth--
+		}
+	}
+	return int(dec.InputOffset()), nil
+}
+
+// withDeclarations adds the namespace declarations of the enclosing elements that the
+// element doesn't make itself to its start tag
+func withDeclarations(element []byte, start xml.StartElement, scope [][]xml.Attr) []byte {
+	inScope := make(map[string]string) // Prefix, "" for the default namespace, to URI
+	for _, attrs := range scope {
+		for _, a := range attrs {
+			if prefix, ok := declaredPrefix(a); ok {
+				inScope[prefix] = a.Value
+			}
+		}
+	}
+	for _, a := range start.Attr {
+		if prefix, ok := declaredPrefix(a); ok {
+			delete(inScope, prefix)
+		}
+	}
+	if len(inScope) == 0 {
+		return element
+	}
+
+	prefixes := make([]string, 0, len(inScope))
+	for prefix := range inScope {
+		prefixes = append(prefixes, prefix)
+	}
+	sort.Strings(prefixes)
+	var decls bytes.Buffer
+	for _, prefix := range prefixes {
+		name := "xmlns"
+		if prefix != "" {
+			name += ":" + prefix
+		}
+		decls.WriteString(" " + name + `="`)
+		xml.EscapeText(&decls, []byte(inScope[prefix]))
+		decls.WriteString(`"`)
+	}
+
+	// Insert right after the element name: "<" plus the name as written
+	nameLen := 1 + len(start.Name.Local)
+	if start.Name.Space != "" {
+		nameLen += len(start.Name.Space) + 1
+	}
+	out := make([]byte, 0, len(element)+decls.Len())
+	out = append(out, element[:nameLen]...)
+	out = append(out, decls.Bytes()...)
+	return append(out, element[nameLen:]...)
+}
+
+// declaredPrefix returns the prefix an attribute declares a namespace for, "" for the
+// default namespace, if it is a namespace declaration
+func declaredPrefix(a xml.Attr) (string, bool) {
+	switch {
+	case a.Name.Space == "" && a.Name.Local == "xmlns":
+		return "", true
+	case a.Name.Space == "xmlns":
+		return a.Name.Local, true
+	}
+	return "", false
+}
+
+// RPC sends an operation wrapped in an <rpc> element and returns the <rpc-reply>. A
+// reply carrying an error-severity <rpc-error> is returned as an *RPCError.
+func (s *Session) RPC(operation string) ([]byte, error) {
+	s.messageID++
+	request := fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?><rpc message-id="%d" xmlns="%s">%s</rpc>`,
+		s.messageID, namespace, operation)
+	reply, err := s.exchange([]byte(request))
+	if err != nil {
+		s.abort()
+		return nil, err
+	}
+
+	var header struct {
+		XMLName   xml.Name   `xml:"rpc-reply"`
+		MessageID string     `xml:"message-id,attr"`
+		Errors    []RPCError `xml:"rpc-error"`
+	}
+	if err := xml.Unmarshal(reply, &header); err != nil {
+		return nil, fmt.Errorf("invalid rpc-reply: %w", err)
+	}
+	if header.MessageID != "" && header.MessageID != strconv.Itoa(s.messageID) {
+		return nil, fmt.Errorf("rpc-reply for message %s, expected %d", header.MessageID, s.messageID)
+	}
+	for i := range header.Errors {
+		if strings.TrimSpace(header.Errors[i].Severity) != "warning" {
+			return nil, &header.Errors[i]
+		}
+	}
+	return reply, nil
+}
+
+// Close ends the session with <close-session> and closes the transport
+func (s *Session) Close() error {
+	var err error
+	s.closeOnce.Do(func() {
+		s.messageID++
+		request := fmt.Sprintf(`<rpc message-id="%d" xmlns="%s"><close-session/></rpc>`, s.messageID, namespace)
+		s.exchange([]byte(request))
+		err = s.transport.Close()
+	})
+	return err
+}
+
+// abort closes the transport without ending the session
+func (s *Session) abort() {
+	s.closeOnce.Do(func() { s.transport.Close() })
+}
+
+// exchange writes a message and reads the reply, closing the transport if the
+// server takes longer than the timeout
+func (s *Session) exchange(message []byte) ([]byte, error) {
+	var timedOut bool
+	var mu sync.Mutex
+	if s.timeout > 0 {
+		timer := time.AfterFunc(s.timeout, func() {
+			mu.Lock()
+			timedOut = true
+			mu.Unlock()
+			s.transport.Close()
+		})
+		defer timer.Stop()
+	}
+
+	reply, err := s.roundTrip(message)
+	mu.Lock()
+	defer mu.Unlock()
+	if timedOut {
+		return nil, ErrTimeout
+	}
+	return reply, err
+}
+
+func (s *Session) roundTrip(message []byte) ([]byte, error) {
+	if err := s.write(message); err != nil {
+		return nil, fmt.Errorf("failed to send: %w", err)
+	}
+	reply, err := s.read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read reply: %w", err)
+	}
+	return reply, nil
+}
+
+// write frames a message for the current framing mode
+func (s *Session) write(message []byte) error {
+	var buf bytes.Buffer
+	if s.chunked {
+		fmt.Fprintf(&buf, "\n#%d\n", len(message))
+		buf.Write(message)
+		buf.WriteString("\n##\n")
+	} else {
+		buf.Write(message)
+		buf.WriteString(endOfMessage)
+	}
+	_, err := s.transport.Write(buf.Bytes())
+	return err
+}
+
+// read reads one message in the current framing mode
+func (s *Session) read() ([]byte, error) {
+	if !s.chunked {
+		return s.readDelimited()
+	}
+	return s.readChunked()
+}
+
+// readDelimited reads a base 1.0 message, which ends with ]]>]]>
+func (s *Session) readDelimited() ([]byte, error) {
+	var msg []byte
+	for {
+		b, err := s.reader.ReadByte()
+		if err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return nil, err
+		}
+		msg = append(msg, b)
+		if bytes.HasSuffix(msg, []byte(endOfMessage)) {
+			return bytes.TrimSpace(msg[:len(msg)-len(endOfMessage)]), nil
+		}
+	}
+}
+
+// readChunked reads a base 1.1 message: chunks of "\n#<size>\n<data>" ended by "\n##\n"
+func (s *Session) readChunked() ([]byte, error) {
+	var msg []byte
+	for {
+		header, err := s.reader.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		if strings.TrimSpace(header) == "" {
+			// The newline that starts the chunk header
+			header, err = s.reader.ReadString('\n')
+			if err != nil {
+				return nil, err
+			}
+		}
+		header = strings.TrimSpace(header)
+		if header == "##" {
+			return msg, nil
+		}
+		if !strings.HasPrefix(header, "#") {
+			return nil, fmt.Errorf("invalid chunk header %q", header)
+		}
+		size, err := strconv.Atoi(header[1:])
+		if err != nil || size < 1 {
+			return nil, fmt.Errorf("invalid chunk size %q", header)
+		}
+		chunk := make([]byte, size)
+		if _, err := io.ReadFull(s.reader, chunk); err != nil {
+			return nil, err
+		}
+		msg = append(msg, chunk...)
+	}
+}
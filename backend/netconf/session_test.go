@@ -0,0 +1,247 @@
+package netconf
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+const junosConfig = `<configuration junos:changed-seconds="1700000000" junos:changed-localtime="2023-11-14 22:13:20 UTC">` +
+	`<system><host-name>leaf1</host-name></system></configuration>`
+
+var messageID = regexp.MustCompile(`message-id="(\d+)"`)
+
+// stubServer is an in-process NETCONF server answering a session's requests
+type stubServer struct {
+	conn    net.Conn
+	reader  *bufio.Reader
+	base11  bool // Advertise base 1.1 and switch to chunked framing
+	chunked bool
+}
+
+// startStub runs a stub server on one end of a pipe and returns a session on the other
+func startStub(t *testing.T, base11 bool) *Session {
+	t.Helper()
+	client, server := net.Pipe()
+	stub := &stubServer{conn: server, reader: bufio.NewReader(server), base11: base11}
+	done := make(chan error, 1)
+	go func() { done <- stub.serve() }()
+	t.Cleanup(func() {
+		client.Close()
+		if err := <-done; err != nil {
+			t.Errorf("stub server: %v", err)
+		}
+	})
+
+	session, err := NewSession(client, 5*time.Second)
+	if err != nil {
+		t.Fatalf("NewSession failed: %v", err)
+	}
+	return session
+}
+
+func (s *stubServer) serve() error {
+	defer s.conn.Close()
+
+	caps := `<capability>` + CapBase10 + `</capability>`
+	if s.base11 {
+		caps += `<capability>` + CapBase11 + `</capability>`
+	}
+	caps += `<capability>urn:ietf:params:netconf:capability:candidate:1.0?module=ietf-netconf</capability>`
+	hello := `<?xml version="1.0" encoding="UTF-8"?><hello xmlns="` + namespace + `"><capabilities>` + caps +
+		`</capabilities><session-id>42</session-id></hello>`
+	// Both peers send their hello right away; the pipe has no buffer, so send ours
+	// while reading the client's
+	sent := make(chan error, 1)
+	go func() { sent <- s.write(hello) }()
+	clientHello, err := s.read()
+	if err != nil {
+		return fmt.Errorf("reading hello: %w", err)
+	}
+	if err := <-sent; err != nil {
+		return err
+	}
+	if !strings.Contains(clientHello, "<hello") {
+		return fmt.Errorf("expected hello, got %q", clientHello)
+	}
+	s.chunked = s.base11 && strings.Contains(clientHello, CapBase11)
+
+	for {
+		request, err := s.read()
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrClosedPipe) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("reading rpc: %w", err)
+		}
+		id := ""
+		if m := messageID.FindStringSubmatch(request); m != nil {
+			id = m[1]
+		}
+
+		var body string
+		switch {
+		case strings.Contains(request, "<get-config>"):
+			body = `<data>` + junosConfig + `</data>`
+		case strings.Contains(request, "<close-session/>"):
+			body = `<ok/>`
+		default:
+			body = `<rpc-error><error-type>protocol</error-type><error-tag>operation-not-supported</error-tag>` +
+				`<error-severity>error</error-severity><error-message>unsupported operation</error-message></rpc-error>`
+		}
+		reply := `<?xml version="1.0" encoding="UTF-8"?><rpc-reply xmlns="` + namespace + `" ` +
+			`xmlns:junos="http://xml.juniper.net/junos/23.2R1/junos" message-id="` + id + `">` + body + `</rpc-reply>`
+		if err := s.write(reply); err != nil {
+			return err
+		}
+		if strings.Contains(request, "<close-session/>") {
+			return nil
+		}
+	}
+}
+
+// write sends a message, split over two chunks in chunked framing
+func (s *stubServer) write(msg string) error {
+	if !s.chunked {
+		_, err := io.WriteString(s.conn, msg+endOfMessage)
+		return err
+	}
+	half := len(msg) / 2
+	_, err := fmt.Fprintf(s.conn, "\n#%d\n%s\n#%d\n%s\n##\n", half, msg[:half], len(msg)-half, msg[half:])
+	return err
+}
+
+func (s *stubServer) read() (string, error) {
+	if !s.chunked {
+		var msg []byte
+		for !bytes.HasSuffix(msg, []byte(endOfMessage)) {
+			b, err := s.reader.ReadByte()
+			if err != nil {
+				return "", err
+			}
+			msg = append(msg, b)
+		}
+		return string(msg[:len(msg)-len(endOfMessage)]), nil
+	}
+	var msg strings.Builder
+	for {
+		header, err := s.reader.ReadString('\n')
+		if err != nil {
+			return "", err
+		}
+		header = strings.TrimSpace(header)
+		switch {
+		case header == "":
+			continue
+		case header == "##":
+			return msg.String(), nil
+		}
+		var size int
+		if _, err := fmt.Sscanf(header, "#%d", &size); err != nil {
+			return "", fmt.Errorf("invalid chunk header %q", header)
+		}
+		chunk := make([]byte, size)
+		if _, err := io.ReadFull(s.reader, chunk); err != nil {
+			return "", err
+		}
+		msg.Write(chunk)
+	}
+}
+
+func TestHello(t *testing.T) {
+	session := startStub(t, false)
+	defer session.Close()
+
+	if session.ID != "42" {
+		t.Errorf("session ID = %q, want 42", session.ID)
+	}
+	if session.chunked {
+		t.Error("switched to chunked framing without base 1.1 from the server")
+	}
+	if !session.HasCapability("urn:ietf:params:netconf:capability:candidate:1.0") {
+		t.Errorf("candidate capability not found in %v", session.Capabilities)
+	}
+	if session.HasCapability(CapBase11) {
+		t.Error("reported base 1.1 the server didn't advertise")
+	}
+}
+
+func TestGetConfig(t *testing.T) {
+	for _, base11 := range []bool{false, true} {
+		t.Run(fmt.Sprintf("base11=%v", base11), func(t *testing.T) {
+			session := startStub(t, base11)
+			defer session.Close()
+			if session.chunked != base11 {
+				t.Fatalf("chunked = %v, want %v", session.chunked, base11)
+			}
+
+			data, err := session.GetConfig("running")
+			if err != nil {
+				t.Fatalf("GetConfig failed: %v", err)
+			}
+			want := `<data xmlns="` + namespace + `" xmlns:junos="http://xml.juniper.net/junos/23.2R1/junos">` +
+				junosConfig + `</data>`
+			if data != want {
+				t.Fatalf("GetConfig =\n%s\nwant\n%s", data, want)
+			}
+
+			// The element must stand on its own, with the junos prefix bound
+			var doc struct {
+				Configuration struct {
+					Changed  string `xml:"http://xml.juniper.net/junos/23.2R1/junos changed-seconds,attr"`
+					HostName string `xml:"system>host-name"`
+				} `xml:"configuration"`
+			}
+			if err := xml.Unmarshal([]byte(data), &doc); err != nil {
+				t.Fatalf("data is not well-formed: %v", err)
+			}
+			if doc.Configuration.Changed != "1700000000" || doc.Configuration.HostName != "leaf1" {
+				t.Fatalf("unexpected config: %+v", doc.Configuration)
+			}
+		})
+	}
+}
+
+func TestRPCError(t *testing.T) {
+	for _, base11 := range []bool{false, true} {
+		t.Run(fmt.Sprintf("base11=%v", base11), func(t *testing.T) {
+			session := startStub(t, base11)
+			defer session.Close()
+
+			_, err := session.RPC("<lock><target><candidate/></target></lock>")
+			var rpcErr *RPCError
+			if !errors.As(err, &rpcErr) {
+				t.Fatalf("expected an RPCError, got %v", err)
+			}
+			if rpcErr.Tag != "operation-not-supported" || !strings.Contains(rpcErr.Error(), "unsupported operation") {
+				t.Fatalf("unexpected error: %+v", rpcErr)
+			}
+
+			// The session stays usable after an rpc-error
+			if _, err := session.GetConfig("running"); err != nil {
+				t.Fatalf("GetConfig after rpc-error failed: %v", err)
+			}
+		})
+	}
+}
+
+func TestExtractElementKeepsPrefixedNames(t *testing.T) {
+	reply := []byte(`<nc:rpc-reply xmlns:nc="` + namespace + `" xmlns:x="urn:x"><nc:data xmlns:x="urn:y"><x:a/></nc:data></nc:rpc-reply>`)
+	data, err := extractElement(reply, "data")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The element's own declaration of x wins over the enclosing one
+	want := `<nc:data xmlns:nc="` + namespace + `" xmlns:x="urn:y"><x:a/></nc:data>`
+	if string(data) != want {
+		t.Fatalf("extractElement =\n%s\nwant\n%s", data, want)
+	}
+}
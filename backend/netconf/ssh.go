@@ -0,0 +1,76 @@
+package netconf
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// DefaultPort is the IANA port for NETCONF over SSH
+const DefaultPort = 830
+
+// sshTransport carries a session over the netconf subsystem of an SSH connection
+type sshTransport struct {
+	io.Reader
+	io.WriteCloser
+	session *ssh.Session
+	client  *ssh.Client
+	stop    func() bool
+}
+
+func (t *sshTransport) Close() error {
+	t.stop()
+	t.WriteCloser.Close()
+	t.session.Close()
+	return t.client.Close()
+}
+
+// Dial connects to addr over SSH, starts the netconf subsystem and exchanges hellos.
+// config.Timeout bounds the TCP connect; timeout bounds each NETCONF exchange. Once ctx
+// is done the connection is closed, interrupting whatever the session is doing.
+func Dial(ctx context.Context, addr string, config *ssh.ClientConfig, timeout time.Duration) (*Session, error) {
+	dialer := net.Dialer{Timeout: config.Timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to dial: %w", err)
+	}
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	c, chans, reqs, err := ssh.NewClientConn(conn, addr, config)
+	if err != nil {
+		stop()
+		conn.Close()
+		return nil, fmt.Errorf("failed to dial: %w", err)
+	}
+	client := ssh.NewClient(c, chans, reqs)
+	fail := func() {
+		stop()
+		client.Close()
+	}
+
+	session, err := client.NewSession()
+	if err != nil {
+		fail()
+		return nil, fmt.Errorf("failed to create session: %w", err)
+	}
+
+	stdin, err := session.StdinPipe()
+	if err != nil {
+		fail()
+		return nil, err
+	}
+	stdout, err := session.StdoutPipe()
+	if err != nil {
+		fail()
+		return nil, err
+	}
+	if err := session.RequestSubsystem("netconf"); err != nil {
+		fail()
+		return nil, fmt.Errorf("netconf subsystem unavailable: %w", err)
+	}
+
+	return NewSession(&sshTransport{Reader: stdout, WriteCloser: stdin, session: session, client: client, stop: stop}, timeout)
+}
@@ -0,0 +1,314 @@
+// Package pnp is a Cisco Plug and Play server. IOS-XE agents find it through DHCP
+// option 43 and poll it over HTTP/XML for work. Agents are matched to registered
+// devices by the serial number in their UDI, or failing that by the IP address they
+// were reserved, and are walked through installing their target image, applying their
+// rendered config and running exec commands. Each agent's progress is kept as a session.
+package pnp
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/ztp-server/backend/bootstrap"
+	"github.com/ztp-server/backend/db"
+	"github.com/ztp-server/backend/images"
+	"github.com/ztp-server/backend/models"
+)
+
+// refusedError is returned by workItem when the agent mustn't be given its work item
+// under the config download protections
+type refusedError struct{ reason string }
+
+func (e *refusedError) Error() string { return e.reason }
+
+// Server hands out work items to PnP agents and tracks their sessions
+type Server struct {
+	store     *db.Store
+	images    *images.Service
+	render    func(device *models.Device, templateID string) (string, error)
+	configURL func(device *models.Device) (string, error)
+}
+
+// NewServer creates a PnP server. render renders a template for a device and configURL
+// returns the URL a device downloads its config from.
+func NewServer(store *db.Store, images *images.Service, render func(*models.Device, string) (string, error), configURL func(*models.Device) (string, error)) *Server {
+	return &Server{store: store, images: images, render: render, configURL: configURL}
+}
+
+// WorkRequest answers an agent's request for work with the next work item of its
+// session, a backoff if it has no registered device, or bye once it is done
+func (s *Server) WorkRequest(ip string, msg *Message) ([]byte, error) {
+	udi := msg.UDI
+	if msg.Info != nil && msg.Info.DeviceID.UDI != "" {
+		udi = msg.Info.DeviceID.UDI
+	}
+	pid, serial := ParseUDI(udi)
+	if serial == "" {
+		return nil, fmt.Errorf("no serial number in UDI %q", udi)
+	}
+
+	session, err := s.store.GetPnPSession(serial)
+	if err != nil {
+		return nil, err
+	}
+	if session == nil {
+		session = &models.PnPSession{Serial: serial, State: models.PnPUnknown}
+	}
+	session.UDI, session.PID, session.IP = udi, pid, ip
+	if msg.Info != nil && msg.Info.DeviceID.Hostname != "" {
+		session.Hostname = msg.Info.DeviceID.Hostname
+	}
+
+	device, err := s.findDevice(session)
+	if err != nil {
+		return nil, err
+	}
+	if device == nil {
+		if session.DeviceMAC != "" || session.CreatedAt.IsZero() {
+			log.Printf("PnP agent %s (%s) at %s has no registered device", serial, pid, ip)
+			s.logEvent(session, "pnp_unknown", "PnP agent has no registered device with serial "+serial)
+		}
+		session.DeviceMAC, session.State, session.Step, session.Correlator = "", models.PnPUnknown, 0, ""
+		if err := s.store.SavePnPSession(session); err != nil {
+			return nil, err
+		}
+		return marshal(&envelope{UDI: udi, Request: &request{
+			Correlator: newCorrelator(),
+			Xmlns:      nsBackoff,
+			Backoff:    &backoff{Minutes: 5},
+		}})
+	}
+
+	if session.DeviceMAC != device.MAC || session.State == models.PnPUnknown {
+		log.Printf("PnP agent %s matched device %s (%s)", serial, device.Hostname, device.MAC)
+		session.DeviceMAC = device.MAC
+		session.State, session.Step = s.next(device, models.PnPUnknown), 0
+	}
+
+	var req *request
+	switch session.State {
+	case models.PnPCompleted, models.PnPFailed:
+		session.Correlator = ""
+		if err := s.store.SavePnPSession(session); err != nil {
+			return nil, err
+		}
+		return marshal(&envelope{UDI: udi, Info: &infoBody{Correlator: newCorrelator(), Xmlns: nsInfo}})
+	default:
+		req, err = s.workItem(session, device)
+		var refused *refusedError
+		if errors.As(err, &refused) {
+			// Anyone can claim a serial number, so leave the session alone
+			log.Printf("Refused PnP work item %s for %s to %s: %s", session.State, device.Hostname, ip, refused.reason)
+			return marshal(&envelope{UDI: udi, Request: &request{
+				Correlator: newCorrelator(),
+				Xmlns:      nsBackoff,
+				Backoff:    &backoff{Minutes: 5},
+			}})
+		}
+		if err != nil {
+			session.State, session.LastError, session.Correlator = models.PnPFailed, err.Error(), ""
+			s.store.SavePnPSession(session)
+			s.store.UpdateDeviceError(device.MAC, "PnP: "+err.Error())
+			return nil, err
+		}
+	}
+
+	session.Correlator = req.Correlator
+	if err := s.store.SavePnPSession(session); err != nil {
+		return nil, err
+	}
+	log.Printf("PnP work item %s for %s (step %d)", session.State, device.Hostname, session.Step)
+	return marshal(&envelope{UDI: udi, Request: req})
+}
+
+// WorkResponse records an agent's result for its current work item and moves its
+// session on, or marks it failed. The agent is always told bye; it asks for the next
+// work item in a new request.
+func (s *Server) WorkResponse(ip string, msg *Message) ([]byte, error) {
+	if msg.Response == nil {
+		return nil, fmt.Errorf("work response has no response element")
+	}
+	_, serial := ParseUDI(msg.UDI)
+	session, err := s.store.GetPnPSession(serial)
+	if err != nil {
+		return nil, err
+	}
+	resp := msg.Response
+	bye := &envelope{UDI: msg.UDI, Info: &infoBody{Correlator: resp.Correlator, Xmlns: nsInfo}}
+	if session == nil || session.Correlator == "" || session.Correlator != resp.Correlator {
+		log.Printf("Ignoring PnP work response %s from %s: no matching work item", resp.Correlator, ip)
+		return marshal(bye)
+	}
+	session.IP, session.Correlator = ip, ""
+
+	device, err := s.store.GetDevice(session.DeviceMAC)
+	if err != nil {
+		return nil, err
+	}
+	if device == nil {
+		session.State, session.Step = models.PnPUnknown, 0
+		if err := s.store.SavePnPSession(session); err != nil {
+			return nil, err
+		}
+		return marshal(bye)
+	}
+
+	if !resp.OK() {
+		log.Printf("PnP %s failed for %s: %s", session.State, device.Hostname, resp.Error())
+		session.State, session.LastError = models.PnPFailed, resp.Error()
+		s.store.UpdateDeviceError(device.MAC, "PnP: "+session.LastError)
+		s.logEvent(session, "pnp_failed", "PnP provisioning failed: "+session.LastError)
+		if err := s.store.SavePnPSession(session); err != nil {
+			return nil, err
+		}
+		return marshal(bye)
+	}
+
+	switch session.State {
+	case models.PnPImageInstall:
+		if img, err := s.images.Target(device); err == nil && img != nil {
+			s.store.UpdateDeviceUpgrade(device.MAC, models.UpgradeDownloading, img.Version)
+		}
+	case models.PnPCLIExec:
+		if commands, err := s.commands(device); err == nil && session.Step+1 < len(commands) {
+			session.Step++
+			if err := s.store.SavePnPSession(session); err != nil {
+				return nil, err
+			}
+			return marshal(bye)
+		}
+	}
+	session.State, session.Step, session.LastError = s.next(device, session.State), 0, ""
+	if session.State == models.PnPCompleted {
+		log.Printf("PnP provisioning completed for %s", device.Hostname)
+		s.store.ClearDeviceError(device.MAC)
+		s.store.SetDeviceProvisioned(device.MAC)
+		s.logEvent(session, "pnp_completed", "PnP provisioning completed")
+	}
+	if err := s.store.SavePnPSession(session); err != nil {
+		return nil, err
+	}
+	return marshal(bye)
+}
+
+// findDevice returns the registered device of an agent, matched by serial number and
+// then by IP address
+func (s *Server) findDevice(session *models.PnPSession) (*models.Device, error) {
+	device, err := s.store.FindDeviceBySerial(session.Serial)
+	if err != nil || device != nil {
+		return device, err
+	}
+	return s.store.FindDeviceByIP(session.IP)
+}
+
+// next returns the state after current: installing the target image if the device
+// isn't already running it, applying the config, then running exec commands
+func (s *Server) next(device *models.Device, current string) string {
+	switch current {
+	case models.PnPUnknown:
+		if img, err := s.images.Target(device); err == nil && img != nil &&
+			!(device.UpgradeStatus == models.UpgradeUpgraded && device.UpgradeVersion == img.Version) {
+			return models.PnPImageInstall
+		}
+		return models.PnPConfigUpgrade
+	case models.PnPImageInstall:
+		return models.PnPConfigUpgrade
+	case models.PnPConfigUpgrade:
+		return models.PnPCLIExec
+	default:
+		return models.PnPCompleted
+	}
+}
+
+// workItem builds the work item for a session's state
+func (s *Server) workItem(session *models.PnPSession, device *models.Device) (*request, error) {
+	settings, err := s.store.GetSettings()
+	if err != nil {
+		return nil, err
+	}
+	req := &request{Correlator: newCorrelator()}
+
+	switch session.State {
+	case models.PnPImageInstall:
+		image := s.images.Context(device, settings)
+		if image == nil {
+			return nil, fmt.Errorf("device has no target image")
+		}
+		req.Xmlns = nsImageInstall
+		req.Image = &imageBody{Source: image.URL, Checksum: image.MD5, Destination: "flash:"}
+		req.Reload = &reload{Reason: "pnp image upgrade", User: "pnp"}
+	case models.PnPConfigUpgrade:
+		if reason := bootstrap.CheckConfigURL(device, settings, session.IP); reason != "" {
+			return nil, &refusedError{reason}
+		}
+		location, err := s.configURL(device)
+		if err != nil {
+			return nil, err
+		}
+		req.Xmlns = nsConfigUpgrade
+		req.Config = &configBody{Details: "all", Location: location, ApplyTo: "running"}
+		req.NoReload = &struct{}{}
+	case models.PnPCLIExec:
+		commands, err := s.commands(device)
+		if err != nil {
+			return nil, err
+		}
+		if session.Step >= len(commands) {
+			session.Step = len(commands) - 1
+		}
+		req.Xmlns = nsCLIExec
+		req.ExecCLI = &execBody{MaxWait: "PT60S", Cmd: commands[session.Step]}
+	default:
+		return nil, fmt.Errorf("no work item for state %q", session.State)
+	}
+	return req, nil
+}
+
+// commands returns the exec commands run on a device: the lines of the PnP exec
+// template rendered for it, then write memory to save the applied config
+func (s *Server) commands(device *models.Device) ([]string, error) {
+	settings, err := s.store.GetSettings()
+	if err != nil {
+		return nil, err
+	}
+	var commands []string
+	if settings.PnPExecTemplate != "" {
+		rendered, err := s.render(device, settings.PnPExecTemplate)
+		if err != nil {
+			return nil, fmt.Errorf("failed to render PnP exec template: %w", err)
+		}
+		for _, line := range strings.Split(rendered, "\n") {
+			line = strings.TrimSpace(line)
+			if line == "" || strings.HasPrefix(line, "!") {
+				continue
+			}
+			commands = append(commands, line)
+		}
+	}
+	return append(commands, "write memory"), nil
+}
+
+// logEvent adds a PnP event to the discovery log
+func (s *Server) logEvent(session *models.PnPSession, eventType, message string) {
+	entry := &models.DiscoveryLog{
+		EventType: eventType,
+		MAC:       session.DeviceMAC,
+		IP:        session.IP,
+		Hostname:  session.Hostname,
+		Message:   message,
+	}
+	if err := s.store.CreateDiscoveryLog(entry); err != nil {
+		log.Printf("Failed to log PnP event for %s: %v", session.Serial, err)
+	}
+}
+
+// newCorrelator returns a random ID tying a work item to its response
+func newCorrelator() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return "ztp-" + hex.EncodeToString(b)
+}
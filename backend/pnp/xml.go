@@ -0,0 +1,152 @@
+package pnp
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strings"
+)
+
+// Namespaces of the PnP envelope and the work items this server hands out
+const (
+	nsPnP           = "urn:cisco:pnp"
+	nsInfo          = "urn:cisco:pnp:info"
+	nsImageInstall  = "urn:cisco:pnp:image-install"
+	nsConfigUpgrade = "urn:cisco:pnp:config-upgrade"
+	nsCLIExec       = "urn:cisco:pnp:cli-exec"
+	nsBackoff       = "urn:cisco:pnp:backoff"
+)
+
+// Message is a PnP envelope sent by an agent: a work request carries info, a work
+// response carries the result of a work item
+type Message struct {
+	XMLName  xml.Name      `xml:"pnp"`
+	Version  string        `xml:"version,attr"`
+	UDI      string        `xml:"udi,attr"`
+	Info     *DeviceInfo   `xml:"info"`
+	Response *WorkResponse `xml:"response"`
+}
+
+// DeviceInfo identifies the agent in a work request
+type DeviceInfo struct {
+	DeviceID struct {
+		UDI      string `xml:"udi"`
+		Hostname string `xml:"hostname"`
+	} `xml:"deviceId"`
+}
+
+// WorkResponse is an agent's result for a work item
+type WorkResponse struct {
+	Correlator string `xml:"correlator,attr"`
+	Success    string `xml:"success,attr"` // 1 on success, 0 on failure
+	ErrorInfo  *struct {
+		Message  string `xml:"errorMessage"`
+		Severity string `xml:"errorSeverity"`
+		Code     string `xml:"errorCode"`
+	} `xml:"errorInfo"`
+}
+
+// OK reports whether the work item succeeded
+func (r *WorkResponse) OK() bool {
+	return r.Success == "1" || strings.EqualFold(r.Success, "true")
+}
+
+// Error describes why the work item failed
+func (r *WorkResponse) Error() string {
+	if r.ErrorInfo == nil {
+		return "work item failed"
+	}
+	msg := strings.TrimSpace(r.ErrorInfo.Message)
+	if r.ErrorInfo.Code != "" {
+		msg = fmt.Sprintf("%s (%s)", msg, strings.TrimSpace(r.ErrorInfo.Code))
+	}
+	return msg
+}
+
+// ParseUDI splits a UDI of the form PID:<pid>,VID:<vid>,SN:<serial> into its product ID
+// and serial number
+func ParseUDI(udi string) (pid, serial string) {
+	for _, part := range strings.Split(udi, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(part), ":")
+		if !ok {
+			continue
+		}
+		switch strings.ToUpper(key) {
+		case "PID":
+			pid = value
+		case "SN":
+			serial = value
+		}
+	}
+	return pid, serial
+}
+
+// envelope is a PnP envelope sent to an agent, holding either a work item or info
+type envelope struct {
+	XMLName xml.Name  `xml:"pnp"`
+	Xmlns   string    `xml:"xmlns,attr"`
+	Version string    `xml:"version,attr"`
+	UDI     string    `xml:"udi,attr"`
+	Request *request  `xml:"request,omitempty"`
+	Info    *infoBody `xml:"info,omitempty"`
+}
+
+// request is a work item. Its namespace says which kind it is and which of the
+// optional elements it carries.
+type request struct {
+	Correlator string      `xml:"correlator,attr"`
+	Xmlns      string      `xml:"xmlns,attr"`
+	Image      *imageBody  `xml:"image,omitempty"`
+	Config     *configBody `xml:"config,omitempty"`
+	ExecCLI    *execBody   `xml:"execCLI,omitempty"`
+	Backoff    *backoff    `xml:"backoff,omitempty"`
+	Reload     *reload     `xml:"reload,omitempty"`
+	NoReload   *struct{}   `xml:"noReload,omitempty"`
+}
+
+type imageBody struct {
+	Source      string `xml:"copy>source>location"`
+	Checksum    string `xml:"copy>source>checksum,omitempty"`
+	Destination string `xml:"copy>destination>location"`
+}
+
+type configBody struct {
+	Details  string `xml:"details,attr"`
+	Location string `xml:"copy>source>location"`
+	ApplyTo  string `xml:"copy>applyTo"`
+}
+
+type execBody struct {
+	MaxWait string `xml:"maxWait,attr"`
+	XSD     bool   `xml:"xsd,attr"`
+	Cmd     string `xml:"cmd"`
+}
+
+type backoff struct {
+	Hours   int `xml:"callMeBackIn>hours"`
+	Minutes int `xml:"callMeBackIn>minutes"`
+	Seconds int `xml:"callMeBackIn>seconds"`
+}
+
+type reload struct {
+	Reason     string `xml:"reason"`
+	DelayIn    int    `xml:"delayIn"`
+	User       string `xml:"user"`
+	SaveConfig bool   `xml:"saveConfig"`
+}
+
+type infoBody struct {
+	Correlator string   `xml:"correlator,attr"`
+	Xmlns      string   `xml:"xmlns,attr"`
+	Bye        struct{} `xml:"workInfo>bye"`
+}
+
+// marshal encodes an envelope for an agent
+func marshal(env *envelope) ([]byte, error) {
+	env.Xmlns = nsPnP
+	env.Version = "1.0"
+	data, err := xml.Marshal(env)
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), data...), nil
+}
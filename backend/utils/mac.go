@@ -1,10 +1,76 @@
 package utils
 
-import "strings"
+import (
+	"fmt"
+	"strings"
+)
 
-// NormalizeMac converts MAC to lowercase colon-separated format
+// macFormats lists the accepted MAC notations for error messages
+const macFormats = "aa:bb:cc:dd:ee:ff, aa-bb-cc-dd-ee-ff, aabb.ccdd.eeff or aabbccddeeff"
+
+// ParseMac parses a MAC address in any common notation and returns it in
+// canonical lowercase colon-separated form (aa:bb:cc:dd:ee:ff).
+// Accepted notations: colon or dash separated octets, Cisco dotted
+// (aabb.ccdd.eeff) and bare hex (aabbccddeeff), in any letter case.
+func ParseMac(mac string) (string, error) {
+	s := strings.TrimSpace(mac)
+	if s == "" {
+		return "", fmt.Errorf("MAC address is required")
+	}
+
+	var hex string
+	switch {
+	case len(s) == 17 && (s[2] == ':' || s[2] == '-'):
+		sep := s[2]
+		var b strings.Builder
+		for i := 0; i < 6; i++ {
+			if i > 0 && s[i*3-1] != sep {
+				return "", invalidMac(mac)
+			}
+			b.WriteString(s[i*3 : i*3+2])
+		}
+		hex = b.String()
+	case len(s) == 14 && s[4] == '.' && s[9] == '.':
+		hex = s[0:4] + s[5:9] + s[10:14]
+	case len(s) == 12:
+		hex = s
+	default:
+		return "", invalidMac(mac)
+	}
+
+	hex = strings.ToLower(hex)
+	for _, r := range hex {
+		if !(r >= '0' && r <= '9' || r >= 'a' && r <= 'f') {
+			return "", invalidMac(mac)
+		}
+	}
+
+	return hex[0:2] + ":" + hex[2:4] + ":" + hex[4:6] + ":" + hex[6:8] + ":" + hex[8:10] + ":" + hex[10:12], nil
+}
+
+// IsValidMac reports whether the string is a MAC address in a supported notation
+func IsValidMac(mac string) bool {
+	_, err := ParseMac(mac)
+	return err == nil
+}
+
+// NormalizeMac converts MAC to lowercase colon-separated format.
+// Unparseable input is returned trimmed and lowercased so lookups simply miss;
+// use ParseMac where invalid input must be rejected.
 func NormalizeMac(mac string) string {
-	mac = strings.ToLower(mac)
+	if canonical, err := ParseMac(mac); err == nil {
+		return canonical
+	}
+	mac = strings.ToLower(strings.TrimSpace(mac))
 	mac = strings.ReplaceAll(mac, "-", ":")
 	return mac
 }
+
+// MacToFilename returns the config filename stem for a MAC (aa_bb_cc_dd_ee_ff)
+func MacToFilename(mac string) string {
+	return strings.ReplaceAll(NormalizeMac(mac), ":", "_")
+}
+
+func invalidMac(mac string) error {
+	return fmt.Errorf("invalid MAC address %q: expected %s", mac, macFormats)
+}
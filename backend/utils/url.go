@@ -0,0 +1,21 @@
+package utils
+
+import "net"
+
+// HTTPHost returns the host devices reach the HTTP server at: host, with the port from
+// the server's listen address unless it is the default port 80
+func HTTPHost(host, listenAddr string) string {
+	if _, port, err := net.SplitHostPort(listenAddr); err == nil && port != "" && port != "80" {
+		return net.JoinHostPort(host, port)
+	}
+	return host
+}
+
+// HTTPSHost returns the host devices reach the HTTPS server at: host, with the port from
+// the server's TLS listen address unless it is the default port 443
+func HTTPSHost(host, tlsAddr string) string {
+	if _, port, err := net.SplitHostPort(tlsAddr); err == nil && port != "" && port != "443" {
+		return net.JoinHostPort(host, port)
+	}
+	return host
+}
@@ -0,0 +1,520 @@
+package validate
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/ztp-server/backend/models"
+)
+
+// FieldError describes a validation failure on a single input field
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// Errors collects field errors for a single input
+type Errors []FieldError
+
+// Add records an error for a field
+func (e *Errors) Add(field, format string, args ...interface{}) {
+	*e = append(*e, FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+// Error summarizes all field errors in one line
+func (e Errors) Error() string {
+	parts := make([]string, len(e))
+	for i, fe := range e {
+		parts[i] = fe.Field + ": " + fe.Message
+	}
+	return "validation failed: " + strings.Join(parts, "; ")
+}
+
+// Option value types supported by the dnsmasq config generator
+const (
+	OptionTypeString = "string"
+	OptionTypeIP     = "ip"
+	OptionTypeHex    = "hex"
+	OptionTypeNumber = "number"
+)
+
+// OptionVariables are the placeholders substituted into DHCP option values at generation time
+var OptionVariables = []string{
+	"${tftp_server_ip}",
+	"${dhcp_gateway}",
+	"${opengear_enroll_url}",
+	"${opengear_enroll_bundle}",
+	"${opengear_enroll_password}",
+}
+
+var (
+	hostnameLabel = regexp.MustCompile(`^[a-zA-Z0-9]([a-zA-Z0-9-]*[a-zA-Z0-9])?$`)
+	hexValue      = regexp.MustCompile(`^[0-9a-fA-F]{2}(:[0-9a-fA-F]{2})*$`)
+	variable      = regexp.MustCompile(`\$\{[^}]*\}`)
+	vendorID      = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_-]*$`)
+	slug          = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+	imageFilename = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9._+-]*$`)
+)
+
+// Device validates a device before it is stored. If settings are given, the IP must
+// also fall inside the DHCP subnet.
+func Device(d *models.Device, settings *models.Settings) Errors {
+	var errs Errors
+
+	if d.IP == "" {
+		errs.Add("ip", "is required")
+	} else if ip := parseIPv4(d.IP); ip == nil {
+		errs.Add("ip", "must be a valid IPv4 address")
+	} else if settings != nil {
+		if subnet := Subnet(settings); subnet != nil && !subnet.Contains(ip) {
+			errs.Add("ip", "must be inside the DHCP subnet %s", subnet)
+		}
+	}
+
+	if d.Hostname == "" {
+		errs.Add("hostname", "is required")
+	} else if err := Hostname(d.Hostname); err != nil {
+		errs.Add("hostname", "%v", err)
+	}
+
+	for field, value := range map[string]string{
+		"vendor":          d.Vendor,
+		"model":           d.Model,
+		"serial_number":   d.SerialNumber,
+		"config_template": d.ConfigTemplate,
+		"ssh_user":        d.SSHUser,
+		"ssh_pass":        d.SSHPass,
+		"enable_secret":   d.EnableSecret,
+	} {
+		if hasControlChars(value) {
+			errs.Add(field, "must not contain control characters")
+		}
+	}
+
+	// NetBox references are slugs
+	for field, value := range map[string]string{
+		"site":     d.Site,
+		"role":     d.Role,
+		"tenant":   d.Tenant,
+		"platform": d.Platform,
+	} {
+		if value != "" && !slug.MatchString(value) {
+			errs.Add(field, "must be a slug (letters, digits, - and _)")
+		}
+	}
+
+	errs.sort()
+	return errs
+}
+
+// Reservation checks the device fields that go into its dnsmasq dhcp-host line. It is
+// looser than Device so that devices stored before validation existed, such as
+// hostnames with underscores, keep their reservation; only values that would break
+// the line or inject extra fields or directives are rejected.
+func Reservation(d *models.Device) Errors {
+	var errs Errors
+	for field, value := range map[string]string{
+		"ip":       d.IP,
+		"hostname": d.Hostname,
+		"vendor":   d.Vendor,
+	} {
+		if hasControlChars(value) || strings.Contains(value, ",") {
+			errs.Add(field, "must not contain control characters or commas")
+		}
+	}
+	errs.sort()
+	return errs
+}
+
+// Settings validates global settings: addresses must be valid, the DHCP range and
+// gateway must lie in the subnet and the range must be in ascending order.
+func Settings(s *models.Settings) Errors {
+	var errs Errors
+
+	mask := parseIPv4(s.DHCPSubnet)
+	if mask == nil || !isContiguousMask(mask) {
+		errs.Add("dhcp_subnet", "must be a valid subnet mask (e.g. 255.255.255.0)")
+		mask = nil
+	}
+
+	addrs := map[string]net.IP{}
+	for _, f := range []struct {
+		field string
+		value string
+	}{
+		{"dhcp_range_start", s.DHCPRangeStart},
+		{"dhcp_range_end", s.DHCPRangeEnd},
+		{"dhcp_gateway", s.DHCPGateway},
+		{"tftp_server_ip", s.TFTPServerIP},
+	} {
+		ip := parseIPv4(f.value)
+		if ip == nil {
+			errs.Add(f.field, "must be a valid IPv4 address")
+			continue
+		}
+		addrs[f.field] = ip
+	}
+
+	start, end, gateway := addrs["dhcp_range_start"], addrs["dhcp_range_end"], addrs["dhcp_gateway"]
+	if mask != nil && gateway != nil {
+		subnet := &net.IPNet{IP: gateway.Mask(net.IPMask(mask)), Mask: net.IPMask(mask)}
+		if start != nil && !subnet.Contains(start) {
+			errs.Add("dhcp_range_start", "must be inside the gateway subnet %s", subnet)
+		}
+		if end != nil && !subnet.Contains(end) {
+			errs.Add("dhcp_range_end", "must be inside the gateway subnet %s", subnet)
+		}
+	}
+	if start != nil && end != nil && compareIP(start, end) > 0 {
+		errs.Add("dhcp_range_end", "must not be before dhcp_range_start")
+	}
+
+	if s.BackupDelay < 0 {
+		errs.Add("backup_delay", "must not be negative")
+	}
+	if s.BackupWorkers < 0 || s.BackupWorkers > 64 {
+		errs.Add("backup_workers", "must be between 0 and 64")
+	}
+	for field, value := range map[string]int{
+		"backup_vendor_limit": s.BackupVendorLimit,
+		"backup_subnet_limit": s.BackupSubnetLimit,
+		"backup_rate_limit":   s.BackupRateLimit,
+		"config_token_ttl":    s.ConfigTokenTTL,
+	} {
+		if value < 0 {
+			errs.Add(field, "must not be negative")
+		}
+	}
+
+	for field, value := range map[string]string{
+		"default_ssh_user":         s.DefaultSSHUser,
+		"default_ssh_pass":         s.DefaultSSHPass,
+		"default_enable_secret":    s.DefaultEnableSecret,
+		"backup_command":           s.BackupCommand,
+		"opengear_enroll_url":      s.OpenGearEnrollURL,
+		"opengear_enroll_bundle":   s.OpenGearEnrollBundle,
+		"opengear_enroll_password": s.OpenGearEnrollPassword,
+	} {
+		if hasControlChars(value) {
+			errs.Add(field, "must not contain control characters")
+		}
+	}
+
+	errs.sort()
+	return errs
+}
+
+// Vendor validates a vendor. The ID becomes a dnsmasq tag and the vendor class is
+// matched against DHCP option 60, so both must be safe to write into dnsmasq.conf.
+func Vendor(v *models.Vendor) Errors {
+	var errs Errors
+
+	if !VendorID(v.ID) {
+		errs.Add("id", "may only contain letters, digits, hyphens and underscores")
+	}
+	if hasControlChars(v.Name) {
+		errs.Add("name", "must not contain control characters")
+	}
+	if hasControlChars(v.VendorClass) || strings.Contains(v.VendorClass, `"`) {
+		errs.Add("vendor_class", "must not contain control characters or double quotes")
+	}
+	if hasControlChars(v.BackupCommand) {
+		errs.Add("backup_command", "must not contain control characters")
+	}
+	if v.SSHPort < 0 || v.SSHPort > 65535 {
+		errs.Add("ssh_port", "must be a valid TCP port")
+	}
+	switch v.BackupMode {
+	case "", models.BackupModeExec, models.BackupModeInteractive, models.BackupModeNetconf:
+	default:
+		errs.Add("backup_mode", "must be exec, interactive or netconf")
+	}
+	if v.NetconfPort < 0 || v.NetconfPort > 65535 {
+		errs.Add("netconf_port", "must be a valid TCP port")
+	}
+	for field, pattern := range map[string]string{
+		"prompt_pattern": v.PromptPattern,
+		"pager_pattern":  v.PagerPattern,
+	} {
+		if _, err := regexp.Compile(pattern); err != nil {
+			errs.Add(field, "must be a valid regular expression: %v", err)
+		}
+	}
+	for _, cmd := range v.PreCommands {
+		if strings.TrimSpace(cmd) == "" || hasControlChars(cmd) {
+			errs.Add("pre_commands", "must not contain empty commands or control characters")
+			break
+		}
+	}
+	if hasControlChars(v.EnableCommand) {
+		errs.Add("enable_command", "must not contain control characters")
+	}
+
+	errs.sort()
+	return errs
+}
+
+// InventorySource validates an inventory source's configuration. Whether the type is
+// registered is checked by the caller.
+func InventorySource(src *models.InventorySource) Errors {
+	var errs Errors
+
+	if !VendorID(src.ID) {
+		errs.Add("id", "may only contain letters, digits, hyphens and underscores")
+	}
+	if src.Name == "" {
+		errs.Add("name", "is required")
+	} else if hasControlChars(src.Name) {
+		errs.Add("name", "must not contain control characters")
+	}
+	if u, err := url.Parse(src.URL); src.URL == "" || err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		errs.Add("url", "must be an http or https URL")
+	}
+	if src.Type == models.InventoryHTTP {
+		if src.Format != "" && src.Format != "json" && src.Format != "csv" {
+			errs.Add("format", "must be json or csv")
+		}
+	} else if src.Format != "" {
+		errs.Add("format", "is only used by http sources")
+	}
+	for key, value := range src.Headers {
+		if key == "" || hasControlChars(key) || hasControlChars(value) {
+			errs.Add("headers", "must not contain empty names or control characters")
+			break
+		}
+	}
+
+	return errs
+}
+
+// Image validates an image's metadata. The filename is written into dnsmasq.conf as a
+// boot file and served from the images directory, so it must be a plain file name.
+func Image(img *models.Image) Errors {
+	var errs Errors
+
+	if !VendorID(img.Vendor) {
+		errs.Add("vendor", "may only contain letters, digits, hyphens and underscores")
+	}
+	if hasControlChars(img.Model) {
+		errs.Add("model", "must not contain control characters")
+	}
+	if strings.TrimSpace(img.Version) == "" {
+		errs.Add("version", "is required")
+	} else if hasControlChars(img.Version) {
+		errs.Add("version", "must not contain control characters")
+	}
+	if !imageFilename.MatchString(img.Filename) {
+		errs.Add("filename", "may only contain letters, digits, dots, hyphens, underscores and plus signs")
+	}
+
+	errs.sort()
+	return errs
+}
+
+// VendorID reports whether a vendor ID can be used as a dnsmasq tag
+func VendorID(id string) bool {
+	return vendorID.MatchString(id)
+}
+
+// DhcpOption validates a DHCP option definition and checks that its value conforms to its type.
+// Values may contain the placeholders listed in OptionVariables.
+func DhcpOption(o *models.DhcpOption) Errors {
+	var errs Errors
+
+	if o.OptionNumber < 1 || o.OptionNumber > 254 {
+		errs.Add("option_number", "must be between 1 and 254")
+	}
+	if hasControlChars(o.Name) {
+		errs.Add("name", "must not contain control characters")
+	}
+	if hasControlChars(o.Description) {
+		errs.Add("description", "must not contain control characters")
+	}
+
+	switch o.Type {
+	case OptionTypeString, OptionTypeIP, OptionTypeHex, OptionTypeNumber:
+	default:
+		errs.Add("type", "must be one of string, ip, hex, number")
+		return errs
+	}
+
+	if len(o.SubOptions) > 0 {
+		return append(errs, subOptions(o)...)
+	}
+	if o.EnterpriseNumber != 0 {
+		errs.Add("enterprise_number", "is only used with sub-options")
+	}
+
+	if o.Value == "" {
+		if o.Enabled {
+			errs.Add("value", "is required for enabled options")
+		}
+		return errs
+	}
+	if err := OptionValue(o.Type, o.Value); err != nil {
+		errs.Add("value", "%v", err)
+	}
+
+	return errs
+}
+
+// subOptions validates the TLV entries of a vendor-encapsulated option (43 or 125)
+func subOptions(o *models.DhcpOption) Errors {
+	var errs Errors
+
+	if o.OptionNumber != 43 && o.OptionNumber != 125 {
+		errs.Add("sub_options", "are only supported for options 43 and 125")
+		return errs
+	}
+	if o.Value != "" {
+		errs.Add("value", "must be empty when sub_options are set")
+	}
+	if o.OptionNumber == 125 && (o.EnterpriseNumber <= 0 || int64(o.EnterpriseNumber) > 0xffffffff) {
+		errs.Add("enterprise_number", "is required for option 125")
+	}
+	if o.OptionNumber == 43 && o.EnterpriseNumber != 0 {
+		errs.Add("enterprise_number", "is only used with option 125")
+	}
+
+	for i, sub := range o.SubOptions {
+		field := fmt.Sprintf("sub_options[%d]", i)
+		if sub.Code < 0 || sub.Code > 254 {
+			errs.Add(field+".code", "must be between 0 and 254")
+		}
+		switch sub.Type {
+		case OptionTypeString, OptionTypeIP, OptionTypeHex, OptionTypeNumber:
+		default:
+			errs.Add(field+".type", "must be one of string, ip, hex, number")
+			continue
+		}
+		// Empty sub-options are left out of the encoded option
+		if sub.Value == "" {
+			continue
+		}
+		if err := OptionValue(sub.Type, sub.Value); err != nil {
+			errs.Add(field+".value", "%v", err)
+		}
+	}
+
+	return errs
+}
+
+// OptionValue checks that a raw (unsubstituted) option value conforms to the option type
+func OptionValue(optType, value string) error {
+	if hasControlChars(value) {
+		return fmt.Errorf("must not contain control characters")
+	}
+	for _, v := range variable.FindAllString(value, -1) {
+		if !isOptionVariable(v) {
+			return fmt.Errorf("unknown variable %s (supported: %s)", v, strings.Join(OptionVariables, ", "))
+		}
+	}
+
+	switch optType {
+	case OptionTypeIP:
+		for _, part := range strings.Split(value, ",") {
+			part = strings.TrimSpace(part)
+			if isOptionVariable(part) {
+				continue
+			}
+			if parseIPv4(part) == nil {
+				return fmt.Errorf("%q is not a valid IPv4 address", part)
+			}
+		}
+	case OptionTypeHex:
+		if !hexValue.MatchString(value) {
+			return fmt.Errorf("must be colon-separated hex bytes (e.g. 01:04:c0:a8:00:01)")
+		}
+	case OptionTypeNumber:
+		if _, err := strconv.ParseUint(value, 10, 32); err != nil {
+			return fmt.Errorf("must be an unsigned 32-bit integer")
+		}
+	case OptionTypeString:
+		if strings.Contains(value, `"`) {
+			return fmt.Errorf("must not contain double quotes")
+		}
+	default:
+		return fmt.Errorf("unknown option type %q", optType)
+	}
+
+	return nil
+}
+
+// Hostname checks a hostname against RFC 1123
+func Hostname(hostname string) error {
+	if len(hostname) > 253 {
+		return fmt.Errorf("must be at most 253 characters")
+	}
+	for _, label := range strings.Split(strings.TrimSuffix(hostname, "."), ".") {
+		if len(label) == 0 || len(label) > 63 {
+			return fmt.Errorf("labels must be 1-63 characters")
+		}
+		if !hostnameLabel.MatchString(label) {
+			return fmt.Errorf("may only contain letters, digits and hyphens, and must not start or end with a hyphen")
+		}
+	}
+	return nil
+}
+
+// Subnet returns the DHCP subnet derived from the gateway and subnet mask, or nil if
+// the settings don't describe a valid network
+func Subnet(s *models.Settings) *net.IPNet {
+	mask := parseIPv4(s.DHCPSubnet)
+	base := parseIPv4(s.DHCPGateway)
+	if base == nil {
+		base = parseIPv4(s.DHCPRangeStart)
+	}
+	if mask == nil || base == nil || !isContiguousMask(mask) {
+		return nil
+	}
+	return &net.IPNet{IP: base.Mask(net.IPMask(mask)), Mask: net.IPMask(mask)}
+}
+
+func parseIPv4(s string) net.IP {
+	ip := net.ParseIP(strings.TrimSpace(s))
+	if ip == nil {
+		return nil
+	}
+	return ip.To4()
+}
+
+func isContiguousMask(mask net.IP) bool {
+	ones, bits := net.IPMask(mask).Size()
+	return bits == 32 && ones > 0
+}
+
+func compareIP(a, b net.IP) int {
+	for i := range a {
+		if a[i] != b[i] {
+			if a[i] < b[i] {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+func isOptionVariable(s string) bool {
+	for _, v := range OptionVariables {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
+
+func hasControlChars(s string) bool {
+	return strings.IndexFunc(s, unicode.IsControl) >= 0
+}
+
+// sort orders errors by field so responses are stable despite map iteration
+func (e Errors) sort() {
+	sort.SliceStable(e, func(i, j int) bool { return e[i].Field < e[j].Field })
+}
@@ -16,7 +16,11 @@ const (
 	EventBackupStarted    EventType = "backup_started"
 	EventBackupCompleted  EventType = "backup_completed"
 	EventBackupFailed     EventType = "backup_failed"
+	EventBackupProgress   EventType = "backup_progress"
 	EventConfigPulled     EventType = "config_pulled"
+	EventSerialMismatch   EventType = "serial_mismatch"
+	EventProvisionStatus  EventType = "provision_status"
+	EventConfigReloaded   EventType = "config_reloaded"
 )
 
 // Event represents a WebSocket event message
@@ -42,6 +46,51 @@ type ConfigPulledPayload struct {
 	Protocol string `json:"protocol"` // "tftp" or "http"
 }
 
+// BackupPayload is the payload for backup started, completed and failed events
+type BackupPayload struct {
+	MAC      string `json:"mac"`
+	Hostname string `json:"hostname,omitempty"`
+	Attempt  int    `json:"attempt"`
+	Error    string `json:"error,omitempty"`
+}
+
+// BackupProgressPayload is the payload for bulk backup progress events
+type BackupProgressPayload struct {
+	RunID     string `json:"run_id"`
+	Total     int    `json:"total"`
+	Pending   int    `json:"pending"`
+	Running   int    `json:"running"`
+	Completed int    `json:"completed"`
+	Failed    int    `json:"failed"`
+	Done      bool   `json:"done"`
+}
+
+// SerialMismatchPayload is the payload for serial mismatch events, sent when a device
+// reports a different serial number than the one it was registered with
+type SerialMismatchPayload struct {
+	MAC        string `json:"mac"`
+	Hostname   string `json:"hostname,omitempty"`
+	Registered string `json:"registered"`
+	Reported   string `json:"reported"`
+}
+
+// ProvisionStatusPayload is the payload for provisioning status events, sent when a
+// device or its bootstrap script reports its progress to the callback endpoint
+type ProvisionStatusPayload struct {
+	MAC      string          `json:"mac"`
+	IP       string          `json:"ip"`
+	Hostname string          `json:"hostname,omitempty"`
+	Status   string          `json:"status"`
+	Message  string          `json:"message,omitempty"`
+	Payload  json.RawMessage `json:"payload,omitempty"`
+}
+
+// ConfigReloadedPayload is the payload for config reloaded events
+type ConfigReloadedPayload struct {
+	Generation uint64 `json:"generation"`
+	DurationMS int64  `json:"duration_ms"`
+}
+
 // Hub manages WebSocket connections and broadcasts events
 type Hub struct {
 	clients    map[*Client]bool
@@ -141,6 +190,31 @@ func (h *Hub) BroadcastConfigPulled(mac, ip, hostname, filename, protocol string
 	})
 }
 
+// BroadcastBackup sends a backup started, completed or failed event
+func (h *Hub) BroadcastBackup(eventType EventType, payload BackupPayload) {
+	h.BroadcastEvent(Event{Type: eventType, Payload: payload})
+}
+
+// BroadcastBackupProgress sends a bulk backup progress event
+func (h *Hub) BroadcastBackupProgress(payload BackupProgressPayload) {
+	h.BroadcastEvent(Event{Type: EventBackupProgress, Payload: payload})
+}
+
+// BroadcastSerialMismatch sends a serial mismatch event
+func (h *Hub) BroadcastSerialMismatch(payload SerialMismatchPayload) {
+	h.BroadcastEvent(Event{Type: EventSerialMismatch, Payload: payload})
+}
+
+// BroadcastProvisionStatus sends a provisioning status event
+func (h *Hub) BroadcastProvisionStatus(payload ProvisionStatusPayload) {
+	h.BroadcastEvent(Event{Type: EventProvisionStatus, Payload: payload})
+}
+
+// BroadcastConfigReloaded sends a config reloaded event, once a regenerated config is live
+func (h *Hub) BroadcastConfigReloaded(payload ConfigReloadedPayload) {
+	h.BroadcastEvent(Event{Type: EventConfigReloaded, Payload: payload})
+}
+
 // ClientCount returns the number of connected clients
 func (h *Hub) ClientCount() int {
 	h.mu.RLock()
@@ -96,6 +96,12 @@ export function Dashboard({ onNavigate }: DashboardProps) {
         return { icon: 'refresh', color: 'var(--color-accent-blue)' };
       case 'lease_expired':
         return { icon: 'timer_off', color: 'var(--color-warning)' };
+      case 'serial_mismatch':
+        return { icon: 'warning', color: 'var(--color-error)' };
+      case 'provision_completed':
+        return { icon: 'check_circle', color: 'var(--color-success)' };
+      case 'provision_failed':
+        return { icon: 'error', color: 'var(--color-error)' };
       default:
         return { icon: 'info', color: 'var(--color-text-muted)' };
     }
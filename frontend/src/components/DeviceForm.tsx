@@ -26,6 +26,11 @@ type DeviceFormData = {
   config_template: string;
   ssh_user: string;
   ssh_pass: string;
+  enable_secret: string;
+  site: string;
+  role: string;
+  tenant: string;
+  platform: string;
 };
 
 const emptyFormData: DeviceFormData = {
@@ -38,6 +43,11 @@ const emptyFormData: DeviceFormData = {
   config_template: '',
   ssh_user: '',
   ssh_pass: '',
+  enable_secret: '',
+  site: '',
+  role: '',
+  tenant: '',
+  platform: '',
 };
 
 export function DeviceForm({ isOpen, device, initialData, templates = [], vendors = [], onSubmit, onClose }: Props) {
@@ -92,6 +102,11 @@ export function DeviceForm({ isOpen, device, initialData, templates = [], vendor
           config_template: device.config_template || '',
           ssh_user: device.ssh_user || '',
           ssh_pass: device.ssh_pass || '',
+          enable_secret: device.enable_secret || '',
+          site: device.site || '',
+          role: device.role || '',
+          tenant: device.tenant || '',
+          platform: device.platform || '',
         });
       } else if (initialData) {
         // Pre-fill with initial data from discovery
@@ -217,6 +232,44 @@ export function DeviceForm({ isOpen, device, initialData, templates = [], vendor
           />
         </div>
 
+        <div className="form-row">
+          <FormField
+            label="Site"
+            name="site"
+            type="text"
+            value={formData.site}
+            onChange={onInputChange}
+            placeholder="NetBox site slug (optional)"
+          />
+          <FormField
+            label="Role"
+            name="role"
+            type="text"
+            value={formData.role}
+            onChange={onInputChange}
+            placeholder="NetBox role slug (optional)"
+          />
+        </div>
+
+        <div className="form-row">
+          <FormField
+            label="Tenant"
+            name="tenant"
+            type="text"
+            value={formData.tenant}
+            onChange={onInputChange}
+            placeholder="NetBox tenant slug (optional)"
+          />
+          <FormField
+            label="Platform"
+            name="platform"
+            type="text"
+            value={formData.platform}
+            onChange={onInputChange}
+            placeholder="NetBox platform slug (optional)"
+          />
+        </div>
+
         <div className="form-row">
           <SelectField
             label="Config Template"
@@ -244,6 +297,14 @@ export function DeviceForm({ isOpen, device, initialData, templates = [], vendor
             onChange={onInputChange}
             placeholder="Leave empty for default"
           />
+          <FormField
+            label="Enable Secret (override)"
+            name="enable_secret"
+            type="password"
+            value={formData.enable_secret}
+            onChange={onInputChange}
+            placeholder="Leave empty for default"
+          />
         </div>
 
         <div className="dialog-actions">
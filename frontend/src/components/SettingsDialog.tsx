@@ -36,10 +36,10 @@ export function SettingsDialog({ isOpen, onClose }: Props) {
 
   const handleChange = (e: React.ChangeEvent<HTMLInputElement>) => {
     if (!formData) return;
-    const { name, value, type } = e.target;
+    const { name, value, type, checked } = e.target;
     setFormData((prev) => ({
       ...prev!,
-      [name]: type === 'number' ? parseInt(value, 10) : value,
+      [name]: type === 'number' ? parseInt(value, 10) : type === 'checkbox' ? checked : value,
     }));
   };
 
@@ -115,6 +115,13 @@ export function SettingsDialog({ isOpen, onClose }: Props) {
                 value={formData.default_ssh_pass}
                 onChange={handleChange}
               />
+              <FormField
+                label="Default Enable Secret"
+                name="default_enable_secret"
+                type="password"
+                value={formData.default_enable_secret || ''}
+                onChange={handleChange}
+              />
             </div>
             <div className="form-row">
               <FormField
@@ -133,6 +140,42 @@ export function SettingsDialog({ isOpen, onClose }: Props) {
                 min={0}
               />
             </div>
+            <div className="form-row">
+              <FormField
+                label="Backup Workers"
+                name="backup_workers"
+                type="number"
+                value={formData.backup_workers ?? 4}
+                onChange={handleChange}
+                min={1}
+                max={64}
+              />
+              <FormField
+                label="Per Vendor Limit"
+                name="backup_vendor_limit"
+                type="number"
+                value={formData.backup_vendor_limit ?? 0}
+                onChange={handleChange}
+                min={0}
+              />
+              <FormField
+                label="Per Subnet Limit"
+                name="backup_subnet_limit"
+                type="number"
+                value={formData.backup_subnet_limit ?? 0}
+                onChange={handleChange}
+                min={0}
+              />
+              <FormField
+                label="Backups per Minute"
+                name="backup_rate_limit"
+                type="number"
+                value={formData.backup_rate_limit ?? 0}
+                onChange={handleChange}
+                min={0}
+              />
+            </div>
+            <p className="settings-hint">Limits of 0 are unlimited.</p>
           </div>
 
           <div className="settings-section">
@@ -178,7 +221,79 @@ export function SettingsDialog({ isOpen, onClose }: Props) {
                 value={formData.tftp_server_ip}
                 onChange={handleChange}
               />
+              <FormField
+                label="PnP Exec Template ID"
+                name="pnp_exec_template"
+                type="text"
+                value={formData.pnp_exec_template || ''}
+                onChange={handleChange}
+              />
+            </div>
+          </div>
+
+          <div className="settings-section">
+            <h3>
+              <Icon name="lock" size={18} />
+              Config Downloads
+            </h3>
+            <div className="form-row">
+              <label className="checkbox-label">
+                <input
+                  type="checkbox"
+                  name="config_token_required"
+                  checked={formData.config_token_required ?? false}
+                  onChange={handleChange}
+                />
+                Require download token
+              </label>
+              <label className="checkbox-label">
+                <input
+                  type="checkbox"
+                  name="config_token_one_time"
+                  checked={formData.config_token_one_time ?? false}
+                  onChange={handleChange}
+                />
+                One-time tokens
+              </label>
+              <FormField
+                label="Token Lifetime (minutes)"
+                name="config_token_ttl"
+                type="number"
+                value={formData.config_token_ttl ?? 0}
+                onChange={handleChange}
+                min={0}
+              />
+            </div>
+            <div className="form-row">
+              <label className="checkbox-label">
+                <input
+                  type="checkbox"
+                  name="config_check_source_ip"
+                  checked={formData.config_check_source_ip ?? false}
+                  onChange={handleChange}
+                />
+                Only serve configs to the reserved IP
+              </label>
+              <label className="checkbox-label">
+                <input
+                  type="checkbox"
+                  name="config_lockout"
+                  checked={formData.config_lockout ?? false}
+                  onChange={handleChange}
+                />
+                Lock out configs after provisioning
+              </label>
+              <label className="checkbox-label">
+                <input
+                  type="checkbox"
+                  name="config_require_tls"
+                  checked={formData.config_require_tls ?? false}
+                  onChange={handleChange}
+                />
+                Require HTTPS
+              </label>
             </div>
+            <p className="settings-hint">A token lifetime of 0 never expires. Tokens are handed out through {'{{.Bootstrap.ConfigURL}}'}.</p>
           </div>
 
           <div className="settings-section">
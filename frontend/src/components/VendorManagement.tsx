@@ -3,6 +3,7 @@ import type { Device, Vendor, VendorFormData } from '@core';
 import {
   useVendors,
   EMPTY_VENDOR_FORM,
+  BACKUP_MODE_OPTIONS,
   slugify,
   createChangeHandler,
   formatListValue,
@@ -13,6 +14,7 @@ import { Button } from './Button';
 import { Card } from './Card';
 import { FormDialog } from './FormDialog';
 import { FormField } from './FormField';
+import { SelectField } from './SelectField';
 import { LoadingState } from './LoadingState';
 import { Message } from './Message';
 import { Table, Cell } from './Table';
@@ -69,30 +71,42 @@ export function VendorManagement({ devices }: Props) {
       mac_prefixes: vendor.mac_prefixes || [],
       vendor_class: vendor.vendor_class || '',
       default_template: vendor.default_template || '',
+      backup_mode: vendor.backup_mode || 'exec',
+      prompt_pattern: vendor.prompt_pattern || '',
+      pager_pattern: vendor.pager_pattern || '',
+      pre_commands: vendor.pre_commands || [],
+      enable_command: vendor.enable_command || '',
+      netconf_port: vendor.netconf_port || 0,
+      image_boot: vendor.image_boot || false,
+      bootstrap_template: vendor.bootstrap_template || '',
     });
     setShowForm(true);
   };
 
   const handleAdd = () => {
     setEditingVendor(null);
-    setFormData({ ...EMPTY_VENDOR_FORM, mac_prefixes: [] });
+    setFormData({ ...EMPTY_VENDOR_FORM, mac_prefixes: [], pre_commands: [] });
     setShowForm(true);
   };
 
   const handleCloseForm = () => {
     setShowForm(false);
     setEditingVendor(null);
-    setFormData({ ...EMPTY_VENDOR_FORM, mac_prefixes: [] });
+    setFormData({ ...EMPTY_VENDOR_FORM, mac_prefixes: [], pre_commands: [] });
   };
 
   const handleSubmit = async (e: React.FormEvent) => {
     e.preventDefault();
 
+    const data = {
+      ...formData,
+      pre_commands: formData.pre_commands.map((c) => c.trim()).filter(Boolean),
+    };
     const success = editingVendor
-      ? await updateVendor(editingVendor.id, formData)
+      ? await updateVendor(editingVendor.id, data)
       : await createVendor({
-          ...formData,
-          id: formData.id || slugify(formData.name),
+          ...data,
+          id: data.id || slugify(data.name),
         });
 
     if (success) {
@@ -121,6 +135,12 @@ export function VendorManagement({ devices }: Props) {
     setFormData((prev) => ({ ...prev, mac_prefixes: prefixes }));
   };
 
+  // Pre-commands are one per line, since commands may contain commas. Lines are kept
+  // as typed and tidied up on submit.
+  const handlePreCommandsChange = (e: React.ChangeEvent<HTMLTextAreaElement>) => {
+    setFormData((prev) => ({ ...prev, pre_commands: e.target.value.split('\n') }));
+  };
+
   return (
     <LoadingState loading={loading} error={error} loadingMessage="Loading vendors...">
       {message && <Message type={message.type} text={message.text} onDismiss={clearMessage} />}
@@ -244,6 +264,95 @@ export function VendorManagement({ devices }: Props) {
           onChange={handleChange}
           placeholder="cisco-ios"
         />
+
+        <FormField
+          label="Bootstrap Script Template ID"
+          name="bootstrap_template"
+          type="text"
+          value={formData.bootstrap_template}
+          onChange={handleChange}
+          placeholder="arista-ztp"
+        />
+
+        <div className="form-group">
+          <label className="checkbox-label">
+            <input
+              type="checkbox"
+              name="image_boot"
+              checked={formData.image_boot}
+              onChange={handleChange}
+            />
+            Send target image as DHCP boot file
+          </label>
+        </div>
+
+        <SelectField
+          label="Backup Mode"
+          name="backup_mode"
+          value={formData.backup_mode}
+          onChange={handleChange}
+          options={BACKUP_MODE_OPTIONS}
+        />
+
+        {formData.backup_mode === 'interactive' && (
+          <>
+            <FormField
+              label="Prompt Pattern"
+              name="prompt_pattern"
+              type="text"
+              value={formData.prompt_pattern}
+              onChange={handleChange}
+              placeholder="[\w.\-()/:]+[>#]\s*$ (default matches most prompts)"
+            />
+
+            <FormField
+              label="Pager Pattern"
+              name="pager_pattern"
+              type="text"
+              value={formData.pager_pattern}
+              onChange={handleChange}
+              placeholder="--More-- (default)"
+            />
+
+            <div className="form-field">
+              <label htmlFor="pre_commands">Pre-Commands</label>
+              <textarea
+                id="pre_commands"
+                name="pre_commands"
+                value={formData.pre_commands.join('\n')}
+                onChange={handlePreCommandsChange}
+                placeholder="terminal length 0"
+                rows={3}
+                style={{ fontFamily: 'monospace' }}
+              />
+              <small className="form-help">
+                One command per line, run before the backup command
+              </small>
+            </div>
+
+            <FormField
+              label="Enable Command"
+              name="enable_command"
+              type="text"
+              value={formData.enable_command}
+              onChange={handleChange}
+              placeholder="enable (leave empty to skip escalation)"
+            />
+          </>
+        )}
+
+        {formData.backup_mode === 'netconf' && (
+          <FormField
+            label="NETCONF Port"
+            name="netconf_port"
+            type="number"
+            value={formData.netconf_port || ''}
+            onChange={handleChange}
+            placeholder="830"
+            min={0}
+            max={65535}
+          />
+        )}
       </FormDialog>
     </LoadingState>
   );
@@ -89,6 +89,12 @@ export function DashboardScreen() {
         return { icon: 'refresh', color: colors.accentBlue };
       case 'lease_expired':
         return { icon: 'timer-off', color: colors.error };
+      case 'serial_mismatch':
+        return { icon: 'warning', color: colors.error };
+      case 'provision_completed':
+        return { icon: 'check-circle', color: colors.success };
+      case 'provision_failed':
+        return { icon: 'error', color: colors.error };
       default:
         return { icon: 'info', color: colors.textMuted };
     }
@@ -29,6 +29,7 @@ const emptyFormData: DeviceFormData = {
   config_template: '',
   ssh_user: '',
   ssh_pass: '',
+  enable_secret: '',
 };
 
 export function DeviceFormScreen() {
@@ -83,6 +84,11 @@ export function DeviceFormScreen() {
         config_template: device.config_template,
         ssh_user: device.ssh_user || '',
         ssh_pass: device.ssh_pass || '',
+        enable_secret: device.enable_secret || '',
+        site: device.site || '',
+        role: device.role || '',
+        tenant: device.tenant || '',
+        platform: device.platform || '',
       };
     }
     // For add mode, use params to pre-fill (e.g., from discovery)
@@ -146,6 +152,7 @@ export function DeviceFormScreen() {
         config_template: device.config_template,
         ssh_user: device.ssh_user || '',
         ssh_pass: device.ssh_pass || '',
+        enable_secret: device.enable_secret || '',
       });
     }
   }, [device, isEditMode, resetForm]);
@@ -97,6 +97,14 @@ export function VendorsScreen() {
       mac_prefixes: vendor.mac_prefixes || [],
       vendor_class: vendor.vendor_class || '',
       default_template: vendor.default_template || '',
+      backup_mode: vendor.backup_mode || 'exec',
+      prompt_pattern: vendor.prompt_pattern || '',
+      pager_pattern: vendor.pager_pattern || '',
+      pre_commands: vendor.pre_commands || [],
+      enable_command: vendor.enable_command || '',
+      netconf_port: vendor.netconf_port || 0,
+      image_boot: vendor.image_boot || false,
+      bootstrap_template: vendor.bootstrap_template || '',
     });
     setShowForm(true);
   };
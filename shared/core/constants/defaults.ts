@@ -11,7 +11,7 @@ export const DEFAULT_DHCP_OPTIONS: Omit<DhcpOption, 'created_at' | 'updated_at'>
     option_number: 66,
     name: 'TFTP Server',
     value: '${tftp_server_ip}',
-    type: 'ip',
+    type: 'string',
     description: 'TFTP server for config files',
     enabled: true,
   },
@@ -25,6 +25,16 @@ export const DEFAULT_DHCP_OPTIONS: Omit<DhcpOption, 'created_at' | 'updated_at'>
     description: 'Cisco IOS config filename',
     enabled: true,
   },
+  {
+    id: 'pnp-cisco',
+    option_number: 43,
+    name: 'Cisco PnP Server',
+    value: '5A1N;B2;K4;I${tftp_server_ip};J8080',
+    type: 'string',
+    vendor_id: 'cisco',
+    description: "Cisco Plug and Play server discovery; J is the ZTP server's HTTP port",
+    enabled: false,
+  },
   {
     id: 'bootfile-arista',
     option_number: 67,
@@ -61,6 +71,11 @@ export const DEFAULT_DHCP_OPTIONS: Omit<DhcpOption, 'created_at' | 'updated_at'>
     name: 'OpenGear ZTP',
     value: '',
     type: 'hex',
+    sub_options: [
+      { code: 1, type: 'string', value: '${opengear_enroll_url}' },
+      { code: 2, type: 'string', value: '${opengear_enroll_bundle}' },
+      { code: 3, type: 'string', value: '${opengear_enroll_password}' },
+    ],
     vendor_id: 'opengear',
     description: 'OpenGear vendor-specific enrollment options',
     enabled: false,
@@ -88,6 +103,14 @@ export const EMPTY_VENDOR_FORM = {
   mac_prefixes: [] as string[],
   vendor_class: '',
   default_template: '',
+  backup_mode: 'exec' as const,
+  prompt_pattern: '',
+  pager_pattern: '',
+  pre_commands: [] as string[],
+  enable_command: '',
+  netconf_port: 0,
+  image_boot: false,
+  bootstrap_template: '',
 };
 
 export const EMPTY_DHCP_OPTION_FORM = {
@@ -124,6 +147,15 @@ export const SAMPLE_DEVICE_FOR_PREVIEW = {
   gateway: '192.168.1.1',
 };
 
+/**
+ * Vendor backup modes for form select fields
+ */
+export const BACKUP_MODE_OPTIONS = [
+  { value: 'exec', label: 'Exec (run command directly)' },
+  { value: 'interactive', label: 'Interactive shell (PTY)' },
+  { value: 'netconf', label: 'NETCONF (XML config)' },
+] as const;
+
 /**
  * DHCP Vendor Class options for test container spawn
  */
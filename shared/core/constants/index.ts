@@ -3,6 +3,7 @@
 export {
   DEFAULT_DHCP_OPTIONS,
   DHCP_OPTION_TYPES,
+  BACKUP_MODE_OPTIONS,
   EMPTY_VENDOR_FORM,
   EMPTY_DHCP_OPTION_FORM,
   EMPTY_TEMPLATE_FORM,
@@ -10,6 +10,11 @@ export {
   DeviceService,
   SettingsService,
   NetBoxService,
+  InventoryService,
+  JobService,
+  BackupService,
+  ImageService,
+  PnPService,
   configureServices,
   getServiceConfig,
   getServices,
@@ -20,12 +25,20 @@ export {
   type WebSocketEvent,
   type WebSocketEventType,
   type DeviceDiscoveredPayload,
+  type BackupPayload,
+  type BackupProgressPayload,
+  type SerialMismatchPayload,
+  type ProvisionStatusPayload,
+  type ProvisionStatus,
+  type ConfigReloadedPayload,
   type WebSocketEventHandler,
   type ConnectResult,
   type ConfigResult,
   type BackupContentResult,
   type PingResult,
   type SSHResult,
+  type DriverInfo,
+  type ApplyConfigRequest,
   type NetBoxConfig,
   type NetBoxStatus,
   type NetBoxSyncResult,
@@ -33,6 +46,29 @@ export {
   type NetBoxSite,
   type NetBoxDeviceRole,
   type NetBoxDevice,
+  type NetBoxFieldOwner,
+  type NetBoxReconcileResult,
+  type NetBoxReconcileStatus,
+  type NetBoxPrefix,
+  type NetBoxTenant,
+  type NetBoxPlatform,
+  type NetBoxDeviceContext,
+  type NetBoxPullItem,
+  type NetBoxSyncPullResponse,
+  type NetBoxAvailableIP,
+  type InventorySource,
+  type InventorySourceType,
+  type InventorySyncPullResponse,
+  type Job,
+  type JobStatus,
+  type JobType,
+  type BackupRun,
+  type BackupRunFilter,
+  type Image,
+  type ImageUpload,
+  type ImageUpdate,
+  type PnPSession,
+  type PnPSessionState,
   type DetectedVariable,
   type TemplatizeResponse,
 } from './services';
@@ -112,6 +148,7 @@ export {
   formatEventType,
   getEventTypeIcon,
   validateMacAddress,
+  normalizeMacAddress,
   validateIpAddress,
   validateHostname,
   validateDeviceForm,
@@ -156,6 +193,7 @@ export {
 export {
   DEFAULT_DHCP_OPTIONS,
   DHCP_OPTION_TYPES,
+  BACKUP_MODE_OPTIONS,
   EMPTY_VENDOR_FORM,
   EMPTY_DHCP_OPTION_FORM,
   EMPTY_TEMPLATE_FORM,
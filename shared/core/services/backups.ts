@@ -0,0 +1,44 @@
+// Backup service - bulk backup runs across a filtered set of devices
+
+import { BaseService } from './base';
+
+export type BackupRunDeviceState = 'pending' | 'running' | 'completed' | 'failed';
+
+// Empty fields match every device
+export interface BackupRunFilter {
+  macs?: string[];
+  vendor?: string;
+  site?: string;
+  role?: string;
+  status?: string;
+  subnet?: string; // CIDR, e.g. 10.0.0.0/24
+}
+
+export interface BackupRun {
+  id: string;
+  filter: BackupRunFilter;
+  total: number;
+  pending: number;
+  running: number;
+  completed: number;
+  failed: number;
+  done: boolean;
+  started_at: string;
+  finished_at?: string;
+  devices: Record<string, BackupRunDeviceState>;
+  errors?: Record<string, string>;
+}
+
+export class BackupService extends BaseService {
+  async startRun(filter: BackupRunFilter = {}): Promise<BackupRun> {
+    return this.post<BackupRun>('/backups/run', filter);
+  }
+
+  async listRuns(): Promise<BackupRun[]> {
+    return this.get<BackupRun[]>('/backups/runs');
+  }
+
+  async getRun(id: string): Promise<BackupRun> {
+    return this.get<BackupRun>(`/backups/runs/${encodeURIComponent(id)}`);
+  }
+}
@@ -59,10 +59,12 @@ export class BaseService {
   }
 
   private async executeRequest<T>(url: string, options?: RequestInit): Promise<T> {
+    // Multipart bodies need the Content-Type fetch generates with the boundary
+    const isForm = typeof FormData !== 'undefined' && options?.body instanceof FormData;
     const response = await this.fetchFn(url, {
       ...options,
       headers: {
-        'Content-Type': 'application/json',
+        ...(isForm ? {} : { 'Content-Type': 'application/json' }),
         ...options?.headers,
       },
     });
@@ -168,6 +170,13 @@ export class BaseService {
     });
   }
 
+  protected postForm<T>(path: string, form: FormData): Promise<T> {
+    return this.request<T>(path, {
+      method: 'POST',
+      body: form,
+    });
+  }
+
   protected put<T>(path: string, body: unknown): Promise<T> {
     return this.request<T>(path, {
       method: 'PUT',
@@ -1,7 +1,14 @@
 // Device service - handles all device-related API operations
 
 import { BaseService } from './base';
-import type { Device, Backup } from '../types';
+import type { Device, Backup, DeviceFacts, ConfigChange } from '../types';
+import type { Job } from './jobs';
+
+export interface ApplyConfigRequest {
+  config?: string; // Config snippet; set this or template_id
+  template_id?: string; // Template to render for the device
+  confirm_minutes?: number; // Rollback timer, 5 if unset; 0 disables commit-confirm
+}
 
 export interface PingResult {
   reachable: boolean;
@@ -11,6 +18,7 @@ export interface PingResult {
 
 export interface SSHResult {
   connected: boolean;
+  driver?: string; // Vendor driver used, e.g. cisco-ios or generic
   uptime?: string;
   error?: string;
 }
@@ -29,6 +37,11 @@ export interface ConfigResult {
   exists: boolean;
 }
 
+export interface DriverInfo {
+  vendor: string;
+  driver: string;
+}
+
 export interface BackupContentResult {
   id: number;
   filename: string;
@@ -69,6 +82,43 @@ export class DeviceService extends BaseService {
     return this.post<ConnectResult>(`/devices/${encodeURIComponent(mac)}/connect`);
   }
 
+  async reboot(mac: string): Promise<{ message: string }> {
+    return this.post<{ message: string }>(`/devices/${encodeURIComponent(mac)}/reboot`);
+  }
+
+  async reprovision(mac: string): Promise<{ message: string }> {
+    return this.post<{ message: string }>(`/devices/${encodeURIComponent(mac)}/reprovision`);
+  }
+
+  async getFacts(mac: string): Promise<DeviceFacts> {
+    return this.get<DeviceFacts>(`/devices/${encodeURIComponent(mac)}/facts`);
+  }
+
+  async listFactsHistory(mac: string, limit?: number): Promise<DeviceFacts[]> {
+    const query = limit ? `?limit=${limit}` : '';
+    return this.get<DeviceFacts[]>(`/devices/${encodeURIComponent(mac)}/facts/history${query}`);
+  }
+
+  async collectFacts(mac: string): Promise<{ message: string; job: Job }> {
+    return this.post<{ message: string; job: Job }>(`/devices/${encodeURIComponent(mac)}/facts`);
+  }
+
+  async applyConfig(mac: string, req: ApplyConfigRequest): Promise<{ message: string; change: ConfigChange }> {
+    return this.post<{ message: string; change: ConfigChange }>(`/devices/${encodeURIComponent(mac)}/apply`, req);
+  }
+
+  async listChanges(mac: string): Promise<ConfigChange[]> {
+    return this.get<ConfigChange[]>(`/devices/${encodeURIComponent(mac)}/changes`);
+  }
+
+  async getChange(id: number): Promise<ConfigChange> {
+    return this.get<ConfigChange>(`/changes/${id}`);
+  }
+
+  async listDrivers(): Promise<DriverInfo[]> {
+    return this.get<DriverInfo[]>('/drivers');
+  }
+
   async getConfig(mac: string): Promise<ConfigResult> {
     return this.get<ConfigResult>(`/devices/${encodeURIComponent(mac)}/config`);
   }
@@ -1,7 +1,7 @@
 // DHCP Options service - handles all DHCP option-related API operations
 
 import { BaseService } from './base';
-import type { DhcpOption } from '../types';
+import type { DhcpOption, DhcpOptionPreview, VendorOptionSchema } from '../types';
 
 export class DhcpOptionService extends BaseService {
   async list(): Promise<DhcpOption[]> {
@@ -12,6 +12,18 @@ export class DhcpOptionService extends BaseService {
     return this.get<DhcpOption[]>('/dhcp-options/defaults');
   }
 
+  async listSchemas(): Promise<VendorOptionSchema[]> {
+    return this.get<VendorOptionSchema[]>('/dhcp-options/schemas');
+  }
+
+  async preview(id: string): Promise<DhcpOptionPreview> {
+    return this.get<DhcpOptionPreview>(`/dhcp-options/${encodeURIComponent(id)}/preview`);
+  }
+
+  async previewUnsaved(option: Partial<DhcpOption>): Promise<DhcpOptionPreview> {
+    return this.post<DhcpOptionPreview>('/dhcp-options/preview', option);
+  }
+
   async getById(id: string): Promise<DhcpOption> {
     return this.get<DhcpOption>(`/dhcp-options/${encodeURIComponent(id)}`);
   }
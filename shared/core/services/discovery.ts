@@ -1,7 +1,15 @@
 // Discovery service - handles device discovery from DHCP leases
 
 import { BaseService } from './base';
-import type { DiscoveredDevice, DiscoveryLog } from '../types';
+import type {
+  DiscoveredDevice,
+  DiscoveryLog,
+  Device,
+  PendingDevice,
+  PendingDeviceStatus,
+  ApproveDeviceRequest,
+  BulkApproveResponse,
+} from '../types';
 
 export class DiscoveryService extends BaseService {
   async list(): Promise<DiscoveredDevice[]> {
@@ -24,4 +32,25 @@ export class DiscoveryService extends BaseService {
   async clearLogs(): Promise<void> {
     await this.delete<{ message: string }>('/discovery/logs');
   }
+
+  // Approval queue
+  async listPending(status: PendingDeviceStatus | 'all' = 'pending'): Promise<PendingDevice[]> {
+    return this.get<PendingDevice[]>(`/discovery/pending?status=${status}`);
+  }
+
+  async approve(mac: string, data: ApproveDeviceRequest = {}): Promise<Device> {
+    return this.post<Device>(`/discovery/pending/${encodeURIComponent(mac)}/approve`, data);
+  }
+
+  async bulkApprove(devices: ApproveDeviceRequest[]): Promise<BulkApproveResponse> {
+    return this.post<BulkApproveResponse>('/discovery/pending/approve', { devices });
+  }
+
+  async reject(mac: string, note?: string): Promise<void> {
+    await this.post<{ message: string }>(`/discovery/pending/${encodeURIComponent(mac)}/reject`, note ? { note } : undefined);
+  }
+
+  async removePending(mac: string): Promise<void> {
+    return this.delete<void>(`/discovery/pending/${encodeURIComponent(mac)}`);
+  }
 }
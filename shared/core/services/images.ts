@@ -0,0 +1,61 @@
+// Image service - firmware/OS image repository
+
+import { BaseService } from './base';
+
+export interface Image {
+  id: number;
+  vendor: string;
+  model?: string; // Unset for every model of the vendor
+  version: string;
+  filename: string;
+  size: number;
+  sha256: string;
+  md5: string;
+  target: boolean; // Devices of the vendor and model are upgraded to this image
+  created_at: string;
+}
+
+export interface ImageUpload {
+  file: Blob;
+  filename: string;
+  vendor: string;
+  model?: string;
+  version: string;
+  target?: boolean;
+}
+
+export interface ImageUpdate {
+  vendor: string;
+  model?: string;
+  version: string;
+  target: boolean;
+}
+
+export class ImageService extends BaseService {
+  async list(vendor?: string): Promise<Image[]> {
+    const query = vendor ? `?vendor=${encodeURIComponent(vendor)}` : '';
+    return this.get<Image[]>(`/images${query}`);
+  }
+
+  async getById(id: number): Promise<Image> {
+    return this.get<Image>(`/images/${id}`);
+  }
+
+  async upload(image: ImageUpload): Promise<Image> {
+    const form = new FormData();
+    form.append('file', image.file, image.filename);
+    form.append('vendor', image.vendor);
+    form.append('model', image.model || '');
+    form.append('version', image.version);
+    form.append('target', String(image.target || false));
+    return this.postForm<Image>('/images', form);
+  }
+
+  async update(id: number, image: ImageUpdate): Promise<Image> {
+    return this.put<Image>(`/images/${id}`, image);
+  }
+
+  async remove(id: number): Promise<void> {
+    return this.delete<void>(`/images/${id}`);
+  }
+}
@@ -8,10 +8,15 @@ import { TemplateService } from './templates';
 import { DiscoveryService } from './discovery';
 import { TestContainersService } from './testContainers';
 import { NetBoxService } from './netbox';
+import { InventoryService } from './inventory';
+import { JobService } from './jobs';
+import { BackupService } from './backups';
+import { ImageService } from './images';
+import { PnPService } from './pnp';
 
 export { BaseService, configureServices, getServiceConfig, type ServiceConfig } from './base';
 export { DeviceService } from './devices';
-export type { ConnectResult, ConfigResult, BackupContentResult, PingResult, SSHResult } from './devices';
+export type { ConnectResult, ConfigResult, BackupContentResult, PingResult, SSHResult, DriverInfo, ApplyConfigRequest } from './devices';
 export { SettingsService } from './settings';
 export { VendorService } from './vendors';
 export { DhcpOptionService } from './dhcpOptions';
@@ -20,9 +25,19 @@ export type { DetectedVariable, TemplatizeResponse } from './templates';
 export { DiscoveryService } from './discovery';
 export { TestContainersService } from './testContainers';
 export { NetBoxService } from './netbox';
-export type { NetBoxConfig, NetBoxStatus, NetBoxSyncResult, NetBoxManufacturer, NetBoxSite, NetBoxDeviceRole, NetBoxDevice, NetBoxVendorSyncResponse } from './netbox';
+export type { NetBoxConfig, NetBoxStatus, NetBoxSyncResult, NetBoxManufacturer, NetBoxSite, NetBoxDeviceRole, NetBoxDevice, NetBoxVendorSyncResponse, NetBoxFieldOwner, NetBoxSyncedField, NetBoxReconcileResult, NetBoxReconcileStatus, NetBoxReconcileResponse, NetBoxPrefix, NetBoxAvailableIP, NetBoxTenant, NetBoxPlatform, NetBoxDeviceContext, NetBoxSiteContext, NetBoxInterfaceContext, NetBoxPullItem, NetBoxPullSummary, NetBoxSyncPullRequest, NetBoxSyncPullResponse } from './netbox';
+export { InventoryService } from './inventory';
+export type { InventorySource, InventorySourceType, InventoryRecord, InventorySite, InventoryCheckResult, InventorySyncRequest, InventorySyncPullResponse, InventorySyncPushResponse, InventorySyncJobResponse } from './inventory';
+export { JobService } from './jobs';
+export type { Job, JobStatus, JobType, JobListOptions } from './jobs';
+export { BackupService } from './backups';
+export type { BackupRun, BackupRunFilter, BackupRunDeviceState } from './backups';
+export { ImageService } from './images';
+export type { Image, ImageUpload, ImageUpdate } from './images';
+export { PnPService } from './pnp';
+export type { PnPSession, PnPSessionState } from './pnp';
 export { WebSocketService, getWebSocketService } from './websocket';
-export type { WebSocketEvent, WebSocketEventType, DeviceDiscoveredPayload, ConfigPulledPayload, WebSocketEventHandler } from './websocket';
+export type { WebSocketEvent, WebSocketEventType, DeviceDiscoveredPayload, ConfigPulledPayload, BackupPayload, BackupProgressPayload, SerialMismatchPayload, ProvisionStatusPayload, ProvisionStatus, ConfigReloadedPayload, WebSocketEventHandler } from './websocket';
 
 export interface Services {
   devices: DeviceService;
@@ -33,6 +48,11 @@ export interface Services {
   discovery: DiscoveryService;
   testContainers: TestContainersService;
   netbox: NetBoxService;
+  inventory: InventoryService;
+  jobs: JobService;
+  backups: BackupService;
+  images: ImageService;
+  pnp: PnPService;
 }
 
 // Singleton services that use global config
@@ -49,6 +69,11 @@ export function getServices(): Services {
       discovery: new DiscoveryService(),
       testContainers: new TestContainersService(),
       netbox: new NetBoxService(),
+      inventory: new InventoryService(),
+      jobs: new JobService(),
+      backups: new BackupService(),
+      images: new ImageService(),
+      pnp: new PnPService(),
     };
   }
   return services;
@@ -0,0 +1,121 @@
+// Inventory source service - NetBox, Nautobot and generic HTTP inventories
+
+import { BaseService } from './base';
+import type { Device, Vendor } from '../types';
+import type { NetBoxSyncResult, NetBoxPullItem, NetBoxPullSummary } from './netbox';
+import type { Job } from './jobs';
+
+export type InventorySourceType = 'netbox' | 'nautobot' | 'http';
+
+export interface InventorySource {
+  id: string;
+  name: string;
+  type: InventorySourceType;
+  enabled: boolean;
+  url: string;
+  token?: string; // masked in responses; omit on update to keep the stored token
+  format?: 'json' | 'csv'; // http sources
+  headers?: Record<string, string>; // http sources
+  builtin: boolean; // the NetBox integration, configured via /netbox/config
+  created_at: string;
+  updated_at: string;
+}
+
+export interface InventoryRecord {
+  external_id: string;
+  name: string;
+  device?: Device;
+}
+
+export interface InventorySite {
+  id: string;
+  name: string;
+  slug: string;
+  description?: string;
+}
+
+export interface InventoryCheckResult {
+  connected: boolean;
+  error?: string;
+}
+
+export interface InventorySyncRequest {
+  source?: string; // defaults to the built-in NetBox source
+  direction: 'pull' | 'push';
+  dry_run?: boolean; // pull only
+  macs?: string[]; // only these devices
+  async?: boolean; // queue as a job instead of waiting
+}
+
+export interface InventorySyncJobResponse {
+  message: string;
+  job: Job;
+}
+
+export interface InventorySyncPullResponse {
+  message: string;
+  source: string;
+  dry_run: boolean;
+  summary: NetBoxPullSummary;
+  devices: NetBoxPullItem[];
+}
+
+export interface InventorySyncPushResponse {
+  message: string;
+  source: string;
+  result: NetBoxSyncResult;
+}
+
+export class InventoryService extends BaseService {
+  async listTypes(): Promise<InventorySourceType[]> {
+    return this.get<InventorySourceType[]>('/inventory/types');
+  }
+
+  async list(): Promise<InventorySource[]> {
+    return this.get<InventorySource[]>('/inventory/sources');
+  }
+
+  async getById(id: string): Promise<InventorySource> {
+    return this.get<InventorySource>(`/inventory/sources/${encodeURIComponent(id)}`);
+  }
+
+  async create(source: Partial<InventorySource>): Promise<InventorySource> {
+    return this.post<InventorySource>('/inventory/sources', source);
+  }
+
+  async update(id: string, source: Partial<InventorySource>): Promise<InventorySource> {
+    return this.put<InventorySource>(`/inventory/sources/${encodeURIComponent(id)}`, source);
+  }
+
+  async remove(id: string): Promise<void> {
+    return this.delete<void>(`/inventory/sources/${encodeURIComponent(id)}`);
+  }
+
+  async check(id: string): Promise<InventoryCheckResult> {
+    return this.get<InventoryCheckResult>(`/inventory/sources/${encodeURIComponent(id)}/check`);
+  }
+
+  async listDevices(id: string): Promise<InventoryRecord[]> {
+    return this.get<InventoryRecord[]>(`/inventory/sources/${encodeURIComponent(id)}/devices`);
+  }
+
+  async listVendors(id: string): Promise<Vendor[]> {
+    return this.get<Vendor[]>(`/inventory/sources/${encodeURIComponent(id)}/vendors`);
+  }
+
+  async listSites(id: string): Promise<InventorySite[]> {
+    return this.get<InventorySite[]>(`/inventory/sources/${encodeURIComponent(id)}/sites`);
+  }
+
+  async pull(source: string, options: { dry_run?: boolean; macs?: string[] } = {}): Promise<InventorySyncPullResponse> {
+    return this.post<InventorySyncPullResponse>('/inventory/sync', { source, direction: 'pull', ...options });
+  }
+
+  async push(source: string, macs?: string[]): Promise<InventorySyncPushResponse> {
+    return this.post<InventorySyncPushResponse>('/inventory/sync', { source, direction: 'push', macs });
+  }
+
+  async queueSync(request: InventorySyncRequest): Promise<InventorySyncJobResponse> {
+    return this.post<InventorySyncJobResponse>('/inventory/sync', { ...request, async: true });
+  }
+}
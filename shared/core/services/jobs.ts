@@ -0,0 +1,61 @@
+// Job queue service - background backups, syncs and config generation
+
+import { BaseService } from './base';
+
+export type JobStatus = 'pending' | 'running' | 'succeeded' | 'failed' | 'cancelled';
+
+export type JobType = 'backup' | 'netbox.sync' | 'inventory.sync' | 'facts.collect' | 'config.apply';
+
+export interface Job {
+  id: number;
+  type: JobType;
+  status: JobStatus;
+  key?: string;
+  payload?: unknown;
+  result?: unknown;
+  error?: string;
+  attempts: number;
+  max_attempts: number;
+  run_at: string;
+  started_at?: string;
+  finished_at?: string;
+  created_at: string;
+  updated_at: string;
+}
+
+export interface JobListOptions {
+  type?: JobType;
+  status?: JobStatus;
+  limit?: number;
+}
+
+export class JobService extends BaseService {
+  async list(options: JobListOptions = {}): Promise<Job[]> {
+    const params = new URLSearchParams();
+    if (options.type) params.set('type', options.type);
+    if (options.status) params.set('status', options.status);
+    if (options.limit) params.set('limit', String(options.limit));
+    const query = params.toString();
+    return this.get<Job[]>(`/jobs${query ? `?${query}` : ''}`);
+  }
+
+  async listTypes(): Promise<JobType[]> {
+    return this.get<JobType[]>('/jobs/types');
+  }
+
+  async getById(id: number): Promise<Job> {
+    return this.get<Job>(`/jobs/${id}`);
+  }
+
+  async cancel(id: number): Promise<{ message: string }> {
+    return this.post<{ message: string }>(`/jobs/${id}/cancel`);
+  }
+
+  async retry(id: number): Promise<{ message: string }> {
+    return this.post<{ message: string }>(`/jobs/${id}/retry`);
+  }
+
+  async remove(id: number): Promise<void> {
+    return this.delete<void>(`/jobs/${id}`);
+  }
+}
@@ -2,12 +2,20 @@
 
 import { BaseService } from './base';
 
+export type NetBoxFieldOwner = 'ztp' | 'netbox';
+
+export type NetBoxSyncedField = 'hostname' | 'ip' | 'serial_number' | 'vendor';
+
 export interface NetBoxConfig {
   url: string;
   token: string;
   site_id: number;
   role_id: number;
   sync_enabled: boolean;
+  sync_interval: number; // seconds between background reconciles
+  webhook_secret: string;
+  field_owners: Partial<Record<NetBoxSyncedField, NetBoxFieldOwner>>;
+  prefix_id: number; // prefix new device IPs are allocated from, 0 to disable
 }
 
 export interface NetBoxStatus {
@@ -30,11 +38,73 @@ export interface NetBoxSyncPushResponse {
   result: NetBoxSyncResult;
 }
 
+export type NetBoxPullAction = 'create' | 'update' | 'unchanged' | 'conflict' | 'skip';
+
+export interface NetBoxPullChange {
+  field: string;
+  current: string;
+  incoming: string;
+}
+
+export interface NetBoxPullItem {
+  external_id: string; // device ID in the source
+  netbox_id?: number; // set for NetBox sources
+  mac?: string;
+  hostname: string;
+  action: NetBoxPullAction;
+  reason?: string;
+  changes?: NetBoxPullChange[];
+  applied: boolean;
+  error?: string;
+}
+
+export interface NetBoxPullSummary {
+  created: number;
+  updated: number;
+  unchanged: number;
+  conflicts: number;
+  skipped: number;
+  applied: number;
+  failed: number;
+}
+
+export interface NetBoxSyncPullRequest {
+  dry_run?: boolean;
+  macs?: string[]; // apply only these devices
+}
+
 export interface NetBoxSyncPullResponse {
   message: string;
-  imported: number;
-  skipped: number;
-  result: NetBoxSyncResult;
+  dry_run: boolean;
+  summary: NetBoxPullSummary;
+  devices: NetBoxPullItem[];
+}
+
+export interface NetBoxReconcileResult {
+  trigger: string;
+  started_at: string;
+  finished_at: string;
+  linked: number;
+  created_ztp: number;
+  created_netbox: number;
+  updated_ztp: number;
+  updated_netbox: number;
+  unchanged: number;
+  errors?: string[];
+}
+
+export interface NetBoxReconcileStatus {
+  enabled: boolean;
+  interval: number;
+  running: boolean;
+  last_run?: string;
+  last_result?: NetBoxReconcileResult;
+  last_error?: string;
+}
+
+export interface NetBoxReconcileResponse {
+  message: string;
+  result: NetBoxReconcileResult;
 }
 
 export interface NetBoxManufacturer {
@@ -74,6 +144,73 @@ export interface NetBoxDeviceRole {
   description?: string;
 }
 
+export interface NetBoxSiteContext {
+  name?: string;
+  slug?: string;
+  region?: string;
+  facility?: string;
+  time_zone?: string;
+  physical_address?: string;
+  description?: string;
+  custom_fields: Record<string, unknown>;
+}
+
+export interface NetBoxInterfaceContext {
+  name: string;
+  type?: string;
+  enabled: boolean;
+  mac?: string;
+  mtu?: number;
+  description?: string;
+  addresses?: string[];
+}
+
+// NetBox data exposed to config templates as .NetBox
+export interface NetBoxDeviceContext {
+  found: boolean;
+  id?: number;
+  name?: string;
+  status?: string;
+  role?: string;
+  tenant?: string;
+  platform?: string;
+  primary_ip?: string;
+  config_context: Record<string, unknown>;
+  custom_fields: Record<string, unknown>;
+  site: NetBoxSiteContext;
+  interfaces: NetBoxInterfaceContext[];
+}
+
+export interface NetBoxTenant {
+  id: number;
+  name: string;
+  slug: string;
+  description?: string;
+}
+
+export interface NetBoxPlatform {
+  id: number;
+  name: string;
+  slug: string;
+  manufacturer?: { id: number; name: string; slug: string };
+  description?: string;
+}
+
+export interface NetBoxPrefix {
+  id: number;
+  prefix: string;
+  family: { value: number; label: string };
+  status: { value: string; label: string };
+  site?: { id: number; name: string; slug: string };
+  is_pool: boolean;
+  description?: string;
+}
+
+export interface NetBoxAvailableIP {
+  family: number;
+  address: string;
+}
+
 export interface NetBoxDevice {
   id: number;
   name: string;
@@ -85,6 +222,8 @@ export interface NetBoxDevice {
   };
   role: { id: number; name: string; slug: string };
   site: { id: number; name: string; slug: string };
+  tenant?: { id: number; name: string; slug: string } | null;
+  platform?: { id: number; name: string; slug: string } | null;
   status: { value: string; label: string };
   serial?: string;
   primary_ip4?: { id: number; address: string };
@@ -112,8 +251,21 @@ export class NetBoxService extends BaseService {
     return this.post<NetBoxSyncPushResponse>('/netbox/sync/push');
   }
 
-  async syncPull(): Promise<NetBoxSyncPullResponse> {
-    return this.post<NetBoxSyncPullResponse>('/netbox/sync/pull');
+  async syncPull(request: NetBoxSyncPullRequest = {}): Promise<NetBoxSyncPullResponse> {
+    return this.post<NetBoxSyncPullResponse>('/netbox/sync/pull', request);
+  }
+
+  async previewPull(): Promise<NetBoxSyncPullResponse> {
+    return this.syncPull({ dry_run: true });
+  }
+
+  // Background reconciler
+  async getSyncStatus(): Promise<NetBoxReconcileStatus> {
+    return this.get<NetBoxReconcileStatus>('/netbox/sync/status');
+  }
+
+  async runSync(): Promise<NetBoxReconcileResponse> {
+    return this.post<NetBoxReconcileResponse>('/netbox/sync/run');
   }
 
   // Lists
@@ -129,6 +281,27 @@ export class NetBoxService extends BaseService {
     return this.get<NetBoxDeviceRole[]>('/netbox/device-roles');
   }
 
+  async getDeviceContext(mac: string, refresh = false): Promise<NetBoxDeviceContext> {
+    const query = refresh ? '?refresh=true' : '';
+    return this.get<NetBoxDeviceContext>(`/netbox/context/${encodeURIComponent(mac)}${query}`);
+  }
+
+  async getTenants(): Promise<NetBoxTenant[]> {
+    return this.get<NetBoxTenant[]>('/netbox/tenants');
+  }
+
+  async getPlatforms(): Promise<NetBoxPlatform[]> {
+    return this.get<NetBoxPlatform[]>('/netbox/platforms');
+  }
+
+  async getPrefixes(): Promise<NetBoxPrefix[]> {
+    return this.get<NetBoxPrefix[]>('/netbox/prefixes');
+  }
+
+  async getAvailableIPs(prefixId: number, limit = 10): Promise<NetBoxAvailableIP[]> {
+    return this.get<NetBoxAvailableIP[]>(`/netbox/prefixes/${prefixId}/available-ips?limit=${limit}`);
+  }
+
   // Vendor sync operations
   async syncVendorsPush(): Promise<NetBoxVendorSyncResponse> {
     return this.post<NetBoxVendorSyncResponse>('/netbox/sync/vendors/push');
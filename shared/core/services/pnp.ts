@@ -0,0 +1,36 @@
+// PnP service - Cisco Plug and Play sessions
+
+import { BaseService } from './base';
+
+export type PnPSessionState =
+  | 'unknown'
+  | 'image_install'
+  | 'config_upgrade'
+  | 'cli_exec'
+  | 'completed'
+  | 'failed';
+
+export interface PnPSession {
+  serial: string; // Serial number from the UDI
+  udi: string;
+  pid?: string;
+  ip: string;
+  hostname?: string; // Hostname the agent reported
+  device_mac?: string; // Registered device, unset until one matches
+  state: PnPSessionState;
+  step: number; // cli_exec: index of the command being run
+  correlator?: string; // Work item awaiting a response
+  last_error?: string;
+  created_at: string;
+  updated_at: string; // Last contact from the agent
+}
+
+export class PnPService extends BaseService {
+  async listSessions(): Promise<PnPSession[]> {
+    return this.get<PnPSession[]>('/pnp/sessions');
+  }
+
+  async resetSession(serial: string): Promise<void> {
+    return this.delete<void>(`/pnp/sessions/${encodeURIComponent(serial)}`);
+  }
+}
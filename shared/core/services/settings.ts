@@ -1,7 +1,7 @@
 // Settings service - handles global settings API operations
 
 import { BaseService } from './base';
-import type { Settings, NetworkInterface } from '../types';
+import type { Settings, NetworkInterface, ReloadStatus } from '../types';
 
 export class SettingsService extends BaseService {
   async getSettings(): Promise<Settings> {
@@ -16,6 +16,10 @@ export class SettingsService extends BaseService {
     return this.post<void>('/reload');
   }
 
+  async getReloadStatus(): Promise<ReloadStatus> {
+    return super.get<ReloadStatus>('/reload/status');
+  }
+
   async getLocalAddresses(): Promise<NetworkInterface[]> {
     return super.get<NetworkInterface[]>('/network/addresses');
   }
@@ -2,6 +2,7 @@
 
 import { BaseService } from './base';
 import type { Template, TemplateVariable } from '../types';
+import type { NetBoxDeviceContext } from './netbox';
 
 export class TemplateService extends BaseService {
   async list(): Promise<Template[]> {
@@ -34,6 +35,7 @@ export class TemplateService extends BaseService {
     };
     subnet: string;
     gateway: string;
+    netbox?: NetBoxDeviceContext; // sample data; fetched for the device if omitted
   }): Promise<{ output: string }> {
     return this.post<{ output: string }>(`/templates/${encodeURIComponent(id)}/preview`, data);
   }
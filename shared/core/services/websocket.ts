@@ -9,7 +9,11 @@ export type WebSocketEventType =
   | 'backup_started'
   | 'backup_completed'
   | 'backup_failed'
-  | 'config_pulled';
+  | 'backup_progress'
+  | 'config_pulled'
+  | 'serial_mismatch'
+  | 'provision_status'
+  | 'config_reloaded';
 
 export interface DeviceDiscoveredPayload {
   mac: string;
@@ -26,6 +30,52 @@ export interface ConfigPulledPayload {
   protocol: 'tftp' | 'http';
 }
 
+export interface BackupPayload {
+  mac: string;
+  hostname?: string;
+  attempt: number;
+  error?: string;
+}
+
+export interface BackupProgressPayload {
+  run_id: string;
+  total: number;
+  pending: number;
+  running: number;
+  completed: number;
+  failed: number;
+  done: boolean;
+}
+
+export interface SerialMismatchPayload {
+  mac: string;
+  hostname?: string;
+  registered: string;
+  reported: string;
+}
+
+export interface ProvisionStatusPayload {
+  mac: string;
+  ip: string;
+  hostname?: string;
+  status: ProvisionStatus;
+  message?: string;
+  payload?: unknown; // JSON payload the device sent with its callback
+}
+
+export type ProvisionStatus =
+  | 'started'
+  | 'downloading_image'
+  | 'installing_image'
+  | 'applying_config'
+  | 'completed'
+  | 'failed';
+
+export interface ConfigReloadedPayload {
+  generation: number;
+  duration_ms: number;
+}
+
 export interface WebSocketEvent<T = unknown> {
   type: WebSocketEventType;
   payload: T;
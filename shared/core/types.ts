@@ -12,16 +12,27 @@ export interface Device {
   config_template: string;
   ssh_user?: string;
   ssh_pass?: string;
+  enable_secret?: string; // Enable-mode secret for interactive backups
   status: DeviceStatus;
   last_seen?: string;
   last_backup?: string;
   last_error?: string;
+  upgrade_status?: UpgradeStatus; // Image upgrade step, if the device has a target image
+  upgrade_version?: string; // Image version the upgrade status refers to
+  provisioned_at?: string; // When the device last finished provisioning
+  netbox_id?: number;
+  site?: string; // NetBox slugs
+  role?: string;
+  tenant?: string;
+  platform?: string;
   created_at: string;
   updated_at: string;
 }
 
 export type DeviceStatus = 'online' | 'offline' | 'provisioning' | 'unknown';
 
+export type UpgradeStatus = 'pending' | 'downloading' | 'upgraded' | 'failed';
+
 export interface DeviceFormData {
   mac: string;
   ip: string;
@@ -32,6 +43,11 @@ export interface DeviceFormData {
   config_template: string;
   ssh_user: string;
   ssh_pass: string;
+  enable_secret?: string;
+  site?: string;
+  role?: string;
+  tenant?: string;
+  platform?: string;
 }
 
 export interface Settings {
@@ -48,16 +64,89 @@ export interface Settings {
   opengear_enroll_url: string;
   opengear_enroll_bundle: string;
   opengear_enroll_password: string;
+  // Backup worker pool limits (0 = unlimited)
+  backup_workers: number;
+  backup_vendor_limit: number;
+  backup_subnet_limit: number;
+  backup_rate_limit: number; // Backups started per minute
+  default_enable_secret: string; // Enable-mode secret for devices without their own
+  pnp_exec_template?: string; // Template run as exec commands on Cisco PnP devices
+  // Config download protection for /configs
+  config_token_required?: boolean; // Downloads need a token from {{.Bootstrap.ConfigURL}}
+  config_token_ttl?: number; // Minutes a token is valid, 0 for no expiry
+  config_token_one_time?: boolean;
+  config_check_source_ip?: boolean; // Only serve configs to the device's reserved IP
+  config_lockout?: boolean; // Refuse configs once a device has been provisioned
+  config_require_tls?: boolean; // Refuse configs over plain HTTP
+}
+
+export interface ReloadStatus {
+  generation: number; // Successful regenerations since the server started
+  pending: boolean; // Changes are waiting for a regeneration
+  running: boolean;
+  last_error?: string; // Error of the last regeneration, unset if it succeeded
+  last_duration_ms: number;
+  last_run_at?: string;
+  live_at?: string; // When the current generation went live
 }
 
 export interface Backup {
   id: number;
   device_mac: string;
   filename: string;
+  format?: 'text' | 'xml'; // xml for NETCONF backups
   size: number;
   created_at: string;
 }
 
+// Device facts collected over SSH after provisioning
+export interface DeviceInterface {
+  name: string;
+  status?: string;
+  mac?: string;
+  description?: string;
+}
+
+export interface LLDPNeighbor {
+  local_interface: string;
+  remote_device: string;
+  remote_interface?: string;
+}
+
+export interface DeviceFacts {
+  id: number;
+  device_mac: string;
+  driver: string;
+  hostname?: string;
+  model?: string;
+  serial?: string;
+  version?: string;
+  uptime?: string;
+  interfaces: DeviceInterface[];
+  neighbors: LLDPNeighbor[];
+  serial_mismatch: boolean; // Reported serial differs from the registered one
+  collected_at: string;
+}
+
+export type ConfigChangeStatus = 'pending' | 'running' | 'applied' | 'confirmed' | 'rolled_back' | 'failed';
+
+export interface ConfigChange {
+  id: number;
+  device_mac: string;
+  template_id?: string; // Template the config was rendered from, unset for snippets
+  config: string;
+  confirm_minutes: number; // Rollback timer; 0 applies without commit-confirm
+  status: ConfigChangeStatus;
+  driver?: string;
+  output?: string;
+  error?: string;
+  pre_backup_id?: number;
+  post_backup_id?: number;
+  job_id?: number;
+  created_at: string;
+  finished_at?: string;
+}
+
 // UI State types
 export type Theme = 'dark' | 'light' | 'plain';
 
@@ -75,11 +164,21 @@ export interface Vendor {
   mac_prefixes: string[];
   vendor_class?: string; // DHCP Option 60 vendor class identifier
   default_template?: string; // Default template ID for this vendor
+  backup_mode?: VendorBackupMode;
+  prompt_pattern?: string; // Interactive: regex matching the CLI prompt
+  pager_pattern?: string; // Interactive: regex matching a pagination prompt
+  pre_commands?: string[]; // Interactive: run before the backup command
+  enable_command?: string; // Interactive: privilege escalation command
+  netconf_port?: number; // NETCONF: port of the netconf subsystem, 830 if 0
+  image_boot?: boolean; // Send the target image as the DHCP boot file
+  bootstrap_template?: string; // Template served as the ZTP bootstrap script via DHCP option 67
   device_count?: number;
   created_at?: string;
   updated_at?: string;
 }
 
+export type VendorBackupMode = 'exec' | 'interactive' | 'netconf';
+
 export interface VendorFormData {
   id: string;
   name: string;
@@ -88,28 +187,81 @@ export interface VendorFormData {
   mac_prefixes: string[];
   vendor_class: string;
   default_template: string;
+  backup_mode: VendorBackupMode;
+  prompt_pattern: string;
+  pager_pattern: string;
+  pre_commands: string[];
+  enable_command: string;
+  netconf_port: number;
+  image_boot: boolean;
+  bootstrap_template: string;
 }
 
 // DHCP Option types
 export type DhcpOptionType = 'string' | 'ip' | 'hex' | 'number';
 
+export interface DhcpSubOption {
+  code: number;
+  type: DhcpOptionType;
+  value: string;
+}
+
 export interface DhcpOption {
   id: string;
   option_number: number;
   name: string;
   value: string;
   type: DhcpOptionType;
+  sub_options?: DhcpSubOption[]; // TLV payload for options 43 and 125 (value must be empty)
+  enterprise_number?: number; // Option 125 only
   vendor_id?: string; // If set, only applies to this vendor
   description?: string;
   enabled: boolean;
 }
 
+export interface DhcpSubOptionPreview {
+  code: number;
+  name?: string;
+  value: string;
+  length: number;
+  data: string;
+}
+
+export interface DhcpOptionPreview {
+  option_number: number;
+  type: DhcpOptionType;
+  value?: string; // After variable substitution
+  length: number;
+  data: string; // Payload as colon-separated hex
+  wire: string; // Code, length and payload
+  dnsmasq: string; // Generated dhcp-option directive
+  sub_options?: DhcpSubOptionPreview[];
+}
+
+export interface DhcpSubOptionSchema {
+  code: number;
+  name: string;
+  type: DhcpOptionType;
+  description?: string;
+}
+
+export interface VendorOptionSchema {
+  vendor_id: string;
+  option_number: number;
+  enterprise_number?: number;
+  name: string;
+  description?: string;
+  sub_options: DhcpSubOptionSchema[];
+}
+
 export interface DhcpOptionFormData {
   id: string;
   option_number: number;
   name: string;
   value: string;
   type: DhcpOptionType;
+  sub_options?: DhcpSubOption[];
+  enterprise_number?: number;
   vendor_id: string;
   description: string;
   enabled: boolean;
@@ -119,7 +271,7 @@ export interface DhcpOptionFormData {
 export const COMMON_DHCP_OPTIONS = [
   { number: 66, name: 'TFTP Server', description: 'Boot server hostname or IP' },
   { number: 67, name: 'Bootfile Name', description: 'Boot file path' },
-  { number: 43, name: 'Vendor Specific', description: 'Vendor-specific information (hex encoded)' },
+  { number: 43, name: 'Vendor Specific', description: 'Vendor-specific information (hex or sub-options)' },
   { number: 60, name: 'Vendor Class ID', description: 'Vendor class identifier' },
   { number: 150, name: 'TFTP Server Address', description: 'TFTP server IP (Cisco)' },
   { number: 125, name: 'Vendor-Identifying', description: 'Vendor-identifying vendor-specific info' },
@@ -161,7 +313,51 @@ export interface DiscoveredDevice {
   first_seen?: string;
 }
 
-export type DiscoveryEventType = 'discovered' | 'added' | 'lease_renewed' | 'lease_expired';
+export type DiscoveryEventType = 'discovered' | 'added' | 'rejected' | 'lease_renewed' | 'lease_expired' | 'serial_mismatch'
+  | 'provision_started' | 'provision_downloading_image' | 'provision_installing_image'
+  | 'provision_applying_config' | 'provision_completed' | 'provision_failed'
+  | 'pnp_unknown' | 'pnp_completed' | 'pnp_failed';
+
+// Approval queue types
+export type PendingDeviceStatus = 'pending' | 'approved' | 'rejected';
+
+export interface PendingDevice {
+  mac: string;
+  ip: string;
+  hostname?: string;
+  vendor?: string; // Detected from MAC OUI prefix
+  status: PendingDeviceStatus;
+  note?: string;
+  first_seen: string;
+  last_seen: string;
+  decided_at?: string;
+}
+
+export interface ApproveDeviceRequest {
+  mac?: string;
+  ip?: string;
+  hostname?: string;
+  vendor?: string;
+  model?: string;
+  serial_number?: string;
+  config_template?: string;
+  ssh_user?: string;
+  ssh_pass?: string;
+  allocate_ip?: boolean; // draw the IP from the NetBox prefix instead of the lease
+}
+
+export interface ApproveDeviceResult {
+  mac: string;
+  success: boolean;
+  device?: Device;
+  error?: string;
+}
+
+export interface BulkApproveResponse {
+  approved: number;
+  failed: number;
+  results: ApproveDeviceResult[];
+}
 
 export interface DiscoveryLog {
   id: number;
@@ -171,6 +367,7 @@ export interface DiscoveryLog {
   hostname?: string;
   vendor?: string;
   message?: string;
+  details?: string; // JSON payload a device sent with its callback
   created_at: string;
 }
 
@@ -221,7 +418,13 @@ export interface NetworkInterface {
 }
 
 // API Response types
+export interface FieldError {
+  field: string;
+  message: string;
+}
+
 export interface ApiError {
   error: string;
   code?: string;
+  fields?: FieldError[]; // Present on 400 validation failures
 }
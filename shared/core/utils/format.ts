@@ -61,7 +61,7 @@ export function formatExpiry(expiresAt: string): string {
 /**
  * Discovery event types
  */
-export type DiscoveryEventType = 'discovered' | 'added' | 'lease_renewed' | string;
+export type DiscoveryEventType = 'discovered' | 'added' | 'rejected' | 'lease_renewed' | 'serial_mismatch' | 'provision_completed' | 'provision_failed' | string;
 
 /**
  * Format a discovery event type as a human-readable label
@@ -74,8 +74,22 @@ export function formatEventType(eventType: DiscoveryEventType): string {
       return 'New Device';
     case 'added':
       return 'Device Added';
+    case 'rejected':
+      return 'Device Rejected';
     case 'lease_renewed':
       return 'Lease Renewed';
+    case 'serial_mismatch':
+      return 'Serial Mismatch';
+    case 'provision_completed':
+      return 'Provisioning Completed';
+    case 'provision_failed':
+      return 'Provisioning Failed';
+    case 'pnp_unknown':
+      return 'Unknown PnP Device';
+    case 'pnp_completed':
+      return 'PnP Completed';
+    case 'pnp_failed':
+      return 'PnP Failed';
     default:
       return eventType;
   }
@@ -92,8 +106,20 @@ export function getEventTypeIcon(eventType: DiscoveryEventType): string {
       return 'fiber_new';
     case 'added':
       return 'add_circle';
+    case 'rejected':
+      return 'block';
     case 'lease_renewed':
       return 'refresh';
+    case 'serial_mismatch':
+      return 'warning';
+    case 'provision_completed':
+    case 'pnp_completed':
+      return 'check_circle';
+    case 'provision_failed':
+    case 'pnp_failed':
+      return 'error';
+    case 'pnp_unknown':
+      return 'help';
     default:
       return 'schedule';
   }
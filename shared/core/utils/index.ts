@@ -13,6 +13,7 @@ export {
 
 export {
   validateMacAddress,
+  normalizeMacAddress,
   validateIpAddress,
   validateHostname,
   validateDeviceForm,
@@ -5,9 +5,29 @@ export interface ValidationResult {
   errors: Record<string, string>;
 }
 
+// Accepted MAC notations - must match ParseMac in backend/utils/mac.go
+const MAC_PATTERNS = [
+  /^[0-9A-Fa-f]{2}(:[0-9A-Fa-f]{2}){5}$/, // aa:bb:cc:dd:ee:ff
+  /^[0-9A-Fa-f]{2}(-[0-9A-Fa-f]{2}){5}$/, // aa-bb-cc-dd-ee-ff
+  /^[0-9A-Fa-f]{4}(\.[0-9A-Fa-f]{4}){2}$/, // aabb.ccdd.eeff (Cisco)
+  /^[0-9A-Fa-f]{12}$/, // aabbccddeeff
+];
+
 export function validateMacAddress(mac: string): boolean {
-  const macRegex = /^([0-9A-Fa-f]{2}[:-]){5}([0-9A-Fa-f]{2})$/;
-  return macRegex.test(mac);
+  const trimmed = mac.trim();
+  return MAC_PATTERNS.some((pattern) => pattern.test(trimmed));
+}
+
+/**
+ * Convert a MAC in any accepted notation to canonical aa:bb:cc:dd:ee:ff form.
+ * Returns null if the MAC is not valid.
+ */
+export function normalizeMacAddress(mac: string): string | null {
+  if (!validateMacAddress(mac)) {
+    return null;
+  }
+  const hex = mac.trim().replace(/[:.-]/g, '').toLowerCase();
+  return hex.match(/.{2}/g)!.join(':');
 }
 
 export function validateIpAddress(ip: string): boolean {
@@ -15,9 +35,20 @@ export function validateIpAddress(ip: string): boolean {
   return ipRegex.test(ip);
 }
 
+// RFC 1123 hostname - must match validate.Hostname in backend/validate/validate.go
 export function validateHostname(hostname: string): boolean {
-  const hostnameRegex = /^[a-zA-Z0-9]([a-zA-Z0-9-]*[a-zA-Z0-9])?$/;
-  return hostname.length <= 63 && hostnameRegex.test(hostname);
+  const labelRegex = /^[a-zA-Z0-9]([a-zA-Z0-9-]*[a-zA-Z0-9])?$/;
+  if (hostname.length > 253) {
+    return false;
+  }
+  return hostname
+    .replace(/\.$/, '')
+    .split('.')
+    .every((label) => label.length <= 63 && labelRegex.test(label));
+}
+
+function ipToNumber(ip: string): number {
+  return ip.split('.').reduce((acc, octet) => acc * 256 + parseInt(octet, 10), 0);
 }
 
 export function validateDeviceForm(data: {
@@ -30,7 +61,7 @@ export function validateDeviceForm(data: {
   if (!data.mac) {
     errors.mac = 'MAC address is required';
   } else if (!validateMacAddress(data.mac)) {
-    errors.mac = 'Invalid MAC address format (use aa:bb:cc:dd:ee:ff)';
+    errors.mac = 'Invalid MAC address (use aa:bb:cc:dd:ee:ff, aa-bb-cc-dd-ee-ff, aabb.ccdd.eeff or aabbccddeeff)';
   }
 
   if (!data.ip) {
@@ -42,7 +73,7 @@ export function validateDeviceForm(data: {
   if (!data.hostname) {
     errors.hostname = 'Hostname is required';
   } else if (!validateHostname(data.hostname)) {
-    errors.hostname = 'Invalid hostname (alphanumeric and hyphens only, max 63 chars)';
+    errors.hostname = 'Invalid hostname (letters, digits and hyphens per label, max 63 chars per label)';
   }
 
   return {
@@ -76,6 +107,11 @@ export function validateSettingsForm(data: {
     errors.tftp_server_ip = 'Invalid IP address';
   }
 
+  if (!errors.dhcp_range_start && !errors.dhcp_range_end &&
+      ipToNumber(data.dhcp_range_start) > ipToNumber(data.dhcp_range_end)) {
+    errors.dhcp_range_end = 'Range end must not be before range start';
+  }
+
   return {
     valid: Object.keys(errors).length === 0,
     errors,